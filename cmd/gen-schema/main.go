@@ -0,0 +1,47 @@
+// Command gen-schema writes the complete database schema, as built by the migrations
+// in the migrations directory, to a single SQL file that can be reviewed as a whole and
+// applied to an empty database with psql. It is run by go generate in that directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+func main() {
+	out := flag.String("o", "schema_postgres.sql", "file to write the schema to")
+	flag.Parse()
+
+	var buf bytes.Buffer
+	writeSchema(&buf, memory.Migrations)
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		slog.Error("failed to write schema", slog.String("path", *out), slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+// writeSchema writes the statements of migrations to w in order, each preceded by a
+// comment naming its version and followed by the statement recording it in
+// schema_migrations, so that the agent treats a database created from the schema like
+// one it migrated itself. The output only depends on migrations, so that it can be
+// compared with the committed file.
+func writeSchema(w io.Writer, migrations []memory.Migration) {
+	fmt.Fprintln(w, "-- Code generated by gen-schema from the migrations in this directory. DO NOT EDIT.")
+	fmt.Fprintln(w, "--")
+	fmt.Fprintf(w, "-- Database schema at migration version %d. Apply it to an empty database with\n", len(migrations))
+	fmt.Fprintln(w, "--   psql -v ON_ERROR_STOP=1 --single-transaction -f schema_postgres.sql")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, memory.MigrationsTableSQL)
+	for _, m := range migrations {
+		fmt.Fprintf(w, "\n-- Version %d: %s\n\n", m.Version, m.Name)
+		fmt.Fprintln(w, strings.TrimSpace(m.SQL))
+		fmt.Fprintf(w, "\nINSERT INTO schema_migrations (version, name) VALUES (%d, '%s');\n", m.Version, strings.ReplaceAll(m.Name, "'", "''"))
+	}
+}
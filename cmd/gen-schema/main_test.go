@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+func TestWriteSchema(t *testing.T) {
+	var buf bytes.Buffer
+	writeSchema(&buf, memory.Migrations)
+	schema := buf.String()
+
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS schema_migrations",
+		"CREATE TABLE project_rules",
+		"CREATE TABLE issue_history",
+		"-- Version 1: 001_init.sql",
+		"INSERT INTO schema_migrations (version, name) VALUES (1, '001_init.sql');",
+	} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("schema does not contain %q", want)
+		}
+	}
+	last := memory.Migrations[len(memory.Migrations)-1]
+	if !strings.Contains(schema, fmt.Sprintf("-- Version %d: %s", last.Version, last.Name)) {
+		t.Errorf("schema does not contain the last migration %s", last.Name)
+	}
+}
+
+// TestSchemaUpToDate fails when the committed schema was not regenerated after a
+// migration was added or changed.
+func TestSchemaUpToDate(t *testing.T) {
+	committed, err := os.ReadFile(filepath.Join("..", "..", "migrations", "schema_postgres.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writeSchema(&buf, memory.Migrations)
+	if !bytes.Equal(committed, buf.Bytes()) {
+		t.Error("migrations/schema_postgres.sql is out of date, run go generate ./migrations")
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+const (
+	// browsePageSize is the number of experiences the browse TUI shows per page.
+	browsePageSize = 15
+	// browseSearchLimit is the number of experiences a browse search fetches.
+	browseSearchLimit = 100
+	// browseSearchAlpha is the weight of vector similarity against keyword match in
+	// browse searches, like in the hybrid mode of search_past_issues.
+	browseSearchAlpha = 0.5
+	// browsePatternRunes is the length at which the browse list cuts error patterns.
+	browsePatternRunes = 60
+)
+
+// listedExperience is an experience printed by the list sub-command with --json.
+type listedExperience struct {
+	ID           int       `json:"id"`
+	ProjectID    string    `json:"project_id,omitempty"`
+	Source       string    `json:"source"`
+	ErrorPattern string    `json:"error_pattern"`
+	RootCause    string    `json:"root_cause"`
+	Solution     string    `json:"solution"`
+	Tags         []string  `json:"tags,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// runList prints every experience, one line each, or as a JSON array with --json.
+func runList(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the experiences as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	experiences, err := env.store.ListExperiences(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list experiences: %w", err)
+	}
+	if *asJSON {
+		listed := make([]listedExperience, 0, len(experiences))
+		for _, exp := range experiences {
+			listed = append(listed, listedExperience{
+				ID:           exp.ID,
+				ProjectID:    exp.ProjectID,
+				Source:       exp.Source,
+				ErrorPattern: exp.ErrorPattern,
+				RootCause:    exp.RootCause,
+				Solution:     exp.Solution,
+				Tags:         exp.Tags,
+				OccurredAt:   exp.OccurredAt,
+			})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(listed)
+	}
+	for _, exp := range experiences {
+		fmt.Printf("#%d\t%s\t%s\n", exp.ID, exp.OccurredAt.Local().Format(time.DateTime), truncateRunes(oneLine(exp.ErrorPattern), browsePatternRunes))
+	}
+	fmt.Printf("共 %d 条经验\n", len(experiences))
+	return nil
+}
+
+// runBrowse browses the experiences in an interactive TUI. When stdin or stdout is not a
+// terminal it prints them like `list --json` instead.
+func runBrowse(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return runList(ctx, env, []string{"--json"})
+	}
+
+	_, err := tea.NewProgram(newBrowser(ctx, env), tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// experiencesLoadedMsg carries the experiences listed or found by a browse search.
+type experiencesLoadedMsg struct {
+	experiences []memory.Experience
+	query       string // Search the experiences were found by, empty when listed
+}
+
+// experienceDeletedMsg reports that the experience with the given ID was soft-deleted.
+type experienceDeletedMsg struct {
+	id int
+}
+
+// browseErrMsg reports a failed store operation.
+type browseErrMsg struct {
+	err error
+}
+
+// browser is the bubbletea model of the browse sub-command: a paginated list of
+// experiences with a search prompt and a detail pane.
+type browser struct {
+	ctx context.Context
+	env *commandEnv
+
+	experiences []memory.Experience
+	query       string // Search the list shows the results of, empty when listing everything
+	cursor      int    // Index of the selected experience
+	loading     bool
+	status      string // Outcome of the last operation
+
+	searching bool   // Whether the search prompt has focus
+	input     string // Text typed at the search prompt
+	detail    bool   // Whether the detail pane of the selected experience is shown
+}
+
+// newBrowser creates a browser over the experiences of env's store.
+func newBrowser(ctx context.Context, env *commandEnv) *browser {
+	return &browser{ctx: ctx, env: env, loading: true}
+}
+
+// Init loads every experience.
+func (b *browser) Init() tea.Cmd {
+	return b.load("")
+}
+
+// load returns a command listing every experience, or searching for query with the
+// hybrid search when it is not empty.
+func (b *browser) load(query string) tea.Cmd {
+	ctx, env := b.ctx, b.env
+	return func() tea.Msg {
+		if query == "" {
+			experiences, err := env.store.ListExperiences(ctx)
+			if err != nil {
+				return browseErrMsg{fmt.Errorf("failed to list experiences: %w", err)}
+			}
+			return experiencesLoadedMsg{experiences: experiences}
+		}
+		embedding, err := env.embedder.Embed(ctx, query)
+		if err != nil {
+			return browseErrMsg{fmt.Errorf("failed to generate embedding: %w", err)}
+		}
+		experiences, err := env.store.HybridSearch(ctx, query, embedding, browseSearchLimit, browseSearchAlpha, env.projectID, true)
+		if err != nil {
+			return browseErrMsg{fmt.Errorf("failed to search experiences: %w", err)}
+		}
+		return experiencesLoadedMsg{experiences: experiences, query: query}
+	}
+}
+
+// remove returns a command soft-deleting the experience with the given ID.
+func (b *browser) remove(id int) tea.Cmd {
+	ctx, store := b.ctx, b.env.store
+	return func() tea.Msg {
+		if err := store.DeleteExperience(ctx, id); err != nil {
+			return browseErrMsg{fmt.Errorf("failed to delete experience %d: %w", id, err)}
+		}
+		return experienceDeletedMsg{id: id}
+	}
+}
+
+// Update handles key presses and the results of store operations.
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case experiencesLoadedMsg:
+		b.experiences, b.query, b.cursor, b.loading = msg.experiences, msg.query, 0, false
+		b.status = ""
+		return b, nil
+	case experienceDeletedMsg:
+		for i, exp := range b.experiences {
+			if exp.ID == msg.id {
+				b.experiences = append(b.experiences[:i], b.experiences[i+1:]...)
+				break
+			}
+		}
+		b.cursor = max(0, min(b.cursor, len(b.experiences)-1))
+		b.detail = false
+		b.status = fmt.Sprintf("已删除经验 #%d", msg.id)
+		return b, nil
+	case browseErrMsg:
+		b.loading = false
+		b.status = msg.err.Error()
+		return b, nil
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return b, tea.Quit
+		}
+		switch {
+		case b.searching:
+			return b.updateSearch(msg)
+		case b.detail:
+			return b.updateDetail(msg)
+		}
+		return b.updateList(msg)
+	}
+	return b, nil
+}
+
+// updateSearch handles a key press at the search prompt.
+func (b *browser) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		b.searching, b.loading = false, true
+		return b, b.load(strings.TrimSpace(b.input))
+	case tea.KeyEsc:
+		b.searching = false
+	case tea.KeyBackspace:
+		if runes := []rune(b.input); len(runes) > 0 {
+			b.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		b.input += " "
+	case tea.KeyRunes:
+		b.input += string(msg.Runes)
+	}
+	return b, nil
+}
+
+// updateDetail handles a key press in the detail pane.
+func (b *browser) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "esc", "q":
+		b.detail = false
+	case "D":
+		return b, b.remove(b.experiences[b.cursor].ID)
+	}
+	return b, nil
+}
+
+// updateList handles a key press in the list.
+func (b *browser) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		return b, tea.Quit
+	case "up", "k":
+		b.cursor = max(0, b.cursor-1)
+	case "down", "j":
+		b.cursor = max(0, min(b.cursor+1, len(b.experiences)-1))
+	case "left", "pgup":
+		b.cursor = max(0, b.cursor-browsePageSize)
+	case "right", "pgdown":
+		b.cursor = max(0, min(b.cursor+browsePageSize, len(b.experiences)-1))
+	case "/":
+		b.searching, b.input = true, ""
+	case "enter":
+		if len(b.experiences) > 0 {
+			b.detail = true
+		}
+	case "D":
+		if len(b.experiences) > 0 {
+			return b, b.remove(b.experiences[b.cursor].ID)
+		}
+	}
+	return b, nil
+}
+
+// View renders the detail pane of the selected experience or the current page of the list.
+func (b *browser) View() string {
+	if b.detail {
+		return b.viewDetail()
+	}
+
+	var sb strings.Builder
+	if b.query != "" {
+		fmt.Fprintf(&sb, "搜索结果：%q（%d 条）\n\n", b.query, len(b.experiences))
+	} else {
+		fmt.Fprintf(&sb, "全部经验（%d 条）\n\n", len(b.experiences))
+	}
+	switch {
+	case b.loading:
+		sb.WriteString("加载中...\n")
+	case len(b.experiences) == 0:
+		sb.WriteString("没有经验\n")
+	default:
+		page := b.cursor / browsePageSize
+		start := page * browsePageSize
+		end := min(start+browsePageSize, len(b.experiences))
+		for i := start; i < end; i++ {
+			exp := b.experiences[i]
+			marker := "  "
+			if i == b.cursor {
+				marker = "> "
+			}
+			score := ""
+			if b.query != "" {
+				score = fmt.Sprintf("  %.2f", exp.SimilarityScore)
+			}
+			fmt.Fprintf(&sb, "%s#%-6d %-*s%s  %s\n", marker, exp.ID, browsePatternRunes+3, truncateRunes(oneLine(exp.ErrorPattern), browsePatternRunes), score, exp.OccurredAt.Local().Format(time.DateTime))
+		}
+		pages := (len(b.experiences) + browsePageSize - 1) / browsePageSize
+		fmt.Fprintf(&sb, "\n第 %d/%d 页\n", page+1, pages)
+	}
+
+	if b.searching {
+		fmt.Fprintf(&sb, "\n搜索：%s█\n", b.input)
+	} else if b.status != "" {
+		fmt.Fprintf(&sb, "\n%s\n", b.status)
+	}
+	sb.WriteString("\n↑/↓ 选择  ←/→ 翻页  / 搜索  Enter 详情  D 删除  q 退出\n")
+	return sb.String()
+}
+
+// viewDetail renders every field of the selected experience.
+func (b *browser) viewDetail() string {
+	exp := b.experiences[b.cursor]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "经验 #%d\n\n", exp.ID)
+	fmt.Fprintf(&sb, "错误模式：%s\n\n", exp.ErrorPattern)
+	fmt.Fprintf(&sb, "根本原因：%s\n\n", exp.RootCause)
+	fmt.Fprintf(&sb, "解决方案：%s\n\n", exp.Solution)
+	if len(exp.Tags) > 0 {
+		fmt.Fprintf(&sb, "标签：%s\n", strings.Join(exp.Tags, ", "))
+	}
+	if exp.ProjectID != "" {
+		fmt.Fprintf(&sb, "项目：%s\n", exp.ProjectID)
+	}
+	fmt.Fprintf(&sb, "来源：%s\n", exp.Source)
+	fmt.Fprintf(&sb, "时间：%s\n", exp.OccurredAt.Local().Format(time.DateTime))
+	if b.query != "" {
+		fmt.Fprintf(&sb, "相似度：%.2f\n", exp.SimilarityScore)
+	}
+	sb.WriteString("\nEnter/Esc 返回  D 删除\n")
+	return sb.String()
+}
+
+// oneLine collapses the whitespace of s, including line breaks, into single spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncateRunes shortens s to at most limit runes, appending "..." when truncated.
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "..."
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// keywordEmbedder embeds texts mentioning "deadlock" and other texts in orthogonal
+// directions.
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if strings.Contains(text, "deadlock") {
+		return []float32{1, 0}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+// newTestBrowser creates a browser over a store holding n experiences, the second of
+// which is about a deadlock, and runs its initial load.
+func newTestBrowser(t *testing.T, n int) (*browser, *memory.InMemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewInMemoryStore()
+	for i := 1; i <= n; i++ {
+		pattern := fmt.Sprintf("error number %d", i)
+		if i == 2 {
+			pattern = "deadlock in worker pool"
+		}
+		vector, _ := keywordEmbedder{}.Embed(ctx, pattern)
+		if err := store.SaveExperience(ctx, "", pattern, "cause", fmt.Sprintf("solution %d", i), nil, vector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := newBrowser(ctx, &commandEnv{store: store, embedder: keywordEmbedder{}})
+	run(t, b, b.Init())
+	return b, store
+}
+
+// run executes cmd and feeds its message, and those of the commands that follow, to b.
+// It reports whether the browser quit.
+func run(t *testing.T, b *browser, cmd tea.Cmd) bool {
+	t.Helper()
+	for cmd != nil {
+		msg := cmd()
+		if _, ok := msg.(tea.QuitMsg); ok {
+			return true
+		}
+		_, cmd = b.Update(msg)
+	}
+	return false
+}
+
+// press sends keys to b, running the commands they return, and reports whether the
+// browser quit. Keys are key names such as "down" or "enter", or text to type.
+func press(t *testing.T, b *browser, keys ...string) bool {
+	t.Helper()
+	names := map[string]tea.KeyType{
+		"up": tea.KeyUp, "down": tea.KeyDown, "left": tea.KeyLeft, "right": tea.KeyRight,
+		"enter": tea.KeyEnter, "esc": tea.KeyEsc, "backspace": tea.KeyBackspace, "ctrl+c": tea.KeyCtrlC,
+	}
+	for _, key := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		if kt, ok := names[key]; ok {
+			msg = tea.KeyMsg{Type: kt}
+		}
+		_, cmd := b.Update(msg)
+		if run(t, b, cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBrowser_NavigateAndPaginate(t *testing.T) {
+	b, _ := newTestBrowser(t, browsePageSize+5)
+
+	view := b.View()
+	if !strings.Contains(view, "> #1 ") || !strings.Contains(view, "第 1/2 页") {
+		t.Fatalf("initial view does not select #1 on page 1/2:\n%s", view)
+	}
+	if strings.Contains(view, fmt.Sprintf("#%d ", browsePageSize+1)) {
+		t.Errorf("page 1 shows experience #%d:\n%s", browsePageSize+1, view)
+	}
+
+	press(t, b, "down", "down", "up")
+	if !strings.Contains(b.View(), "> #2 ") {
+		t.Errorf("after down, down, up the selection is not #2:\n%s", b.View())
+	}
+	press(t, b, "up", "up")
+	if b.cursor != 0 {
+		t.Errorf("cursor = %d after moving above the first experience, want 0", b.cursor)
+	}
+
+	press(t, b, "right")
+	view = b.View()
+	if !strings.Contains(view, fmt.Sprintf("> #%d ", browsePageSize+1)) || !strings.Contains(view, "第 2/2 页") {
+		t.Errorf("after right the selection is not #%d on page 2/2:\n%s", browsePageSize+1, view)
+	}
+	press(t, b, "right")
+	if b.cursor != browsePageSize+4 {
+		t.Errorf("cursor = %d after paging past the end, want the last experience %d", b.cursor, browsePageSize+4)
+	}
+	press(t, b, "left", "left")
+	if b.cursor != 0 {
+		t.Errorf("cursor = %d after paging back to the start, want 0", b.cursor)
+	}
+
+	if !press(t, b, "q") {
+		t.Error("q did not quit the browser")
+	}
+}
+
+func TestBrowser_Detail(t *testing.T) {
+	b, _ := newTestBrowser(t, 3)
+
+	press(t, b, "down", "enter")
+	view := b.View()
+	for _, want := range []string{"经验 #2", "错误模式：deadlock in worker pool", "根本原因：cause", "解决方案：solution 2"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail view does not contain %q:\n%s", want, view)
+		}
+	}
+	if press(t, b, "q") {
+		t.Fatal("q in the detail pane quit the browser, want it to return to the list")
+	}
+	if !strings.Contains(b.View(), "> #2 ") {
+		t.Errorf("after leaving the detail pane the list does not select #2:\n%s", b.View())
+	}
+}
+
+func TestBrowser_Search(t *testing.T) {
+	b, _ := newTestBrowser(t, 3)
+
+	press(t, b, "/", "dead", "x", "backspace", "lock")
+	if !strings.Contains(b.View(), "搜索：deadlock") {
+		t.Errorf("search prompt does not show the typed query:\n%s", b.View())
+	}
+	press(t, b, "enter")
+	view := b.View()
+	if !strings.Contains(view, `搜索结果："deadlock"`) || !strings.Contains(view, "> #2 ") || !strings.Contains(view, "1.00") {
+		t.Errorf("search results do not select #2 with its score:\n%s", view)
+	}
+
+	// An empty search lists every experience again
+	press(t, b, "/", "enter")
+	if b.query != "" || len(b.experiences) != 3 || strings.Contains(b.View(), "1.00") {
+		t.Errorf("after an empty search query = %q with %d experiences, want all 3 without scores", b.query, len(b.experiences))
+	}
+
+	// Esc leaves the prompt without searching
+	press(t, b, "/", "error", "esc")
+	if b.searching || b.query != "" {
+		t.Errorf("after esc searching = %v, query = %q, want the list", b.searching, b.query)
+	}
+}
+
+func TestBrowser_Delete(t *testing.T) {
+	b, store := newTestBrowser(t, 3)
+
+	press(t, b, "down", "D")
+	if _, err := store.GetExperience(context.Background(), 2); !errors.Is(err, memory.ErrNotFound) {
+		t.Errorf("GetExperience(2) after D error = %v, want ErrNotFound", err)
+	}
+	view := b.View()
+	if len(b.experiences) != 2 || strings.Contains(view, "#2 ") || !strings.Contains(view, "已删除经验 #2") {
+		t.Errorf("after D the list still shows #2 or no status:\n%s", view)
+	}
+
+	// Deleting from the detail pane returns to the list
+	press(t, b, "enter", "D")
+	if b.detail || len(b.experiences) != 1 {
+		t.Errorf("after D in the detail pane detail = %v with %d experiences, want the list with 1", b.detail, len(b.experiences))
+	}
+
+	press(t, b, "D")
+	if !strings.Contains(b.View(), "没有经验") {
+		t.Errorf("after deleting every experience the view is not empty:\n%s", b.View())
+	}
+	press(t, b, "D", "enter", "down")
+	if b.detail || b.cursor != 0 {
+		t.Errorf("keys on an empty list changed detail = %v, cursor = %d", b.detail, b.cursor)
+	}
+}
+
+func TestBrowser_CtrlCQuitsFromSearch(t *testing.T) {
+	b, _ := newTestBrowser(t, 1)
+	if !press(t, b, "/", "ctrl+c") {
+		t.Error("ctrl+c at the search prompt did not quit the browser")
+	}
+}
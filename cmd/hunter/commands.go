@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// commandEnv holds the dependencies available to CLI sub-commands.
+type commandEnv struct {
+	store     memory.Store
+	embedder  memory.Embedder
+	workDir   string
+	projectID string
+	migrator  memory.MigrationRunner  // Applies pending database migrations, nil for stores without a schema
+	costs     memory.SessionCostStore // Where the cost of ended sessions is recorded
+	sessions  memory.SessionStore     // Saved conversation histories
+	stdin     io.Reader               // Answers to confirmation prompts
+	// newGenerator creates a generator with the chat model, for sub-commands that ask the
+	// LLM; it is only called when needed, as it requires an API key
+	newGenerator func(ctx context.Context) (memory.Generator, error)
+}
+
+// commandFunc is the signature of a CLI sub-command handler.
+// args contains the command line arguments following the sub-command name.
+type commandFunc func(ctx context.Context, env *commandEnv, args []string) error
+
+// commands maps sub-command names to their handlers. Sub-commands operate on the
+// memory store directly and exit instead of starting the interactive launcher.
+var commands = map[string]commandFunc{
+	"export":         runExport,
+	"import":         runImport,
+	"export_kb":      runExportKB,
+	"import_kb":      runImportKB,
+	"import_md":      runImportMarkdown,
+	"index_codebase": runIndexCodebase,
+	"cluster":        runCluster,
+	"compact":        runCompact,
+	"stats":          runStats,
+	"list":           runList,
+	"browse":         runBrowse,
+	"scaffold":       runScaffold,
+	"show_cost":      runShowCost,
+	"diff_sessions":  runDiffSessions,
+}
+
+// runExport writes every experience to a newline-delimited JSON file for backup.
+func runExport(ctx context.Context, env *commandEnv, args []string) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("output", "experiences.jsonl", "path of the JSON Lines file to create")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", *output, err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", *output, cerr)
+		}
+	}()
+
+	if err := memory.ExportExperiences(ctx, env.store, f); err != nil {
+		return fmt.Errorf("failed to export experiences: %w", err)
+	}
+
+	fmt.Printf("经验已导出到 %s\n", *output)
+	return nil
+}
+
+// runImport imports the experiences of a JSON Lines file written by export, re-embedding each one.
+func runImport(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	input := fs.String("input", "experiences.jsonl", "path of the JSON Lines file to import")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", *input, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	count, err := memory.ImportExperiences(ctx, env.store, f, env.embedder)
+	if err != nil {
+		return fmt.Errorf("failed to import experiences after %d experiences: %w", count, err)
+	}
+
+	fmt.Printf("已从 %s 导入 %d 条经验\n", *input, count)
+	return nil
+}
+
+// runExportKB exports the whole knowledge base to a portable .kbz archive.
+func runExportKB(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("export_kb", flag.ContinueOnError)
+	output := fs.String("output", "knowledge.kbz", "path of the .kbz archive to create")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := memory.ExportKBZ(ctx, env.store, *output); err != nil {
+		return fmt.Errorf("failed to export knowledge base: %w", err)
+	}
+
+	fmt.Printf("知识库已导出到 %s\n", *output)
+	return nil
+}
+
+// runImportKB imports a .kbz archive into the knowledge base, re-embedding every experience.
+func runImportKB(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("import_kb", flag.ContinueOnError)
+	input := fs.String("input", "knowledge.kbz", "path of the .kbz archive to import")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := memory.ImportKBZ(ctx, env.store, *input, env.embedder)
+	if err != nil {
+		return fmt.Errorf("failed to import knowledge base after %d experiences: %w", count, err)
+	}
+
+	fmt.Printf("已从 %s 导入 %d 条经验\n", *input, count)
+	return nil
+}
+
+// runImportMarkdown imports the problems of a Markdown troubleshooting guide as experiences.
+func runImportMarkdown(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("import_md", flag.ContinueOnError)
+	input := fs.String("input", "TROUBLESHOOTING.md", "path of the Markdown file with ## problem, ### Cause and ### Solution sections")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := memory.ImportFromMarkdown(ctx, env.store, *input, env.embedder)
+	if err != nil {
+		return fmt.Errorf("failed to import Markdown after %d experiences: %w", count, err)
+	}
+
+	fmt.Printf("已从 %s 导入 %d 条经验\n", *input, count)
+	return nil
+}
+
+// runIndexCodebase ingests the Go symbols under the working directory into the knowledge base.
+func runIndexCodebase(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("index_codebase", flag.ContinueOnError)
+	pattern := fs.String("pattern", strings.Join(memory.DefaultIndexPatterns, ";"), "';'-separated file name patterns to index")
+	dryRun := fs.Bool("dry-run", false, "list the symbols that would be indexed without saving them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	patterns := strings.Split(*pattern, ";")
+
+	if *dryRun {
+		files, err := memory.ScanCodebase(env.workDir, patterns)
+		if err != nil {
+			return err
+		}
+		total := 0
+		for _, file := range files {
+			fmt.Printf("%s: %d 个符号\n", file.Path, len(file.Symbols))
+			total += len(file.Symbols)
+		}
+		fmt.Printf("共 %d 个文件、%d 个符号（未写入知识库）\n", len(files), total)
+		return nil
+	}
+
+	count, err := memory.IndexCodebase(ctx, env.workDir, env.store, env.embedder, patterns)
+	if err != nil {
+		return fmt.Errorf("failed to index codebase after %d symbols: %w", count, err)
+	}
+
+	fmt.Printf("已将 %d 个符号索引到知识库\n", count)
+	return nil
+}
+
+// runCluster groups similar experiences with DBSCAN and prints a summary of each cluster.
+func runCluster(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("cluster", flag.ContinueOnError)
+	eps := fs.Float64("eps", 0.05, "maximum cosine distance between neighbouring experiences")
+	minPts := fs.Int("min-pts", 3, "minimum number of neighbours, including itself, of an experience a cluster grows from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	clusters, err := memory.ClusterExperiences(ctx, env.store, float32(*eps), *minPts)
+	if err != nil {
+		return fmt.Errorf("failed to cluster experiences: %w", err)
+	}
+	for i, cluster := range clusters {
+		pattern := ""
+		if exp, err := env.store.GetExperience(ctx, cluster.RepresentativeID); err == nil {
+			pattern = exp.ErrorPattern
+		}
+		fmt.Printf("簇 %d：%d 条经验，代表 #%d %q\n  成员：%v\n", i+1, len(cluster.MemberIDs), cluster.RepresentativeID, pattern, cluster.MemberIDs)
+	}
+	fmt.Printf("共 %d 个簇\n", len(clusters))
+	return nil
+}
+
+// runCompact merges near-duplicate experiences: each experience within the similarity
+// threshold of an earlier one is soft-deleted, and its frequency added to the earlier one.
+func runCompact(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	threshold := fs.Float64("threshold", 0.98, "minimum cosine similarity of an experience to an earlier one for it to be merged")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *threshold <= 0 || *threshold > 1 {
+		return fmt.Errorf("--threshold must be in (0, 1], got %v", *threshold)
+	}
+
+	report, err := env.store.CompactExperiences(ctx, float32(*threshold))
+	if err != nil {
+		return fmt.Errorf("failed to compact experiences: %w", err)
+	}
+	fmt.Printf("检查了 %d 条经验，合并 %d 条重复经验，剩余 %d 条\n", report.ExaminedCount, report.MergedCount, report.RemainingCount)
+	return nil
+}
+
+// runStats prints how many experiences and rules are stored, how old the experiences are
+// and how much disk space the memory tables take.
+func runStats(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	stats, err := env.store.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load stats: %w", err)
+	}
+	fmt.Printf("经验：%d 条\n", stats.TotalExperiences)
+	fmt.Printf("规则：%d 条\n", stats.TotalRules)
+	if stats.TotalExperiences > 0 {
+		fmt.Printf("最早的经验：%s\n", stats.OldestExperience.Local().Format(time.DateTime))
+		fmt.Printf("最新的经验：%s\n", stats.NewestExperience.Local().Format(time.DateTime))
+	}
+	fmt.Printf("平均嵌入维度：%d\n", stats.AverageEmbeddingDimension)
+	fmt.Printf("存储空间：%.1f MB\n", float64(stats.StorageSizeBytes)/(1<<20))
+	if stats.LastOptimizedAt != nil {
+		fmt.Printf("上次优化索引：%s\n", stats.LastOptimizedAt.Local().Format(time.DateTime))
+	}
+	fmt.Printf("最近 %d 天新增的经验：\n", memory.StatsHistogramDays)
+	for day, count := range stats.ByAgeHistogram {
+		fmt.Printf("  %d 天前：%d 条\n", day, count)
+	}
+	return nil
+}
+
+// runShowCost prints the token usage and estimated API cost of the most recently ended
+// sessions, and their total.
+func runShowCost(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("show_cost", flag.ContinueOnError)
+	sessionID := fs.String("session", "", "only show the costs recorded for this session ID")
+	limit := fs.Int("limit", 20, "maximum number of sessions to show")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("--limit must be positive, got %d", *limit)
+	}
+
+	costs, err := env.costs.ListSessionCosts(ctx, *sessionID, *limit)
+	if err != nil {
+		return fmt.Errorf("failed to load session costs: %w", err)
+	}
+	var total float64
+	for _, c := range costs {
+		fmt.Printf("%s  %s  输入 %d tokens，输出 %d tokens，嵌入 %d 次，约 $%.4f\n",
+			c.EndedAt.Local().Format(time.DateTime), c.SessionID, c.InputTokens, c.OutputTokens, c.EmbeddingCalls, c.CostUSD)
+		total += c.CostUSD
+	}
+	fmt.Printf("共 %d 个会话，约 $%.4f\n", len(costs), total)
+	return nil
+}
+
+// runDiffSessions prints how a saved session differs from an earlier one of the same
+// investigation: the tool calls only one of them made, the experiences only the second
+// saved, and how their conclusions differ.
+func runDiffSessions(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("diff_sessions", flag.ContinueOnError)
+	sessionA := fs.String("a", "", "ID of the earlier session")
+	sessionB := fs.String("b", "", "ID of the session to compare with it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *sessionA == "" || *sessionB == "" {
+		return fmt.Errorf("both --a and --b are required")
+	}
+
+	generator, err := env.newGenerator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %w", err)
+	}
+	diff, err := memory.DiffSessions(ctx, env.sessions, generator, *sessionA, *sessionB)
+	if err != nil {
+		return fmt.Errorf("failed to compare sessions: %w", err)
+	}
+
+	printCalls := func(title string, calls []string) {
+		fmt.Printf("%s（%d）：\n", title, len(calls))
+		for _, call := range calls {
+			fmt.Printf("  %s\n", call)
+		}
+	}
+	printCalls("新增的工具调用", diff.NewToolCalls)
+	printCalls("不再进行的工具调用", diff.DroppedToolCalls)
+	fmt.Printf("新保存的经验：%d 条\n", diff.NewExperiencesSaved)
+	if diff.SummaryDiff != "" {
+		fmt.Printf("\n结论的变化：\n%s\n", diff.SummaryDiff)
+	}
+	return nil
+}
+
+// runMigrate applies pending database migrations. With --baseline it instead records
+// the migrations up to the given version as applied, for databases migrated by hand.
+// It runs before the automatic migration on startup, which fails for such databases.
+func runMigrate(ctx context.Context, store *memory.PostgresStore, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	baseline := fs.Int("baseline", 0, "record migrations up to this version as applied without running them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *baseline > 0 {
+		if err := store.BaselineMigrations(ctx, *baseline); err != nil {
+			return err
+		}
+		fmt.Printf("已将版本 %d 及之前的迁移记录为已执行\n", *baseline)
+	}
+	if err := store.Migrate(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("数据库已迁移到版本 %d\n", len(memory.Migrations))
+	return nil
+}
@@ -2,17 +2,31 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
 	internal "github.com/easeaico/adk-memory-agent/internal/agent"
+	"github.com/easeaico/adk-memory-agent/internal/api"
+	"github.com/easeaico/adk-memory-agent/internal/audit"
 	"github.com/easeaico/adk-memory-agent/internal/config"
+	"github.com/easeaico/adk-memory-agent/internal/logging"
 	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"github.com/easeaico/adk-memory-agent/internal/metrics"
+	"github.com/easeaico/adk-memory-agent/internal/otel"
+	"github.com/easeaico/adk-memory-agent/internal/retry"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/cmd/launcher"
 	"google.golang.org/adk/cmd/launcher/full"
@@ -20,7 +34,37 @@ import (
 
 // main is the entry point for the Legacy Code Hunter agent application.
 func main() {
-	cfg := config.Load()
+	// 读取配置：先将 .env 文件（--env-file，默认 ./.env）中未设置的变量加入环境，
+	// 指定 --config 时从 YAML 文件加载，环境变量优先
+	envFile, args := splitStringFlag(os.Args[1:], "env-file")
+	configPath, args := splitStringFlag(args, "config")
+	wsAddr, args := splitStringFlag(args, "ws-addr")
+	httpAddr, args := splitStringFlag(args, "http-addr")
+	forceConsolidate, args := splitBoolFlag(args, "force-consolidate")
+	sessionID, args := splitStringFlag(args, "session-id")
+	listSessions, args := splitBoolFlag(args, "list-sessions")
+	migrateEmbeddings, args := splitBoolFlag(args, "migrate-embeddings")
+	reindex, args := splitBoolFlag(args, "reindex")
+	envErr := config.LoadDotEnv(cmp.Or(envFile, config.DefaultDotEnvPath))
+	// 日志由 LOG_LEVEL 和 LOG_FORMAT 配置，可以写在 .env 文件中，因此在读取 .env 文件后立即初始化
+	if err := logging.Setup(); err != nil {
+		fatal("failed to configure logging", slog.Any("error", err))
+	}
+	if envErr != nil {
+		if !errors.Is(envErr, fs.ErrNotExist) {
+			fatal("failed to load env file", slog.Any("error", envErr))
+		}
+		slog.Warn("env file not loaded", slog.Any("error", envErr))
+	}
+	var cfg config.Config
+	if configPath != "" {
+		var err error
+		if cfg, err = config.LoadFile(configPath); err != nil {
+			fatal("failed to load config", slog.String("path", configPath), slog.Any("error", err))
+		}
+	} else {
+		cfg = config.Load()
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -32,44 +76,475 @@ func main() {
 		<-sigCh
 		fmt.Println("\n正在关闭...")
 		cancel()
+		if wsAddr != "" {
+			// WebSocket 服务会等待进行中的回答完成后自行退出
+			return
+		}
 		// 给一个短暂的时间让程序优雅关闭，然后强制退出
 		// 这是因为 launcher 可能在阻塞等待 stdin 输入，context 取消无法中断
 		time.Sleep(500 * time.Millisecond)
 		os.Exit(0)
 	}()
 
-	// 初始化数据库连接
-	store, err := memory.NewPostgresStore(ctx, cfg.DatabaseURL)
+	// 初始化链路追踪，未配置 OTLP 端点时使用 no-op 实现
+	tracerProvider, shutdownTracing, err := otel.NewTracerProvider(ctx, cfg.OTLPEndpoint)
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		fatal("failed to initialize tracing", slog.Any("error", err))
 	}
-	defer store.Close()
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Warn("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+	tracer := tracerProvider.Tracer(otel.TracerName)
 
-	// 初始化嵌入服务
-	embedder, err := memory.NewEmbedder(ctx, cfg.APIKey)
+	// 初始化数据库连接
+	pgStore, err := memory.NewPostgresStore(ctx, cfg.DatabaseURL, memory.StoreOptions{
+		DeduplicationThreshold: cfg.DeduplicationThreshold,
+		MaxAgeDays:             cfg.MaxExperienceAgeDays,
+		ProjectID:              cfg.ProjectID,
+		MaxConns:               int32(cfg.PostgresMaxConns),
+		MinConns:               int32(cfg.PostgresMinConns),
+		MaxConnIdleTime:        cfg.PostgresMaxConnIdleTime,
+		IndexType:              cfg.VectorIndexType,
+		IVFFlatLists:           cfg.IVFFlatLists,
+		HNSWM:                  cfg.HNSWM,
+		HNSWEfConstruction:     cfg.HNSWEfConstruction,
+	})
 	if err != nil {
-		log.Fatalf("failed to create embedder service: %v", err)
+		fatal("failed to connect to database", slog.Any("error", err))
 	}
+	defer pgStore.Close()
 
-	// 创建记忆服务
-	memoryService := memory.NewService(embedder, store)
+	// 执行数据库迁移；migrate 子命令可为手动迁移过的数据库记录版本
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(ctx, pgStore, args[1:]); err != nil {
+			fatal("migrate failed", slog.Any("error", err))
+		}
+		return
+	}
+	if err := pgStore.Migrate(ctx); err != nil {
+		if errors.Is(err, memory.ErrSchemaNotVersioned) {
+			fatal("failed to migrate database; record the migrations already applied with `migrate --baseline <version>`", slog.Any("error", err))
+		}
+		fatal("failed to migrate database", slog.Any("error", err))
+	}
+	// --reindex 立即重建向量索引；IVFFlat 索引在嵌入行数增长超过 20% 后也会在优化索引时自动重建
+	if reindex {
+		if err := pgStore.Reindex(ctx); err != nil {
+			fatal("failed to reindex", slog.Any("error", err))
+		}
+		fmt.Println("已重建向量索引")
+		return
+	}
+	store := memory.Store(memory.NewTracedStore(pgStore, tracer))
+
+	// 初始化嵌入服务，根据文本语言和长度选择嵌入模型
+	// 未单独配置的模型使用 EMBEDDING_MODEL
+	modelSelector := memory.SmartModelSelector{
+		ShortEnglishModel: cmp.Or(cfg.EmbeddingModelShort, cfg.EmbeddingModel),
+		LongModel:         cmp.Or(cfg.EmbeddingModelLong, cfg.EmbeddingModel),
+		MultilingualModel: cmp.Or(cfg.EmbeddingModelMultilingual, cfg.EmbeddingModel),
+		ThresholdChars:    cfg.EmbeddingLongThreshold,
+	}
+	var apiEmbedder memory.Embedder
+	if cfg.OllamaHost != "" {
+		// 使用本地 Ollama 生成嵌入，代码不会发送到外部 API
+		model := cfg.OllamaEmbedModel
+		if model == "" {
+			model = memory.DefaultOllamaEmbedModel
+		}
+		modelSelector = memory.SmartModelSelector{ShortEnglishModel: model, LongModel: model, MultilingualModel: model}
+		apiEmbedder = memory.NewOllamaEmbedder(cfg.OllamaHost, model)
+	} else {
+		apiEmbedder, err = memory.NewEmbedder(ctx, cfg.APIKey, modelSelector)
+		if err != nil {
+			fatal("failed to create embedder service", slog.Any("error", err))
+		}
+	}
+	pgStore.SetEmbeddingModelSelector(modelSelector)
+	// 估算每个会话的 API 费用，会话结束时记录到 session_costs 表；未配置的价格使用默认值
+	costs := metrics.NewCostTracker(metrics.CostRates{
+		InputPerMillion:     cmp.Or(float64(cfg.CostInputPerMillion), metrics.DefaultCostRates.InputPerMillion),
+		OutputPerMillion:    cmp.Or(float64(cfg.CostOutputPerMillion), metrics.DefaultCostRates.OutputPerMillion),
+		EmbeddingPerMillion: cmp.Or(float64(cfg.CostEmbeddingPerMillion), metrics.DefaultCostRates.EmbeddingPerMillion),
+	}, pgStore)
+	// 对限流等临时错误重试，并缓存嵌入结果，避免对相同文本重复调用 API；命中缓存的嵌入不计费
+	retryPolicy := retry.Policy{MaxAttempts: cfg.RetryMaxAttempts, InitialDelay: cfg.RetryInitialDelay}
+	uncachedEmbedder := memory.NewTracedEmbedder(memory.NewRetryingEmbedder(apiEmbedder, retryPolicy), tracer)
+	embedder := memory.NewCachedEmbedder(costs.InstrumentEmbedder(uncachedEmbedder), cfg.EmbeddingCacheSize, cfg.EmbeddingCacheTTL)
+
+	// 检查当前嵌入模型的维度与已存储的嵌入一致，--migrate-embeddings 用当前模型重新生成所有嵌入
+	if migrateEmbeddings {
+		meta, err := memory.ProbeEmbeddingMeta(ctx, embedder, modelSelector)
+		if err != nil {
+			fatal("failed to migrate embeddings", slog.Any("error", err))
+		}
+		count, err := pgStore.ReembedExperiences(ctx, embedder, meta)
+		if err != nil {
+			fatal("failed to migrate embeddings", slog.Any("error", err))
+		}
+		fmt.Printf("已使用 %s 重新生成 %d 条经验的嵌入（%d 维）\n", meta.ModelName, count, meta.Dimension)
+		return
+	}
+	if err := memory.ValidateEmbeddingCompatibility(ctx, pgStore, embedder, modelSelector); err != nil {
+		fatal("failed to validate embeddings", slog.Any("error", err))
+	}
+
+	// 记录 Prometheus 指标
+	agentMetrics := metrics.New(embedder)
+	store = agentMetrics.InstrumentStore(store)
+
+	// 执行子命令（如 export_kb / import_kb），完成后直接退出
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			newGenerator := func(ctx context.Context) (memory.Generator, error) {
+				return memory.NewGenerator(ctx, cfg.APIKey, cmp.Or(cfg.ChatModel, internal.DefaultChatModel))
+			}
+			if err := cmd(ctx, &commandEnv{store: store, embedder: embedder, workDir: cfg.WorkDir, projectID: cfg.ProjectID, migrator: pgStore, costs: pgStore, sessions: pgStore, stdin: os.Stdin, newGenerator: newGenerator}, args[1:]); err != nil {
+				fatal("command failed", slog.String("command", args[0]), slog.Any("error", err))
+			}
+			return
+		}
+	}
+
+	// 列出 --session-id 保存的会话
+	if listSessions {
+		if err := printSessions(ctx, pgStore); err != nil {
+			fatal("failed to list sessions", slog.Any("error", err))
+		}
+		return
+	}
+
+	// 暴露 /metrics 和供存活探针使用的 /healthz，随主 context 取消而关闭
+	metricsPort := cfg.MetricsPort
+	if metricsPort == 0 {
+		metricsPort = metrics.DefaultPort
+	}
+	// 健康检查绕过缓存，确保每次探测都真正调用嵌入 API，且不计入缓存命中率
+	health := metrics.HealthHandler(metrics.HealthChecks{Store: store, Embedder: uncachedEmbedder, WorkDir: cfg.WorkDir})
+	go func() {
+		if err := agentMetrics.Serve(ctx, fmt.Sprintf(":%d", metricsPort), health); err != nil {
+			slog.WarnContext(ctx, "metrics server failed", slog.Int("port", metricsPort), slog.Any("error", err))
+		}
+	}()
+
+	// 指定 --http-addr 时通过 REST API 共享知识库，随主 context 取消而关闭
+	if httpAddr != "" {
+		go func() {
+			if err := serveMemoryAPI(ctx, httpAddr, cfg.HTTPAuthToken, store, embedder, cfg.ProjectID); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.WarnContext(ctx, "memory API server failed", slog.String("addr", httpAddr), slog.Any("error", err))
+			}
+		}()
+	}
+
+	// 定期检查数据库连接，失败时重建连接
+	go memory.MonitorConnection(ctx, pgStore, memory.HealthCheckInterval, memory.ReconnectAttempts, memory.ReconnectDelay)
+
+	// 定期清理过期的经验和已删除的经验
+	deletedRetention := time.Duration(cfg.DeletedRetentionDays) * 24 * time.Hour
+	if cfg.MaxExperienceAgeDays > 0 || cfg.DeletedRetentionDays > 0 {
+		go purgeExpiredExperiences(ctx, store, 24*time.Hour, deletedRetention)
+	}
+
+	// 指定 INGEST_LOG_PATH 时持续读取日志文件，把匹配的新行保存为待补充原因和方案的经验
+	if cfg.IngestLogPath != "" {
+		pattern, err := regexp.Compile(cmp.Or(cfg.IngestLogPattern, memory.DefaultIngestionPattern))
+		if err != nil {
+			fatal("invalid log ingestion pattern", slog.String("pattern", cfg.IngestLogPattern), slog.Any("error", err))
+		}
+		ingestion := memory.BackgroundIngestionConfig{LogFilePath: cfg.IngestLogPath, Pattern: pattern, CheckInterval: cfg.IngestLogInterval}
+		if err := memory.StartBackgroundIngestion(ctx, ingestion, store, embedder); err != nil {
+			fatal("failed to start log ingestion", slog.Any("error", err))
+		}
+	}
+
+	// 记录工具调用审计日志，未配置 AUDIT_LOG_PATH 时不记录
+	var auditLog *audit.Logger
+	if cfg.AuditLogPath != "" {
+		if auditLog, err = audit.NewLogger(cfg.AuditLogPath, cfg.AuditLogMaxSizeMB); err != nil {
+			fatal("failed to open audit log", slog.String("path", cfg.AuditLogPath), slog.Any("error", err))
+		}
+		defer func() { _ = auditLog.Close() }()
+	}
 
 	// 初始化Agent
-	llmAgent, err := internal.NewHunterAgent(ctx, embedder, store, &cfg)
+	hunter, err := internal.NewHunterAgent(ctx, embedder, store, pgStore, tracer, agentMetrics, costs, auditLog, &cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize agent: %v", err)
+		fatal("failed to initialize agent", slog.Any("error", err))
+	}
+	// 退出时记录仍在进行的会话的费用
+	defer func() {
+		if err := costs.EndAll(context.Background()); err != nil {
+			slog.Warn("failed to record session costs", slog.Any("error", err))
+		}
+	}()
+
+	// 创建记忆服务
+	// 会话结束时先由整合模型（CONSOLIDATION_MODEL）评估解决质量，只保存得分足够高的会话
+	memoryService := memory.NewServiceWithQualityGate(embedder, store, cfg.ProjectID, memory.QualityGate{
+		Generator: hunter.ConsolidationGenerator(),
+		MinScore:  cfg.MinConsolidationScore,
+		Force:     forceConsolidate,
+	})
+
+	// evaluate 子命令用整合模型为 --session-id 保存的会话中最近的回答打分
+	if len(args) > 0 && args[0] == "evaluate" {
+		if err := runEvaluate(ctx, hunter, pgStore, sessionID, args[1:]); err != nil {
+			fatal("evaluate failed", slog.Any("error", err))
+		}
+		return
+	}
+
+	// 指定 --ws-addr 时通过 WebSocket 提供服务，替代交互式命令行
+	if wsAddr != "" {
+		if err := serveWebSocket(ctx, wsAddr, cfg.WSAuthToken, hunter); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("WebSocket server failed", slog.String("addr", wsAddr), slog.Any("error", err))
+		}
+		return
+	}
+
+	// 指定 --session-id 时在终端中进行可持久化的对话，重启后可用同一 ID 继续
+	if sessionID != "" {
+		if err := runSession(ctx, hunter, pgStore, sessionID); err != nil && !errors.Is(err, context.Canceled) {
+			fatal("session failed", slog.String("session_id", sessionID), slog.Any("error", err))
+		}
+		return
 	}
 
 	// 启动launcher
 	launcherConfig := &launcher.Config{
 		MemoryService: memoryService,
-		AgentLoader:   agent.NewSingleLoader(llmAgent),
+		AgentLoader:   agent.NewSingleLoader(hunter.Agent),
 	}
 	l := full.NewLauncher()
 
-	if err := l.Execute(ctx, launcherConfig, os.Args[1:]); err != nil {
+	if err := l.Execute(ctx, launcherConfig, args); err != nil {
 		if err != context.Canceled && err != context.DeadlineExceeded {
-			log.Fatalf("Failed to run agent: %v\n\n%s", err, l.CommandLineSyntax())
+			fmt.Fprintln(os.Stderr, l.CommandLineSyntax())
+			fatal("failed to run agent", slog.Any("error", err))
+		}
+	}
+}
+
+// fatal logs msg with args at error level and exits with status 1, like log.Fatal.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// splitStringFlag removes a "--<name> <value>" or "--<name>=<value>" flag, such as
+// "--config agent.yaml", from args and returns its value together with the remaining
+// arguments for the launcher.
+func splitStringFlag(args []string, name string) (string, []string) {
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--"+name && i+1 < len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--"+name+"="):
+			value = strings.TrimPrefix(args[i], "--"+name+"=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return value, rest
+}
+
+// splitBoolFlag removes a "--<name>" flag from args and reports whether it was present,
+// together with the remaining arguments for the launcher.
+func splitBoolFlag(args []string, name string) (bool, []string) {
+	var set bool
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--"+name {
+			set = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return set, rest
+}
+
+// wsDrainTimeout is how long the WebSocket server waits for answers in progress when
+// shutting down before canceling them.
+const wsDrainTimeout = 30 * time.Second
+
+// serveWebSocket serves hunter on addr until ctx is canceled, then drains the open
+// connections, see api.Server.
+func serveWebSocket(ctx context.Context, addr, token string, hunter *internal.HunterAgent) error {
+	server, err := api.NewServer(addr, token, func(ctx context.Context) (api.Chat, error) {
+		chat, err := hunter.NewChat(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return chat, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w (set WS_AUTH_TOKEN)", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	slog.InfoContext(ctx, "WebSocket 服务已启动", slog.String("url", "ws://"+addr+"/ws"))
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), wsDrainTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// memoryAPIShutdownTimeout is how long the memory REST server waits for requests in
+// progress when shutting down.
+const memoryAPIShutdownTimeout = 5 * time.Second
+
+// serveMemoryAPI serves store on addr over the REST API of api.MemoryServer until ctx is
+// canceled.
+func serveMemoryAPI(ctx context.Context, addr, token string, store memory.Store, embedder memory.Embedder, projectID string) error {
+	server, err := api.NewMemoryServer(addr, token, store, embedder, projectID)
+	if err != nil {
+		return fmt.Errorf("%w (set HTTP_AUTH_TOKEN)", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	slog.InfoContext(ctx, "知识库 REST 服务已启动", slog.String("url", "http://"+addr))
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), memoryAPIShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// runSession holds a conversation with hunter on stdin and stdout, resuming the one saved
+// in store as id and saving it after every answer, until stdin ends. The launcher's
+// console cannot restore a conversation, hence this minimal console of its own.
+func runSession(ctx context.Context, hunter *internal.HunterAgent, store memory.SessionStore, id string) error {
+	chat, err := hunter.StartSession(ctx, id, store)
+	if err != nil {
+		return err
+	}
+	history, err := chat.History(ctx)
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 {
+		fmt.Printf("已恢复会话 %s（%d 条历史记录）\n", id, len(history))
+	} else {
+		fmt.Printf("已创建会话 %s\n", id)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(nil, 1<<20)
+	for {
+		fmt.Print("\nUser -> ")
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		msg := strings.TrimSpace(scanner.Text())
+		if msg == "" {
+			continue
+		}
+
+		out := make(chan string)
+		errc := make(chan error, 1)
+		go func() { errc <- chat.ChatStream(ctx, msg, out) }()
+		fmt.Print("\nAgent -> ")
+		for text := range out {
+			fmt.Print(text)
+		}
+		fmt.Println()
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+}
+
+// runEvaluate scores the last answers, --last of them (default 5), of the conversation
+// saved in store as sessionID and prints the scores, marking low-scoring turns.
+func runEvaluate(ctx context.Context, hunter *internal.HunterAgent, store memory.SessionStore, sessionID string, args []string) error {
+	fs := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	last := fs.Int("last", 5, "number of most recent answers to score")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if sessionID == "" {
+		return errors.New("evaluate needs --session-id naming a saved conversation")
+	}
+
+	chat, err := hunter.StartSession(ctx, sessionID, store)
+	if err != nil {
+		return err
+	}
+	report, err := chat.SelfEvaluate(ctx, *last)
+	if err != nil {
+		return err
+	}
+	for i, turn := range report.Turns {
+		mark := ""
+		if turn.Flagged {
+			mark = "  [低分]"
+		}
+		fmt.Printf("%d. %s%s\n", i+1, truncateRunes(oneLine(turn.UserMessage), 80), mark)
+		fmt.Printf("   helpfulness %d  correctness %d  conciseness %d\n", turn.Scores[internal.CriterionHelpfulness], turn.Scores[internal.CriterionCorrectness], turn.Scores[internal.CriterionConciseness])
+		if turn.Comment != "" {
+			fmt.Printf("   %s\n", turn.Comment)
+		}
+	}
+	fmt.Printf("平均分：%.2f\n", report.AverageScore)
+	return nil
+}
+
+// printSessions prints the ID and last update time of the sessions saved in store.
+func printSessions(ctx context.Context, store memory.SessionStore) error {
+	sessions, err := store.ListSessions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		fmt.Printf("%s\t%s\n", s.ID, s.UpdatedAt.Local().Format(time.DateTime))
+	}
+	fmt.Printf("共 %d 个会话\n", len(sessions))
+	return nil
+}
+
+// purgeExpiredExperiences deletes expired experiences, and experiences deleted more than
+// deletedRetention ago unless it is zero, immediately and then once per interval until
+// ctx is cancelled, logging how many were deleted.
+func purgeExpiredExperiences(ctx context.Context, store memory.Store, interval, deletedRetention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := store.PurgeExpiredExperiences(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to purge expired experiences", slog.Any("error", err))
+		} else {
+			slog.InfoContext(ctx, "purged expired experiences", slog.Int64("deleted", deleted))
+		}
+		if deletedRetention > 0 {
+			deleted, err := store.PurgeDeleted(ctx, deletedRetention)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to purge deleted experiences", slog.Any("error", err))
+			} else {
+				slog.InfoContext(ctx, "purged deleted experiences", slog.Int64("deleted", deleted), slog.Duration("retention", deletedRetention))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 	}
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// scaffoldDBTypes are the db_type values a scaffold template may name.
+var scaffoldDBTypes = []string{"postgres"}
+
+// scaffoldTemplate is the YAML template read by the scaffold sub-command.
+type scaffoldTemplate struct {
+	DBType            string               `yaml:"db_type"`            // Database the template is for, see scaffoldDBTypes
+	Rules             []scaffoldRule       `yaml:"rules"`              // Rules added to the project
+	SampleExperiences []scaffoldExperience `yaml:"sample_experiences"` // Experiences saved for the project
+}
+
+// scaffoldRule is a project rule of a scaffold template.
+type scaffoldRule struct {
+	Category    string `yaml:"category"`
+	RuleContent string `yaml:"rule_content"`
+	Priority    int    `yaml:"priority"`
+}
+
+// scaffoldExperience is a sample experience of a scaffold template. Its tags are detected
+// from the error pattern when not given.
+type scaffoldExperience struct {
+	ErrorPattern string   `yaml:"error_pattern"`
+	RootCause    string   `yaml:"root_cause"`
+	Solution     string   `yaml:"solution"`
+	Tags         []string `yaml:"tags"`
+}
+
+// runScaffold sets up the memory of a new project from a YAML template: it migrates the
+// database, then adds the template's rules and saves its sample experiences. When the
+// store already holds experiences or rules it asks for confirmation first.
+func runScaffold(ctx context.Context, env *commandEnv, args []string) error {
+	fs := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	templatePath := fs.String("template", "template.yaml", "path of the YAML template with db_type, rules and sample_experiences")
+	dryRun := fs.Bool("dry-run", false, "print the operations without executing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tmpl, err := loadScaffoldTemplate(*templatePath)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Println("执行数据库迁移：")
+		for _, m := range memory.Migrations {
+			fmt.Printf("  %s\n", m.Name)
+		}
+		for _, rule := range tmpl.Rules {
+			fmt.Printf("添加规则 [%s]（优先级 %d）：%s\n", rule.Category, rule.Priority, rule.RuleContent)
+		}
+		for _, exp := range tmpl.SampleExperiences {
+			fmt.Printf("保存经验：%s\n", exp.ErrorPattern)
+		}
+		return nil
+	}
+
+	if env.migrator != nil {
+		if err := env.migrator.Migrate(ctx); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	stats, err := env.store.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load stats: %w", err)
+	}
+	if stats.TotalExperiences > 0 || stats.TotalRules > 0 {
+		fmt.Printf("数据库中已有 %d 条经验和 %d 条规则，模板中相同的规则会被覆盖。是否继续？[y/N] ", stats.TotalExperiences, stats.TotalRules)
+		if !confirmed(env.stdin) {
+			fmt.Println("已取消")
+			return nil
+		}
+	}
+
+	for _, rule := range tmpl.Rules {
+		if _, err := env.store.UpsertProjectRule(ctx, rule.Category, rule.RuleContent, rule.Priority); err != nil {
+			return fmt.Errorf("failed to add rule %q: %w", rule.RuleContent, err)
+		}
+	}
+	saved := 0
+	for _, exp := range tmpl.SampleExperiences {
+		vector, err := env.embedder.Embed(ctx, exp.ErrorPattern)
+		if err != nil {
+			return fmt.Errorf("failed to embed experience %q: %w", exp.ErrorPattern, err)
+		}
+		tags := exp.Tags
+		if len(tags) == 0 {
+			tags = memory.DetectTags(exp.ErrorPattern)
+		}
+		err = env.store.SaveExperience(ctx, "", exp.ErrorPattern, exp.RootCause, exp.Solution, tags, vector)
+		if errors.Is(err, memory.ErrDuplicateExperience) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save experience %q: %w", exp.ErrorPattern, err)
+		}
+		saved++
+	}
+
+	fmt.Printf("已添加 %d 条规则，保存 %d 条经验\n", len(tmpl.Rules), saved)
+	return nil
+}
+
+// loadScaffoldTemplate reads and validates the scaffold template at path.
+func loadScaffoldTemplate(path string) (*scaffoldTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	var tmpl scaffoldTemplate
+	if err := yaml.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+
+	if tmpl.DBType != "" && !slices.Contains(scaffoldDBTypes, tmpl.DBType) {
+		return nil, fmt.Errorf("unsupported db_type %q in %s, want one of %s", tmpl.DBType, path, strings.Join(scaffoldDBTypes, ", "))
+	}
+	for i, rule := range tmpl.Rules {
+		if rule.Category == "" || rule.RuleContent == "" {
+			return nil, fmt.Errorf("rule %d in %s: category and rule_content are required", i+1, path)
+		}
+	}
+	for i, exp := range tmpl.SampleExperiences {
+		if exp.ErrorPattern == "" || exp.Solution == "" {
+			return nil, fmt.Errorf("sample experience %d in %s: error_pattern and solution are required", i+1, path)
+		}
+	}
+	return &tmpl, nil
+}
+
+// confirmed reads a line from r and reports whether it answers yes.
+func confirmed(r io.Reader) bool {
+	if r == nil {
+		return false
+	}
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
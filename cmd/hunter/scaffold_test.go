@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// countMemory returns the number of rules and experiences in store.
+func countMemory(t *testing.T, store memory.Store) (rules, experiences int) {
+	t.Helper()
+	ctx := context.Background()
+	ruleList, err := store.ListProjectRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expList, err := store.ListExperiences(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(ruleList), len(expList)
+}
+
+// countingMigrator counts how often Migrate is called.
+type countingMigrator struct {
+	runs int
+}
+
+func (m *countingMigrator) Migrate(ctx context.Context) error {
+	m.runs++
+	return nil
+}
+
+func TestRunScaffold(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewInMemoryStore()
+	migrator := &countingMigrator{}
+	env := &commandEnv{store: store, embedder: keywordEmbedder{}, migrator: migrator}
+	args := []string{"--template", filepath.Join("testdata", "scaffold.yaml")}
+
+	if err := runScaffold(ctx, env, append(args, "--dry-run")); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if rules, experiences := countMemory(t, store); rules != 0 || experiences != 0 || migrator.runs != 0 {
+		t.Fatalf("dry run created %d rules and %d experiences and migrated %d times, want nothing", rules, experiences, migrator.runs)
+	}
+
+	if err := runScaffold(ctx, env, args); err != nil {
+		t.Fatalf("scaffold failed: %v", err)
+	}
+	if rules, experiences := countMemory(t, store); rules != 3 || experiences != 2 || migrator.runs != 1 {
+		t.Fatalf("scaffold created %d rules and %d experiences and migrated %d times, want 3, 2 and 1", rules, experiences, migrator.runs)
+	}
+	exps, _ := store.ListExperiences(ctx)
+	for _, exp := range exps {
+		if strings.Contains(exp.ErrorPattern, "deadlock") && strings.Join(exp.Tags, ",") != "concurrency,go" {
+			t.Errorf("deadlock experience tags = %v, want the template's [concurrency go]", exp.Tags)
+		}
+	}
+
+	// The store is not empty anymore: declining the prompt leaves it alone
+	if err := store.DeleteExperience(ctx, exps[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	env.stdin = strings.NewReader("n\n")
+	if err := runScaffold(ctx, env, args); err != nil {
+		t.Fatalf("declined scaffold failed: %v", err)
+	}
+	if _, experiences := countMemory(t, store); experiences != 1 {
+		t.Errorf("declined scaffold left %d experiences, want 1", experiences)
+	}
+
+	// Confirming it adds what is missing without duplicating the rest
+	env.stdin = strings.NewReader("y\n")
+	if err := runScaffold(ctx, env, args); err != nil {
+		t.Fatalf("confirmed scaffold failed: %v", err)
+	}
+	if rules, experiences := countMemory(t, store); rules != 3 || experiences != 2 {
+		t.Errorf("confirmed scaffold left %d rules and %d experiences, want 3 and 2", rules, experiences)
+	}
+}
+
+func TestLoadScaffoldTemplate_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"db_type":    "db_type: oracle\n",
+		"rule":       "rules:\n  - category: testing\n",
+		"experience": "sample_experiences:\n  - error_pattern: boom\n",
+		"yaml":       "rules: [\n",
+	} {
+		path := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadScaffoldTemplate(path); err == nil {
+			t.Errorf("loadScaffoldTemplate accepted the template with an invalid %s", name)
+		}
+	}
+}
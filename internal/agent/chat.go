@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// chatUserID is the user that the ChatStream conversation belongs to.
+const chatUserID = "user"
+
+// Chat is a conversation with the agent in a session of its own, so that several
+// conversations can run side by side.
+type Chat struct {
+	mu        sync.Mutex // Serializes ChatStream calls
+	runner    *runner.Runner
+	sessions  session.Service
+	appName   string
+	sessionID string
+
+	store   memory.SessionStore // Where the history is saved after every answer, if set
+	storeID string              // ID of the conversation in store
+
+	evaluator memory.Generator // Model scoring the answers in SelfEvaluate, without tools
+}
+
+// NewChat starts a new conversation with the agent.
+func (a *HunterAgent) NewChat(ctx context.Context) (*Chat, error) {
+	return a.newChat(ctx, nil)
+}
+
+// StartSession resumes the conversation saved in store as id, or starts it if store has
+// no history for id. The history is saved to store after every answer, so that the
+// conversation can be resumed after the process restarts.
+func (a *HunterAgent) StartSession(ctx context.Context, id string, store memory.SessionStore) (*Chat, error) {
+	history, err := store.LoadSession(ctx, id)
+	if err != nil && !errors.Is(err, memory.ErrNotFound) {
+		return nil, err
+	}
+	chat, err := a.newChat(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+	chat.store, chat.storeID = store, id
+	return chat, nil
+}
+
+// newChat starts a conversation whose session already holds the turns of history.
+func (a *HunterAgent) newChat(ctx context.Context, history []*genai.Content) (*Chat, error) {
+	sessions := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: a.Agent.Name(), Agent: a.Agent, SessionService: sessions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runner: %w", err)
+	}
+	created, err := sessions.Create(ctx, &session.CreateRequest{AppName: a.Agent.Name(), UserID: chatUserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	for _, content := range history {
+		// Model turns must be the agent's own, or the model is shown them as another agent's
+		event := session.NewEvent("")
+		event.Author = a.Agent.Name()
+		if content.Role == genai.RoleUser {
+			event.Author = chatUserID
+		}
+		event.Content = content
+		if err := sessions.AppendEvent(ctx, created.Session, event); err != nil {
+			return nil, fmt.Errorf("failed to restore session: %w", err)
+		}
+	}
+	return &Chat{runner: r, sessions: sessions, appName: a.Agent.Name(), sessionID: created.Session.ID(), evaluator: a.consolidation}, nil
+}
+
+// History returns the turns of the conversation so far: user messages, model responses,
+// tool calls and tool results, oldest first.
+func (c *Chat) History(ctx context.Context) ([]*genai.Content, error) {
+	resp, err := c.sessions.Get(ctx, &session.GetRequest{AppName: c.appName, UserID: chatUserID, SessionID: c.sessionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	var history []*genai.Content
+	for event := range resp.Session.Events().All() {
+		if event.Content != nil && !event.Partial {
+			history = append(history, event.Content)
+		}
+	}
+	return history, nil
+}
+
+// ChatStream sends userMessage to the agent in the agent's default conversation, see
+// Chat.ChatStream. The conversation is started by the first call.
+func (a *HunterAgent) ChatStream(ctx context.Context, userMessage string, out chan<- string) error {
+	a.chatMu.Lock()
+	if a.chat == nil {
+		chat, err := a.NewChat(ctx)
+		if err != nil {
+			a.chatMu.Unlock()
+			close(out)
+			return err
+		}
+		a.chat = chat
+	}
+	chat := a.chat
+	a.chatMu.Unlock()
+	return chat.ChatStream(ctx, userMessage, out)
+}
+
+// ChatStream sends userMessage to the agent and writes the text of its answer to out as
+// the model generates it. Tool calls made by the model are executed in between, while
+// no text is written. Successive calls continue the same conversation. out is closed
+// when the answer is complete or an error occurs, and calls are serialized, so each
+// call needs its own channel. Conversations started with StartSession are saved once the
+// answer is complete; failing to save is logged rather than failing the answer.
+func (c *Chat) ChatStream(ctx context.Context, userMessage string, out chan<- string) error {
+	defer close(out)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msg := genai.NewContentFromText(userMessage, genai.RoleUser)
+	var streamed strings.Builder // Text streamed for the current model response
+	for event, err := range c.runner.Run(ctx, chatUserID, c.sessionID, msg, agent.RunConfig{StreamingMode: agent.StreamingModeSSE}) {
+		if err != nil {
+			return fmt.Errorf("agent failed: %w", err)
+		}
+		text := eventText(event)
+
+		// The complete response follows its partial ones; only write it if it was not streamed
+		if !event.Partial {
+			if text == streamed.String() {
+				text = ""
+			}
+			streamed.Reset()
+		} else {
+			streamed.WriteString(text)
+		}
+		if text == "" {
+			continue
+		}
+
+		select {
+		case out <- text:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if c.store != nil {
+		c.save(ctx)
+	}
+	return nil
+}
+
+// save saves the history of the conversation to c.store.
+func (c *Chat) save(ctx context.Context) {
+	history, err := c.History(ctx)
+	if err == nil {
+		err = c.store.SaveSession(ctx, c.storeID, history)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to save session", slog.String("session_id", c.storeID), slog.Any("error", err))
+	}
+}
+
+// eventText returns the answer text of event, leaving out thoughts.
+func eventText(event *session.Event) string {
+	if event.Content == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range event.Content.Parts {
+		if !part.Thought {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
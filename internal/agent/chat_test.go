@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+// streamingLLM is a fake model that first calls the lookup tool and, once it has the
+// tool's response, streams its answer in chunks.
+type streamingLLM struct {
+	chunks []string
+}
+
+func (m *streamingLLM) Name() string { return "fake" }
+
+func (m *streamingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		last := req.Contents[len(req.Contents)-1]
+		if last.Parts[0].FunctionResponse == nil {
+			yield(&model.LLMResponse{Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				genai.NewPartFromFunctionCall("lookup", map[string]any{"key": "answer"}),
+			}}}, nil)
+			return
+		}
+
+		full := ""
+		for _, chunk := range m.chunks {
+			full += chunk
+			if stream && !yield(&model.LLMResponse{Content: genai.NewContentFromText(chunk, genai.RoleModel), Partial: true}, nil) {
+				return
+			}
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(full, genai.RoleModel), TurnComplete: true}, nil)
+	}
+}
+
+func TestHunterAgent_ChatStream(t *testing.T) {
+	type lookupArgs struct {
+		Key string `json:"key"`
+	}
+	var lookups []string
+	lookup, err := functiontool.New(functiontool.Config{Name: "lookup", Description: "looks up a value"},
+		func(ctx tool.Context, args lookupArgs) (map[string]any, error) {
+			lookups = append(lookups, args.Key)
+			return map[string]any{"value": 42}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := []string{"The ", "answer ", "is ", "42."}
+	llmAgent, err := llmagent.New(llmagent.Config{Name: "test_agent", Model: &streamingLLM{chunks: chunks}, Tools: []tool.Tool{lookup}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunter := &HunterAgent{Agent: llmAgent}
+
+	out := make(chan string)
+	errc := make(chan error, 1)
+	go func() { errc <- hunter.ChatStream(context.Background(), "what is the answer?", out) }()
+
+	var got []string
+	for token := range out {
+		got = append(got, token)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if !slices.Equal(got, chunks) {
+		t.Errorf("expected the chunks in order, each once, got %q", got)
+	}
+	if !slices.Equal(lookups, []string{"answer"}) {
+		t.Errorf("expected the tool to be called once mid-stream, got %v", lookups)
+	}
+}
+
+// recordingLLM is a fake model that answers the n-th request with "answer n" and records
+// the texts of the contents of each request.
+type recordingLLM struct {
+	requests [][]string
+}
+
+func (m *recordingLLM) Name() string { return "fake" }
+
+func (m *recordingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var texts []string
+		for _, c := range req.Contents {
+			for _, part := range c.Parts {
+				texts = append(texts, part.Text)
+			}
+		}
+		m.requests = append(m.requests, texts)
+		answer := fmt.Sprintf("answer %d", len(m.requests))
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(answer, genai.RoleModel), TurnComplete: true}, nil)
+	}
+}
+
+func TestHunterAgent_StartSession(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewInMemoryStore()
+	newHunter := func(llm model.LLM) *HunterAgent {
+		llmAgent, err := llmagent.New(llmagent.Config{Name: "test_agent", Model: llm})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &HunterAgent{Agent: llmAgent}
+	}
+	ask := func(chat *Chat, msg string) {
+		out := make(chan string)
+		errc := make(chan error, 1)
+		go func() { errc <- chat.ChatStream(ctx, msg, out) }()
+		for range out {
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("ChatStream failed: %v", err)
+		}
+	}
+
+	chat, err := newHunter(&recordingLLM{}).StartSession(ctx, "s1", store)
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	ask(chat, "why does it panic?")
+
+	// A new agent, as after a restart, resumes the saved conversation
+	llm := &recordingLLM{}
+	resumed, err := newHunter(llm).StartSession(ctx, "s1", store)
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	history, err := resumed.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	var texts []string
+	for _, c := range history {
+		texts = append(texts, c.Role+": "+c.Parts[0].Text)
+	}
+	if want := []string{"user: why does it panic?", "model: answer 1"}; !slices.Equal(texts, want) {
+		t.Fatalf("expected the prior turns in the history, got %q", texts)
+	}
+
+	ask(resumed, "and how do I fix it?")
+	if want := []string{"why does it panic?", "answer 1", "and how do I fix it?"}; len(llm.requests) != 1 || !slices.Equal(llm.requests[0], want) {
+		t.Errorf("expected the model to see the prior turns, got %q", llm.requests)
+	}
+	saved, err := store.LoadSession(ctx, "s1")
+	if err != nil || len(saved) != 4 {
+		t.Errorf("expected both exchanges to be saved, got %d turns, %v", len(saved), err)
+	}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/genai"
+)
+
+// Criteria every answer is scored on by SelfEvaluate, from 1 to 5.
+const (
+	CriterionHelpfulness = "helpfulness"
+	CriterionCorrectness = "correctness"
+	CriterionConciseness = "conciseness"
+)
+
+// evaluationCriteria are the criteria of SelfEvaluate in the order they are reported.
+var evaluationCriteria = []string{CriterionHelpfulness, CriterionCorrectness, CriterionConciseness}
+
+// lowScoreThreshold is the average score below which a turn is flagged.
+const lowScoreThreshold = 3
+
+// maxEvaluatedTextRunes caps each question and answer shown to the evaluating model.
+const maxEvaluatedTextRunes = 4000
+
+// TurnScore is the evaluation of the answer to one user message.
+type TurnScore struct {
+	UserMessage string         // The user message answered
+	Scores      map[string]int // Score from 1 to 5 by criterion
+	Comment     string         // Why the answer got these scores
+	Flagged     bool           // Whether the average of Scores is below 3
+}
+
+// EvaluationReport is the result of SelfEvaluate.
+type EvaluationReport struct {
+	Turns        []TurnScore // Evaluated turns, oldest first
+	AverageScore float64     // Average of all scores of all turns
+}
+
+// turn is a user message and the text of the agent's answer to it.
+type turn struct {
+	question string
+	answer   string
+}
+
+// SelfEvaluate scores the last lastN answers of the agent's default conversation, see
+// EvaluateTurns.
+func (a *HunterAgent) SelfEvaluate(ctx context.Context, lastN int) (*EvaluationReport, error) {
+	a.chatMu.Lock()
+	chat := a.chat
+	a.chatMu.Unlock()
+	if chat == nil {
+		return nil, errors.New("there is no conversation to evaluate")
+	}
+	return chat.SelfEvaluate(ctx, lastN)
+}
+
+// SelfEvaluate scores the last lastN answers of the conversation with the consolidation
+// model, see EvaluateTurns.
+func (c *Chat) SelfEvaluate(ctx context.Context, lastN int) (*EvaluationReport, error) {
+	if c.evaluator == nil {
+		return nil, errors.New("self-evaluation requires a generator")
+	}
+	history, err := c.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateTurns(ctx, c.evaluator, history, lastN)
+}
+
+// EvaluateTurns asks generator to score the last lastN answers of history on helpfulness,
+// correctness and conciseness, from 1 to 5. generator is a plain model call without
+// tools, so that the evaluation cannot call back into the agent. Turns whose average
+// score is below 3 are flagged.
+func EvaluateTurns(ctx context.Context, generator memory.Generator, history []*genai.Content, lastN int) (*EvaluationReport, error) {
+	if lastN <= 0 {
+		return nil, fmt.Errorf("the number of turns to evaluate must be positive, got %d", lastN)
+	}
+	turns := conversationTurns(history)
+	if len(turns) == 0 {
+		return nil, errors.New("there are no answered turns to evaluate")
+	}
+	turns = turns[max(len(turns)-lastN, 0):]
+
+	text, err := generator.Generate(ctx, evaluationPrompt(turns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate answers: %w", err)
+	}
+	var ratings []struct {
+		Index       int    `json:"index"`
+		Helpfulness int    `json:"helpfulness"`
+		Correctness int    `json:"correctness"`
+		Conciseness int    `json:"conciseness"`
+		Comment     string `json:"comment"`
+	}
+	if err := json.Unmarshal([]byte(memory.StripCodeFence(text)), &ratings); err != nil {
+		return nil, fmt.Errorf("failed to parse evaluation: %w", err)
+	}
+
+	report := &EvaluationReport{Turns: make([]TurnScore, len(turns))}
+	rated := make([]bool, len(turns))
+	total := 0
+	for _, r := range ratings {
+		if r.Index < 1 || r.Index > len(turns) || rated[r.Index-1] {
+			return nil, fmt.Errorf("evaluation has an unexpected turn index %d", r.Index)
+		}
+		scores := map[string]int{
+			CriterionHelpfulness: r.Helpfulness,
+			CriterionCorrectness: r.Correctness,
+			CriterionConciseness: r.Conciseness,
+		}
+		sum := 0
+		for _, criterion := range evaluationCriteria {
+			if scores[criterion] < 1 || scores[criterion] > 5 {
+				return nil, fmt.Errorf("%s score %d of turn %d is outside 1-5", criterion, scores[criterion], r.Index)
+			}
+			sum += scores[criterion]
+		}
+		rated[r.Index-1] = true
+		total += sum
+		report.Turns[r.Index-1] = TurnScore{
+			UserMessage: turns[r.Index-1].question,
+			Scores:      scores,
+			Comment:     r.Comment,
+			Flagged:     float64(sum)/float64(len(evaluationCriteria)) < lowScoreThreshold,
+		}
+	}
+	if len(ratings) != len(turns) {
+		return nil, fmt.Errorf("evaluation rates %d of %d turns", len(ratings), len(turns))
+	}
+	report.AverageScore = float64(total) / float64(len(turns)*len(evaluationCriteria))
+	return report, nil
+}
+
+// conversationTurns pairs the user messages of history with the text the model answered
+// them with. Tool calls and results are left out, and so are messages without an answer.
+func conversationTurns(history []*genai.Content) []turn {
+	var turns []turn
+	var current *turn
+	for _, content := range history {
+		text := contentText(content)
+		if text == "" {
+			continue // Tool calls and results
+		}
+		if content.Role == genai.RoleUser {
+			if current != nil && current.answer != "" {
+				turns = append(turns, *current)
+			}
+			current = &turn{question: text}
+			continue
+		}
+		if current != nil {
+			current.answer = strings.TrimSpace(current.answer + "\n" + text)
+		}
+	}
+	if current != nil && current.answer != "" {
+		turns = append(turns, *current)
+	}
+	return turns
+}
+
+// evaluationPrompt asks the model to rate the answers of turns as a JSON array.
+func evaluationPrompt(turns []turn) string {
+	var sb strings.Builder
+	sb.WriteString("请评估下面编程助手对用户问题的回答质量。对每一轮回答，分别从 helpfulness（是否有帮助）、correctness（是否正确）和 conciseness（是否简洁）三个方面打 1 到 5 的整数分，5 为最好，并用一句话说明理由。\n")
+	fmt.Fprintf(&sb, "只输出 JSON 数组，每轮一个对象，共 %d 个，格式为 [{\"index\": 1, \"helpfulness\": 5, \"correctness\": 5, \"conciseness\": 5, \"comment\": \"...\"}]。\n", len(turns))
+	for i, t := range turns {
+		fmt.Fprintf(&sb, "\n第 %d 轮\n用户：\n%s\n回答：\n%s\n", i+1, truncateRunes(t.question, maxEvaluatedTextRunes), truncateRunes(t.answer, maxEvaluatedTextRunes))
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// scriptedGenerator answers every prompt with response and records the prompts.
+type scriptedGenerator struct {
+	response string
+	prompts  []string
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	g.prompts = append(g.prompts, prompt)
+	return g.response, nil
+}
+
+// evaluationHistory is a conversation of three answered questions, the second answered
+// after a tool call.
+func evaluationHistory() []*genai.Content {
+	return []*genai.Content{
+		genai.NewContentFromText("What does ErrNotFound mean?", genai.RoleUser),
+		genai.NewContentFromText("The experience does not exist.", genai.RoleModel),
+		genai.NewContentFromText("Why does the server panic on startup?", genai.RoleUser),
+		genai.NewContentFromFunctionCall("read_file_content", map[string]any{"filepath": "main.go"}, genai.RoleModel),
+		genai.NewContentFromFunctionResponse("read_file_content", map[string]any{"success": true}, genai.RoleUser),
+		genai.NewContentFromText("The config map is nil.", genai.RoleModel),
+		genai.NewContentFromText("How do I fix it?", genai.RoleUser),
+		genai.NewContentFromText("Initialize the map in NewServer.", genai.RoleModel),
+		genai.NewContentFromText("Thanks", genai.RoleUser), // Not answered yet
+	}
+}
+
+func TestEvaluateTurns(t *testing.T) {
+	generator := &scriptedGenerator{response: "```json\n" + `[
+		{"index": 1, "helpfulness": 2, "correctness": 3, "conciseness": 2, "comment": "Guesses without reading the code"},
+		{"index": 2, "helpfulness": 5, "correctness": 5, "conciseness": 4, "comment": "Clear fix"}
+	]` + "\n```"}
+
+	report, err := EvaluateTurns(context.Background(), generator, evaluationHistory(), 2)
+	if err != nil {
+		t.Fatalf("EvaluateTurns failed: %v", err)
+	}
+	if len(report.Turns) != 2 {
+		t.Fatalf("expected 2 turns, got %+v", report.Turns)
+	}
+	first, second := report.Turns[0], report.Turns[1]
+	if first.UserMessage != "Why does the server panic on startup?" || first.Scores[CriterionCorrectness] != 3 || !first.Flagged || first.Comment != "Guesses without reading the code" {
+		t.Errorf("unexpected first turn %+v", first)
+	}
+	if second.UserMessage != "How do I fix it?" || second.Scores[CriterionConciseness] != 4 || second.Flagged {
+		t.Errorf("unexpected second turn %+v", second)
+	}
+	if want := 21.0 / 6; report.AverageScore != want {
+		t.Errorf("AverageScore = %v, want %v", report.AverageScore, want)
+	}
+
+	// Only the last two answered turns are shown to the model, without tool calls
+	prompt := generator.prompts[0]
+	for _, want := range []string{"第 2 轮", "The config map is nil.", "Initialize the map in NewServer."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+	for _, unwanted := range []string{"ErrNotFound", "Thanks", "read_file_content"} {
+		if strings.Contains(prompt, unwanted) {
+			t.Errorf("prompt contains %q:\n%s", unwanted, prompt)
+		}
+	}
+}
+
+func TestEvaluateTurns_InvalidEvaluation(t *testing.T) {
+	for name, response := range map[string]string{
+		"not JSON":         "The answers look good.",
+		"score too high":   `[{"index": 1, "helpfulness": 6, "correctness": 5, "conciseness": 5}]`,
+		"missing score":    `[{"index": 1, "helpfulness": 4, "correctness": 5}]`,
+		"unknown turn":     `[{"index": 2, "helpfulness": 4, "correctness": 5, "conciseness": 5}]`,
+		"missing turn":     `[]`,
+		"turn rated twice": `[{"index": 1, "helpfulness": 4, "correctness": 5, "conciseness": 5}, {"index": 1, "helpfulness": 4, "correctness": 5, "conciseness": 5}]`,
+	} {
+		if _, err := EvaluateTurns(context.Background(), &scriptedGenerator{response: response}, evaluationHistory(), 1); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := EvaluateTurns(context.Background(), &scriptedGenerator{}, evaluationHistory()[:1], 5); err == nil {
+		t.Error("expected an error for a conversation without answers")
+	}
+	if _, err := EvaluateTurns(context.Background(), &scriptedGenerator{}, evaluationHistory(), 0); err == nil {
+		t.Error("expected an error for lastN 0")
+	}
+}
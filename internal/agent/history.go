@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// maxHistoryTokens is the estimated size, in tokens, above which the oldest turns of a
+// conversation are left out of model requests, well below the model's context window.
+const maxHistoryTokens = 500_000
+
+// trimHistoryCallback returns a BeforeModelCallback that trims the conversation sent to
+// the model to about maxTokens, see trimHistory. The session itself is left untouched.
+func trimHistoryCallback(maxTokens int) llmagent.BeforeModelCallback {
+	return func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+		var dropped int
+		req.Contents, dropped = trimHistory(req.Contents, maxTokens)
+		if dropped > 0 {
+			slog.InfoContext(ctx, "dropped old turns from the model request", slog.String("session_id", ctx.SessionID()), slog.Int("turns", dropped))
+		}
+		return nil, nil
+	}
+}
+
+// trimHistory drops the oldest turns of contents until its estimated size is at most
+// maxTokens, and returns the remaining contents and the number of turns dropped.
+// A turn starts with a user message and includes the model responses, tool calls and
+// tool results that follow it, so a tool call is never separated from its result.
+// The first turn, which states the task, and the last turn are always kept, even when
+// they alone exceed maxTokens.
+func trimHistory(contents []*genai.Content, maxTokens int) ([]*genai.Content, int) {
+	total := 0
+	for _, c := range contents {
+		total += estimateTokens(c)
+	}
+	if total <= maxTokens {
+		return contents, 0
+	}
+
+	// starts holds the index of the first content of every turn
+	var starts []int
+	for i, c := range contents {
+		if i == 0 || isUserMessage(c) {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) <= 2 {
+		return contents, 0
+	}
+
+	// Drop turns from the second one on, keeping the last
+	dropped, end := 0, starts[1]
+	for turn := 1; turn < len(starts)-1 && total > maxTokens; turn++ {
+		for _, c := range contents[starts[turn]:starts[turn+1]] {
+			total -= estimateTokens(c)
+		}
+		dropped++
+		end = starts[turn+1]
+	}
+	if dropped == 0 {
+		return contents, 0
+	}
+
+	trimmed := make([]*genai.Content, 0, len(contents)-(end-starts[1]))
+	trimmed = append(trimmed, contents[:starts[1]]...)
+	trimmed = append(trimmed, contents[end:]...)
+	return trimmed, dropped
+}
+
+// isUserMessage reports whether c is a message typed by the user, as opposed to a tool
+// result, which is sent with the user role as well.
+func isUserMessage(c *genai.Content) bool {
+	if c == nil || c.Role != genai.RoleUser {
+		return false
+	}
+	for _, part := range c.Parts {
+		if part != nil && part.FunctionResponse != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// estimateTokens roughly estimates the number of tokens of c as a quarter of the length
+// of its text, tool calls and tool results.
+func estimateTokens(c *genai.Content) int {
+	if c == nil {
+		return 0
+	}
+	n := 0
+	for _, part := range c.Parts {
+		if part == nil {
+			continue
+		}
+		n += len(part.Text)
+		if part.FunctionCall != nil {
+			data, _ := json.Marshal(part.FunctionCall)
+			n += len(data)
+		}
+		if part.FunctionResponse != nil {
+			data, _ := json.Marshal(part.FunctionResponse)
+			n += len(data)
+		}
+	}
+	return n / 4
+}
@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestTrimHistory(t *testing.T) {
+	// 100 turns of about 100 tokens each: a question, a tool call, its result and an answer
+	var contents []*genai.Content
+	for i := range 100 {
+		text := fmt.Sprintf("question %d %s", i, strings.Repeat("q", 200))
+		contents = append(contents,
+			genai.NewContentFromText(text, genai.RoleUser),
+			genai.NewContentFromFunctionCall("read_file_content", map[string]any{"filepath": "main.go"}, genai.RoleModel),
+			genai.NewContentFromFunctionResponse("read_file_content", map[string]any{"data": strings.Repeat("d", 100)}, genai.RoleUser),
+			genai.NewContentFromText(fmt.Sprintf("answer %d", i), genai.RoleModel),
+		)
+	}
+
+	trimmed, dropped := trimHistory(contents, 2000)
+	total := 0
+	for _, c := range trimmed {
+		total += estimateTokens(c)
+	}
+	if total > 2000 {
+		t.Errorf("expected at most 2000 tokens, got %d", total)
+	}
+	if dropped == 0 || len(trimmed) != len(contents)-4*dropped {
+		t.Fatalf("expected whole turns to be dropped, got %d contents after dropping %d turns", len(trimmed), dropped)
+	}
+	if !strings.HasPrefix(trimmed[0].Parts[0].Text, "question 0 ") {
+		t.Errorf("expected the first turn to be kept, got %q", trimmed[0].Parts[0].Text)
+	}
+	if !strings.HasPrefix(trimmed[4].Parts[0].Text, fmt.Sprintf("question %d ", dropped+1)) {
+		t.Errorf("expected the oldest turns after the first to be dropped, got %q", trimmed[4].Parts[0].Text)
+	}
+	if last := trimmed[len(trimmed)-1]; last.Parts[0].Text != "answer 99" {
+		t.Errorf("expected the most recent turn to be kept, got %q", last.Parts[0].Text)
+	}
+	for i, c := range trimmed {
+		if c.Parts[0].FunctionResponse != nil && trimmed[i-1].Parts[0].FunctionCall == nil {
+			t.Errorf("expected every tool result to follow its call, got %+v at %d", c, i)
+		}
+	}
+
+	if same, dropped := trimHistory(contents, 1_000_000); dropped != 0 || len(same) != len(contents) {
+		t.Errorf("expected a history within the limit to be kept, got %d contents and %d dropped", len(same), dropped)
+	}
+	// The first and the last turn are kept even when they exceed the limit
+	if kept, _ := trimHistory(contents, 0); len(kept) != 8 {
+		t.Errorf("expected the first and last turn to remain, got %d contents", len(kept))
+	}
+}
@@ -5,68 +5,212 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"text/template"
+	"time"
 
+	"github.com/easeaico/adk-memory-agent/internal/audit"
 	"github.com/easeaico/adk-memory-agent/internal/config"
 	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"github.com/easeaico/adk-memory-agent/internal/metrics"
+	"github.com/easeaico/adk-memory-agent/internal/retry"
 	"github.com/easeaico/adk-memory-agent/internal/tools"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
 	"google.golang.org/adk/model/gemini"
 	"google.golang.org/genai"
 )
 
+// sessionIdleTimeout is how long the working memory of a session is kept after its last
+// tool call or turn. The launcher does not report when a session ends.
+const sessionIdleTimeout = 2 * time.Hour
+
+// HunterAgent is the coding agent together with the system prompt it is instructed with,
+// which is built from the project rules.
+type HunterAgent struct {
+	Agent agent.Agent // The ADK agent, run by the launcher
+
+	store     memory.Store
+	projectID string
+
+	consolidation memory.Generator // Scores sessions before they are saved to memory
+
+	mu           sync.RWMutex
+	systemPrompt string
+
+	chatMu sync.Mutex // Guards chat
+	chat   *Chat      // The ChatStream conversation, created by the first call
+}
+
 // NewHunterAgent creates and initializes a new coding agent with all required components.
 // It loads project rules, creates tools, initializes the LLM model, and configures
-// the agent with a system prompt. Returns the agent and an error.
-func NewHunterAgent(ctx context.Context, embedder memory.Embedder, store memory.Store, cfg *config.Config) (agent.Agent, error) {
-	// Load project rules for system prompt
-	rules, err := store.GetProjectRules(ctx)
+// the agent with a system prompt. Tool calls are recorded as spans of tracer, and in
+// auditLog when it is not nil, LLM call durations in m when it is not nil, and the tokens
+// of each session in costs when it is not nil. sqlDB, when not nil, is the database the
+// execute_sql debug tool queries.
+// Returns the agent and an error.
+func NewHunterAgent(ctx context.Context, embedder memory.Embedder, store memory.Store, sqlDB memory.SQLQuerier, tracer trace.Tracer, m *metrics.Metrics, costs *metrics.CostTracker, auditLog *audit.Logger, cfg *config.Config) (*HunterAgent, error) {
+	// Load the rules of this project, and those shared by all projects, for system prompt
+	rules, err := store.GetProjectRules(ctx, cfg.ProjectID, true)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load project rules", slog.String("project_id", cfg.ProjectID), slog.Any("error", err))
+	}
+	projectContext, err := store.ListProjectContext(ctx)
 	if err != nil {
-		log.Printf("Warning: failed to load project rules: %v", err)
+		slog.WarnContext(ctx, "failed to load project context", slog.String("project_id", cfg.ProjectID), slog.Any("error", err))
 	}
 
 	// Build system instruction
-	systemPrompt := buildSystemPrompt(rules)
+	hunter := &HunterAgent{store: store, projectID: cfg.ProjectID, systemPrompt: buildSystemPrompt(rules, projectContext)}
+
+	// Working memory tracks per-session state such as the files read by tools, and the
+	// limiter the calls counted against the per-session tool limits
+	workingMemory := memory.NewWorkingMemory(cfg.MaxRecentToolResults)
+	limiter := tools.NewSessionLimiter()
+
+	// Generator is used by merge_experiences to write combined experiences with the chat
+	// model, and the consolidation generator by the memory service to score sessions
+	chatModel := chatModelName(cfg)
+	generator, consolidation, err := roleGenerators(chatModel, consolidationModelName(cfg), func(model string) (memory.Generator, error) {
+		return memory.NewGenerator(ctx, cfg.APIKey, model)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create generator: %w", err)
+	}
+	hunter.consolidation = consolidation
+
+	// Reject prompt injection attempts before they reach the model, or the system prompt
+	// through a project rule
+	injectionFilter, err := NewPromptInjectionFilter(cfg.InjectionPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prompt injection filter: %w", err)
+	}
 
 	// Create tools
 	agentTools, err := tools.BuildTools(tools.ToolsConfig{
-		Store:    store,
-		Embedder: embedder,
-		WorkDir:  cfg.WorkDir,
+		Store:               store,
+		Embedder:            embedder,
+		WorkDir:             cfg.WorkDir,
+		Mounts:              cfg.Mounts,
+		AllowedExtensions:   cfg.ReadAllowedExtensions,
+		DeniedExtensions:    cfg.ReadDeniedExtensions,
+		ProjectID:           cfg.ProjectID,
+		ProjectRules:        rules,
+		ToolEnablementRules: tools.ParseToolEnablementRules(cfg.ToolEnablementRules),
+		WorkingMemory:       workingMemory,
+		Generator:           generator,
+		MinSimilarity:       cfg.MinSimilarity,
+		MaxResultCount:      cfg.MaxResultCount,
+		DebugTools:          cfg.DebugTools,
+		SQL:                 sqlDB,
+		Tracer:              tracer,
+		AuditLog:            auditLog,
+		DetectInjection:     injectionFilter.Detect,
+		StartedAt:           time.Now(),
+		// Rules and context changed by the tools take effect in the system prompt right away
+		PromptChanged: func(ctx context.Context) {
+			if err := hunter.RefreshRules(ctx); err != nil {
+				slog.WarnContext(ctx, "failed to refresh project rules", slog.Any("error", err))
+			}
+		},
+		// Both limits are disabled when zero
+		MaxSearchCallsPerSession: cfg.MaxSearchCallsPerSession,
+		MaxSaveCallsPerSession:   cfg.MaxSaveCallsPerSession,
+		Limiter:                  limiter,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to build tools: %w", err)
 	}
 
 	// Create LLM model using ADK's gemini wrapper
-	llmModel, err := gemini.NewModel(ctx, "gemini-3-pro-preview", &genai.ClientConfig{
+	llmModel, err := gemini.NewModel(ctx, chatModel, &genai.ClientConfig{
 		APIKey:  cfg.APIKey,
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LLM model: %w", err)
 	}
+	llmModel = newRetryingLLM(llmModel, retry.Policy{MaxAttempts: cfg.RetryMaxAttempts, InitialDelay: cfg.RetryInitialDelay})
+	if m != nil {
+		llmModel = m.InstrumentLLM(llmModel)
+	}
+
+	// Leave a breadcrumb of the files each session read, if enabled, then drop the working
+	// memory of sessions that have gone idle
+	var afterAgentCallbacks []agent.AfterAgentCallback
+	if cfg.SnapshotDir != "" {
+		afterAgentCallbacks = append(afterAgentCallbacks, workspaceSnapshotCallback(cfg.WorkDir, cfg.SnapshotDir, workingMemory))
+	}
+	afterAgentCallbacks = append(afterAgentCallbacks, releaseIdleSessionsCallback(workingMemory, limiter, costs, sessionIdleTimeout))
+	var afterModelCallbacks []llmagent.AfterModelCallback
+	if costs != nil {
+		afterModelCallbacks = append(afterModelCallbacks, recordUsageCallback(costs))
+	}
 
 	// Create LLM agent
 	llmAgent, err := llmagent.New(llmagent.Config{
-		Name:        "legacy_code_hunter",
-		Description: "帮助开发者理解、调试和修复代码问题的智能助手",
-		Model:       llmModel,
-		Instruction: systemPrompt,
-		Tools:       agentTools,
+		Name:                 "legacy_code_hunter",
+		Description:          "帮助开发者理解、调试和修复代码问题的智能助手",
+		Model:                llmModel,
+		InstructionProvider:  hunter.instruction,
+		Tools:                agentTools,
+		BeforeModelCallbacks: []llmagent.BeforeModelCallback{promptInjectionCallback(injectionFilter), tools.RecordSessionHistoryCallback(workingMemory), trimHistoryCallback(maxHistoryTokens)},
+		AfterModelCallbacks:  afterModelCallbacks,
+		BeforeToolCallbacks:  []llmagent.BeforeToolCallback{tools.ValidateArgsCallback},
+		AfterToolCallbacks:   []llmagent.AfterToolCallback{tools.RecordToolResultCallback(workingMemory)},
+		AfterAgentCallbacks:  afterAgentCallbacks,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
 	}
 
-	log.Printf("Agent initialized with %d project rules loaded", len(rules))
-	return llmAgent, nil
+	slog.InfoContext(ctx, "agent initialized", slog.Int("rules", len(rules)))
+	hunter.Agent = llmAgent
+	return hunter, nil
+}
+
+// RefreshRules reloads the project rules and context and rebuilds the system prompt from
+// them, so that model requests made from now on follow the updated rules. The tools are not rebuilt:
+// tools disabled by project rules (see config.Config.ToolEnablementRules) only change when
+// the agent is restarted.
+func (a *HunterAgent) RefreshRules(ctx context.Context) error {
+	rules, err := a.store.GetProjectRules(ctx, a.projectID, true)
+	if err != nil {
+		return fmt.Errorf("failed to reload project rules: %w", err)
+	}
+	projectContext, err := a.store.ListProjectContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reload project context: %w", err)
+	}
+
+	systemPrompt := buildSystemPrompt(rules, projectContext)
+	a.mu.Lock()
+	a.systemPrompt = systemPrompt
+	a.mu.Unlock()
+
+	slog.InfoContext(ctx, "reloaded project rules and context, the next model request will use the updated system prompt", slog.Int("rules", len(rules)), slog.Int("context_entries", len(projectContext)))
+	return nil
+}
+
+// ConsolidationGenerator returns the generator of the consolidation model, which scores
+// sessions before the memory service saves them.
+func (a *HunterAgent) ConsolidationGenerator() memory.Generator {
+	return a.consolidation
+}
+
+// instruction returns the current system prompt. It is the agent's instruction provider,
+// which also keeps ADK from substituting session state for "{...}" in rule text.
+func (a *HunterAgent) instruction(agent.ReadonlyContext) (string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.systemPrompt, nil
 }
 
 // systemPromptTmpl is the template for generating the agent's system prompt.
-// It includes project rules when available and provides instructions for
+// It includes project rules and context when available and provides instructions for
 // using the available tools. The template uses the "inc" helper function
 // to number rules starting from 1.
 var systemPromptTmpl = template.Must(template.New("systemPrompt").Funcs(template.FuncMap{"inc": inc}).Parse(`
@@ -86,6 +230,14 @@ var systemPromptTmpl = template.Must(template.New("systemPrompt").Funcs(template
 {{end}}
 {{end}}
 
+{{- if .Context }}
+
+项目背景信息：
+{{- range $key, $value := .Context }}
+- {{$key}}: {{$value}}
+{{- end}}
+{{- end}}
+
 在回答问题时：
 - 首先考虑是否需要搜索历史问题库
 - 如果需要查看代码，使用 read_file_content 工具
@@ -98,24 +250,62 @@ var systemPromptTmpl = template.Must(template.New("systemPrompt").Funcs(template
 func inc(i int) int { return i + 1 }
 
 // buildSystemPrompt constructs the system prompt by executing the template
-// with the given project rules. If template execution fails, it returns
+// with the given project rules and context. If template execution fails, it returns
 // a basic fallback prompt. The prompt guides the agent's behavior and
 // instructs it on how to use available tools.
-func buildSystemPrompt(rules []string) string {
+func buildSystemPrompt(rules []string, projectContext map[string]string) string {
 	data := struct {
 		Rules    []string
 		HasRules bool
+		Context  map[string]string // Listed by key
 	}{
 		Rules:    rules,
 		HasRules: len(rules) > 0,
+		Context:  projectContext,
 	}
 
 	var buf bytes.Buffer
 	if err := systemPromptTmpl.Execute(&buf, data); err != nil {
-		log.Printf("Warning: failed to execute system prompt template: %v", err)
+		slog.Warn("failed to execute system prompt template", slog.Any("error", err))
 		// Return a basic fallback prompt
 		return `你是一个资深的 Go 工程师，名为"遗留代码猎手"(Legacy Code Hunter)。
 你的任务是帮助开发者理解、调试和修复代码问题。`
 	}
 	return buf.String()
 }
+
+// releaseIdleSessionsCallback returns an AfterAgentCallback that releases the working
+// memory of the sessions that have not been used for maxIdle, and resets their tool
+// limits in limiter. The released sessions have ended, and their cost is recorded by
+// costs when it is not nil. The session of the turn that just ended has been used by it
+// and is kept.
+func releaseIdleSessionsCallback(wm *memory.WorkingMemory, limiter *tools.SessionLimiter, costs *metrics.CostTracker, maxIdle time.Duration) agent.AfterAgentCallback {
+	return func(ctx agent.CallbackContext) (*genai.Content, error) {
+		wm.Session(ctx.SessionID()) // Marks the session as used
+		released := wm.ReleaseIdle(maxIdle)
+		for _, sessionID := range released {
+			limiter.ResetSessionLimits(sessionID)
+			if costs != nil {
+				if err := costs.EndSession(ctx, sessionID); err != nil {
+					slog.WarnContext(ctx, "failed to record session cost", slog.String("session_id", sessionID), slog.Any("error", err))
+				}
+			}
+		}
+		if len(released) > 0 {
+			slog.InfoContext(ctx, "released the working memory of idle sessions", slog.Int("sessions", len(released)))
+		}
+		return nil, nil
+	}
+}
+
+// recordUsageCallback returns an AfterModelCallback that adds the token counts of each
+// complete model response to the usage of its session in costs. Partial responses of a
+// stream are skipped, since the complete response counts the whole call.
+func recordUsageCallback(costs *metrics.CostTracker) llmagent.AfterModelCallback {
+	return func(ctx agent.CallbackContext, resp *model.LLMResponse, respErr error) (*model.LLMResponse, error) {
+		if resp != nil && !resp.Partial {
+			costs.AddUsage(ctx.SessionID(), resp.UsageMetadata)
+		}
+		return nil, nil
+	}
+}
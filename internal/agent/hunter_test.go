@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+func TestHunterAgent_RefreshRules(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewInMemoryStore()
+	store.ProjectID = "payments"
+	hunter := &HunterAgent{store: store, projectID: "payments", systemPrompt: buildSystemPrompt(nil, nil)}
+
+	if _, err := store.AddProjectRule(ctx, "errors", "wrap errors with {context}", 1); err != nil {
+		t.Fatalf("AddProjectRule failed: %v", err)
+	}
+	prompt, _ := hunter.instruction(nil)
+	if strings.Contains(prompt, "wrap errors") {
+		t.Fatalf("expected the prompt to change only on refresh, got %q", prompt)
+	}
+
+	if err := hunter.RefreshRules(ctx); err != nil {
+		t.Fatalf("RefreshRules failed: %v", err)
+	}
+	prompt, _ = hunter.instruction(nil)
+	if !strings.Contains(prompt, "1. wrap errors with {context}") {
+		t.Errorf("expected the new rule in the prompt, got %q", prompt)
+	}
+
+	if err := store.SetProjectContext(ctx, "entry_point", "cmd/api/main.go"); err != nil {
+		t.Fatalf("SetProjectContext failed: %v", err)
+	}
+	if err := hunter.RefreshRules(ctx); err != nil {
+		t.Fatalf("RefreshRules failed: %v", err)
+	}
+	prompt, _ = hunter.instruction(nil)
+	if !strings.Contains(prompt, "entry_point: cmd/api/main.go") {
+		t.Errorf("expected the project context in the prompt, got %q", prompt)
+	}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"github.com/easeaico/adk-memory-agent/internal/config"
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+const (
+	// DefaultChatModel is the Gemini model that drives the agent and writes merged
+	// experiences when Config.ChatModel is empty.
+	DefaultChatModel = "gemini-3-pro-preview"
+	// DefaultConsolidationModel is the Gemini model that scores sessions before they are
+	// saved to memory when Config.ConsolidationModel is empty. Scoring is a small task for
+	// which a fast model suffices.
+	DefaultConsolidationModel = "gemini-2.5-flash"
+)
+
+// chatModelName returns the model of the chat role configured in cfg.
+func chatModelName(cfg *config.Config) string {
+	if cfg.ChatModel == "" {
+		return DefaultChatModel
+	}
+	return cfg.ChatModel
+}
+
+// consolidationModelName returns the model of the consolidation role configured in cfg.
+func consolidationModelName(cfg *config.Config) string {
+	if cfg.ConsolidationModel == "" {
+		return DefaultConsolidationModel
+	}
+	return cfg.ConsolidationModel
+}
+
+// roleGenerators creates the generators of the chat and consolidation roles with
+// newGenerator. Both roles share one generator when they use the same model.
+func roleGenerators(chatModel, consolidationModel string, newGenerator func(model string) (memory.Generator, error)) (chat, consolidation memory.Generator, err error) {
+	if chat, err = newGenerator(chatModel); err != nil {
+		return nil, nil, err
+	}
+	if consolidationModel == chatModel {
+		return chat, chat, nil
+	}
+	if consolidation, err = newGenerator(consolidationModel); err != nil {
+		return nil, nil, err
+	}
+	return chat, consolidation, nil
+}
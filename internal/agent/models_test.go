@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/config"
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// namedGenerator is a Generator that only records its model.
+type namedGenerator struct {
+	model string
+}
+
+func (g *namedGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	return g.model, nil
+}
+
+func TestRoleGenerators(t *testing.T) {
+	var created []string
+	newGenerator := func(model string) (memory.Generator, error) {
+		created = append(created, model)
+		return &namedGenerator{model: model}, nil
+	}
+
+	cfg := &config.Config{ConsolidationModel: "gemini-1.5-pro"}
+	chat, consolidation, err := roleGenerators(chatModelName(cfg), consolidationModelName(cfg), newGenerator)
+	if err != nil {
+		t.Fatalf("roleGenerators failed: %v", err)
+	}
+	if chat == consolidation {
+		t.Error("expected separate generators for different models")
+	}
+	if chat.(*namedGenerator).model != DefaultChatModel || consolidation.(*namedGenerator).model != "gemini-1.5-pro" {
+		t.Errorf("expected the default chat model and the configured consolidation model, got %v", created)
+	}
+
+	created = nil
+	cfg = &config.Config{ChatModel: "gemini-2.0-flash", ConsolidationModel: "gemini-2.0-flash"}
+	chat, consolidation, err = roleGenerators(chatModelName(cfg), consolidationModelName(cfg), newGenerator)
+	if err != nil {
+		t.Fatalf("roleGenerators failed: %v", err)
+	}
+	if chat != consolidation || len(created) != 1 {
+		t.Errorf("expected one shared generator for the same model, created %v", created)
+	}
+
+	if got := consolidationModelName(&config.Config{}); got != DefaultConsolidationModel {
+		t.Errorf("expected the default consolidation model, got %q", got)
+	}
+
+	failing := func(model string) (memory.Generator, error) { return nil, errors.New("no client") }
+	if _, _, err := roleGenerators("a", "b", failing); err == nil {
+		t.Error("expected an error when a generator cannot be created")
+	}
+}
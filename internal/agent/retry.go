@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"iter"
+
+	"github.com/easeaico/adk-memory-agent/internal/retry"
+	"google.golang.org/adk/model"
+)
+
+// retryingLLM retries calls to the embedded LLM that fail with a transient error
+// before any response has been received.
+type retryingLLM struct {
+	model.LLM
+	policy retry.Policy
+}
+
+// newRetryingLLM wraps llm so that calls failing with a retry.Retryable error, such as
+// a rate limit, are retried according to policy. Errors after the first response has
+// been passed on are not retried, since the caller has already seen part of the answer.
+func newRetryingLLM(llm model.LLM, policy retry.Policy) model.LLM {
+	return &retryingLLM{LLM: llm, policy: policy}
+}
+
+func (l *retryingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		received := false
+		err := l.policy.Do(ctx, func() error {
+			for resp, err := range l.LLM.GenerateContent(ctx, req, stream) {
+				if err != nil && !received {
+					return err
+				}
+				received = true
+				if !yield(resp, err) {
+					return nil
+				}
+			}
+			return nil
+		})
+		// Only errors before the first response are returned by Do
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
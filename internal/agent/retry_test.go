@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/retry"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// flakyLLM fails its first failures calls with a 503 error before responding.
+type flakyLLM struct {
+	failures int
+	calls    int
+}
+
+func (l *flakyLLM) Name() string { return "flaky" }
+
+func (l *flakyLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		l.calls++
+		if l.calls <= l.failures {
+			yield(nil, genai.APIError{Code: http.StatusServiceUnavailable})
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("done", genai.RoleModel)}, nil)
+	}
+}
+
+func TestRetryingLLM(t *testing.T) {
+	inner := &flakyLLM{failures: 2}
+	llm := newRetryingLLM(inner, retry.Policy{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	var responses []*model.LLMResponse
+	for resp, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err != nil {
+			t.Fatalf("GenerateContent failed: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	if inner.calls != 3 || len(responses) != 1 || responses[0].Content.Parts[0].Text != "done" {
+		t.Errorf("expected the response of the third attempt, got %d responses after %d calls", len(responses), inner.calls)
+	}
+
+	inner.calls, inner.failures = 0, 5
+	var errs int
+	for _, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err != nil {
+			errs++
+		}
+	}
+	if inner.calls != 3 || errs != 1 {
+		t.Errorf("expected a single error after 3 attempts, got %d errors after %d calls", errs, inner.calls)
+	}
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// defaultInjectionPatterns are regular expressions matching common prompt injection phrases.
+var defaultInjectionPatterns = []string{
+	`(?i)ignore (all )?(the )?(previous|prior|above) instructions?`,
+	`(?i)disregard (all )?(your |the )?(previous|prior|above) (instructions?|rules)`,
+	`(?i)you are now`,
+	`(?i)forget (all )?your (system prompt|instructions|rules)`,
+	`(?i)(reveal|print|show|dump) (me )?(your|the) system prompt`,
+	`(?i)pretend (that )?you are`,
+	`(?i)act as if you have no (rules|restrictions)`,
+	`忽略(之前|以上|前面)的(所有)?(指令|指示|规则)`,
+	// Chat template markers are harmless when quoted once, but input repeating them is
+	// trying to pass itself off as a new system or instruction turn
+	`(?is)(\[/?INST\]|<\|im_(start|end)\|>|<</?SYS>>|###\s*system\b).*(\[/?INST\]|<\|im_(start|end)\|>|<</?SYS>>|###\s*system\b)`,
+}
+
+// injectionBlockedMessage is the answer given instead of the model's to user input
+// flagged as a prompt injection attempt.
+const injectionBlockedMessage = "这条消息看起来像是在试图修改我的指令，因此没有被处理。请直接描述你遇到的编程问题。"
+
+// PromptInjectionFilter detects prompt injection attempts in user input
+// using a list of compiled regular expressions.
+type PromptInjectionFilter struct {
+	patterns []*regexp.Regexp
+}
+
+// NewPromptInjectionFilter creates a filter using the default patterns plus any
+// custom patterns. Returns an error if a custom pattern is not a valid regular expression.
+func NewPromptInjectionFilter(customPatterns []string) (*PromptInjectionFilter, error) {
+	f := &PromptInjectionFilter{}
+	for _, p := range append(append([]string{}, defaultInjectionPatterns...), customPatterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid injection pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// Detect reports whether input looks like a prompt injection attempt.
+// When it does, the returned reason names the matched phrase.
+func (f *PromptInjectionFilter) Detect(input string) (bool, string) {
+	for _, re := range f.patterns {
+		if match := re.FindString(input); match != "" {
+			return true, fmt.Sprintf("matched %q", match)
+		}
+	}
+	return false, ""
+}
+
+// defaultInjectionFilter is used by DetectPromptInjection.
+var defaultInjectionFilter, _ = NewPromptInjectionFilter(nil)
+
+// DetectPromptInjection checks input against the default injection patterns.
+// It returns true and a reason string when an injection attempt is detected.
+func DetectPromptInjection(input string) (bool, string) {
+	return defaultInjectionFilter.Detect(input)
+}
+
+// promptInjectionCallback returns a BeforeModelCallback that answers with
+// injectionBlockedMessage instead of calling the LLM when the user's message is flagged
+// by filter.
+func promptInjectionCallback(filter *PromptInjectionFilter) llmagent.BeforeModelCallback {
+	return func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+		input := contentText(ctx.UserContent())
+		if input == "" {
+			return nil, nil
+		}
+
+		if detected, reason := filter.Detect(input); detected {
+			slog.WarnContext(ctx, "blocked user input", slog.String("session_id", ctx.SessionID()), slog.String("reason", reason), slog.String("input", truncateRunes(input, 200)))
+			return injectionBlockedResponse(), nil
+		}
+		return nil, nil
+	}
+}
+
+// injectionBlockedResponse is the response promptInjectionCallback replaces the model's with.
+func injectionBlockedResponse() *model.LLMResponse {
+	return &model.LLMResponse{Content: genai.NewContentFromText(injectionBlockedMessage, genai.RoleModel), TurnComplete: true}
+}
+
+// contentText joins all text parts of a content message.
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range content.Parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// truncateRunes shortens s to at most limit runes, appending "..." when truncated.
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "..."
+}
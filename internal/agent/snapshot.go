@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/genai"
+)
+
+// FileSnap records the state of a single file at the time of a snapshot.
+type FileSnap struct {
+	Path    string    `json:"path"`     // Path relative to the working directory
+	Hash    string    `json:"hash"`     // Hex-encoded SHA-256 of the file content, empty if missing
+	Size    int64     `json:"size"`     // File size in bytes
+	ModTime time.Time `json:"mod_time"` // Last modification time
+	Missing bool      `json:"missing"`  // Whether the file no longer exists
+}
+
+// WorkspaceSnapshot is a breadcrumb of the files an agent session looked at
+// and the state they were in, used for debugging sessions after the fact.
+type WorkspaceSnapshot struct {
+	Files      []FileSnap `json:"files"`
+	SnapshotAt time.Time  `json:"snapshot_at"`
+}
+
+// SnapshotDiff describes how a file differs between two snapshots.
+type SnapshotDiff struct {
+	Path   string `json:"path"`
+	Change string `json:"change"` // "added", "removed" or "modified"
+}
+
+// SnapshotWorkspace records the hash, size and modification time of each file in readFiles.
+// Relative paths are resolved against workDir. Files that no longer exist are recorded
+// as missing rather than causing an error.
+func SnapshotWorkspace(workDir string, readFiles []string) (WorkspaceSnapshot, error) {
+	snapshot := WorkspaceSnapshot{SnapshotAt: time.Now().UTC()}
+	for _, path := range readFiles {
+		full := path
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(workDir, full)
+		}
+
+		snap, err := snapshotFile(full)
+		if err != nil {
+			return snapshot, err
+		}
+		snap.Path = path
+		snapshot.Files = append(snapshot.Files, snap)
+	}
+	return snapshot, nil
+}
+
+// snapshotFile hashes the file at path and records its size and modification time.
+func snapshotFile(path string) (FileSnap, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return FileSnap{Missing: true}, nil
+	}
+	if err != nil {
+		return FileSnap{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return FileSnap{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return FileSnap{}, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	return FileSnap{
+		Hash:    hex.EncodeToString(h.Sum(nil)),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// CompareSnapshot returns the files that were added, removed or modified between
+// snapshot a and the later snapshot b, in the order they appear in a followed by b.
+func CompareSnapshot(a, b WorkspaceSnapshot) []SnapshotDiff {
+	before := make(map[string]FileSnap, len(a.Files))
+	for _, f := range a.Files {
+		before[f.Path] = f
+	}
+	after := make(map[string]FileSnap, len(b.Files))
+	for _, f := range b.Files {
+		after[f.Path] = f
+	}
+
+	var diffs []SnapshotDiff
+	for _, old := range a.Files {
+		cur, ok := after[old.Path]
+		switch {
+		case !ok || (cur.Missing && !old.Missing):
+			diffs = append(diffs, SnapshotDiff{Path: old.Path, Change: "removed"})
+		case old.Missing && !cur.Missing:
+			diffs = append(diffs, SnapshotDiff{Path: old.Path, Change: "added"})
+		case old.Hash != cur.Hash:
+			diffs = append(diffs, SnapshotDiff{Path: old.Path, Change: "modified"})
+		}
+	}
+	for _, cur := range b.Files {
+		if _, ok := before[cur.Path]; !ok && !cur.Missing {
+			diffs = append(diffs, SnapshotDiff{Path: cur.Path, Change: "added"})
+		}
+	}
+	return diffs
+}
+
+// WriteSnapshot saves snapshot as indented JSON to <dir>/<sessionID>.snapshot.json.
+func WriteSnapshot(dir, sessionID string, snapshot WorkspaceSnapshot) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	path := filepath.Join(dir, sessionID+".snapshot.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// workspaceSnapshotCallback returns an AfterAgentCallback that snapshots the files read
+// during the session and writes the snapshot to dir. The snapshot is rewritten after
+// every turn, so the file always reflects the state at the end of the latest turn.
+func workspaceSnapshotCallback(workDir, dir string, wm *memory.WorkingMemory) agent.AfterAgentCallback {
+	return func(ctx agent.CallbackContext) (*genai.Content, error) {
+		readFiles := wm.Session(ctx.SessionID()).ReadFiles()
+		if len(readFiles) == 0 {
+			return nil, nil
+		}
+
+		snapshot, err := SnapshotWorkspace(workDir, readFiles)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to snapshot workspace", slog.String("session_id", ctx.SessionID()), slog.Any("error", err))
+			return nil, nil
+		}
+		if err := WriteSnapshot(dir, ctx.SessionID(), snapshot); err != nil {
+			slog.WarnContext(ctx, "failed to write workspace snapshot", slog.String("session_id", ctx.SessionID()), slog.Any("error", err))
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotWorkspace_CompareSnapshot(t *testing.T) {
+	workDir := t.TempDir()
+	files := []string{"a.go", "b.go", "c.go"}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(workDir, name), []byte("package demo // "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := SnapshotWorkspace(workDir, files)
+	if err != nil {
+		t.Fatalf("SnapshotWorkspace failed: %v", err)
+	}
+	if len(first.Files) != 3 || first.Files[0].Hash == "" || first.Files[0].Size == 0 {
+		t.Fatalf("unexpected snapshot: %+v", first)
+	}
+
+	if err := os.WriteFile(filepath.Join(workDir, "b.go"), []byte("package demo // changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := SnapshotWorkspace(workDir, files)
+	if err != nil {
+		t.Fatalf("SnapshotWorkspace failed: %v", err)
+	}
+
+	diffs := CompareSnapshot(first, second)
+	if len(diffs) != 1 || diffs[0].Path != "b.go" || diffs[0].Change != "modified" {
+		t.Errorf("expected b.go to be the only modified file, got %+v", diffs)
+	}
+
+	snapshotDir := filepath.Join(t.TempDir(), "snapshots")
+	if err := WriteSnapshot(snapshotDir, "session-1", second); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(snapshotDir, "session-1.snapshot.json"))
+	if err != nil {
+		t.Fatalf("snapshot file not written: %v", err)
+	}
+	var decoded WorkspaceSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Files) != 3 {
+		t.Errorf("unexpected snapshot file contents: %s (%v)", data, err)
+	}
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+const (
+	// defaultSearchLimit is the number of experiences GET /experiences returns when the
+	// limit parameter is not set.
+	defaultSearchLimit = 5
+	// maxSearchLimit is the largest number of experiences GET /experiences returns.
+	maxSearchLimit = 50
+	// maxRequestBytes is the largest request body the memory server reads.
+	maxRequestBytes = 1 << 20
+)
+
+// Response is the JSON envelope of every response of the memory server, matching the
+// {success, data, error} results of the agent's tools.
+type Response struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    any    `json:"data,omitempty"`  // Result of the operation
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// ExperienceResponse is an experience returned by the memory server.
+type ExperienceResponse struct {
+	ID           int       `json:"id"`                   // Experience ID
+	ProjectID    string    `json:"project_id,omitempty"` // Project of the experience (empty when shared by all projects)
+	ErrorPattern string    `json:"error_pattern"`        // Description of the error or problem pattern
+	RootCause    string    `json:"root_cause"`           // Root cause analysis of the issue
+	Solution     string    `json:"solution"`             // Solution or fix that resolved the issue
+	Similarity   float32   `json:"similarity"`           // Cosine similarity to the query
+	Confidence   string    `json:"confidence,omitempty"` // Confidence level of the match
+	Tags         []string  `json:"tags,omitempty"`       // Labels of the experience
+	OccurredAt   time.Time `json:"occurred_at"`          // When the issue was encountered and resolved
+}
+
+// SaveExperienceRequest is the body of POST /experiences.
+type SaveExperienceRequest struct {
+	UserID       string   `json:"user_id,omitempty"` // ID of the user the experience came from
+	ErrorPattern string   `json:"error_pattern"`     // Description of the error or problem pattern
+	RootCause    string   `json:"root_cause"`        // Root cause analysis of the issue
+	Solution     string   `json:"solution"`          // Solution or fix that resolved the issue
+	Tags         []string `json:"tags,omitempty"`    // Labels such as "panic" or "timeout"
+}
+
+// SaveExperienceResponse is the data of a successful POST /experiences.
+type SaveExperienceResponse struct {
+	MergedInto int     `json:"merged_into,omitempty"` // ID of the near duplicate the experience was merged into
+	Similarity float32 `json:"similarity,omitempty"`  // Similarity to that near duplicate
+}
+
+// RuleResponse is a project rule returned by the memory server.
+type RuleResponse struct {
+	ID          int       `json:"id"`                   // Rule ID
+	ProjectID   string    `json:"project_id,omitempty"` // Project of the rule (empty when shared by all projects)
+	Category    string    `json:"category"`             // Category of the rule, e.g. "naming"
+	RuleContent string    `json:"rule_content"`         // Rule text
+	Priority    int       `json:"priority"`             // Higher values take precedence
+	IsActive    bool      `json:"is_active"`            // Whether the rule is active
+	CreatedAt   time.Time `json:"created_at"`           // When the rule was created
+}
+
+// UpsertRuleRequest is the body of POST /rules.
+type UpsertRuleRequest struct {
+	Category    string `json:"category"`           // Category of the rule, e.g. "naming"
+	RuleContent string `json:"rule_content"`       // Rule text
+	Priority    int    `json:"priority,omitempty"` // Higher values take precedence
+}
+
+// UpsertRuleResponse is the data of a successful POST /rules.
+type UpsertRuleResponse struct {
+	ID int `json:"id"` // ID of the created or updated rule
+}
+
+// MemoryServer serves a memory.Store over a JSON REST API, so that agents of several
+// processes or machines can share one knowledge store:
+//
+//	GET    /experiences?q=&limit=  search experiences similar to q
+//	POST   /experiences            save an experience
+//	DELETE /experiences/{id}       soft-delete an experience
+//	GET    /rules                  list the project rules
+//	POST   /rules                  add a project rule, or update the identical one
+//
+// Every request must carry the server's bearer token in its Authorization header.
+type MemoryServer struct {
+	token     string
+	store     memory.Store
+	embedder  memory.Embedder
+	projectID string
+
+	http *http.Server
+}
+
+// NewMemoryServer creates a MemoryServer listening on addr for the store of projectID,
+// embedding queries and new experiences with embedder. Clients must send token as a
+// bearer token in the Authorization header.
+func NewMemoryServer(addr, token string, store memory.Store, embedder memory.Embedder, projectID string) (*MemoryServer, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	s := &MemoryServer{token: token, store: store, embedder: embedder, projectID: projectID}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /experiences", s.handleSearch)
+	mux.HandleFunc("POST /experiences", s.handleSave)
+	mux.HandleFunc("DELETE /experiences/{id}", s.handleDelete)
+	mux.HandleFunc("GET /rules", s.handleListRules)
+	mux.HandleFunc("POST /rules", s.handleUpsertRule)
+	s.http = &http.Server{Addr: addr, Handler: s.requireToken(mux), ReadHeaderTimeout: 10 * time.Second}
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the server, for serving it on a listener of its own.
+func (s *MemoryServer) Handler() http.Handler {
+	return s.http.Handler
+}
+
+// ListenAndServe serves requests until Shutdown is called, after which it returns
+// http.ErrServerClosed.
+func (s *MemoryServer) ListenAndServe() error {
+	return s.http.ListenAndServe()
+}
+
+// Serve serves requests accepted on l until Shutdown is called, like ListenAndServe.
+func (s *MemoryServer) Serve(l net.Listener) error {
+	return s.http.Serve(l)
+}
+
+// Shutdown stops accepting requests and waits for the ones in progress, or ctx to end.
+func (s *MemoryServer) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// requireToken rejects requests without the server's bearer token.
+func (s *MemoryServer) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, s.token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleSearch serves GET /experiences.
+func (s *MemoryServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be a positive integer", v))
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	embedding, err := s.embedder.Embed(r.Context(), query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to generate embedding: %v", err))
+		return
+	}
+	experiences, err := s.store.SearchSimilarIssues(r.Context(), query, embedding, limit, 0, s.projectID, true, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to search issues: %v", err))
+		return
+	}
+	data := make([]ExperienceResponse, 0, len(experiences))
+	for _, exp := range experiences {
+		data = append(data, ExperienceResponse{
+			ID:           exp.ID,
+			ProjectID:    exp.ProjectID,
+			ErrorPattern: exp.ErrorPattern,
+			RootCause:    exp.RootCause,
+			Solution:     exp.Solution,
+			Similarity:   exp.SimilarityScore,
+			Confidence:   exp.ConfidenceLevel,
+			Tags:         exp.Tags,
+			OccurredAt:   exp.OccurredAt,
+		})
+	}
+	writeData(w, http.StatusOK, data)
+}
+
+// handleSave serves POST /experiences.
+func (s *MemoryServer) handleSave(w http.ResponseWriter, r *http.Request) {
+	var req SaveExperienceRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.ErrorPattern == "" || req.RootCause == "" || req.Solution == "" {
+		writeError(w, http.StatusBadRequest, "error_pattern, root_cause, and solution are all required")
+		return
+	}
+
+	embedding, err := s.embedder.Embed(r.Context(), req.ErrorPattern)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to generate embedding: %v", err))
+		return
+	}
+	if err := s.store.SaveExperience(r.Context(), req.UserID, req.ErrorPattern, req.RootCause, req.Solution, req.Tags, embedding); err != nil {
+		var exists *memory.ErrExperienceAlreadyExists
+		if errors.As(err, &exists) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("an experience with the same error pattern already exists (id %d)", exists.ExistingID))
+			return
+		}
+		var merged *memory.ErrExperienceMerged
+		if errors.As(err, &merged) {
+			writeData(w, http.StatusOK, SaveExperienceResponse{MergedInto: merged.ExistingID, Similarity: merged.Similarity})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to save experience: %v", err))
+		return
+	}
+	writeData(w, http.StatusCreated, SaveExperienceResponse{})
+}
+
+// handleDelete serves DELETE /experiences/{id}.
+func (s *MemoryServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid experience id %q", r.PathValue("id")))
+		return
+	}
+	if err := s.store.DeleteExperience(r.Context(), id); err != nil {
+		if errors.Is(err, memory.ErrNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete experience: %v", err))
+		return
+	}
+	writeData(w, http.StatusOK, nil)
+}
+
+// handleListRules serves GET /rules.
+func (s *MemoryServer) handleListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.store.ListProjectRules(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to list project rules: %v", err))
+		return
+	}
+	data := make([]RuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		data = append(data, RuleResponse{
+			ID:          rule.ID,
+			ProjectID:   rule.ProjectID,
+			Category:    rule.Category,
+			RuleContent: rule.RuleContent,
+			Priority:    rule.Priority,
+			IsActive:    rule.IsActive,
+			CreatedAt:   rule.CreatedAt,
+		})
+	}
+	writeData(w, http.StatusOK, data)
+}
+
+// handleUpsertRule serves POST /rules.
+func (s *MemoryServer) handleUpsertRule(w http.ResponseWriter, r *http.Request) {
+	var req UpsertRuleRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Category == "" || req.RuleContent == "" {
+		writeError(w, http.StatusBadRequest, "category and rule_content are required")
+		return
+	}
+	id, err := s.store.UpsertProjectRule(r.Context(), req.Category, req.RuleContent, req.Priority)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to save project rule: %v", err))
+		return
+	}
+	writeData(w, http.StatusOK, UpsertRuleResponse{ID: id})
+}
+
+// readJSON decodes the body of r into v. It replies with a 400 error and returns false
+// when the body is not a valid JSON object of v's type.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// writeData replies with status and a successful Response carrying data.
+func writeData(w http.ResponseWriter, status int, data any) {
+	writeResponse(w, status, Response{Success: true, Data: data})
+}
+
+// writeError replies with status and a failed Response carrying msg.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeResponse(w, status, Response{Success: false, Error: msg})
+}
+
+// writeResponse replies with status and resp encoded as JSON.
+func writeResponse(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Warn("failed to write response", slog.Int("status", status), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// wordEmbedder embeds texts containing "timeout" and other texts in orthogonal directions.
+type wordEmbedder struct {
+	err error
+}
+
+func (e wordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if strings.Contains(text, "timeout") {
+		return []float32{1, 0}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+// do sends a request to the handler of s with the test token and decodes the envelope
+// of the response, with its data left raw for decodeData.
+func do(t *testing.T, s *MemoryServer, method, target, body string) (int, Response) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("%s %s: Content-Type = %q, want application/json", method, target, got)
+	}
+	var resp Response
+	var data json.RawMessage
+	resp.Data = &data
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: invalid response %q: %v", method, target, rec.Body.String(), err)
+	}
+	resp.Data = data
+	return rec.Code, resp
+}
+
+// decodeData decodes the data of resp into v.
+func decodeData(t *testing.T, resp Response, v any) {
+	t.Helper()
+	if err := json.Unmarshal(resp.Data.(json.RawMessage), v); err != nil {
+		t.Fatalf("invalid data %s: %v", resp.Data, err)
+	}
+}
+
+func newTestMemoryServer(t *testing.T, embedder memory.Embedder) (*MemoryServer, *memory.InMemoryStore) {
+	t.Helper()
+	store := memory.NewInMemoryStore()
+	s, err := NewMemoryServer(":0", "secret", store, embedder, "")
+	if err != nil {
+		t.Fatalf("NewMemoryServer failed: %v", err)
+	}
+	return s, store
+}
+
+func TestNewMemoryServer_RequiresToken(t *testing.T) {
+	if _, err := NewMemoryServer(":0", "", memory.NewInMemoryStore(), wordEmbedder{}, ""); !errors.Is(err, ErrNoToken) {
+		t.Errorf("NewMemoryServer without token error = %v, want ErrNoToken", err)
+	}
+}
+
+func TestMemoryServer_Unauthorized(t *testing.T) {
+	s, _ := newTestMemoryServer(t, wordEmbedder{})
+	for name, header := range map[string]string{"missing": "", "wrong": "Bearer nope", "not bearer": "secret"} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rules", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", rec.Code)
+			}
+			if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Success || resp.Error != "unauthorized" {
+				t.Errorf("response = %+v (%v), want unauthorized error", resp, err)
+			}
+		})
+	}
+}
+
+func TestMemoryServer_Experiences(t *testing.T) {
+	s, store := newTestMemoryServer(t, wordEmbedder{})
+
+	saves := []string{
+		`{"error_pattern":"dial tcp: i/o timeout","root_cause":"slow DNS","solution":"raise the dial timeout","tags":["Timeout"]}`,
+		`{"error_pattern":"nil pointer dereference","root_cause":"missing check","solution":"check for nil"}`,
+	}
+	for _, body := range saves {
+		if code, resp := do(t, s, http.MethodPost, "/experiences", body); code != http.StatusCreated || !resp.Success {
+			t.Fatalf("POST /experiences = %d %+v, want 201 success", code, resp)
+		}
+	}
+
+	code, resp := do(t, s, http.MethodPost, "/experiences", saves[0])
+	if code != http.StatusConflict || resp.Success || !strings.Contains(resp.Error, "already exists") {
+		t.Errorf("POST duplicate = %d %+v, want 409 already exists", code, resp)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/experiences?q=request+timeout&limit=1", "")
+	if code != http.StatusOK || !resp.Success {
+		t.Fatalf("GET /experiences = %d %+v, want 200 success", code, resp)
+	}
+	var found []ExperienceResponse
+	decodeData(t, resp, &found)
+	if len(found) != 1 || found[0].ErrorPattern != "dial tcp: i/o timeout" || found[0].Similarity != 1 {
+		t.Fatalf("GET /experiences = %+v, want the timeout experience with similarity 1", found)
+	}
+	if len(found[0].Tags) != 1 || found[0].Tags[0] != "timeout" {
+		t.Errorf("tags = %v, want [timeout]", found[0].Tags)
+	}
+
+	code, resp = do(t, s, http.MethodDelete, "/experiences/"+strconv.Itoa(found[0].ID), "")
+	if code != http.StatusOK || !resp.Success {
+		t.Fatalf("DELETE /experiences/%d = %d %+v, want 200 success", found[0].ID, code, resp)
+	}
+	if _, err := store.GetExperience(context.Background(), found[0].ID); !errors.Is(err, memory.ErrNotFound) {
+		t.Errorf("GetExperience after DELETE error = %v, want ErrNotFound", err)
+	}
+	code, resp = do(t, s, http.MethodDelete, "/experiences/"+strconv.Itoa(found[0].ID), "")
+	if code != http.StatusNotFound || resp.Success {
+		t.Errorf("DELETE twice = %d %+v, want 404", code, resp)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/experiences?q=timeout", "")
+	decodeData(t, resp, &found)
+	if code != http.StatusOK || len(found) != 1 || found[0].ErrorPattern != "nil pointer dereference" {
+		t.Errorf("GET after DELETE = %d %+v, want only the remaining experience", code, found)
+	}
+}
+
+func TestMemoryServer_BadRequests(t *testing.T) {
+	s, _ := newTestMemoryServer(t, wordEmbedder{})
+	tests := []struct {
+		method, target, body string
+		wantCode             int
+		wantError            string
+	}{
+		{http.MethodGet, "/experiences", "", http.StatusBadRequest, "q is required"},
+		{http.MethodGet, "/experiences?q=x&limit=0", "", http.StatusBadRequest, "invalid limit"},
+		{http.MethodGet, "/experiences?q=x&limit=ten", "", http.StatusBadRequest, "invalid limit"},
+		{http.MethodPost, "/experiences", `{"error_pattern":"x"}`, http.StatusBadRequest, "all required"},
+		{http.MethodPost, "/experiences", `{"error_pattern":`, http.StatusBadRequest, "invalid request body"},
+		{http.MethodPost, "/experiences", `{"pattern":"x"}`, http.StatusBadRequest, "invalid request body"},
+		{http.MethodDelete, "/experiences/abc", "", http.StatusBadRequest, "invalid experience id"},
+		{http.MethodPost, "/rules", `{"category":"naming"}`, http.StatusBadRequest, "are required"},
+	}
+	for _, tt := range tests {
+		code, resp := do(t, s, tt.method, tt.target, tt.body)
+		if code != tt.wantCode || resp.Success || !strings.Contains(resp.Error, tt.wantError) {
+			t.Errorf("%s %s %s = %d %+v, want %d with error containing %q", tt.method, tt.target, tt.body, code, resp, tt.wantCode, tt.wantError)
+		}
+	}
+}
+
+func TestMemoryServer_EmbeddingError(t *testing.T) {
+	s, _ := newTestMemoryServer(t, wordEmbedder{err: errors.New("quota exceeded")})
+	code, resp := do(t, s, http.MethodGet, "/experiences?q=timeout", "")
+	if code != http.StatusInternalServerError || resp.Success || !strings.Contains(resp.Error, "quota exceeded") {
+		t.Errorf("GET with failing embedder = %d %+v, want 500 with the embedding error", code, resp)
+	}
+}
+
+func TestMemoryServer_Rules(t *testing.T) {
+	s, _ := newTestMemoryServer(t, wordEmbedder{})
+
+	code, resp := do(t, s, http.MethodPost, "/rules", `{"category":"naming","rule_content":"Use camelCase","priority":1}`)
+	if code != http.StatusOK || !resp.Success {
+		t.Fatalf("POST /rules = %d %+v, want 200 success", code, resp)
+	}
+	var created UpsertRuleResponse
+	decodeData(t, resp, &created)
+
+	// Posting the same rule again updates its priority instead of adding a rule
+	code, resp = do(t, s, http.MethodPost, "/rules", `{"category":"naming","rule_content":"Use camelCase","priority":5}`)
+	var updated UpsertRuleResponse
+	decodeData(t, resp, &updated)
+	if code != http.StatusOK || updated.ID != created.ID {
+		t.Errorf("POST same rule = %d id %d, want 200 id %d", code, updated.ID, created.ID)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/rules", "")
+	var rules []RuleResponse
+	decodeData(t, resp, &rules)
+	if code != http.StatusOK || len(rules) != 1 {
+		t.Fatalf("GET /rules = %d %+v, want one rule", code, rules)
+	}
+	if rules[0].ID != created.ID || rules[0].Category != "naming" || rules[0].RuleContent != "Use camelCase" || rules[0].Priority != 5 || !rules[0].IsActive {
+		t.Errorf("rule = %+v, want the updated naming rule", rules[0])
+	}
+}
+
+func TestMemoryServer_MethodNotAllowed(t *testing.T) {
+	s, _ := newTestMemoryServer(t, wordEmbedder{})
+	req := httptest.NewRequest(http.MethodPut, "/rules", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT /rules status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,242 @@
+// Package api serves the agent to remote clients over WebSocket, as an alternative to
+// the interactive console of the launcher, and its memory store over a REST API.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Chat is a conversation with the agent, such as an agent.Chat.
+type Chat interface {
+	// ChatStream sends userMessage to the agent and writes the text of its answer to out
+	// as it is generated, closing out when the answer is complete.
+	ChatStream(ctx context.Context, userMessage string, out chan<- string) error
+}
+
+// NewChatFunc starts a new conversation with the agent.
+type NewChatFunc func(ctx context.Context) (Chat, error)
+
+// closeTimeout is how long the server waits for a close frame to be written.
+const closeTimeout = 5 * time.Second
+
+// ErrNoToken is returned by NewServer when no bearer token is given.
+var ErrNoToken = errors.New("a bearer token is required")
+
+// Server serves the agent on a /ws WebSocket endpoint. Every connection is a conversation
+// of its own: each text frame the client sends is a user message, and the agent's answer
+// is streamed back as text frames followed by an empty text frame marking its end.
+// Messages of one connection are answered one at a time. The server closes the connection
+// with a close frame when it shuts down or an answer fails.
+type Server struct {
+	token   string
+	newChat NewChatFunc
+
+	http     *http.Server
+	upgrader websocket.Upgrader
+
+	ctx    context.Context // Passed to ChatStream, canceled when shutdown gives up on draining
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	closing  bool
+	done     chan struct{}  // Closed when the server starts shutting down
+	sessions sync.WaitGroup // Open connections
+}
+
+// NewServer creates a Server listening on addr. Clients must send token as a bearer
+// token in the Authorization header of the upgrade request.
+func NewServer(addr, token string, newChat NewChatFunc) (*Server, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		token:   token,
+		newChat: newChat,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.handleWebSocket)
+	s.http = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the server, for serving it on a listener of its own.
+func (s *Server) Handler() http.Handler {
+	return s.http.Handler
+}
+
+// ListenAndServe serves connections until Shutdown is called, after which it returns
+// http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	return s.http.ListenAndServe()
+}
+
+// Serve serves connections accepted on l until Shutdown is called, like ListenAndServe.
+func (s *Server) Serve(l net.Listener) error {
+	return s.http.Serve(l)
+}
+
+// Shutdown stops accepting connections and drains the open ones: answers in progress are
+// completed, then every connection is closed with a close frame. When ctx ends first, the
+// answers in progress are canceled and ctx's error is returned once all connections closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closing {
+		s.closing = true
+		close(s.done)
+	}
+	s.mu.Unlock()
+
+	err := s.http.Shutdown(ctx)
+
+	drained := make(chan struct{})
+	go func() {
+		s.sessions.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.cancel()
+		<-drained
+		return ctx.Err()
+	}
+	s.cancel()
+	return err
+}
+
+// handleWebSocket checks the bearer token, upgrades the request and serves the connection.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, s.token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	s.sessions.Add(1)
+	s.mu.Unlock()
+	defer s.sessions.Done()
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client
+		slog.WarnContext(r.Context(), "WebSocket upgrade failed", slog.String("remote_addr", r.RemoteAddr), slog.Any("error", err))
+		return
+	}
+	defer conn.Close()
+	s.serve(conn)
+}
+
+// authorized reports whether r carries token as a bearer token in its Authorization header.
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// serve answers the messages of conn until the client goes away, an answer fails or
+// the server shuts down.
+func (s *Server) serve(conn *websocket.Conn) {
+	chat, err := s.newChat(s.ctx)
+	if err != nil {
+		slog.ErrorContext(s.ctx, "failed to start WebSocket session", slog.Any("error", err))
+		writeClose(conn, websocket.CloseInternalServerErr, "failed to start session")
+		return
+	}
+
+	// Read in a goroutine of its own so that shutdown is noticed while waiting for a message
+	stop := make(chan struct{})
+	defer close(stop)
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if kind != websocket.TextMessage {
+				continue
+			}
+			select {
+			case messages <- string(data):
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-s.done:
+			writeClose(conn, websocket.CloseGoingAway, "server shutting down")
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if err := s.answer(conn, chat, msg); err != nil {
+				slog.ErrorContext(s.ctx, "WebSocket session failed", slog.Any("error", err))
+				writeClose(conn, websocket.CloseInternalServerErr, "agent failed")
+				return
+			}
+		}
+	}
+}
+
+// answer streams the agent's answer to msg to conn, followed by an empty text frame.
+func (s *Server) answer(conn *websocket.Conn, chat Chat, msg string) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	out := make(chan string)
+	errc := make(chan error, 1)
+	go func() { errc <- chat.ChatStream(ctx, msg, out) }()
+
+	var writeErr error
+	for text := range out {
+		if writeErr != nil {
+			continue // Drain the answer that was canceled below
+		}
+		if writeErr = conn.WriteMessage(websocket.TextMessage, []byte(text)); writeErr != nil {
+			cancel()
+		}
+	}
+	if err := <-errc; err != nil && writeErr == nil {
+		return err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write answer: %w", writeErr)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, nil); err != nil {
+		return fmt.Errorf("failed to write answer: %w", err)
+	}
+	return nil
+}
+
+// writeClose sends a close frame with code and reason to conn.
+func writeClose(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout)); err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		slog.Warn("failed to close WebSocket connection", slog.Int("code", code), slog.Any("error", err))
+	}
+}
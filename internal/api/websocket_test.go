@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// echoChat answers every message with its words, one frame each. When release is set,
+// answers wait for it to be closed before the last word.
+type echoChat struct {
+	release chan struct{}
+	started chan struct{} // Receives a value when an answer starts, when set
+}
+
+func (c *echoChat) ChatStream(ctx context.Context, userMessage string, out chan<- string) error {
+	defer close(out)
+	if c.started != nil {
+		c.started <- struct{}{}
+	}
+	words := strings.Fields(userMessage)
+	for i, word := range words {
+		if c.release != nil && i == len(words)-1 {
+			select {
+			case <-c.release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case out <- word:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if userMessage == "fail" {
+		return errors.New("agent failed")
+	}
+	return nil
+}
+
+// startServer serves s on a test server and returns the URL of its /ws endpoint.
+func startServer(t *testing.T, s *Server) string {
+	t.Helper()
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+// dial connects to url with token as bearer token.
+func dial(t *testing.T, url, token string) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("Dial failed: %v (status %d)", err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readAnswer reads text frames from conn up to the empty frame ending an answer.
+func readAnswer(t *testing.T, conn *websocket.Conn) []string {
+	t.Helper()
+	var frames []string
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage failed after %q: %v", frames, err)
+		}
+		if kind != websocket.TextMessage {
+			t.Fatalf("expected a text frame, got type %d", kind)
+		}
+		if len(data) == 0 {
+			return frames
+		}
+		frames = append(frames, string(data))
+	}
+}
+
+func TestNewServer_RequiresToken(t *testing.T) {
+	if _, err := NewServer(":0", "", nil); !errors.Is(err, ErrNoToken) {
+		t.Errorf("expected ErrNoToken, got %v", err)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	s, err := NewServer(":0", "secret", func(ctx context.Context) (Chat, error) { return &echoChat{}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startServer(t, s)
+
+	for _, header := range []http.Header{
+		nil,
+		{"Authorization": {"Bearer wrong"}},
+		{"Authorization": {"secret"}},
+	} {
+		_, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if err == nil {
+			t.Errorf("expected the upgrade with %v to be refused", header)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 for %v, got %v", header, resp)
+		}
+	}
+}
+
+func TestServer_StreamsAnswers(t *testing.T) {
+	var mu sync.Mutex
+	chats := 0
+	s, err := NewServer(":0", "secret", func(ctx context.Context) (Chat, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		chats++
+		return &echoChat{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startServer(t, s)
+
+	// Concurrent connections are answered independently, each in a session of its own
+	var wg sync.WaitGroup
+	for _, msg := range []string{"the answer is 42", "hello world"} {
+		conn := dial(t, url, "secret")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 2 {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+					t.Errorf("WriteMessage failed: %v", err)
+					return
+				}
+				if got := readAnswer(t, conn); !slices.Equal(got, strings.Fields(msg)) {
+					t.Errorf("expected the words of %q, got %q", msg, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if chats != 2 {
+		t.Errorf("expected one session per connection, got %d", chats)
+	}
+}
+
+func TestServer_ClosesOnFailure(t *testing.T) {
+	s, err := NewServer(":0", "secret", func(ctx context.Context) (Chat, error) { return &echoChat{}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dial(t, startServer(t, s), "secret")
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("fail")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "fail" {
+		t.Fatalf("expected the streamed text before the failure, got %q, %v", data, err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseInternalServerErr) {
+		t.Errorf("expected an internal error close frame, got %v", err)
+	}
+}
+
+func TestServer_ShutdownDrainsSessions(t *testing.T) {
+	chat := &echoChat{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	s, err := NewServer(":0", "secret", func(ctx context.Context) (Chat, error) { return chat, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startServer(t, s)
+	busy := dial(t, url, "secret")
+	idle := dial(t, url, "secret")
+
+	if err := busy.WriteMessage(websocket.TextMessage, []byte("still answering")); err != nil {
+		t.Fatal(err)
+	}
+	<-chat.started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	// The idle connection is closed right away, the busy one once its answer is complete
+	idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := idle.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected a going away close frame on the idle connection, got %v", err)
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned before the answer in progress was complete: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(chat.release)
+	if got := readAnswer(t, busy); !slices.Equal(got, []string{"still", "answering"}) {
+		t.Errorf("expected the complete answer, got %q", got)
+	}
+	if _, _, err := busy.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected a going away close frame after the answer, got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+
+	if _, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer secret"}}); err == nil {
+		t.Error("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	chat := &echoChat{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	s, err := NewServer(":0", "secret", func(ctx context.Context) (Chat, error) { return chat, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dial(t, startServer(t, s), "secret")
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("never finished")); err != nil {
+		t.Fatal(err)
+	}
+	<-chat.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the answer in progress to be canceled at the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package audit records every tool call of the agent to a JSON Lines file, so that
+// deployments can tell which files were read and which experiences were saved by whom.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSizeMB is the size at which the log file is rotated when none is configured.
+	DefaultMaxSizeMB = 100
+	// MaxBackups is the number of rotated log files kept, named <path>.1 (the newest) to
+	// <path>.<MaxBackups>; older ones are deleted.
+	MaxBackups = 5
+)
+
+// Entry is the record of a single tool call.
+type Entry struct {
+	Timestamp  time.Time `json:"timestamp"`   // When the call started
+	SessionID  string    `json:"session_id"`  // Session the tool was called in, empty outside sessions
+	UserID     string    `json:"user_id"`     // User of the session
+	ToolName   string    `json:"tool_name"`   // Name of the tool
+	ArgsJSON   string    `json:"args_json"`   // Arguments of the call, as JSON
+	Success    bool      `json:"success"`     // Whether the tool reported success
+	DurationMS int64     `json:"duration_ms"` // Duration of the call in milliseconds
+	ResultHash string    `json:"result_hash"` // Hex SHA-256 of the JSON result, identifying it without storing it
+}
+
+// Logger appends entries to a log file as JSON Lines and rotates the file when it would
+// grow beyond its maximum size. It is safe for concurrent use.
+type Logger struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewLogger opens the log file at path for appending, creating it and its directory if
+// needed. The file is rotated at maxSizeMB megabytes, DefaultMaxSizeMB when zero.
+func NewLogger(path string, maxSizeMB int) (*Logger, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = DefaultMaxSizeMB
+	}
+	l := &Logger{path: path, maxSize: int64(maxSizeMB) << 20}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Log appends e to the log file, rotating it first if e would not fit.
+func (l *Logger) Log(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("audit log %s is closed", l.path)
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file. Entries logged afterwards are rejected.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// open opens the log file for appending and records its current size. The caller must
+// hold l.mu, or have exclusive access to l.
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// rotate closes the log file, shifts the backups up by one, dropping the oldest, moves
+// the log file to <path>.1 and opens a new one. The caller must hold l.mu.
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	l.file = nil
+
+	for i := MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(l.backup(i), l.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, l.backup(1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return l.open()
+}
+
+// backup returns the path of the i-th newest rotated log file.
+func (l *Logger) backup(i int) string {
+	return l.path + "." + strconv.Itoa(i)
+}
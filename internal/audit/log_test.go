@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEntries decodes the JSON lines of the file at path, failing on invalid JSON.
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLogger_Log(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	l, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := Entry{
+		Timestamp:  start,
+		SessionID:  "session-1",
+		UserID:     "alice",
+		ToolName:   "read_file_content",
+		ArgsJSON:   `{"filepath":"main.go"}`,
+		Success:    true,
+		DurationMS: 12,
+		ResultHash: "abc",
+	}
+	if err := l.Log(entry); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"timestamp"`, `"session_id"`, `"tool_name"`, `"args_json"`, `"success"`, `"duration_ms"`, `"result_hash"`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("expected key %s in %s", key, data)
+		}
+	}
+	if got := readEntries(t, path); len(got) != 1 || got[0] != entry {
+		t.Errorf("expected the logged entry back, got %+v", got)
+	}
+
+	if err := l.Log(entry); err == nil {
+		t.Error("expected Log to fail after Close")
+	}
+
+	// Reopening appends to the existing file
+	l, err = NewLogger(path, 0)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	if err := l.Log(entry); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	l.Close()
+	if got := readEntries(t, path); len(got) != 2 {
+		t.Errorf("expected 2 entries after reopening, got %d", len(got))
+	}
+}
+
+func TestLogger_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := NewLogger(path, 1)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer l.Close()
+
+	entry := Entry{ToolName: "grep_in_files", ArgsJSON: strings.Repeat("x", 100), DurationMS: 99}
+	line, _ := json.Marshal(entry)
+	l.maxSize = int64(3*(len(line)+1) + 2) // Three entries per file
+
+	total := 3 * (MaxBackups + 2)
+	for i := range total {
+		entry.DurationMS = int64(i)
+		if err := l.Log(entry); err != nil {
+			t.Fatalf("Log %d failed: %v", i, err)
+		}
+	}
+
+	// The current file holds the last three entries, the backups the ones before
+	if got := readEntries(t, path); len(got) != 3 || got[0].DurationMS != int64(total-3) {
+		t.Errorf("unexpected current file: %+v", got)
+	}
+	for i := 1; i <= MaxBackups; i++ {
+		got := readEntries(t, l.backup(i))
+		if want := int64(total - 3*(i+1)); len(got) != 3 || got[0].DurationMS != want {
+			t.Errorf("backup %d: expected 3 entries starting at %d, got %+v", i, want, got)
+		}
+	}
+	if _, err := os.Stat(l.backup(MaxBackups + 1)); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d backups, got %v", MaxBackups, err)
+	}
+}
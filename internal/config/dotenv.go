@@ -0,0 +1,101 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultDotEnvPath is the .env file LoadDotEnv is called with at startup.
+const DefaultDotEnvPath = ".env"
+
+// LoadDotEnv reads KEY=VALUE lines from the .env file at path and sets each key as an
+// environment variable unless it is already set to a non-empty value, so that the real
+// environment takes precedence over the file. Blank lines and lines starting with # are
+// skipped, an optional "export " prefix is ignored, and values may be quoted: double-quoted
+// values support Go escape sequences, single-quoted values are taken literally, and
+// unquoted values end at a " #" comment. The error wraps fs.ErrNotExist when the file
+// does not exist.
+func LoadDotEnv(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := parseDotEnvLine(line)
+		if err != nil {
+			return fmt.Errorf("%s: line %d: %w", path, lineNum, err)
+		}
+		if os.Getenv(key) != "" {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s: line %d: failed to set %s: %w", path, lineNum, key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseDotEnvLine splits a trimmed, non-comment line of a .env file into its key and
+// unquoted value.
+func parseDotEnvLine(line string) (string, string, error) {
+	line = strings.TrimPrefix(line, "export ")
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", fmt.Errorf("expected KEY=VALUE, got %q", line)
+	}
+	key = strings.TrimSpace(key)
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := closingQuote(value)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted value for %s", key)
+		}
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid quoted value for %s: %v", key, err)
+		}
+		return key, unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted value for %s", key)
+		}
+		return key, value[1 : end+1], nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return key, value, nil
+}
+
+// closingQuote returns the index of the double quote closing the double-quoted string at
+// the start of s, skipping escaped quotes, or -1 if it is not closed.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDotEnv writes content to a .env file in a temporary directory and returns its path.
+func writeDotEnv(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	for _, name := range []string{"DOTENV_PLAIN", "DOTENV_DOUBLE", "DOTENV_SINGLE", "DOTENV_COMMENT", "DOTENV_EXPORT", "DOTENV_EMPTY", "DOTENV_EQUALS"} {
+		t.Setenv(name, "")
+	}
+	path := writeDotEnv(t, `
+# Local settings
+DOTENV_PLAIN=plain value
+DOTENV_DOUBLE="line one\nsays \"hi\"" # trailing comment
+DOTENV_SINGLE='no $expansion \n here'
+DOTENV_COMMENT=value # comment
+export DOTENV_EXPORT=exported
+DOTENV_EMPTY=
+  DOTENV_EQUALS = postgres://u:p@host/db?sslmode=disable
+`)
+
+	if err := LoadDotEnv(path); err != nil {
+		t.Fatalf("LoadDotEnv failed: %v", err)
+	}
+	want := map[string]string{
+		"DOTENV_PLAIN":   "plain value",
+		"DOTENV_DOUBLE":  "line one\nsays \"hi\"",
+		"DOTENV_SINGLE":  `no $expansion \n here`,
+		"DOTENV_COMMENT": "value",
+		"DOTENV_EXPORT":  "exported",
+		"DOTENV_EMPTY":   "",
+		"DOTENV_EQUALS":  "postgres://u:p@host/db?sslmode=disable",
+	}
+	for name, value := range want {
+		if got := os.Getenv(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestLoadDotEnv_EnvTakesPrecedence(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://env/db")
+	path := writeDotEnv(t, "DATABASE_URL=postgres://file/db\nGOOGLE_API_KEY=file-key\n")
+
+	if err := LoadDotEnv(path); err != nil {
+		t.Fatalf("LoadDotEnv failed: %v", err)
+	}
+	if got := os.Getenv("DATABASE_URL"); got != "postgres://env/db" {
+		t.Errorf("DATABASE_URL = %q, want the environment's value", got)
+	}
+
+	cfg := Load()
+	if cfg.APIKey != "file-key" || cfg.DatabaseURL != "postgres://env/db" {
+		t.Errorf("Load() APIKey = %q, DatabaseURL = %q, want file-key and postgres://env/db", cfg.APIKey, cfg.DatabaseURL)
+	}
+}
+
+func TestLoadDotEnv_Errors(t *testing.T) {
+	if err := LoadDotEnv(filepath.Join(t.TempDir(), ".env")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadDotEnv of a missing file error = %v, want fs.ErrNotExist", err)
+	}
+
+	t.Setenv("DOTENV_BAD", "")
+	tests := map[string]string{
+		"DOTENV_BAD":           "line 1: expected KEY=VALUE",
+		"=value":               "line 1: invalid key",
+		`DOTENV_BAD="open`:     "line 1: unterminated quoted value",
+		`DOTENV_BAD='open`:     "line 1: unterminated quoted value",
+		"# ok\nDOTENV BAD=x":   "line 2: invalid key",
+		`DOTENV_BAD="bad \q"`:  "line 1: invalid quoted value",
+		"DOTENV_BAD=ok\nnoeq":  "line 2: expected KEY=VALUE",
+		"\n\nDOTENV_BAD=\"x\\": "line 3: unterminated quoted value",
+	}
+	for content, want := range tests {
+		err := LoadDotEnv(writeDotEnv(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadDotEnv(%q) error = %v, want error containing %q", content, err, want)
+		}
+	}
+}
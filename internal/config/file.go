@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKeys maps each key of the YAML config file to the Config field it sets.
+// Keys are the snake_case form of the field names.
+var fileKeys = map[string]func(*Config) any{
+	"database_url":                 func(c *Config) any { return &c.DatabaseURL },
+	"api_key":                      func(c *Config) any { return &c.APIKey },
+	"work_dir":                     func(c *Config) any { return &c.WorkDir },
+	"project_id":                   func(c *Config) any { return &c.ProjectID },
+	"mounts":                       func(c *Config) any { return &c.Mounts },
+	"read_allowed_extensions":      func(c *Config) any { return &c.ReadAllowedExtensions },
+	"read_denied_extensions":       func(c *Config) any { return &c.ReadDeniedExtensions },
+	"tool_enablement_rules":        func(c *Config) any { return &c.ToolEnablementRules },
+	"injection_patterns":           func(c *Config) any { return &c.InjectionPatterns },
+	"snapshot_dir":                 func(c *Config) any { return &c.SnapshotDir },
+	"max_recent_tool_results":      func(c *Config) any { return &c.MaxRecentToolResults },
+	"chat_model":                   func(c *Config) any { return &c.ChatModel },
+	"consolidation_model":          func(c *Config) any { return &c.ConsolidationModel },
+	"embedding_model":              func(c *Config) any { return &c.EmbeddingModel },
+	"embedding_model_short":        func(c *Config) any { return &c.EmbeddingModelShort },
+	"embedding_model_long":         func(c *Config) any { return &c.EmbeddingModelLong },
+	"embedding_model_multilingual": func(c *Config) any { return &c.EmbeddingModelMultilingual },
+	"ollama_host":                  func(c *Config) any { return &c.OllamaHost },
+	"ollama_embed_model":           func(c *Config) any { return &c.OllamaEmbedModel },
+	"embedding_long_threshold":     func(c *Config) any { return &c.EmbeddingLongThreshold },
+	"embedding_cache_size":         func(c *Config) any { return &c.EmbeddingCacheSize },
+	"embedding_cache_ttl":          func(c *Config) any { return &c.EmbeddingCacheTTL },
+	"postgres_max_conns":           func(c *Config) any { return &c.PostgresMaxConns },
+	"postgres_min_conns":           func(c *Config) any { return &c.PostgresMinConns },
+	"postgres_max_conn_idle_time":  func(c *Config) any { return &c.PostgresMaxConnIdleTime },
+	"vector_index_type":            func(c *Config) any { return &c.VectorIndexType },
+	"ivfflat_lists":                func(c *Config) any { return &c.IVFFlatLists },
+	"hnsw_m":                       func(c *Config) any { return &c.HNSWM },
+	"hnsw_ef_construction":         func(c *Config) any { return &c.HNSWEfConstruction },
+	"deduplication_threshold":      func(c *Config) any { return &c.DeduplicationThreshold },
+	"max_experience_age_days":      func(c *Config) any { return &c.MaxExperienceAgeDays },
+	"deleted_retention_days":       func(c *Config) any { return &c.DeletedRetentionDays },
+	"min_similarity":               func(c *Config) any { return &c.MinSimilarity },
+	"min_consolidation_score":      func(c *Config) any { return &c.MinConsolidationScore },
+	"cost_input_per_million":       func(c *Config) any { return &c.CostInputPerMillion },
+	"cost_output_per_million":      func(c *Config) any { return &c.CostOutputPerMillion },
+	"cost_embedding_per_million":   func(c *Config) any { return &c.CostEmbeddingPerMillion },
+	"max_result_count":             func(c *Config) any { return &c.MaxResultCount },
+	"max_search_calls_per_session": func(c *Config) any { return &c.MaxSearchCallsPerSession },
+	"max_save_calls_per_session":   func(c *Config) any { return &c.MaxSaveCallsPerSession },
+	"otlp_endpoint":                func(c *Config) any { return &c.OTLPEndpoint },
+	"metrics_port":                 func(c *Config) any { return &c.MetricsPort },
+	"debug_tools":                  func(c *Config) any { return &c.DebugTools },
+	"audit_log_path":               func(c *Config) any { return &c.AuditLogPath },
+	"audit_log_max_size_mb":        func(c *Config) any { return &c.AuditLogMaxSizeMB },
+	"ws_auth_token":                func(c *Config) any { return &c.WSAuthToken },
+	"http_auth_token":              func(c *Config) any { return &c.HTTPAuthToken },
+	"retry_max_attempts":           func(c *Config) any { return &c.RetryMaxAttempts },
+	"retry_initial_delay":          func(c *Config) any { return &c.RetryInitialDelay },
+	"ingest_log_path":              func(c *Config) any { return &c.IngestLogPath },
+	"ingest_log_pattern":           func(c *Config) any { return &c.IngestLogPattern },
+	"ingest_log_interval":          func(c *Config) any { return &c.IngestLogInterval },
+}
+
+// LoadFile loads configuration from the YAML file at path and merges it with
+// environment variables, which take precedence over values in the file.
+// Unlike Load, it returns an error naming the offending YAML key instead of exiting.
+func LoadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := decodeFile(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	applyEnv(&cfg)
+
+	// Set defaults
+	if cfg.WorkDir == "" {
+		cfg.WorkDir, _ = os.Getwd()
+	}
+	if cfg.ProjectID == "" {
+		cfg.ProjectID = filepath.Base(cfg.WorkDir)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// decodeFile decodes the YAML document data into cfg, one key at a time so that
+// errors can name the key they come from.
+func decodeFile(data []byte, cfg *Config) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil // Empty file
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of config keys", root.Line)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		field, ok := fileKeys[key.Value]
+		if !ok {
+			return fmt.Errorf("line %d: unknown key %q", key.Line, key.Value)
+		}
+		if err := value.Decode(field(cfg)); err != nil {
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+				err = errors.New(typeErr.Errors[0])
+			}
+			return fmt.Errorf("invalid value for key %q: %v", key.Value, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that the required fields are set and that numeric fields are in range.
+// Errors name the YAML key of the invalid field.
+func (c Config) validate() error {
+	if c.APIKey == "" {
+		return errors.New("api_key is required (or set GOOGLE_API_KEY)")
+	}
+	if c.DatabaseURL == "" {
+		return errors.New("database_url is required (or set DATABASE_URL)")
+	}
+
+	for alias, path := range c.Mounts {
+		if alias == "" || strings.Contains(alias, "/") {
+			return fmt.Errorf("mounts: invalid alias %q, must be non-empty and must not contain /", alias)
+		}
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("mounts: path of %q must be absolute, got %q", alias, path)
+		}
+	}
+
+	for key, n := range map[string]int{
+		"max_recent_tool_results":      c.MaxRecentToolResults,
+		"embedding_long_threshold":     c.EmbeddingLongThreshold,
+		"embedding_cache_size":         c.EmbeddingCacheSize,
+		"ivfflat_lists":                c.IVFFlatLists,
+		"hnsw_m":                       c.HNSWM,
+		"hnsw_ef_construction":         c.HNSWEfConstruction,
+		"max_experience_age_days":      c.MaxExperienceAgeDays,
+		"deleted_retention_days":       c.DeletedRetentionDays,
+		"max_result_count":             c.MaxResultCount,
+		"max_search_calls_per_session": c.MaxSearchCallsPerSession,
+		"max_save_calls_per_session":   c.MaxSaveCallsPerSession,
+		"metrics_port":                 c.MetricsPort,
+		"audit_log_max_size_mb":        c.AuditLogMaxSizeMB,
+		"retry_max_attempts":           c.RetryMaxAttempts,
+	} {
+		if n < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", key, n)
+		}
+	}
+	for key, d := range map[string]time.Duration{
+		"embedding_cache_ttl": c.EmbeddingCacheTTL,
+		"retry_initial_delay": c.RetryInitialDelay,
+		"ingest_log_interval": c.IngestLogInterval,
+	} {
+		if d < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", key, d)
+		}
+	}
+	for key, f := range map[string]float32{
+		"deduplication_threshold": c.DeduplicationThreshold,
+		"min_similarity":          c.MinSimilarity,
+		"min_consolidation_score": c.MinConsolidationScore,
+	} {
+		if f < 0 || f > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %g", key, f)
+		}
+	}
+	for key, f := range map[string]float32{
+		"cost_input_per_million":     c.CostInputPerMillion,
+		"cost_output_per_million":    c.CostOutputPerMillion,
+		"cost_embedding_per_million": c.CostEmbeddingPerMillion,
+	} {
+		if f < 0 {
+			return fmt.Errorf("%s must not be negative, got %g", key, f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the environment variables read by LoadFile for the duration of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"DATABASE_URL", "GOOGLE_API_KEY", "WORK_DIR", "MOUNTS", "READ_ALLOWED_EXTENSIONS", "READ_DENIED_EXTENSIONS", "TOOL_ENABLEMENT_RULES", "INJECTION_PATTERNS",
+		"SNAPSHOT_DIR", "MAX_RECENT_TOOL_RESULTS", "CHAT_MODEL", "CONSOLIDATION_MODEL", "EMBEDDING_MODEL", "EMBEDDING_MODEL_SHORT", "EMBEDDING_MODEL_LONG",
+		"EMBEDDING_MODEL_MULTILINGUAL", "OLLAMA_HOST", "OLLAMA_EMBED_MODEL", "EMBEDDING_LONG_THRESHOLD", "EMBEDDING_CACHE_SIZE",
+		"EMBEDDING_CACHE_TTL", "POSTGRES_MAX_CONN", "POSTGRES_MIN_CONN", "POSTGRES_MAX_CONN_IDLE_TIME", "DEDUPLICATION_THRESHOLD", "MAX_EXPERIENCE_AGE_DAYS", "DELETED_RETENTION_DAYS",
+		"MIN_SIMILARITY", "MIN_CONSOLIDATION_SCORE", "MAX_RESULT_COUNT", "MAX_SEARCH_CALLS_PER_SESSION", "MAX_SAVE_CALLS_PER_SESSION", "OTEL_EXPORTER_OTLP_ENDPOINT",
+		"METRICS_PORT", "DEBUG_TOOLS", "AUDIT_LOG_PATH", "AUDIT_LOG_MAX_SIZE_MB", "WS_AUTH_TOKEN", "HTTP_AUTH_TOKEN", "PROJECT_ID", "RETRY_MAX_ATTEMPTS", "RETRY_INITIAL_DELAY",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+// writeConfig writes content to a config file in a temporary directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile_PartialFile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+database_url: postgres://localhost:5432/agent
+api_key: file-key
+tool_enablement_rules:
+  - No external HTTP => read_url
+embedding_cache_size: 512
+embedding_cache_ttl: 30m
+min_similarity: 0.6
+retry_initial_delay: 2s
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://localhost:5432/agent" || cfg.APIKey != "file-key" {
+		t.Errorf("unexpected required fields: %+v", cfg)
+	}
+	if len(cfg.ToolEnablementRules) != 1 || cfg.ToolEnablementRules[0] != "No external HTTP => read_url" {
+		t.Errorf("unexpected tool enablement rules: %v", cfg.ToolEnablementRules)
+	}
+	if cfg.EmbeddingCacheSize != 512 || cfg.EmbeddingCacheTTL != 30*time.Minute || cfg.MinSimilarity != 0.6 || cfg.RetryInitialDelay != 2*time.Second {
+		t.Errorf("unexpected optional fields: %+v", cfg)
+	}
+	// Keys missing from the file keep their defaults
+	if cfg.MaxResultCount != 0 || cfg.WorkDir == "" {
+		t.Errorf("expected defaults for missing keys, got %+v", cfg)
+	}
+}
+
+func TestLoadFile_EnvOverride(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GOOGLE_API_KEY", "env-key")
+	t.Setenv("MAX_RESULT_COUNT", "5")
+	path := writeConfig(t, `
+database_url: postgres://localhost:5432/agent
+api_key: file-key
+max_result_count: 10
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.APIKey != "env-key" || cfg.MaxResultCount != 5 {
+		t.Errorf("expected environment variables to take precedence, got %+v", cfg)
+	}
+	if cfg.DatabaseURL != "postgres://localhost:5432/agent" {
+		t.Errorf("expected file value without env override, got %q", cfg.DatabaseURL)
+	}
+}
+
+func TestLoadFile_ProjectID(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("WORK_DIR", "/src/payments-service")
+	path := writeConfig(t, `
+database_url: postgres://localhost:5432/agent
+api_key: file-key
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.ProjectID != "payments-service" {
+		t.Errorf("expected project ID derived from work dir, got %q", cfg.ProjectID)
+	}
+
+	t.Setenv("PROJECT_ID", "billing")
+	cfg, err = LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.ProjectID != "billing" {
+		t.Errorf("expected PROJECT_ID to take precedence, got %q", cfg.ProjectID)
+	}
+}
+
+func TestLoadFile_Mounts(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+database_url: postgres://localhost:5432/agent
+api_key: file-key
+mounts:
+  lib: /src/shared-lib
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(cfg.Mounts) != 1 || cfg.Mounts["lib"] != "/src/shared-lib" {
+		t.Errorf("unexpected mounts from file: %v", cfg.Mounts)
+	}
+
+	t.Setenv("MOUNTS", "proto=/src/proto; docs = /src/docs")
+	cfg, err = LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(cfg.Mounts) != 2 || cfg.Mounts["proto"] != "/src/proto" || cfg.Mounts["docs"] != "/src/docs" {
+		t.Errorf("expected MOUNTS to replace the mounts from file, got %v", cfg.Mounts)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"missing api key", "database_url: postgres://localhost/agent\n", "api_key is required"},
+		{"missing database url", "api_key: key\n", "database_url is required"},
+		{"unknown key", "api_key: key\napi_keys: key\n", `unknown key "api_keys"`},
+		{"invalid type", "api_key: key\nembedding_cache_size: lots\n", `"embedding_cache_size"`},
+		{"invalid duration", "api_key: key\nembedding_cache_ttl: soon\n", `"embedding_cache_ttl"`},
+		{"out of range", "api_key: key\ndatabase_url: postgres://localhost/agent\nmin_similarity: 1.5\n", "min_similarity must be between 0 and 1"},
+		{"relative mount", "api_key: key\ndatabase_url: postgres://localhost/agent\nmounts:\n  lib: ../lib\n", "must be absolute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			_, err := LoadFile(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
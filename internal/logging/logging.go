@@ -0,0 +1,49 @@
+// Package logging configures the structured logger of the agent.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log formats of NewHandler.
+const (
+	FormatText = "text" // key=value pairs, the default
+	FormatJSON = "json" // One JSON object per line, for log aggregation
+)
+
+// NewHandler returns a handler writing records of level and above to w in format.
+// level is one of "debug", "info", "warn" and "error", and an empty level or format
+// selects "info" and FormatText.
+func NewHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, want %q or %q", format, FormatText, FormatJSON)
+	}
+}
+
+// Setup makes the handler configured by the LOG_LEVEL and LOG_FORMAT environment
+// variables, writing to stderr, the default of slog and of the log package.
+func Setup() error {
+	handler, err := NewHandler(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewHandler_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewHandler(&buf, "warn", "JSON")
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	logger := slog.New(handler)
+	logger.InfoContext(context.Background(), "below the level", slog.String("tool", "grep_in_files"))
+	logger.WarnContext(context.Background(), "search failed", slog.String("tool", "search_past_issues"), slog.Int("results", 3))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected only the warning to be logged, got %q", buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %v", lines[0], err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "search failed" || record["tool"] != "search_past_issues" || record["results"] != float64(3) {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestNewHandler_TextDefault(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewHandler(&buf, "", "")
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	logger := slog.New(handler)
+	logger.Debug("hidden")
+	logger.Info("rules loaded", slog.Int("rules", 2))
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("expected debug records to be dropped at the default level, got %q", out)
+	}
+	if !strings.Contains(out, "level=INFO") || !strings.Contains(out, `msg="rules loaded" rules=2`) {
+		t.Errorf("expected a text record with its fields, got %q", out)
+	}
+}
+
+func TestNewHandler_Invalid(t *testing.T) {
+	if _, err := NewHandler(&bytes.Buffer{}, "verbose", ""); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := NewHandler(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
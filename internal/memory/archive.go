@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Entry names inside a .kbz knowledge base archive.
+const (
+	kbzExperiencesFile = "experiences.ndjson"
+	kbzRulesFile       = "project_rules.json"
+	kbzMetadataFile    = "metadata.json"
+)
+
+// ArchiveMetadata describes the origin of a .kbz knowledge base archive.
+type ArchiveMetadata struct {
+	StoreType       string    `json:"store_type"`       // Concrete store implementation the archive was exported from
+	ExperienceCount int       `json:"experience_count"` // Number of experiences in experiences.ndjson
+	RuleCount       int       `json:"rule_count"`       // Number of rules in project_rules.json
+	ExportedAt      time.Time `json:"exported_at"`      // Timestamp when the archive was created
+	EmbeddingModel  string    `json:"embedding_model"`  // Embedding model used by the exporting store
+}
+
+// archivedExperience is the portable representation of an Experience.
+// Embedding vectors are deliberately excluded; they are regenerated on import.
+type archivedExperience struct {
+	ID           int       `json:"id"`
+	UserID       string    `json:"user_id,omitempty"`
+	ProjectID    *string   `json:"project_id,omitempty"` // Empty for shared experiences, nil in archives written before projects were exported
+	Source       string    `json:"source,omitempty"`
+	ErrorPattern string    `json:"error_pattern"`
+	RootCause    string    `json:"root_cause"`
+	Solution     string    `json:"solution"`
+	Tags         []string  `json:"tags,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// archivedRule is the portable representation of a ProjectRule.
+type archivedRule struct {
+	Category    string `json:"category"`
+	RuleContent string `json:"rule_content"`
+	Priority    int    `json:"priority"`
+	IsActive    bool   `json:"is_active"`
+}
+
+// ExportKBZ writes the entire knowledge base held by store to a zip archive at destPath.
+// The archive contains experiences.ndjson (one experience per line), project_rules.json
+// and metadata.json. Embedding vectors are not exported.
+func ExportKBZ(ctx context.Context, store Store, destPath string) (err error) {
+	experiences, err := store.ListExperiences(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list experiences: %w", err)
+	}
+
+	rules, err := store.ListProjectRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list project rules: %w", err)
+	}
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close archive: %w", cerr)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+
+	w, err := zw.Create(kbzExperiencesFile)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", kbzExperiencesFile, err)
+	}
+	if err := writeExperiences(w, experiences); err != nil {
+		return err
+	}
+
+	archivedRules := make([]archivedRule, 0, len(rules))
+	for _, rule := range rules {
+		archivedRules = append(archivedRules, archivedRule{
+			Category:    rule.Category,
+			RuleContent: rule.RuleContent,
+			Priority:    rule.Priority,
+			IsActive:    rule.IsActive,
+		})
+	}
+	if err := writeZipJSON(zw, kbzRulesFile, archivedRules); err != nil {
+		return err
+	}
+
+	metadata := ArchiveMetadata{
+		StoreType:       fmt.Sprintf("%T", store),
+		ExperienceCount: len(experiences),
+		RuleCount:       len(rules),
+		ExportedAt:      time.Now().UTC(),
+		EmbeddingModel:  EmbeddingModel,
+	}
+	if err := writeZipJSON(zw, kbzMetadataFile, metadata); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return nil
+}
+
+// ImportKBZ reads a .kbz archive created by ExportKBZ and saves its contents into store.
+// Every experience is re-embedded with embedder because vectors are not part of the archive.
+// Rules keep their active state and replace the priority of the same rules in store, and
+// experiences that already exist in store are skipped.
+// Returns the number of experiences imported.
+func ImportKBZ(ctx context.Context, store Store, zipPath string, embedder Embedder) (int, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	var rules []archivedRule
+	if err := readZipJSON(&zr.Reader, kbzRulesFile, &rules); err != nil {
+		return 0, err
+	}
+	for _, rule := range rules {
+		id, err := store.UpsertProjectRule(ctx, rule.Category, rule.RuleContent, rule.Priority)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import project rule: %w", err)
+		}
+		// Rules are added, or updated, active
+		if !rule.IsActive {
+			if err := store.UpdateProjectRule(ctx, id, rule.RuleContent, rule.Priority, false); err != nil {
+				return 0, fmt.Errorf("failed to deactivate imported project rule %d: %w", id, err)
+			}
+		}
+	}
+
+	rc, err := zr.Open(kbzExperiencesFile)
+	if err != nil {
+		return 0, fmt.Errorf("archive is missing %s: %w", kbzExperiencesFile, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	return ImportExperiences(ctx, store, rc, embedder)
+}
+
+// ReadKBZMetadata returns the metadata stored in a .kbz archive without importing it.
+func ReadKBZMetadata(zipPath string) (ArchiveMetadata, error) {
+	var metadata ArchiveMetadata
+
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return metadata, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	err = readZipJSON(&zr.Reader, kbzMetadataFile, &metadata)
+	return metadata, err
+}
+
+// writeZipJSON adds a file named name to the archive containing v encoded as indented JSON.
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// readZipJSON decodes the JSON file named name from the archive into v.
+func readZipJSON(zr *zip.Reader, name string, v any) error {
+	rc, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("archive is missing %s: %w", name, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
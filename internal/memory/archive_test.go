@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportImportKBZ(t *testing.T) {
+	ctx := context.Background()
+
+	source := NewInMemoryStore()
+	for i := 1; i <= 5; i++ {
+		pattern := fmt.Sprintf("error pattern %d", i)
+		if err := source.SaveExperience(ctx, "user-a", pattern, "cause", "solution", nil, []float32{0.1, 0.2, 0.3}); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+	if _, err := source.AddProjectRule(ctx, "STYLE", "禁止在循环中使用 defer", 1); err != nil {
+		t.Fatalf("AddProjectRule failed: %v", err)
+	}
+	inactiveID, err := source.AddProjectRule(ctx, "SECURITY", "禁止在代码中硬编码密钥或密码", 2)
+	if err != nil {
+		t.Fatalf("AddProjectRule failed: %v", err)
+	}
+	if err := source.UpdateProjectRule(ctx, inactiveID, "禁止在代码中硬编码密钥或密码", 2, false); err != nil {
+		t.Fatalf("UpdateProjectRule failed: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "knowledge.kbz")
+	if err := ExportKBZ(ctx, source, archivePath); err != nil {
+		t.Fatalf("ExportKBZ failed: %v", err)
+	}
+
+	metadata, err := ReadKBZMetadata(archivePath)
+	if err != nil {
+		t.Fatalf("ReadKBZMetadata failed: %v", err)
+	}
+	if metadata.ExperienceCount != 5 || metadata.RuleCount != 2 {
+		t.Errorf("unexpected metadata counts: %+v", metadata)
+	}
+	if metadata.EmbeddingModel != EmbeddingModel {
+		t.Errorf("expected embedding model %q, got %q", EmbeddingModel, metadata.EmbeddingModel)
+	}
+
+	destination := NewInMemoryStore()
+	embedder := &mockEmbedder{embedValue: []float32{0.4, 0.5, 0.6}}
+	imported, err := ImportKBZ(ctx, destination, archivePath, embedder)
+	if err != nil {
+		t.Fatalf("ImportKBZ failed: %v", err)
+	}
+
+	if imported != 5 || len(destination.experiences) != 5 {
+		t.Errorf("expected 5 imported experiences, got %d (store has %d)", imported, len(destination.experiences))
+	}
+	if len(destination.rules) != 2 {
+		t.Errorf("expected 2 imported rules, got %d", len(destination.rules))
+	}
+	if active, _ := destination.GetProjectRules(ctx, "", true); len(active) != 1 || active[0] != "禁止在循环中使用 defer" {
+		t.Errorf("expected the inactive rule to be imported inactive, got active rules %v", active)
+	}
+	for i, saved := range destination.experiences {
+		want := fmt.Sprintf("error pattern %d", i+1)
+		if saved.ErrorPattern != want {
+			t.Errorf("experience %d: expected pattern %q, got %q", i, want, saved.ErrorPattern)
+		}
+		if saved.UserID != "user-a" {
+			t.Errorf("experience %d: expected user ID to be preserved, got %q", i, saved.UserID)
+		}
+		if len(saved.vector) != 3 || saved.vector[0] != 0.4 {
+			t.Errorf("experience %d: expected re-embedded vector, got %v", i, saved.vector)
+		}
+	}
+}
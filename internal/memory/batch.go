@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchEmbedder is implemented by embedders that can embed several texts in one request.
+// It is kept separate from Embedder so that existing embedders keep working unchanged.
+type BatchEmbedder interface {
+	Embedder
+	BatchEmbed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedAll embeds texts, in a single BatchEmbed call if embedder supports it and
+// one Embed call per text otherwise. The vectors are returned in the order of texts.
+func EmbedAll(ctx context.Context, embedder Embedder, texts []string) ([][]float32, error) {
+	if batcher, ok := embedder.(BatchEmbedder); ok {
+		vectors, err := batcher.BatchEmbed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(texts) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(vectors))
+		}
+		return vectors, nil
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := embedder.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// SaveExperienceBatch embeds the patterns of all items at once and stores them with
+// a single BatchSaveExperiences call, instead of one embedding request and one insert
+// per experience.
+func SaveExperienceBatch(ctx context.Context, store Store, embedder Embedder, items []ExperienceInput) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, len(items))
+	for i, item := range items {
+		patterns[i] = item.Pattern
+	}
+	vectors, err := EmbedAll(ctx, embedder, patterns)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+
+	withVectors := make([]ExperienceInput, len(items))
+	for i, item := range items {
+		item.Vector = vectors[i]
+		withVectors[i] = item
+	}
+	return store.BatchSaveExperiences(ctx, withVectors)
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// embedLatency simulates the round trip of an embedding API request.
+const embedLatency = time.Millisecond
+
+// latencyEmbedder pays embedLatency per request, whether it embeds one text or many.
+type latencyEmbedder struct {
+	calls      int
+	batchCalls int
+}
+
+func (e *latencyEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	time.Sleep(embedLatency)
+	return []float32{float32(len(text))}, nil
+}
+
+func (e *latencyEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.batchCalls++
+	time.Sleep(embedLatency)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func batchItems(n int) []ExperienceInput {
+	items := make([]ExperienceInput, n)
+	for i := range items {
+		items[i] = ExperienceInput{Pattern: fmt.Sprintf("error pattern %d", i), Cause: "cause", Solution: "solution"}
+	}
+	return items
+}
+
+func TestSaveExperienceBatch(t *testing.T) {
+	ctx := context.Background()
+	store := &mockStore{}
+	embedder := &latencyEmbedder{}
+
+	if err := SaveExperienceBatch(ctx, store, embedder, batchItems(10)); err != nil {
+		t.Fatalf("SaveExperienceBatch failed: %v", err)
+	}
+
+	if embedder.batchCalls != 1 || embedder.calls != 0 {
+		t.Errorf("expected a single BatchEmbed call, got %d batch and %d single calls", embedder.batchCalls, embedder.calls)
+	}
+	if store.batchSaves != 1 {
+		t.Errorf("expected a single BatchSaveExperiences call, got %d", store.batchSaves)
+	}
+	if len(store.experiences) != 10 {
+		t.Fatalf("expected 10 saved experiences, got %d", len(store.experiences))
+	}
+	for i, saved := range store.experiences {
+		if want := float32(len(saved.ErrorPattern)); len(saved.vector) != 1 || saved.vector[0] != want {
+			t.Errorf("experience %d has vector %v, want the embedding of its own pattern", i, saved.vector)
+		}
+	}
+}
+
+func TestEmbedAll_FallsBackToEmbed(t *testing.T) {
+	embedder := &countingEmbedder{}
+	vectors, err := EmbedAll(context.Background(), embedder, []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("EmbedAll failed: %v", err)
+	}
+	if embedder.calls != 3 || len(vectors) != 3 || vectors[2][0] != 3 {
+		t.Errorf("expected one Embed call per text in order, got %d calls and %v", embedder.calls, vectors)
+	}
+}
+
+// BenchmarkSaveExperiences_Sequential saves 100 experiences with one embedding request each.
+func BenchmarkSaveExperiences_Sequential(b *testing.B) {
+	ctx := context.Background()
+	items := batchItems(100)
+	for n := 0; n < b.N; n++ {
+		store := &mockStore{}
+		embedder := &latencyEmbedder{}
+		for _, item := range items {
+			vector, err := embedder.Embed(ctx, item.Pattern)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := store.SaveExperience(ctx, item.UserID, item.Pattern, item.Cause, item.Solution, nil, vector); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkSaveExperiences_Batch saves the same 100 experiences with SaveExperienceBatch.
+func BenchmarkSaveExperiences_Batch(b *testing.B) {
+	ctx := context.Background()
+	items := batchItems(100)
+	for n := 0; n < b.N; n++ {
+		if err := SaveExperienceBatch(ctx, &mockStore{}, &latencyEmbedder{}, items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultEmbeddingCacheSize is the number of embeddings kept by CachedEmbedder by default.
+const DefaultEmbeddingCacheSize = 256
+
+// CacheStats reports how effective a CachedEmbedder has been.
+type CacheStats struct {
+	Hits      int64 // Embed calls answered from the cache
+	Misses    int64 // Embed calls passed to the inner embedder
+	Evictions int64 // Entries removed to make room for new ones or because they expired
+}
+
+// CachedEmbedder wraps an Embedder with an LRU cache keyed on the exact text, so
+// repeated embeddings of the same text within a session do not call the API again.
+// It is safe for concurrent use. Cached vectors are shared and must not be modified.
+type CachedEmbedder struct {
+	inner   Embedder
+	maxSize int
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used at the front
+	stats   CacheStats
+}
+
+// cacheEntry is a cached embedding stored in CachedEmbedder.order.
+type cacheEntry struct {
+	text     string
+	vector   []float32
+	storedAt time.Time
+}
+
+// NewCachedEmbedder creates a CachedEmbedder holding at most maxSize embeddings, each
+// valid for ttl. DefaultEmbeddingCacheSize is used when maxSize is not positive, and
+// entries never expire when ttl is not positive.
+func NewCachedEmbedder(inner Embedder, maxSize int, ttl time.Duration) *CachedEmbedder {
+	if maxSize <= 0 {
+		maxSize = DefaultEmbeddingCacheSize
+	}
+	return &CachedEmbedder{
+		inner:   inner,
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Embed returns the cached embedding of text, or embeds it with the inner embedder
+// and caches the result. Errors are not cached.
+func (c *CachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if vector, ok := c.lookup(text); ok {
+		return vector, nil
+	}
+
+	vector, err := c.inner.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	c.store(text, vector)
+	return vector, nil
+}
+
+// BatchEmbed returns the cached embeddings of texts and embeds the rest with a single
+// EmbedAll call on the inner embedder.
+func (c *CachedEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	var missing []string
+	var missingIdx []int
+	for i, text := range texts {
+		if vector, ok := c.lookup(text); ok {
+			vectors[i] = vector
+			continue
+		}
+		missing = append(missing, text)
+		missingIdx = append(missingIdx, i)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := EmbedAll(ctx, c.inner, missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missingIdx {
+		vectors[i] = embedded[j]
+		c.store(missing[j], embedded[j])
+	}
+	return vectors, nil
+}
+
+// CacheStats returns the hit, miss and eviction counts since the cache was created.
+func (c *CachedEmbedder) CacheStats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// lookup returns the cached embedding of text and marks it as recently used.
+// Expired entries are evicted and reported as a miss.
+func (c *CachedEmbedder) lookup(text string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[text]
+	if ok {
+		entry := elem.Value.(*cacheEntry)
+		if c.ttl <= 0 || time.Since(entry.storedAt) < c.ttl {
+			c.order.MoveToFront(elem)
+			c.stats.Hits++
+			return entry.vector, true
+		}
+		c.remove(elem)
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+// store caches vector as the embedding of text, evicting the least recently used
+// entry when the cache is full.
+func (c *CachedEmbedder) store(text string, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[text]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.vector, entry.storedAt = vector, time.Now()
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for c.order.Len() >= c.maxSize {
+		c.remove(c.order.Back())
+	}
+	c.entries[text] = c.order.PushFront(&cacheEntry{text: text, vector: vector, storedAt: time.Now()})
+}
+
+// remove evicts elem from the cache. The caller must hold c.mu.
+func (c *CachedEmbedder) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).text)
+	c.stats.Evictions++
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingEmbedder returns a vector derived from the text length and counts its calls.
+type countingEmbedder struct {
+	calls int
+	err   error
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	return []float32{float32(len(text))}, nil
+}
+
+func TestCachedEmbedder_RepeatedInput(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{}
+	cache := NewCachedEmbedder(inner, 0, 0)
+
+	for i := 0; i < 5; i++ {
+		vector, err := cache.Embed(ctx, "nil pointer dereference")
+		if err != nil {
+			t.Fatalf("Embed failed: %v", err)
+		}
+		if len(vector) != 1 || vector[0] != 23 {
+			t.Errorf("unexpected vector %v", vector)
+		}
+	}
+
+	if inner.calls != 1 {
+		t.Errorf("expected 1 call to the inner embedder, got %d", inner.calls)
+	}
+	if stats := cache.CacheStats(); stats.Hits != 4 || stats.Misses != 1 || stats.Evictions != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCachedEmbedder_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{}
+	cache := NewCachedEmbedder(inner, 2, 0)
+
+	for _, text := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, err := cache.Embed(ctx, text); err != nil {
+			t.Fatalf("Embed failed: %v", err)
+		}
+	}
+
+	// "b" is evicted by "c" because "a" was used more recently, so it is embedded twice
+	if inner.calls != 4 {
+		t.Errorf("expected 4 calls to the inner embedder, got %d", inner.calls)
+	}
+	if stats := cache.CacheStats(); stats.Hits != 2 || stats.Misses != 4 || stats.Evictions != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCachedEmbedder_TTL(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{}
+	cache := NewCachedEmbedder(inner, 0, time.Millisecond)
+
+	if _, err := cache.Embed(ctx, "timeout"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.Embed(ctx, "timeout"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	if inner.calls != 2 {
+		t.Errorf("expected expired entry to be embedded again, got %d calls", inner.calls)
+	}
+}
+
+func TestCachedEmbedder_ErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{err: errors.New("quota exceeded")}
+	cache := NewCachedEmbedder(inner, 0, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Embed(ctx, "deadlock"); err == nil {
+			t.Fatal("expected error from inner embedder")
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected failed embeddings to be retried, got %d calls", inner.calls)
+	}
+}
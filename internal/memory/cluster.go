@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// ExperienceCluster is a group of experiences whose embeddings lie close together, such
+// as many reports of the same class of error.
+type ExperienceCluster struct {
+	RepresentativeID int       // Member closest to the centroid
+	MemberIDs        []int     // IDs of all members, in ascending order
+	Centroid         []float32 // Mean of the members' embeddings
+}
+
+// ClusterExperiences groups the experiences of the store's project, and those shared by
+// all projects, with DBSCAN over their embeddings. Two experiences are neighbours when
+// their cosine distance (1 - similarity) is at most eps, and a cluster grows from every
+// experience with at least minPts neighbours, itself included. Experiences in no cluster
+// are left out, as are codebase index entries. Clusters are ordered by their lowest ID.
+func ClusterExperiences(ctx context.Context, store Store, eps float32, minPts int) ([]ExperienceCluster, error) {
+	experiences, err := store.ListExperiences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiences: %w", err)
+	}
+	ids := make([]int, 0, len(experiences))
+	for _, exp := range experiences {
+		if exp.Source != SourceCodebaseIndex {
+			ids = append(ids, exp.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := store.GetEmbeddings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	ids = slices.DeleteFunc(ids, func(id int) bool { return vectors[id] == nil })
+	return clusterVectors(ids, vectors, eps, minPts), nil
+}
+
+// CollapseClusters keeps only the first of every group of results whose embeddings lie
+// within cosine distance eps of each other, directly or through other results, so that
+// near-duplicates do not crowd out different experiences. results keep their order;
+// results without a vector are always kept.
+func CollapseClusters(results []Experience, vectors map[int][]float32, eps float32) []Experience {
+	ids := make([]int, 0, len(results))
+	for _, exp := range results {
+		if vectors[exp.ID] != nil {
+			ids = append(ids, exp.ID)
+		}
+	}
+
+	// With minPts 1 every result is in exactly one cluster
+	clusterOf := make(map[int]int, len(ids))
+	for i, cluster := range dbscan(ids, vectors, eps, 1) {
+		for _, id := range cluster {
+			clusterOf[id] = i
+		}
+	}
+
+	collapsed := make([]Experience, 0, len(results))
+	seen := make(map[int]bool)
+	for _, exp := range results {
+		if cluster, ok := clusterOf[exp.ID]; ok {
+			if seen[cluster] {
+				continue
+			}
+			seen[cluster] = true
+		}
+		collapsed = append(collapsed, exp)
+	}
+	return collapsed
+}
+
+// clusterVectors runs dbscan and describes the resulting clusters.
+func clusterVectors(ids []int, vectors map[int][]float32, eps float32, minPts int) []ExperienceCluster {
+	var clusters []ExperienceCluster
+	for _, members := range dbscan(ids, vectors, eps, minPts) {
+		slices.Sort(members)
+		centroid := make([]float32, len(vectors[members[0]]))
+		for _, id := range members {
+			for i, v := range vectors[id] {
+				centroid[i] += v / float32(len(members))
+			}
+		}
+		representative, best := members[0], float32(-2)
+		for _, id := range members {
+			if similarity := CosineSimilarity(vectors[id], centroid); similarity > best {
+				representative, best = id, similarity
+			}
+		}
+		clusters = append(clusters, ExperienceCluster{RepresentativeID: representative, MemberIDs: members, Centroid: centroid})
+	}
+	slices.SortFunc(clusters, func(a, b ExperienceCluster) int { return a.MemberIDs[0] - b.MemberIDs[0] })
+	return clusters
+}
+
+// dbscan clusters the vectors of ids by cosine distance and returns the IDs of the
+// members of each cluster. Noise, points that are not within eps of a core point, is
+// not returned.
+func dbscan(ids []int, vectors map[int][]float32, eps float32, minPts int) [][]int {
+	neighbours := func(p int) []int {
+		var found []int
+		for q := range ids {
+			if 1-CosineSimilarity(vectors[ids[p]], vectors[ids[q]]) <= eps {
+				found = append(found, q)
+			}
+		}
+		return found
+	}
+
+	const unvisited, noise = -2, -1
+	labels := make([]int, len(ids))
+	for i := range labels {
+		labels[i] = unvisited
+	}
+
+	var clusters [][]int
+	for p := range ids {
+		if labels[p] != unvisited {
+			continue
+		}
+		seeds := neighbours(p)
+		if len(seeds) < minPts {
+			labels[p] = noise
+			continue
+		}
+
+		cluster := len(clusters)
+		clusters = append(clusters, nil)
+		labels[p] = cluster
+		for len(seeds) > 0 {
+			q := seeds[0]
+			seeds = seeds[1:]
+			if labels[q] == noise {
+				labels[q] = cluster // Border point
+			}
+			if labels[q] != unvisited {
+				continue
+			}
+			labels[q] = cluster
+			if more := neighbours(q); len(more) >= minPts {
+				seeds = append(seeds, more...)
+			}
+		}
+	}
+
+	for p, label := range labels {
+		if label >= 0 {
+			clusters[label] = append(clusters[label], ids[p])
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// clusteredVector returns a unit-ish vector close to the axis-th axis, nudged along the
+// next axis by offset.
+func clusteredVector(axis int, offset float32) []float32 {
+	vector := make([]float32, 4)
+	vector[axis] = 1
+	vector[(axis+1)%4] = offset
+	return vector
+}
+
+func TestClusterExperiences(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	// Four experiences around axis 0, three around axis 1, one on its own near axis 2
+	// and one codebase index entry that is not clustered
+	vectors := [][]float32{
+		clusteredVector(0, 0), clusteredVector(0, 0.05), clusteredVector(1, 0), clusteredVector(0, 0.1),
+		clusteredVector(1, 0.05), clusteredVector(2, 0), clusteredVector(1, 0.1), clusteredVector(0, 0.15),
+	}
+	for i, vector := range vectors {
+		if err := store.SaveExperience(ctx, "", fmt.Sprintf("pattern %d", i+1), "cause", "solution", nil, vector); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+	if err := store.SaveSourcedExperience(ctx, SourceCodebaseIndex, "codebase_index:a:b", "func A()", "a.go:1", "func A()", clusteredVector(2, 0)); err != nil {
+		t.Fatalf("SaveSourcedExperience failed: %v", err)
+	}
+
+	clusters, err := ClusterExperiences(ctx, store, 0.05, 3)
+	if err != nil {
+		t.Fatalf("ClusterExperiences failed: %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+	if !slices.Equal(clusters[0].MemberIDs, []int{1, 2, 4, 8}) || !slices.Equal(clusters[1].MemberIDs, []int{3, 5, 7}) {
+		t.Errorf("expected clusters [1 2 4 8] and [3 5 7], got %v and %v", clusters[0].MemberIDs, clusters[1].MemberIDs)
+	}
+	// The middle members lie closest to the centroids
+	if clusters[0].RepresentativeID != 2 && clusters[0].RepresentativeID != 4 {
+		t.Errorf("expected a central member to represent the first cluster, got %d", clusters[0].RepresentativeID)
+	}
+	if clusters[1].RepresentativeID != 5 {
+		t.Errorf("expected experience 5 to represent the second cluster, got %d", clusters[1].RepresentativeID)
+	}
+	if c := clusters[1].Centroid; len(c) != 4 || c[1] != 1 || c[2] < 0.049 || c[2] > 0.051 {
+		t.Errorf("expected the mean of the members as centroid, got %v", c)
+	}
+
+	// With a higher minPts no experience is dense enough
+	clusters, err = ClusterExperiences(ctx, store, 0.05, 5)
+	if err != nil || len(clusters) != 0 {
+		t.Errorf("expected no clusters, got %+v, %v", clusters, err)
+	}
+}
+
+func TestCollapseClusters(t *testing.T) {
+	vectors := map[int][]float32{
+		1: clusteredVector(0, 0),
+		2: clusteredVector(0, 0.05),
+		3: clusteredVector(1, 0),
+		4: clusteredVector(0, 0.1), // Only close to 1 through 2
+	}
+	results := []Experience{{ID: 2}, {ID: 3}, {ID: 1}, {ID: 5}, {ID: 4}}
+
+	collapsed := CollapseClusters(results, vectors, 0.01)
+	var ids []int
+	for _, exp := range collapsed {
+		ids = append(ids, exp.ID)
+	}
+	if !slices.Equal(ids, []int{2, 3, 5}) {
+		t.Errorf("expected the first result of each cluster and the one without a vector, got %v", ids)
+	}
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// compactionBatchSize is the number of embeddings CompactExperiences loads per query.
+const compactionBatchSize = 1000
+
+// CompactionReport describes the outcome of Store.CompactExperiences.
+type CompactionReport struct {
+	ExaminedCount  int `json:"examined_count"`  // Experiences compared
+	MergedCount    int `json:"merged_count"`    // Near-duplicates soft-deleted into an earlier experience
+	RemainingCount int `json:"remaining_count"` // Examined experiences left after compaction
+}
+
+// compactionEntry is an experience considered by CompactExperiences.
+type compactionEntry struct {
+	id        int
+	model     string // Embedding model; vectors of different models are never compared
+	vector    []float32
+	frequency int
+}
+
+// greedyDuplicates walks entries in order and marks every entry within
+// similarityThreshold of an earlier representative as a duplicate of the earliest such
+// representative; other entries become representatives. It returns the representative of
+// each duplicate, and the frequency each representative gains from its duplicates.
+func greedyDuplicates(entries []compactionEntry, similarityThreshold float32) (duplicateOf map[int]int, gained map[int]int) {
+	duplicateOf, gained = make(map[int]int), make(map[int]int)
+	var representatives []compactionEntry
+	for _, entry := range entries {
+		merged := false
+		for _, rep := range representatives {
+			if rep.model == entry.model && CosineSimilarity(rep.vector, entry.vector) >= similarityThreshold {
+				duplicateOf[entry.id] = rep.id
+				gained[rep.id] += entry.frequency
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			representatives = append(representatives, entry)
+		}
+	}
+	return duplicateOf, gained
+}
+
+// CompactExperiences loads the embeddings of the store's project in batches, merges
+// near-duplicates greedily in ID order and, within one transaction, soft-deletes the
+// duplicates and adds their frequency to their representative.
+func (s *PostgresStore) CompactExperiences(ctx context.Context, similarityThreshold float32) (CompactionReport, error) {
+	var entries []compactionEntry
+	for lastID := 0; ; {
+		rows, err := s.pool.Query(ctx, `
+			SELECT id, COALESCE(embedding_model, ''), embedding, frequency
+			FROM issue_history
+			WHERE id > $1 AND embedding IS NOT NULL AND deleted_at IS NULL
+			  AND project_id = $2 AND source <> $3
+			ORDER BY id
+			LIMIT $4
+		`, lastID, s.projectID, SourceCodebaseIndex, compactionBatchSize)
+		if err != nil {
+			return CompactionReport{}, fmt.Errorf("failed to load embeddings: %w", err)
+		}
+		n := 0
+		for rows.Next() {
+			var entry compactionEntry
+			var vec pgvector.Vector
+			if err := rows.Scan(&entry.id, &entry.model, &vec, &entry.frequency); err != nil {
+				rows.Close()
+				return CompactionReport{}, fmt.Errorf("failed to scan embedding: %w", err)
+			}
+			entry.vector = vec.Slice()
+			entries = append(entries, entry)
+			lastID = entry.id
+			n++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return CompactionReport{}, fmt.Errorf("error iterating embeddings: %w", err)
+		}
+		if n < compactionBatchSize {
+			break
+		}
+	}
+
+	duplicateOf, gained := greedyDuplicates(entries, similarityThreshold)
+	report := CompactionReport{ExaminedCount: len(entries), MergedCount: len(duplicateOf), RemainingCount: len(entries) - len(duplicateOf)}
+	if len(duplicateOf) == 0 {
+		return report, nil
+	}
+
+	duplicates := make([]int, 0, len(duplicateOf))
+	for id := range duplicateOf {
+		duplicates = append(duplicates, id)
+	}
+	representatives, extra := make([]int, 0, len(gained)), make([]int, 0, len(gained))
+	for id, n := range gained {
+		representatives, extra = append(representatives, id), append(extra, n)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return CompactionReport{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `UPDATE issue_history SET deleted_at = NOW() WHERE id = ANY($1) AND deleted_at IS NULL`, duplicates); err != nil {
+		return CompactionReport{}, fmt.Errorf("failed to delete duplicate experiences: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE issue_history h SET frequency = h.frequency + d.extra
+		FROM unnest($1::int[], $2::int[]) AS d(id, extra)
+		WHERE h.id = d.id
+	`, representatives, extra); err != nil {
+		return CompactionReport{}, fmt.Errorf("failed to update experience frequency: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return CompactionReport{}, fmt.Errorf("failed to commit compaction: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testCompaction seeds store with five near-identical experiences and two distinct ones
+// of project, whose patterns start with prefix, and checks that compaction merges the
+// four later near-identical ones into the first.
+func testCompaction(t *testing.T, store Store, project, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+
+	vector := func(dims ...float32) []float32 {
+		v := make([]float32, embeddingDimensions)
+		copy(v, dims)
+		return v
+	}
+	seeds := []struct {
+		pattern string
+		vector  []float32
+	}{
+		{"nil map write", vector(1)},
+		{"nil map write in handler", vector(1, 0.01)},
+		{"assignment to entry in nil map", vector(1, 0.02)},
+		{"nil map write in worker", vector(1, 0, 0.03)},
+		{"panic: nil map write", vector(1, 0.01, 0.01)},
+		{"index out of range", vector(0, 0, 0, 1)},
+		{"deadlock on mutex", vector(0, 0, 0, 0, 1)},
+	}
+	for _, seed := range seeds {
+		exp := Experience{ProjectID: project, ErrorPattern: prefix + seed.pattern, RootCause: "cause", Solution: "fix"}
+		if err := store.ImportExperience(ctx, exp, seed.vector); err != nil {
+			t.Fatalf("ImportExperience failed: %v", err)
+		}
+	}
+	ids := make(map[string]int)
+	experiences, err := store.ListExperiences(ctx)
+	if err != nil {
+		t.Fatalf("ListExperiences failed: %v", err)
+	}
+	for _, exp := range experiences {
+		ids[exp.ErrorPattern] = exp.ID
+	}
+
+	report, err := store.CompactExperiences(ctx, 0.98)
+	if err != nil {
+		t.Fatalf("CompactExperiences failed: %v", err)
+	}
+	want := CompactionReport{ExaminedCount: 7, MergedCount: 4, RemainingCount: 3}
+	if report != want {
+		t.Errorf("expected report %+v, got %+v", want, report)
+	}
+
+	first, err := store.GetExperience(ctx, ids[prefix+"nil map write"])
+	if err != nil {
+		t.Fatalf("expected the earliest experience to survive: %v", err)
+	}
+	if first.Frequency != 5 {
+		t.Errorf("expected the earliest experience to count all 5 occurrences, got %d", first.Frequency)
+	}
+	for _, seed := range seeds[1:5] {
+		if _, err := store.GetExperience(ctx, ids[prefix+seed.pattern]); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected %q to be merged, got %v", seed.pattern, err)
+		}
+	}
+	for _, seed := range seeds[5:] {
+		if _, err := store.GetExperience(ctx, ids[prefix+seed.pattern]); err != nil {
+			t.Errorf("expected %q to survive: %v", seed.pattern, err)
+		}
+	}
+
+	report, err = store.CompactExperiences(ctx, 0.98)
+	if err != nil {
+		t.Fatalf("CompactExperiences failed: %v", err)
+	}
+	if report.MergedCount != 0 || report.RemainingCount != 3 {
+		t.Errorf("expected a second compaction to merge nothing, got %+v", report)
+	}
+}
+
+func TestInMemoryStore_CompactExperiences(t *testing.T) {
+	store := NewInMemoryStore()
+	store.ProjectID = "payments"
+	testCompaction(t, store, "payments", "")
+}
+
+// TestPostgresStore_CompactExperiences runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_CompactExperiences(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("compaction-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() { _, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project) }()
+
+	testCompaction(t, s, project, project+" ")
+}
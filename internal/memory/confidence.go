@@ -0,0 +1,47 @@
+package memory
+
+// Values of Experience.ConfidenceLevel.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+const (
+	// highConfidenceSimilarity is the similarity above which a result is a high confidence match.
+	highConfidenceSimilarity = 0.9
+	// mediumConfidenceSimilarity is the similarity from which a result is a medium confidence match.
+	mediumConfidenceSimilarity = 0.7
+	// strongMatchGap is the lead over the second result from which a medium confidence top
+	// result is a high confidence match: the query clearly singles it out.
+	strongMatchGap = 0.1
+)
+
+// AssignConfidence sets the ConfidenceLevel of experiences, which must be the results of
+// a similarity search ordered by decreasing SimilarityScore. A result is high confidence
+// above a similarity of 0.9, medium from 0.7 and low below. The top result is also high
+// confidence from 0.7 when it leads the second result by at least 0.1.
+func AssignConfidence(experiences []Experience) {
+	for i := range experiences {
+		experiences[i].ConfidenceLevel = confidenceLevel(experiences[i].SimilarityScore)
+	}
+	if len(experiences) > 1 {
+		top := &experiences[0]
+		if top.ConfidenceLevel == ConfidenceMedium && top.SimilarityScore-experiences[1].SimilarityScore >= strongMatchGap {
+			top.ConfidenceLevel = ConfidenceHigh
+		}
+	}
+}
+
+// confidenceLevel returns the confidence level of a result with the given similarity,
+// without regard to the other results.
+func confidenceLevel(similarity float32) string {
+	switch {
+	case similarity > highConfidenceSimilarity:
+		return ConfidenceHigh
+	case similarity >= mediumConfidenceSimilarity:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
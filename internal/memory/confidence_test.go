@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAssignConfidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float32
+		want   []string
+	}{
+		{"high", []float32{0.95}, []string{"high"}},
+		{"medium", []float32{0.8}, []string{"medium"}},
+		{"low", []float32{0.6}, []string{"low"}},
+		{"boundaries", []float32{0.9, 0.85, 0.7, 0.69}, []string{"medium", "medium", "medium", "low"}},
+		{"each result on its own", []float32{0.92, 0.85, 0.5}, []string{"high", "medium", "low"}},
+		{"clear lead", []float32{0.85, 0.74}, []string{"high", "medium"}},
+		{"close runner-up", []float32{0.85, 0.8}, []string{"medium", "medium"}},
+		{"lead of a weak match", []float32{0.65, 0.4}, []string{"low", "low"}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		experiences := make([]Experience, len(tt.scores))
+		for i, score := range tt.scores {
+			experiences[i].SimilarityScore = score
+		}
+		AssignConfidence(experiences)
+
+		var got []string
+		for _, exp := range experiences {
+			got = append(got, exp.ConfidenceLevel)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+// ErrEmbeddingIncompatible is returned by ValidateEmbeddingCompatibility when the embedding
+// model produces vectors of another size than the stored embeddings.
+var ErrEmbeddingIncompatible = errors.New("the embedding model is incompatible with the stored embeddings")
+
+// embeddingProbeText is the text embedded to learn the dimension of the current model.
+const embeddingProbeText = "embedding compatibility check"
+
+// EmbeddingMeta describes the vectors held by a store.
+type EmbeddingMeta struct {
+	ModelName string // Model that produced the vectors
+	Dimension int    // Number of dimensions of every vector
+}
+
+// EmbeddingMetaStore records the model and dimension of the embeddings of a store, so
+// that a change of embedding model can be detected before it breaks similarity search.
+// It is implemented by PostgresStore and InMemoryStore.
+type EmbeddingMetaStore interface {
+	// EmbeddingMeta returns the recorded model and dimension, or ErrNotFound.
+	EmbeddingMeta(ctx context.Context) (EmbeddingMeta, error)
+	// SetEmbeddingMeta records the model and dimension of the embeddings.
+	SetEmbeddingMeta(ctx context.Context, meta EmbeddingMeta) error
+	// ReembedExperiences embeds the error pattern of every experience again with embedder
+	// and records the new model and dimension. Returns the number of experiences embedded.
+	ReembedExperiences(ctx context.Context, embedder Embedder, meta EmbeddingMeta) (int, error)
+}
+
+// ProbeEmbeddingMeta embeds a test text with embedder to find the dimension of its vectors.
+// selector names the model the text is embedded with; EmbeddingModel is used when it is nil.
+func ProbeEmbeddingMeta(ctx context.Context, embedder Embedder, selector EmbeddingModelSelector) (EmbeddingMeta, error) {
+	vector, err := embedder.Embed(ctx, embeddingProbeText)
+	if err != nil {
+		return EmbeddingMeta{}, fmt.Errorf("failed to embed test text: %w", err)
+	}
+	return EmbeddingMeta{ModelName: selectEmbeddingModel(selector, embeddingProbeText), Dimension: len(vector)}, nil
+}
+
+// ValidateEmbeddingCompatibility checks that embedder produces vectors of the dimension
+// recorded in store, since vectors of different sizes cannot be compared. When nothing
+// is recorded yet, the dimension of embedder is recorded instead. selector names the
+// model of embedder, see ProbeEmbeddingMeta. Returns ErrEmbeddingIncompatible with
+// instructions when the dimensions differ.
+func ValidateEmbeddingCompatibility(ctx context.Context, store EmbeddingMetaStore, embedder Embedder, selector EmbeddingModelSelector) error {
+	current, err := ProbeEmbeddingMeta(ctx, embedder, selector)
+	if err != nil {
+		return err
+	}
+
+	stored, err := store.EmbeddingMeta(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return store.SetEmbeddingMeta(ctx, current)
+	}
+	if err != nil {
+		return err
+	}
+	if stored.Dimension != current.Dimension {
+		return fmt.Errorf("%w: the stored embeddings of %s have %d dimensions, %s produces %d; "+
+			"run with --migrate-embeddings to re-embed every experience with %s, or switch back to %s",
+			ErrEmbeddingIncompatible, stored.ModelName, stored.Dimension, current.ModelName, current.Dimension, current.ModelName, stored.ModelName)
+	}
+	return nil
+}
+
+// EmbeddingMeta returns the model and dimension recorded in the embedding_meta table.
+func (s *PostgresStore) EmbeddingMeta(ctx context.Context) (EmbeddingMeta, error) {
+	var meta EmbeddingMeta
+	err := s.pool.QueryRow(ctx, `SELECT model_name, dimension FROM embedding_meta ORDER BY updated_at DESC LIMIT 1`).Scan(&meta.ModelName, &meta.Dimension)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return EmbeddingMeta{}, fmt.Errorf("embedding meta: %w", ErrNotFound)
+	}
+	if err != nil {
+		return EmbeddingMeta{}, fmt.Errorf("failed to load embedding meta: %w", err)
+	}
+	s.dimension.Store(int64(meta.Dimension))
+	return meta, nil
+}
+
+// SetEmbeddingMeta replaces the row of the embedding_meta table.
+func (s *PostgresStore) SetEmbeddingMeta(ctx context.Context, meta EmbeddingMeta) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := setEmbeddingMeta(ctx, tx, meta); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit embedding meta: %w", err)
+	}
+	s.dimension.Store(int64(meta.Dimension))
+	return nil
+}
+
+// setEmbeddingMeta replaces the row of the embedding_meta table in tx.
+func setEmbeddingMeta(ctx context.Context, tx pgx.Tx, meta EmbeddingMeta) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM embedding_meta`); err != nil {
+		return fmt.Errorf("failed to clear embedding meta: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO embedding_meta (model_name, dimension) VALUES ($1, $2)`, meta.ModelName, meta.Dimension); err != nil {
+		return fmt.Errorf("failed to save embedding meta: %w", err)
+	}
+	return nil
+}
+
+// ReembedExperiences embeds the error pattern of every embedded experience of every
+// project again with embedder, resizing the embedding column to meta.Dimension, and
+// records meta. The experiences are embedded before the database is changed, and the
+// change is a single transaction, so a failure leaves the stored embeddings as they were.
+func (s *PostgresStore) ReembedExperiences(ctx context.Context, embedder Embedder, meta EmbeddingMeta) (int, error) {
+	rows, err := s.pool.Query(ctx, `SELECT id, error_pattern FROM issue_history WHERE embedding IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query experiences: %w", err)
+	}
+	var ids []int
+	var patterns []string
+	for rows.Next() {
+		var id int
+		var pattern string
+		if err := rows.Scan(&id, &pattern); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan experience: %w", err)
+		}
+		ids = append(ids, id)
+		patterns = append(patterns, pattern)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read experiences: %w", err)
+	}
+
+	vectors, err := EmbedAll(ctx, embedder, patterns)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed experiences: %w", err)
+	}
+	for i, vector := range vectors {
+		if len(vector) != meta.Dimension {
+			return 0, fmt.Errorf("%w: experience %d was embedded with %d dimensions, want %d", ErrEmbeddingDimensions, ids[i], len(vector), meta.Dimension)
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// The vector index is tied to the column type, so it is rebuilt around the resize
+	resize := []string{
+		`DROP INDEX IF EXISTS ` + vectorIndexName,
+		fmt.Sprintf(`ALTER TABLE issue_history ALTER COLUMN embedding TYPE vector(%d) USING NULL`, meta.Dimension),
+	}
+	for _, stmt := range resize {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("failed to resize embedding column: %w", err)
+		}
+	}
+	batch := &pgx.Batch{}
+	for i, id := range ids {
+		batch.Queue(`UPDATE issue_history SET embedding = $2, embedding_model = $3 WHERE id = $1`,
+			id, pgvector.NewVector(vectors[i]), selectEmbeddingModel(s.modelSelector, patterns[i]))
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, fmt.Errorf("failed to save embeddings: %w", err)
+	}
+	if stmt := s.vectorIndex.createSQL(); stmt != "" {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return 0, fmt.Errorf("failed to rebuild embedding index: %w", err)
+		}
+	}
+	if err := setEmbeddingMeta(ctx, tx, meta); err != nil {
+		return 0, err
+	}
+	// The index was just built from every embedded experience
+	if _, err := tx.Exec(ctx, `UPDATE embedding_meta SET reindexed_rows = $1`, len(ids)); err != nil {
+		return 0, fmt.Errorf("failed to record reindexed rows: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit embeddings: %w", err)
+	}
+	s.dimension.Store(int64(meta.Dimension))
+	return len(ids), nil
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateEmbeddingCompatibility(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	gemini := &mockEmbedder{embedValue: []float32{1, 0, 0}}
+
+	// The first run records the dimension of the current model
+	if err := ValidateEmbeddingCompatibility(ctx, store, gemini, nil); err != nil {
+		t.Fatalf("ValidateEmbeddingCompatibility on an empty store failed: %v", err)
+	}
+	meta, err := store.EmbeddingMeta(ctx)
+	if err != nil || meta != (EmbeddingMeta{ModelName: EmbeddingModel, Dimension: 3}) {
+		t.Fatalf("EmbeddingMeta = %+v, %v, want %s with 3 dimensions", meta, err, EmbeddingModel)
+	}
+	if err := ValidateEmbeddingCompatibility(ctx, store, gemini, nil); err != nil {
+		t.Errorf("ValidateEmbeddingCompatibility with the same model failed: %v", err)
+	}
+	if err := store.SaveExperience(ctx, "", "deadlock in worker pool", "cause", "solution", nil, gemini.embedValue); err != nil {
+		t.Fatal(err)
+	}
+
+	// A model with vectors of another size is rejected with instructions
+	ollama := &mockEmbedder{embedValue: []float32{0, 1, 0, 0, 0}}
+	selector := SmartModelSelector{ShortEnglishModel: "nomic-embed-text", LongModel: "nomic-embed-text", MultilingualModel: "nomic-embed-text"}
+	err = ValidateEmbeddingCompatibility(ctx, store, ollama, selector)
+	if !errors.Is(err, ErrEmbeddingIncompatible) {
+		t.Fatalf("ValidateEmbeddingCompatibility error = %v, want ErrEmbeddingIncompatible", err)
+	}
+	for _, want := range []string{"3 dimensions", "nomic-embed-text produces 5", "--migrate-embeddings"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	// Re-embedding with the wrong dimension changes nothing
+	current, err := ProbeEmbeddingMeta(ctx, ollama, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReembedExperiences(ctx, gemini, current); !errors.Is(err, ErrEmbeddingDimensions) {
+		t.Errorf("ReembedExperiences with 3-dimensional vectors error = %v, want ErrEmbeddingDimensions", err)
+	}
+	if meta, _ := store.EmbeddingMeta(ctx); meta.Dimension != 3 {
+		t.Errorf("failed ReembedExperiences recorded %d dimensions, want 3", meta.Dimension)
+	}
+
+	// Re-embedding with the new model makes it compatible
+	count, err := store.ReembedExperiences(ctx, ollama, current)
+	if err != nil || count != 1 {
+		t.Fatalf("ReembedExperiences = %d, %v, want 1 experience", count, err)
+	}
+	if err := ValidateEmbeddingCompatibility(ctx, store, ollama, selector); err != nil {
+		t.Errorf("ValidateEmbeddingCompatibility after re-embedding failed: %v", err)
+	}
+	results, err := store.SearchSimilarIssues(ctx, "", ollama.embedValue, 1, 0.5, "", true, nil)
+	if err != nil || len(results) != 1 || results[0].SimilarityScore < 0.99 {
+		t.Errorf("SearchSimilarIssues with the new model = %+v, %v, want the re-embedded experience", results, err)
+	}
+}
+
+// TestPostgresStore_EmbeddingMeta runs against the database in TEST_DATABASE_URL, which
+// must have all migrations applied. It restores the recorded meta afterwards.
+func TestPostgresStore_EmbeddingMeta(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	original, err := s.EmbeddingMeta(ctx)
+	if err != nil {
+		t.Fatalf("EmbeddingMeta failed: %v", err)
+	}
+	defer func() { _ = s.SetEmbeddingMeta(ctx, original) }()
+
+	if err := s.SetEmbeddingMeta(ctx, EmbeddingMeta{ModelName: "meta-test-model", Dimension: embeddingDimensions}); err != nil {
+		t.Fatalf("SetEmbeddingMeta failed: %v", err)
+	}
+	if meta, err := s.EmbeddingMeta(ctx); err != nil || meta.ModelName != "meta-test-model" {
+		t.Errorf("EmbeddingMeta = %+v, %v, want the meta just set", meta, err)
+	}
+
+	err = ValidateEmbeddingCompatibility(ctx, s, &mockEmbedder{embedValue: make([]float32, 1536)}, nil)
+	if !errors.Is(err, ErrEmbeddingIncompatible) {
+		t.Errorf("ValidateEmbeddingCompatibility with 1536 dimensions error = %v, want ErrEmbeddingIncompatible", err)
+	}
+}
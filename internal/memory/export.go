@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ExportExperiences writes every experience in store to w as newline-delimited JSON,
+// one experience per line. Embedding vectors are not exported, which keeps the output
+// human-readable; ImportExperiences regenerates them.
+func ExportExperiences(ctx context.Context, store Store, w io.Writer) error {
+	experiences, err := store.ListExperiences(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list experiences: %w", err)
+	}
+	return writeExperiences(w, experiences)
+}
+
+// ImportExperiences reads newline-delimited JSON written by ExportExperiences from r and
+// saves each experience into store, embedding its error pattern with embedder.
+// Experiences keep their project, user, tags and occurred_at; the store assigns new IDs.
+// Codebase index experiences are skipped, as index_codebase regenerates them, and so are
+// experiences that already exist in store. Records written before projects were exported
+// are saved like new experiences of the store's project instead. Returns the number of
+// experiences imported.
+func ImportExperiences(ctx context.Context, store Store, r io.Reader, embedder Embedder) (int, error) {
+	// Skip existing experiences before spending an embedding on them
+	existing, err := store.ListExperiences(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list experiences: %w", err)
+	}
+	type key struct{ project, hash string }
+	stored := make(map[key]bool, len(existing))
+	for _, exp := range existing {
+		if exp.Source == SourceSession {
+			stored[key{exp.ProjectID, ContentHash(exp.ErrorPattern)}] = true
+		}
+	}
+
+	imported, lineNo := 0, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record archivedExperience
+		if err := json.Unmarshal(line, &record); err != nil {
+			return imported, fmt.Errorf("failed to decode experience on line %d: %w", lineNo, err)
+		}
+
+		if record.Source == SourceCodebaseIndex {
+			continue
+		}
+		if record.ProjectID == nil {
+			if _, err := store.FindExperienceByHash(ctx, ContentHash(record.ErrorPattern)); err == nil {
+				continue
+			} else if !errors.Is(err, ErrNotFound) {
+				return imported, fmt.Errorf("failed to check experience %d: %w", record.ID, err)
+			}
+		} else if stored[key{*record.ProjectID, ContentHash(record.ErrorPattern)}] {
+			continue
+		}
+
+		vector, err := embedder.Embed(ctx, record.ErrorPattern)
+		if err != nil {
+			return imported, fmt.Errorf("failed to embed experience %d: %w", record.ID, err)
+		}
+
+		if record.ProjectID == nil {
+			err = store.SaveExperience(ctx, record.UserID, record.ErrorPattern, record.RootCause, record.Solution, record.Tags, vector)
+		} else {
+			err = store.ImportExperience(ctx, Experience{
+				UserID:       record.UserID,
+				ProjectID:    *record.ProjectID,
+				ErrorPattern: record.ErrorPattern,
+				RootCause:    record.RootCause,
+				Solution:     record.Solution,
+				Tags:         record.Tags,
+				OccurredAt:   record.OccurredAt,
+			}, vector)
+		}
+		if err != nil {
+			if errors.Is(err, ErrDuplicateExperience) {
+				continue
+			}
+			return imported, fmt.Errorf("failed to save experience %d: %w", record.ID, err)
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, fmt.Errorf("failed to read experiences: %w", err)
+	}
+
+	return imported, nil
+}
+
+// markdownEntry is a problem read from a Markdown troubleshooting guide.
+type markdownEntry struct {
+	Problem  string // Text of the "## " heading
+	Cause    string // Content of the "### Cause" section
+	Solution string // Content of the "### Solution" section
+}
+
+// ImportFromMarkdown reads a troubleshooting guide in Markdown from the file at path and
+// saves each of its problems into store as an experience. Every "## " heading starts a
+// problem, whose text becomes the error pattern and is embedded with embedder; the
+// "### Cause" and "### Solution" sections below it become the root cause and solution.
+// Other sections and text are ignored, as are headings inside fenced code blocks.
+// Problems lacking either section are skipped with a warning, and so are problems that
+// already exist in store. Returns the number of experiences imported.
+func ImportFromMarkdown(ctx context.Context, store Store, path string, embedder Embedder) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	entries, err := parseMarkdownEntries(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	imported := 0
+	for _, entry := range entries {
+		if entry.Cause == "" || entry.Solution == "" {
+			slog.WarnContext(ctx, "skipping Markdown problem: it needs both a ### Cause and a ### Solution section", slog.String("problem", entry.Problem))
+			continue
+		}
+		if _, err := store.FindExperienceByHash(ctx, ContentHash(entry.Problem)); err == nil {
+			continue
+		} else if !errors.Is(err, ErrNotFound) {
+			return imported, fmt.Errorf("failed to check problem %q: %w", entry.Problem, err)
+		}
+
+		vector, err := embedder.Embed(ctx, entry.Problem)
+		if err != nil {
+			return imported, fmt.Errorf("failed to embed problem %q: %w", entry.Problem, err)
+		}
+		err = store.SaveExperience(ctx, "", entry.Problem, entry.Cause, entry.Solution, DetectTags(entry.Problem), vector)
+		if err != nil {
+			if errors.Is(err, ErrDuplicateExperience) {
+				continue
+			}
+			return imported, fmt.Errorf("failed to save problem %q: %w", entry.Problem, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// parseMarkdownEntries splits the Markdown document read from r into one entry per
+// "## " heading, with the trimmed content of its "### Cause" and "### Solution" sections.
+func parseMarkdownEntries(r io.Reader) ([]markdownEntry, error) {
+	var entries []markdownEntry
+	var section *strings.Builder // Section of the last entry being read, nil outside Cause and Solution
+	var cause, solution strings.Builder
+	inFence := false
+
+	flush := func() {
+		if len(entries) > 0 {
+			last := &entries[len(entries)-1]
+			last.Cause = strings.TrimSpace(cause.String())
+			last.Solution = strings.TrimSpace(solution.String())
+		}
+		cause.Reset()
+		solution.Reset()
+		section = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		if fence {
+			inFence = !inFence
+		}
+
+		if !inFence && !fence {
+			if problem, ok := strings.CutPrefix(line, "## "); ok {
+				flush()
+				entries = append(entries, markdownEntry{Problem: strings.TrimSpace(problem)})
+				continue
+			}
+			if heading, ok := strings.CutPrefix(line, "### "); ok {
+				section = nil
+				if len(entries) > 0 {
+					switch strings.ToLower(strings.TrimSpace(heading)) {
+					case "cause":
+						section = &cause
+					case "solution":
+						section = &solution
+					}
+				}
+				continue
+			}
+		}
+
+		if section != nil {
+			section.WriteString(line)
+			section.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return entries, nil
+}
+
+// writeExperiences encodes experiences to w as newline-delimited archivedExperience records.
+func writeExperiences(w io.Writer, experiences []Experience) error {
+	enc := json.NewEncoder(w)
+	for _, exp := range experiences {
+		record := archivedExperience{
+			ID:           exp.ID,
+			UserID:       exp.UserID,
+			ProjectID:    &exp.ProjectID,
+			Source:       exp.Source,
+			ErrorPattern: exp.ErrorPattern,
+			RootCause:    exp.RootCause,
+			Solution:     exp.Solution,
+			Tags:         exp.Tags,
+			OccurredAt:   exp.OccurredAt,
+		}
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode experience %d: %w", exp.ID, err)
+		}
+	}
+	return nil
+}
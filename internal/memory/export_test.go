@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportExperiences(t *testing.T) {
+	ctx := context.Background()
+
+	source := NewInMemoryStore()
+	source.ProjectID = "payments"
+	for _, exp := range []Experience{
+		{UserID: "user-a", ErrorPattern: "nil pointer in handler", RootCause: "missing check", Solution: "check for nil"},
+		{UserID: "", ErrorPattern: "连接池耗尽", RootCause: "连接未释放", Solution: "defer rows.Close()"},
+	} {
+		if err := source.SaveExperience(ctx, exp.UserID, exp.ErrorPattern, exp.RootCause, exp.Solution, []string{"db"}, []float32{1, 0}); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+	source.ProjectID = ""
+	if err := source.SaveExperience(ctx, "", "shared timeout", "slow network", "retry", nil, []float32{1, 0}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	source.ProjectID = "payments"
+	if err := source.SaveSourcedExperience(ctx, SourceCodebaseIndex, "codebase_index:a:b", "func Handle()", "defined in a.go:1", "func Handle()", []float32{1, 0}); err != nil {
+		t.Fatalf("SaveSourcedExperience failed: %v", err)
+	}
+	source.experiences[0].OccurredAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := ExportExperiences(ctx, source, &buf); err != nil {
+		t.Fatalf("ExportExperiences failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected one line per experience, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "vector") || strings.Contains(buf.String(), "embedding") {
+		t.Errorf("expected no vectors in the export, got %s", lines[0])
+	}
+
+	target := NewInMemoryStore()
+	target.ProjectID = "payments"
+	embedder := &mockEmbedder{}
+	count, err := ImportExperiences(ctx, target, bytes.NewReader(buf.Bytes()), embedder)
+	if err != nil {
+		t.Fatalf("ImportExperiences failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected the 3 session experiences to be imported, got %d", count)
+	}
+
+	all, _ := source.ListExperiences(ctx)
+	want := slices.DeleteFunc(all, func(exp Experience) bool { return exp.Source == SourceCodebaseIndex })
+	got, _ := target.ListExperiences(ctx)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d experiences after import, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].UserID != want[i].UserID || got[i].ErrorPattern != want[i].ErrorPattern ||
+			got[i].RootCause != want[i].RootCause || got[i].Solution != want[i].Solution ||
+			got[i].ProjectID != want[i].ProjectID || got[i].Source != want[i].Source ||
+			!got[i].OccurredAt.Equal(want[i].OccurredAt) || !slices.Equal(got[i].Tags, want[i].Tags) {
+			t.Errorf("experience %d changed in the round trip: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if results, _ := target.SearchSimilarIssues(ctx, "", []float32{0.1, 0.2, 0.3}, 5, 0.99, "", false, nil); len(results) != 3 {
+		t.Errorf("expected imported experiences to be re-embedded, got %d matches", len(results))
+	}
+
+	// Importing the same file again skips existing experiences
+	count, err = ImportExperiences(ctx, target, bytes.NewReader(buf.Bytes()), embedder)
+	if err != nil || count != 0 {
+		t.Errorf("expected a repeated import to skip everything, got %d, %v", count, err)
+	}
+
+	if _, err := ImportExperiences(ctx, target, strings.NewReader("{not json}\n"), embedder); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected a decode error naming the line, got %v", err)
+	}
+}
+
+func TestImportExperiences_LegacyRecords(t *testing.T) {
+	ctx := context.Background()
+	target := NewInMemoryStore()
+	target.ProjectID = "payments"
+
+	// Records exported before projects were part of the format go to the store's project
+	legacy := `{"id":1,"error_pattern":"nil map write","root_cause":"map not initialized","solution":"use make","occurred_at":"2024-03-01T12:00:00Z"}` + "\n"
+	count, err := ImportExperiences(ctx, target, strings.NewReader(legacy), &mockEmbedder{})
+	if err != nil || count != 1 {
+		t.Fatalf("expected the legacy record to be imported, got %d, %v", count, err)
+	}
+	got, _ := target.ListExperiences(ctx)
+	if len(got) != 1 || got[0].ProjectID != "payments" || got[0].Source != SourceSession {
+		t.Errorf("expected a session experience of the store's project, got %+v", got)
+	}
+}
+
+func TestImportFromMarkdown(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	embedder := &mockEmbedder{}
+
+	count, err := ImportFromMarkdown(ctx, store, "testdata/troubleshooting.md", embedder)
+	if err != nil {
+		t.Fatalf("ImportFromMarkdown failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected the 3 well-formed problems to be imported, got %d", count)
+	}
+
+	experiences, _ := store.ListExperiences(ctx)
+	if len(experiences) != 3 {
+		t.Fatalf("expected 3 experiences, got %d", len(experiences))
+	}
+	first := experiences[0]
+	if first.ErrorPattern != "panic: assignment to entry in nil map" {
+		t.Errorf("unexpected error pattern %q", first.ErrorPattern)
+	}
+	if !strings.HasPrefix(first.RootCause, "The `limits` map") {
+		t.Errorf("unexpected root cause %q", first.RootCause)
+	}
+	if !strings.Contains(first.Solution, "## not a heading") || !strings.HasSuffix(first.Solution, "```") {
+		t.Errorf("expected the code block to stay in the solution, got %q", first.Solution)
+	}
+	if !slices.Contains(first.Tags, "panic") {
+		t.Errorf("expected tags detected from the problem, got %v", first.Tags)
+	}
+	if experiences[1].Solution != "Add `defer rows.Close()` right after checking the error of `Query`." {
+		t.Errorf("expected other sections to be left out of the solution, got %q", experiences[1].Solution)
+	}
+	if experiences[2].ErrorPattern != "Request timeout when calling the ledger API" || experiences[2].RootCause == "" {
+		t.Errorf("unexpected experience without blank lines: %+v", experiences[2])
+	}
+
+	// Importing the same guide again adds nothing
+	count, err = ImportFromMarkdown(ctx, store, "testdata/troubleshooting.md", embedder)
+	if err != nil {
+		t.Fatalf("second ImportFromMarkdown failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no experiences on the second import, got %d", count)
+	}
+
+	if _, err := ImportFromMarkdown(ctx, store, "testdata/missing.md", embedder); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Health check defaults used by the agent.
+const (
+	// HealthCheckInterval is how often the database connection is pinged.
+	HealthCheckInterval = 30 * time.Second
+	// ReconnectAttempts is the number of reconnection attempts after a failed ping.
+	ReconnectAttempts = 3
+	// ReconnectDelay is the delay before each reconnection attempt.
+	ReconnectDelay = 5 * time.Second
+)
+
+// Reconnector is a store whose connection can be checked and re-established, such as
+// PostgresStore.
+type Reconnector interface {
+	Ping(ctx context.Context) error
+	Reconnect(ctx context.Context) error
+}
+
+// MonitorConnection pings db once per interval until ctx is cancelled. When a ping
+// fails, it logs a warning and tries to reconnect up to attempts times, waiting delay
+// before each attempt. A connection that stays down is checked again at the next
+// interval.
+func MonitorConnection(ctx context.Context, db Reconnector, interval time.Duration, attempts int, delay time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := db.Ping(ctx)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		slog.WarnContext(ctx, "database health check failed", slog.Any("error", err))
+		reconnect(ctx, db, attempts, delay)
+	}
+}
+
+// reconnect calls db.Reconnect until it succeeds or has failed attempts times, waiting
+// delay before each attempt, and reports whether it succeeded.
+func reconnect(ctx context.Context, db Reconnector, attempts int, delay time.Duration) bool {
+	for attempt := 1; attempt <= attempts; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		err := db.Reconnect(ctx)
+		if err == nil {
+			slog.InfoContext(ctx, "reconnected to the database", slog.Int("attempts", attempt))
+			return true
+		}
+		slog.WarnContext(ctx, "database reconnection attempt failed", slog.Int("attempt", attempt), slog.Int("max_attempts", attempts), slog.Any("error", err))
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyDatabase fails its pings until it has been reconnected failures+1 times.
+type flakyDatabase struct {
+	mu         sync.Mutex
+	failures   int
+	reconnects int
+	pings      int
+}
+
+func (d *flakyDatabase) Ping(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pings++
+	if d.reconnects <= d.failures {
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func (d *flakyDatabase) Reconnect(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reconnects++
+	if d.reconnects <= d.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestReconnect(t *testing.T) {
+	ctx := context.Background()
+
+	db := &flakyDatabase{failures: 2}
+	if !reconnect(ctx, db, 3, time.Millisecond) {
+		t.Fatal("expected the third attempt to reconnect")
+	}
+	if db.reconnects != 3 {
+		t.Errorf("expected 3 reconnection attempts, got %d", db.reconnects)
+	}
+
+	db = &flakyDatabase{failures: 5}
+	if reconnect(ctx, db, 3, time.Millisecond) {
+		t.Fatal("expected reconnecting to give up")
+	}
+	if db.reconnects != 3 {
+		t.Errorf("expected to give up after 3 attempts, got %d", db.reconnects)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	db = &flakyDatabase{}
+	if reconnect(cancelled, db, 3, time.Hour) || db.reconnects != 0 {
+		t.Errorf("expected a cancelled context to stop reconnecting, got %d attempts", db.reconnects)
+	}
+}
+
+func TestMonitorConnection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := &flakyDatabase{failures: 1}
+	done := make(chan struct{})
+	go func() {
+		MonitorConnection(ctx, db, time.Millisecond, 3, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		db.mu.Lock()
+		reconnects, pings := db.reconnects, db.pings
+		db.mu.Unlock()
+		if reconnects == 2 && pings > 1 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("expected the monitor to reconnect, got %d reconnects and %d pings", reconnects, pings)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	<-done
+	if db.reconnects != 2 {
+		t.Errorf("expected no reconnection once the connection works, got %d", db.reconnects)
+	}
+}
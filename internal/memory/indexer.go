@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultIndexPatterns are the file patterns indexed when none are given.
+var DefaultIndexPatterns = []string{"*.go"}
+
+// CodeSymbol is a package-level declaration extracted from a Go source file.
+type CodeSymbol struct {
+	Package   string // Name of the package declaring the symbol
+	Name      string // Symbol name, prefixed with the receiver type for methods
+	Kind      string // "func", "method" or "type"
+	Signature string // Declaration without its body
+	Doc       string // Doc comment text, empty if undocumented
+	File      string // Path of the declaring file, relative to the indexed directory
+	Line      int    // Line of the declaration in File
+}
+
+// Text returns the text that is embedded and stored for the symbol.
+func (s CodeSymbol) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n", s.Package)
+	if s.Doc != "" {
+		b.WriteString(s.Doc)
+		if !strings.HasSuffix(s.Doc, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString(s.Signature)
+	return b.String()
+}
+
+// CodeFile holds the symbols extracted from a single source file.
+type CodeFile struct {
+	Path      string       // Path relative to the indexed directory
+	Signature string       // Task signature derived from the path and content hash, see fileSignaturePrefix
+	Symbols   []CodeSymbol // Package-level symbols declared in the file
+}
+
+// ScanCodebase walks workDir and extracts the symbols of every Go file whose name
+// matches one of patterns. Test files, hidden directories, vendor and testdata
+// directories are skipped, and files that fail to parse are skipped with a warning.
+// Nothing is written to the store.
+func ScanCodebase(workDir string, patterns []string) ([]CodeFile, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultIndexPatterns
+	}
+
+	var files []CodeFile
+	err := filepath.WalkDir(workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != workDir && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(rel, ".go") || strings.HasSuffix(rel, "_test.go") || !matchesAny(rel, patterns) {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		file, err := parseCodeFile(filepath.ToSlash(rel), src)
+		if err != nil {
+			slog.Warn("skipping file that does not parse", slog.String("path", rel), slog.Any("error", err))
+			return nil
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", workDir, err)
+	}
+
+	return files, nil
+}
+
+// IndexCodebase ingests the Go symbols found under workDir into store as searchable
+// documentation. Each symbol's signature and doc comment is embedded and saved with
+// source SourceCodebaseIndex. Files whose content has not changed since they were last
+// indexed are skipped; the symbols of a changed file replace those of its earlier
+// versions in one transaction. Returns the number of symbols indexed.
+func IndexCodebase(ctx context.Context, workDir string, store Store, embedder Embedder, patterns []string) (int, error) {
+	files, err := ScanCodebase(workDir, patterns)
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, file := range files {
+		exists, err := store.HasTaskSignature(ctx, file.Signature)
+		if err != nil {
+			return indexed, err
+		}
+		if exists {
+			continue
+		}
+
+		items := make([]ExperienceInput, 0, len(file.Symbols))
+		for _, sym := range file.Symbols {
+			text := sym.Text()
+			vector, err := embedder.Embed(ctx, text)
+			if err != nil {
+				return indexed, fmt.Errorf("failed to embed %s: %w", sym.Name, err)
+			}
+			items = append(items, ExperienceInput{
+				Pattern:  text,
+				Cause:    fmt.Sprintf("defined in %s:%d", sym.File, sym.Line),
+				Solution: sym.Signature,
+				Vector:   vector,
+			})
+		}
+
+		if err := store.ReplaceSourcedExperiences(ctx, SourceCodebaseIndex, fileSignaturePrefix(file.Path), file.Signature, items); err != nil {
+			return indexed, fmt.Errorf("failed to index %s: %w", file.Path, err)
+		}
+		indexed += len(items)
+	}
+
+	return indexed, nil
+}
+
+// fileSignaturePrefix returns the start of the task signatures of every version of the
+// file at rel. The full signature appends a hash of the file's content.
+func fileSignaturePrefix(rel string) string {
+	sum := sha256.Sum256([]byte(rel))
+	return "codebase_index:" + hex.EncodeToString(sum[:8]) + ":"
+}
+
+// parseCodeFile parses the Go source src of the file at rel and extracts its
+// package-level symbols.
+func parseCodeFile(rel string, src []byte) (CodeFile, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, rel, src, parser.ParseComments)
+	if err != nil {
+		return CodeFile{}, fmt.Errorf("failed to parse %s: %w", rel, err)
+	}
+
+	sum := sha256.Sum256(src)
+	file := CodeFile{Path: rel, Signature: fileSignaturePrefix(rel) + hex.EncodeToString(sum[:8])}
+	pkg := f.Name.Name
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sig := *d
+			sig.Body = nil
+			sig.Doc = nil
+
+			name, kind := d.Name.Name, "func"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name, kind = ReceiverName(d.Recv.List[0].Type)+"."+name, "method"
+			}
+			file.Symbols = append(file.Symbols, CodeSymbol{
+				Package:   pkg,
+				Name:      name,
+				Kind:      kind,
+				Signature: formatNode(fset, &sig),
+				Doc:       d.Doc.Text(),
+				File:      rel,
+				Line:      fset.Position(d.Pos()).Line,
+			})
+
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc.Text()
+				if doc == "" && len(d.Specs) == 1 {
+					doc = d.Doc.Text()
+				}
+				file.Symbols = append(file.Symbols, CodeSymbol{
+					Package:   pkg,
+					Name:      ts.Name.Name,
+					Kind:      "type",
+					Signature: "type " + formatNode(fset, ts),
+					Doc:       doc,
+					File:      rel,
+					Line:      fset.Position(ts.Pos()).Line,
+				})
+			}
+		}
+	}
+
+	return file, nil
+}
+
+// ReceiverName returns the type name of a method receiver, without pointer or type
+// parameters, e.g. "Store" for "*Store[K, V]". Methods are named "<receiver>.<method>"
+// by the indexer and the file reading tools.
+func ReceiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return ReceiverName(t.X)
+	case *ast.IndexExpr:
+		return ReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return ReceiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// formatNode renders an AST node as Go source.
+func formatNode(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// matchesAny reports whether the file name or relative path matches one of patterns.
+func matchesAny(rel string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, filepath.Base(rel)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.ToSlash(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexCodebase(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	count, err := IndexCodebase(ctx, "../tools", store, &mockEmbedder{}, nil)
+	if err != nil {
+		t.Fatalf("IndexCodebase failed: %v", err)
+	}
+	if count == 0 || count != len(store.experiences) {
+		t.Fatalf("expected indexed count to match saved symbols, got %d and %d", count, len(store.experiences))
+	}
+
+	found := false
+	for _, saved := range store.experiences {
+		if saved.Source != SourceCodebaseIndex {
+			t.Errorf("expected source %q, got %q", SourceCodebaseIndex, saved.Source)
+		}
+		if strings.Contains(saved.ErrorPattern, "_test.go") || strings.Contains(saved.RootCause, "_test.go") {
+			t.Errorf("expected test files to be skipped, got %q", saved.RootCause)
+		}
+		if strings.Contains(saved.ErrorPattern, "func createSearchPastIssuesTool(cfg ToolsConfig) (tool.Tool, error)") &&
+			strings.Contains(saved.ErrorPattern, "search_past_issues") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected the search_past_issues tool constructor and its doc comment to be indexed")
+	}
+
+	count, err = IndexCodebase(ctx, "../tools", store, &mockEmbedder{}, nil)
+	if err != nil {
+		t.Fatalf("second IndexCodebase failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected unchanged files to be skipped, indexed %d symbols", count)
+	}
+}
+
+func TestScanCodebase_Patterns(t *testing.T) {
+	files, err := ScanCodebase("../tools", []string{"validate.go"})
+	if err != nil {
+		t.Fatalf("ScanCodebase failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "validate.go" {
+		t.Fatalf("expected only validate.go, got %+v", files)
+	}
+
+	kinds := map[string]string{}
+	for _, sym := range files[0].Symbols {
+		kinds[sym.Name] = sym.Kind
+	}
+	if kinds["ValidateToolArgs"] != "func" || kinds["ValidationError"] != "type" {
+		t.Errorf("unexpected symbols: %v", kinds)
+	}
+}
+
+func TestIndexCodebase_ReplacesChangedFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package a\n\nfunc Old() {}\n\nfunc Kept() {}\n")
+	write("broken.go", "package a\n\nfunc Broken( {\n")
+
+	store := NewInMemoryStore()
+	count, err := IndexCodebase(ctx, dir, store, &mockEmbedder{}, nil)
+	if err != nil {
+		t.Fatalf("expected unparsable files to be skipped, got %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected the 2 symbols of a.go to be indexed, got %d", count)
+	}
+
+	write("a.go", "package a\n\nfunc Kept() {}\n\nfunc New() {}\n")
+	if count, err = IndexCodebase(ctx, dir, store, &mockEmbedder{}, nil); err != nil || count != 2 {
+		t.Fatalf("expected the changed file to be indexed again, got %d, %v", count, err)
+	}
+
+	experiences, _ := store.ListExperiences(ctx)
+	var names []string
+	for _, exp := range experiences {
+		names = append(names, strings.TrimPrefix(exp.Solution, "func "))
+	}
+	if strings.Join(names, ",") != "Kept(),New()" {
+		t.Errorf("expected only the symbols of the current a.go, got %v", names)
+	}
+}
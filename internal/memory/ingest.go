@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultIngestionInterval is how often StartBackgroundIngestion checks the log file
+	// when BackgroundIngestionConfig.CheckInterval is not set.
+	DefaultIngestionInterval = 10 * time.Second
+	// maxIngestionReadBytes is the most StartBackgroundIngestion reads from the log file
+	// per check; the rest is read by the next checks.
+	maxIngestionReadBytes = 1 << 20
+)
+
+// DefaultIngestionPattern matches the log lines worth saving when no other pattern is
+// configured: errors, fatal errors and panics.
+const DefaultIngestionPattern = `(?i)\b(error|fatal|panic)\b`
+
+// IngestionTag is the tag of the experiences saved by StartBackgroundIngestion.
+const IngestionTag = "log"
+
+// BackgroundIngestionConfig configures StartBackgroundIngestion.
+type BackgroundIngestionConfig struct {
+	LogFilePath   string         // Log file to watch
+	Pattern       *regexp.Regexp // Lines matching it are saved as experiences
+	CheckInterval time.Duration  // How often the file is checked for new lines (default DefaultIngestionInterval)
+}
+
+// StartBackgroundIngestion watches the log file of cfg in a goroutine until ctx is
+// canceled, saving every new line that matches cfg.Pattern as an experience with an
+// empty cause and solution, to be filled in later with update_experience. Lines already
+// in the file when it starts are skipped; a file that does not exist yet is read from
+// its start once it appears, and a truncated or rotated file from its start again.
+// Returns an error if cfg is invalid; failures while watching are logged.
+func StartBackgroundIngestion(ctx context.Context, cfg BackgroundIngestionConfig, store Store, embedder Embedder) error {
+	if cfg.LogFilePath == "" || cfg.Pattern == nil {
+		return errors.New("background ingestion requires a log file path and a pattern")
+	}
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = DefaultIngestionInterval
+	}
+
+	tail := &LogTail{Path: cfg.LogFilePath}
+	info, err := os.Stat(cfg.LogFilePath)
+	switch {
+	case err == nil:
+		tail.File, tail.Offset = info, info.Size()
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to watch %s: %w", cfg.LogFilePath, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			lines, err := tail.ReadLines(maxIngestionReadBytes)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to read log file", slog.String("path", cfg.LogFilePath), slog.Any("error", err))
+				continue
+			}
+			for _, line := range lines {
+				if text := strings.TrimSpace(line.Text); text != "" && cfg.Pattern.MatchString(text) {
+					ingestLogLine(ctx, store, embedder, text)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// ingestLogLine saves line as an experience without cause and solution. Lines that
+// are already stored, or merged into a near duplicate, are not reported.
+func ingestLogLine(ctx context.Context, store Store, embedder Embedder, line string) {
+	vector, err := embedder.Embed(ctx, line)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to embed log line", slog.String("line", line), slog.Any("error", err))
+		return
+	}
+	err = store.SaveExperience(ctx, "", line, "", "", []string{IngestionTag}, vector)
+	var exists *ErrExperienceAlreadyExists
+	var merged *ErrExperienceMerged
+	switch {
+	case err == nil:
+		slog.InfoContext(ctx, "ingested log line", slog.String("line", line))
+	case errors.As(err, &exists), errors.As(err, &merged):
+	default:
+		slog.WarnContext(ctx, "failed to save log line", slog.String("line", line), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+// ingestedPatterns returns the patterns of the experiences in store, ordered by ID.
+func ingestedPatterns(t *testing.T, store *InMemoryStore) []string {
+	t.Helper()
+	experiences, err := store.ListExperiences(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patterns []string
+	for _, exp := range experiences {
+		patterns = append(patterns, exp.ErrorPattern)
+	}
+	return patterns
+}
+
+// appendFile appends text to the file at path.
+func appendFile(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartBackgroundIngestion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("ERROR already in the file before ingestion starts\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const interval = 200 * time.Millisecond
+	store := &InMemoryStore{}
+	cfg := BackgroundIngestionConfig{LogFilePath: path, Pattern: regexp.MustCompile(`\bERROR\b`), CheckInterval: interval}
+	if err := StartBackgroundIngestion(ctx, cfg, store, &mockEmbedder{}); err != nil {
+		t.Fatalf("StartBackgroundIngestion failed: %v", err)
+	}
+
+	// waitFor waits up to two check intervals for the patterns in store to be want
+	waitFor := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * interval)
+		for !slices.Equal(ingestedPatterns(t, store), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("ingested %q, want %q", ingestedPatterns(t, store), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	appendFile(t, path, "INFO server started\nERROR dial tcp 10.0.0.1:5432: connection refused\nERROR half written")
+	waitFor("ERROR dial tcp 10.0.0.1:5432: connection refused")
+	exp, err := store.GetExperience(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.RootCause != "" || exp.Solution != "" || !slices.Equal(exp.Tags, []string{IngestionTag}) {
+		t.Errorf("expected an empty cause and solution tagged %q, got %+v", IngestionTag, exp)
+	}
+
+	// The line is ingested once it is complete
+	appendFile(t, path, " line\n")
+	waitFor("ERROR dial tcp 10.0.0.1:5432: connection refused", "ERROR half written line")
+
+	// A rotated file is read from its start
+	if err := os.WriteFile(path, []byte("ERROR after rotation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("ERROR dial tcp 10.0.0.1:5432: connection refused", "ERROR half written line", "ERROR after rotation")
+}
+
+func TestStartBackgroundIngestion_InvalidConfig(t *testing.T) {
+	ctx := context.Background()
+	if err := StartBackgroundIngestion(ctx, BackgroundIngestionConfig{Pattern: regexp.MustCompile("ERROR")}, &InMemoryStore{}, &mockEmbedder{}); err == nil {
+		t.Error("expected an error without a log file path")
+	}
+	if err := StartBackgroundIngestion(ctx, BackgroundIngestionConfig{LogFilePath: "app.log"}, &InMemoryStore{}, &mockEmbedder{}); err == nil {
+		t.Error("expected an error without a pattern")
+	}
+}
@@ -0,0 +1,951 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"google.golang.org/genai"
+)
+
+// InMemoryStore implements the Store interface with plain Go slices, for tests that
+// need a working store without a database. Similarity search is a linear scan using
+// CosineSimilarity. Unlike PostgresStore it does not detect near-duplicate experiences
+// or expire old ones. The zero value is an empty store ready to use, and it is safe
+// for concurrent use.
+type InMemoryStore struct {
+	// ProjectID is the project that new experiences and rules are saved for, like
+	// StoreOptions.ProjectID. It must not be changed while the store is in use.
+	ProjectID string
+
+	mu             sync.Mutex
+	rules          []ProjectRule
+	lastRuleID     int                          // ID of the most recently added rule, so that IDs of deleted rules are not reused
+	experiences    []storedExperience           // Indexed by experience ID - 1, including deleted ones
+	history        map[int][]ExperienceVersion  // Versions of each experience, oldest first
+	sessions       map[string]savedSession      // Saved conversations by session ID
+	projectContext map[string]map[string]string // Project context by project ID and key
+	sessionCosts   []SessionCost                // Recorded session costs, oldest first
+	meta           *EmbeddingMeta               // Model and dimension of the embeddings, nil until recorded
+}
+
+// savedSession is a conversation saved in InMemoryStore. The history is kept encoded,
+// like in PostgresStore, so that callers never share it.
+type savedSession struct {
+	history   []byte
+	updatedAt time.Time
+}
+
+// storedExperience is an experience held by InMemoryStore together with its embedding.
+type storedExperience struct {
+	Experience
+	vector    []float32
+	deleted   bool
+	deletedAt time.Time // When the experience was deleted, if it was
+}
+
+// NewInMemoryStore creates an empty InMemoryStore.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{}
+}
+
+// Reset removes all rules, experiences, history, sessions, session costs and project
+// context from the store.
+func (s *InMemoryStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	s.lastRuleID = 0
+	s.experiences = nil
+	s.history = nil
+	s.sessions = nil
+	s.sessionCosts = nil
+	s.projectContext = nil
+}
+
+// GetProjectRules returns the content of the active rules of projectID, and of the
+// rules shared by all projects when includeGlobal is set, ordered like PostgresStore.
+func (s *InMemoryStore) GetProjectRules(ctx context.Context, projectID string, includeGlobal bool) ([]string, error) {
+	s.mu.Lock()
+	rules := s.sortedRules()
+	s.mu.Unlock()
+
+	var active []string
+	for _, rule := range rules {
+		if rule.IsActive && inProject(rule.ProjectID, projectID, includeGlobal) {
+			active = append(active, rule.RuleContent)
+		}
+	}
+	return active, nil
+}
+
+// ListProjectRules returns every rule of the store's project and every rule shared by all
+// projects, ordered by priority (highest first), category and ID.
+func (s *InMemoryStore) ListProjectRules(ctx context.Context) ([]ProjectRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rules []ProjectRule
+	for _, rule := range s.sortedRules() {
+		if inProject(rule.ProjectID, s.ProjectID, true) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// sortedRules returns a copy of all rules ordered by priority (highest first), category
+// and ID. The caller must hold s.mu.
+func (s *InMemoryStore) sortedRules() []ProjectRule {
+	rules := append([]ProjectRule(nil), s.rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		if rules[i].Category != rules[j].Category {
+			return rules[i].Category < rules[j].Category
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// AddProjectRule stores a new active rule and returns its ID, or ErrDuplicateRule if the
+// store's project already has it.
+func (s *InMemoryStore) AddProjectRule(ctx context.Context, category, content string, priority int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sameRuleIndex(category, content, 0) >= 0 {
+		return 0, fmt.Errorf("failed to add project rule: %w", ErrDuplicateRule)
+	}
+	return s.addRule(category, content, priority), nil
+}
+
+// UpsertProjectRule stores a new active rule, or sets the priority of the store's project's
+// rule with the same category and content and activates it. Returns the ID of the rule.
+func (s *InMemoryStore) UpsertProjectRule(ctx context.Context, category, content string, priority int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.sameRuleIndex(category, content, 0); i >= 0 {
+		rule := &s.rules[i]
+		rule.Priority, rule.IsActive = priority, true
+		return rule.ID, nil
+	}
+	return s.addRule(category, content, priority), nil
+}
+
+// addRule appends an active rule of the store's project and returns its ID. The caller
+// must hold s.mu.
+func (s *InMemoryStore) addRule(category, content string, priority int) int {
+	s.lastRuleID++
+	s.rules = append(s.rules, ProjectRule{
+		ID:          s.lastRuleID,
+		ProjectID:   s.ProjectID,
+		Category:    category,
+		RuleContent: content,
+		Priority:    priority,
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+	})
+	return s.lastRuleID
+}
+
+// UpdateProjectRule replaces the content, priority and active flag of a rule.
+func (s *InMemoryStore) UpdateProjectRule(ctx context.Context, id int, content string, priority int, isActive bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.ruleIndex(id)
+	if err != nil {
+		return err
+	}
+	rule := &s.rules[i]
+	if s.sameRuleIndex(rule.Category, content, id) >= 0 {
+		return fmt.Errorf("failed to update project rule: %w", ErrDuplicateRule)
+	}
+	rule.RuleContent, rule.Priority, rule.IsActive = content, priority, isActive
+	return nil
+}
+
+// DeleteProjectRule removes a rule.
+func (s *InMemoryStore) DeleteProjectRule(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.ruleIndex(id)
+	if err != nil {
+		return err
+	}
+	s.rules = slices.Delete(s.rules, i, i+1)
+	return nil
+}
+
+// SetProjectContext records value under key for the store's project.
+func (s *InMemoryStore) SetProjectContext(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.projectContext == nil {
+		s.projectContext = make(map[string]map[string]string)
+	}
+	if s.projectContext[s.ProjectID] == nil {
+		s.projectContext[s.ProjectID] = make(map[string]string)
+	}
+	s.projectContext[s.ProjectID][key] = value
+	return nil
+}
+
+// GetProjectContext returns the value of key for the store's project, or ErrNotFound.
+func (s *InMemoryStore) GetProjectContext(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.projectContext[s.ProjectID][key]
+	if !ok {
+		return "", fmt.Errorf("project context %q: %w", key, ErrNotFound)
+	}
+	return value, nil
+}
+
+// ListProjectContext returns the values recorded for the store's project, by key.
+func (s *InMemoryStore) ListProjectContext(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make(map[string]string, len(s.projectContext[s.ProjectID]))
+	maps.Copy(entries, s.projectContext[s.ProjectID])
+	return entries, nil
+}
+
+// SearchSimilarIssues returns up to limit experiences of projectID at least minSimilarity
+// similar to queryVector and carrying all of tags, most similar first. Experiences shared
+// by all projects are included when includeGlobal is set. query is not used: the in-memory
+// store does not record embedding models, so all vectors are assumed to be comparable.
+func (s *InMemoryStore) SearchSimilarIssues(ctx context.Context, query string, queryVector []float32, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, error) {
+	results, _, err := s.SearchSimilarIssuesPage(ctx, query, queryVector, 0, limit, minSimilarity, projectID, includeGlobal, tags)
+	return results, err
+}
+
+// SearchSimilarIssuesPage returns the experiences SearchSimilarIssues would return after
+// skipping the offset most similar ones, and the number of matching experiences.
+func (s *InMemoryStore) SearchSimilarIssuesPage(ctx context.Context, query string, queryVector []float32, offset, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, int64, error) {
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must not be negative, got %d", offset)
+	}
+	tags = NormalizeTags(tags)
+	results := s.search(-1, func(stored storedExperience) (float32, bool) {
+		if stored.vector == nil || !inProject(stored.ProjectID, projectID, includeGlobal) || !hasAllTags(stored.Tags, tags) {
+			return 0, false
+		}
+		score := CosineSimilarity(queryVector, stored.vector)
+		return score, score >= minSimilarity
+	})
+	total := int64(len(results))
+	results = results[min(offset, len(results)):]
+	if limit >= 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, total, nil
+}
+
+// SearchByUser returns up to limit experiences of userID in the store's project or shared
+// by all projects, most similar to queryVector first.
+func (s *InMemoryStore) SearchByUser(ctx context.Context, userID, query string, queryVector []float32, limit int) ([]Experience, error) {
+	return s.search(limit, func(stored storedExperience) (float32, bool) {
+		matches := stored.vector != nil && stored.UserID == userID && inProject(stored.ProjectID, s.ProjectID, true)
+		return CosineSimilarity(queryVector, stored.vector), matches
+	}), nil
+}
+
+// HybridSearch returns up to limit experiences ranked by alpha times their cosine
+// similarity to queryVector plus 1-alpha times the share of the query terms found in
+// their error pattern. With alpha 0 only experiences matching a query term are returned.
+// Experiences are scoped to projectID like in SearchSimilarIssues.
+func (s *InMemoryStore) HybridSearch(ctx context.Context, query string, queryVector []float32, limit int, alpha float32, projectID string, includeGlobal bool) ([]Experience, error) {
+	if alpha < 0 || alpha > 1 {
+		return nil, fmt.Errorf("alpha must be between 0 and 1, got %v", alpha)
+	}
+	terms := keywordTerms(query)
+	return s.search(limit, func(stored storedExperience) (float32, bool) {
+		if !inProject(stored.ProjectID, projectID, includeGlobal) {
+			return 0, false
+		}
+		keyword := keywordScore(terms, stored.ErrorPattern)
+		if alpha == 0 {
+			return keyword, keyword > 0
+		}
+		if stored.vector == nil {
+			return 0, false
+		}
+		return alpha*CosineSimilarity(queryVector, stored.vector) + (1-alpha)*keyword, true
+	}), nil
+}
+
+// search scores every experience that is not deleted and returns up to limit of those
+// accepted by rank, highest score first.
+func (s *InMemoryStore) search(limit int, rank func(storedExperience) (float32, bool)) []Experience {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var results []Experience
+	for _, stored := range s.experiences {
+		if stored.deleted {
+			continue
+		}
+		score, ok := rank(stored)
+		if !ok {
+			continue
+		}
+		exp := stored.Experience
+		exp.SimilarityScore = score
+		results = append(results, exp)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].SimilarityScore > results[j].SimilarityScore
+	})
+	if limit >= 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// SaveExperience stores a session experience. Returns *ErrExperienceAlreadyExists when a
+// session experience with the same pattern is already stored.
+func (s *InMemoryStore) SaveExperience(ctx context.Context, userID, pattern, cause, solution string, tags []string, vector []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.findSession(func(exp storedExperience) bool { return exp.ErrorPattern == pattern }); existing != nil {
+		return &ErrExperienceAlreadyExists{ExistingID: existing.ID}
+	}
+	s.insert(Experience{
+		UserID:        userID,
+		Source:        SourceSession,
+		TaskSignature: taskSignature(pattern),
+		ErrorPattern:  pattern,
+		RootCause:     cause,
+		Solution:      solution,
+		Tags:          NormalizeTags(tags),
+	}, vector)
+	return nil
+}
+
+// BatchSaveExperiences saves each item with SaveExperience, skipping items whose pattern
+// is already stored.
+func (s *InMemoryStore) BatchSaveExperiences(ctx context.Context, items []ExperienceInput) error {
+	for _, item := range items {
+		err := s.SaveExperience(ctx, item.UserID, item.Pattern, item.Cause, item.Solution, item.Tags, item.Vector)
+		if err != nil && !errors.Is(err, ErrDuplicateExperience) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportExperience saves exp as a session experience of exp.ProjectID, keeping its user,
+// tags and occurred_at (the current time when zero).
+func (s *InMemoryStore) ImportExperience(ctx context.Context, exp Experience, vector []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.experiences {
+		if !stored.deleted && stored.Source == SourceSession && stored.ProjectID == exp.ProjectID && stored.ErrorPattern == exp.ErrorPattern {
+			return &ErrExperienceAlreadyExists{ExistingID: stored.ID}
+		}
+	}
+	occurredAt := exp.OccurredAt
+	s.insert(Experience{
+		UserID:        exp.UserID,
+		Source:        SourceSession,
+		TaskSignature: taskSignature(exp.ErrorPattern),
+		ErrorPattern:  exp.ErrorPattern,
+		RootCause:     exp.RootCause,
+		Solution:      exp.Solution,
+		Tags:          NormalizeTags(exp.Tags),
+	}, vector)
+	imported := &s.experiences[len(s.experiences)-1]
+	imported.ProjectID = exp.ProjectID
+	if !occurredAt.IsZero() {
+		imported.OccurredAt = occurredAt
+	}
+	return nil
+}
+
+// UpsertExperience saves a session experience or overwrites the one with the same pattern,
+// recording the overwritten content as a version.
+func (s *InMemoryStore) UpsertExperience(ctx context.Context, pattern, cause, solution string, vector []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.findSession(func(exp storedExperience) bool { return exp.ErrorPattern == pattern }); existing != nil {
+		s.snapshot(existing)
+		existing.RootCause, existing.Solution = cause, solution
+		existing.vector = vector
+		return nil
+	}
+	s.insert(Experience{
+		Source:        SourceSession,
+		TaskSignature: taskSignature(pattern),
+		ErrorPattern:  pattern,
+		RootCause:     cause,
+		Solution:      solution,
+	}, vector)
+	return nil
+}
+
+// SaveSourcedExperience stores an experience with the given source and task signature.
+func (s *InMemoryStore) SaveSourcedExperience(ctx context.Context, source, signature, pattern, cause, solution string, vector []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.insert(Experience{
+		Source:        source,
+		TaskSignature: signature,
+		ErrorPattern:  pattern,
+		RootCause:     cause,
+		Solution:      solution,
+	}, vector)
+	return nil
+}
+
+// ReplaceSourcedExperiences removes the experiences of the store's project with the given
+// source whose task signature starts with signaturePrefix, then saves items in their place.
+func (s *InMemoryStore) ReplaceSourcedExperiences(ctx context.Context, source, signaturePrefix, signature string, items []ExperienceInput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like PurgeDeleted, the removed experiences keep their IDs reserved
+	for i, stored := range s.experiences {
+		if stored.Source == source && stored.ProjectID == s.ProjectID && strings.HasPrefix(stored.TaskSignature, signaturePrefix) {
+			s.experiences[i] = storedExperience{deleted: true}
+			delete(s.history, i+1)
+		}
+	}
+	for _, item := range items {
+		s.insert(Experience{
+			Source:        source,
+			TaskSignature: signature,
+			ErrorPattern:  item.Pattern,
+			RootCause:     item.Cause,
+			Solution:      item.Solution,
+		}, item.Vector)
+	}
+	return nil
+}
+
+// HasTaskSignature reports whether any experience of the store's project, including
+// deleted ones, has the given signature.
+func (s *InMemoryStore) HasTaskSignature(ctx context.Context, signature string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.experiences {
+		if stored.TaskSignature == signature && stored.ProjectID == s.ProjectID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindExperienceByHash returns the ID of the session experience whose pattern has the given ContentHash.
+func (s *InMemoryStore) FindExperienceByHash(ctx context.Context, hash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.findSession(func(exp storedExperience) bool { return ContentHash(exp.ErrorPattern) == hash }); existing != nil {
+		return existing.ID, nil
+	}
+	return 0, ErrNotFound
+}
+
+// UpdateExperience records the current version of the experience and replaces its cause and solution.
+func (s *InMemoryStore) UpdateExperience(ctx context.Context, id int, cause, solution string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	s.snapshot(stored)
+	stored.RootCause, stored.Solution = cause, solution
+	return nil
+}
+
+// GetExperienceHistory returns the recorded versions of the experience, oldest first, or
+// none if it belongs to another project.
+func (s *InMemoryStore) GetExperienceHistory(ctx context.Context, id int) ([]ExperienceVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > len(s.experiences) || !inProject(s.experiences[id-1].ProjectID, s.ProjectID, true) {
+		return nil, nil
+	}
+	return append([]ExperienceVersion(nil), s.history[id]...), nil
+}
+
+// RevertExperienceTo records the current version of the experience and restores the given version.
+func (s *InMemoryStore) RevertExperienceTo(ctx context.Context, id, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var target *ExperienceVersion
+	for i, v := range s.history[id] {
+		if v.Version == version {
+			target = &s.history[id][i]
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("experience %d version %d: %w", id, version, ErrNotFound)
+	}
+	stored, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	pattern, cause, solution := target.ErrorPattern, target.RootCause, target.Solution
+	s.snapshot(stored)
+	stored.ErrorPattern, stored.RootCause, stored.Solution = pattern, cause, solution
+	return nil
+}
+
+// GetExperience returns the experience with the given ID unless it has been deleted.
+func (s *InMemoryStore) GetExperience(ctx context.Context, id int) (Experience, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.get(id)
+	if err != nil {
+		return Experience{}, err
+	}
+	return stored.Experience, nil
+}
+
+// MergeExperiences deletes secondaryID and replaces the content of primaryID with merged.
+func (s *InMemoryStore) MergeExperiences(ctx context.Context, primaryID, secondaryID int, merged Experience, vector []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	primary, err := s.get(primaryID)
+	if err != nil {
+		return err
+	}
+	secondary, err := s.get(secondaryID)
+	if err != nil {
+		return err
+	}
+	secondary.deleted, secondary.deletedAt = true, time.Now()
+	s.snapshot(primary)
+	primary.TaskSignature = taskSignature(merged.ErrorPattern)
+	primary.ErrorPattern, primary.RootCause, primary.Solution = merged.ErrorPattern, merged.RootCause, merged.Solution
+	if vector != nil {
+		primary.vector = vector
+	}
+	return nil
+}
+
+// CompactExperiences merges the near-duplicate experiences of the store's project like
+// PostgresStore, comparing all embeddings since InMemoryStore records no embedding model.
+func (s *InMemoryStore) CompactExperiences(ctx context.Context, similarityThreshold float32) (CompactionReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []compactionEntry
+	for _, stored := range s.experiences {
+		if stored.deleted || stored.vector == nil || stored.ProjectID != s.ProjectID || stored.Source == SourceCodebaseIndex {
+			continue
+		}
+		entries = append(entries, compactionEntry{id: stored.ID, vector: stored.vector, frequency: stored.Frequency})
+	}
+	duplicateOf, gained := greedyDuplicates(entries, similarityThreshold)
+	now := time.Now()
+	for id := range duplicateOf {
+		s.experiences[id-1].deleted, s.experiences[id-1].deletedAt = true, now
+	}
+	for id, n := range gained {
+		s.experiences[id-1].Frequency += n
+	}
+	return CompactionReport{ExaminedCount: len(entries), MergedCount: len(duplicateOf), RemainingCount: len(entries) - len(duplicateOf)}, nil
+}
+
+// PurgeExpiredExperiences does nothing, since InMemoryStore does not expire experiences.
+func (s *InMemoryStore) PurgeExpiredExperiences(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// DeleteExperience marks the experience with the given ID as deleted.
+func (s *InMemoryStore) DeleteExperience(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	stored.deleted, stored.deletedAt = true, time.Now()
+	return nil
+}
+
+// PurgeDeleted drops the content and history of experiences deleted more than
+// olderThan ago. Their IDs stay reserved, since IDs index the stored experiences.
+func (s *InMemoryStore) PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	cutoff := time.Now().Add(-olderThan)
+	for i, stored := range s.experiences {
+		if stored.deleted && stored.deletedAt.Before(cutoff) && stored.ID != 0 {
+			s.experiences[i] = storedExperience{deleted: true}
+			delete(s.history, i+1)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// ListExperiences returns every experience of the store's project, or shared by all
+// projects, that has not been deleted, ordered by ID.
+func (s *InMemoryStore) ListExperiences(ctx context.Context) ([]Experience, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	experiences := make([]Experience, 0, len(s.experiences))
+	for _, stored := range s.experiences {
+		if !stored.deleted && inProject(stored.ProjectID, s.ProjectID, true) {
+			experiences = append(experiences, stored.Experience)
+		}
+	}
+	return experiences, nil
+}
+
+// ListExperiencesByTag returns up to limit experiences of the store's project, or shared
+// by all projects, that carry tag, most recently saved first.
+func (s *InMemoryStore) ListExperiencesByTag(ctx context.Context, tag string, limit int) ([]Experience, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	required := NormalizeTags([]string{tag})
+	if len(required) == 0 {
+		return nil, nil
+	}
+	var experiences []Experience
+	for i := len(s.experiences) - 1; i >= 0 && len(experiences) < limit; i-- {
+		stored := s.experiences[i]
+		if !stored.deleted && inProject(stored.ProjectID, s.ProjectID, true) && hasAllTags(stored.Tags, required) {
+			experiences = append(experiences, stored.Experience)
+		}
+	}
+	return experiences, nil
+}
+
+// GetEmbeddings returns the vectors of the experiences with the given IDs of the store's
+// project, or shared by all projects.
+func (s *InMemoryStore) GetEmbeddings(ctx context.Context, ids []int) (map[int][]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vectors := make(map[int][]float32, len(ids))
+	for _, id := range ids {
+		if stored, err := s.get(id); err == nil && stored.vector != nil {
+			vectors[id] = stored.vector
+		}
+	}
+	return vectors, nil
+}
+
+// Stats counts the experiences and rules of the store's project and of those shared by
+// all projects. StorageSizeBytes and LastOptimizedAt are always zero.
+func (s *InMemoryStore) Stats(ctx context.Context) (*MemoryStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &MemoryStats{}
+	for _, rule := range s.rules {
+		if inProject(rule.ProjectID, s.ProjectID, true) {
+			stats.TotalRules++
+		}
+	}
+	var dims, embedded int
+	for _, stored := range s.experiences {
+		if stored.deleted || !inProject(stored.ProjectID, s.ProjectID, true) {
+			continue
+		}
+		stats.TotalExperiences++
+		if stats.OldestExperience.IsZero() || stored.OccurredAt.Before(stats.OldestExperience) {
+			stats.OldestExperience = stored.OccurredAt
+		}
+		if stored.OccurredAt.After(stats.NewestExperience) {
+			stats.NewestExperience = stored.OccurredAt
+		}
+		if stored.vector != nil {
+			dims += len(stored.vector)
+			embedded++
+		}
+		if age := int(time.Since(stored.OccurredAt) / (24 * time.Hour)); age >= 0 && age < StatsHistogramDays {
+			stats.ByAgeHistogram[age]++
+		}
+	}
+	if embedded > 0 {
+		stats.AverageEmbeddingDimension = int(math.Round(float64(dims) / float64(embedded)))
+	}
+	return stats, nil
+}
+
+// SaveSession replaces the saved history of session id.
+func (s *InMemoryStore) SaveSession(ctx context.Context, id string, history []*genai.Content) error {
+	data, err := encodeHistory(history)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]savedSession)
+	}
+	s.sessions[id] = savedSession{history: data, updatedAt: time.Now()}
+	return nil
+}
+
+// LoadSession returns the saved history of session id, or ErrNotFound.
+func (s *InMemoryStore) LoadSession(ctx context.Context, id string) ([]*genai.Content, error) {
+	s.mu.Lock()
+	saved, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("session %s: %w", id, ErrNotFound)
+	}
+	return decodeHistory(saved.history)
+}
+
+// ListSessions returns the saved sessions, most recently updated first.
+func (s *InMemoryStore) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := make([]SessionInfo, 0, len(s.sessions))
+	for id, saved := range s.sessions {
+		sessions = append(sessions, SessionInfo{ID: id, UpdatedAt: saved.updatedAt})
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].UpdatedAt.Equal(sessions[j].UpdatedAt) {
+			return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// SaveSessionCost records cost for the store's project.
+func (s *InMemoryStore) SaveSessionCost(ctx context.Context, cost SessionCost) error {
+	if cost.EndedAt.IsZero() {
+		cost.EndedAt = time.Now()
+	}
+	cost.ProjectID = s.ProjectID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionCosts = append(s.sessionCosts, cost)
+	return nil
+}
+
+// ListSessionCosts returns up to limit recorded costs of the store's project, or of every
+// project when ProjectID is empty, most recent first.
+func (s *InMemoryStore) ListSessionCosts(ctx context.Context, sessionID string, limit int) ([]SessionCost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var costs []SessionCost
+	for _, cost := range slices.Backward(s.sessionCosts) {
+		if len(costs) == limit {
+			break
+		}
+		if (s.ProjectID == "" || cost.ProjectID == s.ProjectID) && (sessionID == "" || cost.SessionID == sessionID) {
+			costs = append(costs, cost)
+		}
+	}
+	return costs, nil
+}
+
+// EmbeddingMeta returns the recorded model and dimension of the embeddings, or ErrNotFound.
+func (s *InMemoryStore) EmbeddingMeta(ctx context.Context) (EmbeddingMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.meta == nil {
+		return EmbeddingMeta{}, fmt.Errorf("embedding meta: %w", ErrNotFound)
+	}
+	return *s.meta, nil
+}
+
+// SetEmbeddingMeta records the model and dimension of the embeddings.
+func (s *InMemoryStore) SetEmbeddingMeta(ctx context.Context, meta EmbeddingMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta = &meta
+	return nil
+}
+
+// ReembedExperiences embeds the error pattern of every embedded experience again with
+// embedder and records meta. Nothing is changed if an embedding fails.
+func (s *InMemoryStore) ReembedExperiences(ctx context.Context, embedder Embedder, meta EmbeddingMeta) (int, error) {
+	s.mu.Lock()
+	var indices []int
+	var patterns []string
+	for i, stored := range s.experiences {
+		if stored.vector != nil {
+			indices = append(indices, i)
+			patterns = append(patterns, stored.ErrorPattern)
+		}
+	}
+	s.mu.Unlock()
+
+	vectors, err := EmbedAll(ctx, embedder, patterns)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed experiences: %w", err)
+	}
+	for i, vector := range vectors {
+		if len(vector) != meta.Dimension {
+			return 0, fmt.Errorf("%w: experience %d was embedded with %d dimensions, want %d", ErrEmbeddingDimensions, indices[i]+1, len(vector), meta.Dimension)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, index := range indices {
+		s.experiences[index].vector = vectors[i]
+	}
+	s.meta = &meta
+	return len(indices), nil
+}
+
+// Close does nothing.
+func (s *InMemoryStore) Close() {
+}
+
+// Ping always succeeds, since InMemoryStore has nothing to connect to.
+func (s *InMemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// insert appends exp with the next ID. The caller must hold s.mu.
+func (s *InMemoryStore) insert(exp Experience, vector []float32) {
+	exp.ID = len(s.experiences) + 1
+	exp.ProjectID = s.ProjectID
+	exp.Frequency = 1
+	exp.OccurredAt = time.Now()
+	s.experiences = append(s.experiences, storedExperience{Experience: exp, vector: vector})
+}
+
+// get returns the experience with the given ID, or ErrNotFound if it does not exist, has
+// been deleted or belongs to another project than the store's. The caller must hold s.mu.
+func (s *InMemoryStore) get(id int) (*storedExperience, error) {
+	if id < 1 || id > len(s.experiences) || s.experiences[id-1].deleted || !inProject(s.experiences[id-1].ProjectID, s.ProjectID, true) {
+		return nil, fmt.Errorf("experience %d: %w", id, ErrNotFound)
+	}
+	return &s.experiences[id-1], nil
+}
+
+// ruleIndex returns the index in s.rules of the rule with the given ID if it belongs to the
+// store's project, or ErrNotFound. Shared rules only belong to a store without a project.
+// The caller must hold s.mu.
+func (s *InMemoryStore) ruleIndex(id int) (int, error) {
+	i := slices.IndexFunc(s.rules, func(rule ProjectRule) bool {
+		return rule.ID == id && rule.ProjectID == s.ProjectID
+	})
+	if i < 0 {
+		return 0, fmt.Errorf("project rule %d: %w", id, ErrNotFound)
+	}
+	return i, nil
+}
+
+// sameRuleIndex returns the index of the rule of the store's project with category and
+// content, other than the rule with ID except, or -1. The caller must hold s.mu.
+func (s *InMemoryStore) sameRuleIndex(category, content string, except int) int {
+	return slices.IndexFunc(s.rules, func(rule ProjectRule) bool {
+		return rule.ID != except && rule.ProjectID == s.ProjectID && rule.Category == category && rule.RuleContent == content
+	})
+}
+
+// findSession returns the first session experience of the store's project that has not
+// been deleted and matches match, or nil. The caller must hold s.mu.
+func (s *InMemoryStore) findSession(match func(storedExperience) bool) *storedExperience {
+	for i, stored := range s.experiences {
+		if !stored.deleted && stored.Source == SourceSession && stored.ProjectID == s.ProjectID && match(stored) {
+			return &s.experiences[i]
+		}
+	}
+	return nil
+}
+
+// inProject reports whether an experience or rule of project belongs to the results of a
+// query for projectID. An empty projectID matches every project.
+func inProject(project, projectID string, includeGlobal bool) bool {
+	return projectID == "" || project == projectID || (includeGlobal && project == "")
+}
+
+// snapshot records the current content of stored as its next version. The caller must hold s.mu.
+func (s *InMemoryStore) snapshot(stored *storedExperience) {
+	if s.history == nil {
+		s.history = make(map[int][]ExperienceVersion)
+	}
+	versions := s.history[stored.ID]
+	s.history[stored.ID] = append(versions, ExperienceVersion{
+		ExperienceID: stored.ID,
+		Version:      len(versions) + 1,
+		ErrorPattern: stored.ErrorPattern,
+		RootCause:    stored.RootCause,
+		Solution:     stored.Solution,
+		UpdatedAt:    time.Now(),
+	})
+}
+
+// keywordTerms returns the distinct lower-cased words and numbers of text.
+func keywordTerms(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, term := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		terms[term] = true
+	}
+	return terms
+}
+
+// keywordScore returns the share of terms that occur in text, between 0 and 1.
+func keywordScore(terms map[string]bool, text string) float32 {
+	if len(terms) == 0 {
+		return 0
+	}
+	found := 0
+	for term := range keywordTerms(text) {
+		if terms[term] {
+			found++
+		}
+	}
+	return float32(found) / float32(len(terms))
+}
+
+// CosineSimilarity returns the cosine similarity of a and b, or 0 if either is a zero
+// vector or their dimensions differ.
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
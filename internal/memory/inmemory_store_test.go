@@ -0,0 +1,639 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInMemoryStore_Search(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for _, exp := range []struct {
+		user, pattern string
+		vector        []float32
+	}{
+		{"user-a", "nil pointer in handler", []float32{1, 0}},
+		{"user-b", "nil map write", []float32{0.8, 0.6}},
+		{"user-a", "unrelated timeout", []float32{0, 1}},
+	} {
+		if err := store.SaveExperience(ctx, exp.user, exp.pattern, "cause", "solution", nil, exp.vector); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+
+	results, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 10, 0.5, "", false, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 1 || results[1].ID != 2 {
+		t.Fatalf("expected experiences 1 and 2 by similarity, got %+v", results)
+	}
+	if results[0].SimilarityScore < 0.99 {
+		t.Errorf("expected identical vectors to score 1, got %v", results[0].SimilarityScore)
+	}
+
+	results, err = store.SearchByUser(ctx, "user-a", "", []float32{0, 1}, 1)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 3 {
+		t.Errorf("expected only experience 3 for user-a, got %+v", results)
+	}
+}
+
+func TestInMemoryStore_SearchSimilarIssuesPage(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	// Experience i is the i-th most similar to {1, 0} of 10
+	for i := 1; i <= 10; i++ {
+		if err := store.SaveExperience(ctx, "", fmt.Sprintf("error %d", i), "cause", "solution", nil, []float32{1, float32(i) / 10}); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		offset int
+		ids    []int
+	}{
+		{0, []int{1, 2, 3, 4}},
+		{4, []int{5, 6, 7, 8}},
+		{8, []int{9, 10}},
+		{12, nil},
+	} {
+		results, total, err := store.SearchSimilarIssuesPage(ctx, "", []float32{1, 0}, tt.offset, 4, 0.5, "", false, nil)
+		if err != nil {
+			t.Fatalf("SearchSimilarIssuesPage failed: %v", err)
+		}
+		var ids []int
+		for _, exp := range results {
+			ids = append(ids, exp.ID)
+		}
+		if !slices.Equal(ids, tt.ids) || total != 10 {
+			t.Errorf("offset %d: expected %v of 10, got %v of %d", tt.offset, tt.ids, ids, total)
+		}
+	}
+
+	if _, _, err := store.SearchSimilarIssuesPage(ctx, "", []float32{1, 0}, -1, 4, 0.5, "", false, nil); err == nil {
+		t.Error("expected an error for a negative offset")
+	}
+}
+
+func TestInMemoryStore_ProjectIsolation(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	// The same pattern is saved for each project; duplicates are only detected within a project
+	for _, project := range []string{"project-b", "project-a", ""} {
+		store.ProjectID = project
+		if err := store.SaveExperience(ctx, "", "nil pointer in handler", "cause", "solution for "+project, nil, []float32{1, 0}); err != nil {
+			t.Fatalf("SaveExperience for %q failed: %v", project, err)
+		}
+		if _, err := store.AddProjectRule(ctx, "style", "rule for "+project, 1); err != nil {
+			t.Fatalf("AddProjectRule for %q failed: %v", project, err)
+		}
+	}
+
+	results, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 10, 0.5, "project-a", false, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ProjectID != "project-a" {
+		t.Fatalf("expected only the experience of project-a, got %+v", results)
+	}
+
+	results, _ = store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 10, 0.5, "project-a", true, nil)
+	if len(results) != 2 {
+		t.Fatalf("expected experiences of project-a and global ones, got %+v", results)
+	}
+	for _, exp := range results {
+		if exp.ProjectID == "project-b" {
+			t.Errorf("expected no experience of project-b, got %+v", exp)
+		}
+	}
+
+	results, _ = store.HybridSearch(ctx, "nil pointer", nil, 10, 0, "project-a", false)
+	if len(results) != 1 || results[0].ProjectID != "project-a" {
+		t.Errorf("expected keyword search to return only project-a, got %+v", results)
+	}
+
+	rules, _ := store.GetProjectRules(ctx, "project-a", true)
+	if len(rules) != 2 || rules[0] != "rule for project-a" || rules[1] != "rule for " {
+		t.Errorf("expected the rules of project-a and global ones, got %v", rules)
+	}
+}
+
+func TestInMemoryStore_HybridSearch(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for _, exp := range []struct {
+		pattern string
+		vector  []float32
+	}{
+		{"read tcp: ECONNRESET", []float32{0, 1}},
+		{"connection reset by peer", []float32{1, 0}},
+	} {
+		if err := store.SaveExperience(ctx, "", exp.pattern, "cause", "solution", nil, exp.vector); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+
+	// Keyword search only returns exact term matches
+	results, err := store.HybridSearch(ctx, "econnreset", nil, 10, 0, "", false)
+	if err != nil {
+		t.Fatalf("HybridSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 1 {
+		t.Errorf("expected only the ECONNRESET experience, got %+v", results)
+	}
+
+	// The keyword match outweighs a closer vector when both are weighted equally
+	results, err = store.HybridSearch(ctx, "ECONNRESET", []float32{0.6, 0.8}, 10, 0.5, "", false)
+	if err != nil {
+		t.Fatalf("HybridSearch failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 1 {
+		t.Errorf("expected the keyword match to rank first, got %+v", results)
+	}
+	results, _ = store.HybridSearch(ctx, "ECONNRESET", []float32{1, 0}, 10, 1, "", false)
+	if len(results) != 2 || results[0].ID != 2 {
+		t.Errorf("expected vector-only search to rank by similarity, got %+v", results)
+	}
+
+	if _, err := store.HybridSearch(ctx, "ECONNRESET", nil, 10, 1.5, "", false); err == nil {
+		t.Error("expected an alpha above 1 to be rejected")
+	}
+}
+
+func TestInMemoryStore_ProjectScopedIDs(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	// Experience 1 belongs to project-b, 2 is shared and 3 belongs to project-a
+	for _, project := range []string{"project-b", "", "project-a"} {
+		store.ProjectID = project
+		if err := store.SaveExperience(ctx, "user-a", "timeout in "+project, "cause", "solution", nil, []float32{1, 0}); err != nil {
+			t.Fatalf("SaveExperience for %q failed: %v", project, err)
+		}
+		if _, err := store.AddProjectRule(ctx, "style", "rule for "+project, 1); err != nil {
+			t.Fatalf("AddProjectRule for %q failed: %v", project, err)
+		}
+	}
+	store.ProjectID = "project-b"
+	if err := store.UpdateExperience(ctx, 1, "new cause", "new solution"); err != nil {
+		t.Fatalf("UpdateExperience failed: %v", err)
+	}
+
+	store.ProjectID = "project-a"
+	for name, op := range map[string]func() error{
+		"GetExperience":         func() error { _, err := store.GetExperience(ctx, 1); return err },
+		"UpdateExperience":      func() error { return store.UpdateExperience(ctx, 1, "cause", "solution") },
+		"DeleteExperience":      func() error { return store.DeleteExperience(ctx, 1) },
+		"RevertExperienceTo":    func() error { return store.RevertExperienceTo(ctx, 1, 1) },
+		"MergeExperiences":      func() error { return store.MergeExperiences(ctx, 3, 1, Experience{ErrorPattern: "merged"}, nil) },
+		"MergeIntoOtherProject": func() error { return store.MergeExperiences(ctx, 1, 3, Experience{ErrorPattern: "merged"}, nil) },
+	} {
+		if err := op(); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound for an experience of another project, got %v", name, err)
+		}
+	}
+	if history, _ := store.GetExperienceHistory(ctx, 1); len(history) != 0 {
+		t.Errorf("expected no history of an experience of another project, got %+v", history)
+	}
+	for _, id := range []int{2, 3} {
+		if _, err := store.GetExperience(ctx, id); err != nil {
+			t.Errorf("expected experience %d to be found, got %v", id, err)
+		}
+	}
+
+	experiences, _ := store.ListExperiences(ctx)
+	if len(experiences) != 2 || experiences[0].ID != 2 || experiences[1].ID != 3 {
+		t.Errorf("expected the shared experience and the one of project-a, got %+v", experiences)
+	}
+	results, _ := store.SearchByUser(ctx, "user-a", "", []float32{1, 0}, 10)
+	if len(results) != 2 {
+		t.Errorf("expected the shared experience and the one of project-a, got %+v", results)
+	}
+	rules, _ := store.ListProjectRules(ctx)
+	if len(rules) != 2 {
+		t.Errorf("expected the shared rule and the one of project-a, got %+v", rules)
+	}
+	for _, rule := range rules {
+		if rule.ProjectID == "project-b" {
+			t.Errorf("expected no rule of project-b, got %+v", rule)
+		}
+	}
+}
+
+func TestInMemoryStore_UpsertExperience(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	// Both patterns share the 50-rune task signature but are different experiences
+	prefix := strings.Repeat("p", 50)
+	first, second := prefix+" first", prefix+" second"
+	for _, pattern := range []string{first, second} {
+		if err := store.SaveExperience(ctx, "", pattern, "cause", "solution", nil, []float32{1}); err != nil {
+			t.Fatalf("SaveExperience(%q) failed: %v", pattern, err)
+		}
+	}
+	var exists *ErrExperienceAlreadyExists
+	err := store.SaveExperience(ctx, "", second, "cause", "solution", nil, []float32{1})
+	if !errors.As(err, &exists) || exists.ExistingID != 2 {
+		t.Fatalf("expected ErrExperienceAlreadyExists for id 2, got %v", err)
+	}
+
+	if err := store.UpsertExperience(ctx, first, "new cause", "new solution", []float32{1}); err != nil {
+		t.Fatalf("UpsertExperience failed: %v", err)
+	}
+	updated, _ := store.GetExperience(ctx, 1)
+	if updated.RootCause != "new cause" || updated.Solution != "new solution" {
+		t.Errorf("expected the upsert to overwrite experience 1, got %+v", updated)
+	}
+	if history, _ := store.GetExperienceHistory(ctx, 1); len(history) != 1 || history[0].Solution != "solution" {
+		t.Errorf("expected the overwritten content as version 1, got %+v", history)
+	}
+	if untouched, _ := store.GetExperience(ctx, 2); untouched.Solution != "solution" {
+		t.Errorf("expected experience 2 to be untouched, got %+v", untouched)
+	}
+
+	if err := store.UpsertExperience(ctx, "a new pattern", "cause", "solution", []float32{1}); err != nil {
+		t.Fatalf("UpsertExperience failed: %v", err)
+	}
+	if all, _ := store.ListExperiences(ctx); len(all) != 3 {
+		t.Errorf("expected the upsert of a new pattern to insert it, got %d experiences", len(all))
+	}
+}
+
+func TestInMemoryStore_DuplicatesAndHistory(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	if err := store.SaveExperience(ctx, "", "connection refused", "db down", "restart db", nil, []float32{1}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+
+	var exists *ErrExperienceAlreadyExists
+	err := store.SaveExperience(ctx, "", "connection refused", "other", "other", nil, []float32{1})
+	if !errors.As(err, &exists) || exists.ExistingID != 1 {
+		t.Fatalf("expected ErrExperienceAlreadyExists for id 1, got %v", err)
+	}
+
+	if err := store.UpdateExperience(ctx, 1, "pool exhausted", "raise pool size"); err != nil {
+		t.Fatalf("UpdateExperience failed: %v", err)
+	}
+	if err := store.RevertExperienceTo(ctx, 1, 1); err != nil {
+		t.Fatalf("RevertExperienceTo failed: %v", err)
+	}
+	exp, err := store.GetExperience(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetExperience failed: %v", err)
+	}
+	if exp.RootCause != "db down" {
+		t.Errorf("expected reverted root cause, got %q", exp.RootCause)
+	}
+	if exp.Frequency != 1 {
+		t.Errorf("expected frequency 1, got %d", exp.Frequency)
+	}
+	history, _ := store.GetExperienceHistory(ctx, 1)
+	if len(history) != 2 || history[1].RootCause != "pool exhausted" {
+		t.Errorf("expected 2 versions with the update recorded, got %+v", history)
+	}
+
+	if err := store.UpdateExperience(ctx, 42, "cause", "solution"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing experience, got %v", err)
+	}
+}
+
+func TestInMemoryStore_MergeAndReset(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for _, pattern := range []string{"first pattern", "second pattern"} {
+		if err := store.SaveExperience(ctx, "", pattern, "cause", "solution", nil, []float32{1}); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+	if _, err := store.AddProjectRule(ctx, "STYLE", "use gofmt", 1); err != nil {
+		t.Fatalf("AddProjectRule failed: %v", err)
+	}
+
+	merged := Experience{ErrorPattern: "merged pattern", RootCause: "cause", Solution: "solution"}
+	if err := store.MergeExperiences(ctx, 1, 2, merged, nil); err != nil {
+		t.Fatalf("MergeExperiences failed: %v", err)
+	}
+	if _, err := store.GetExperience(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected merged secondary to be deleted, got %v", err)
+	}
+	experiences, _ := store.ListExperiences(ctx)
+	if len(experiences) != 1 || experiences[0].ErrorPattern != "merged pattern" {
+		t.Errorf("expected only the merged experience, got %+v", experiences)
+	}
+
+	store.Reset()
+	experiences, _ = store.ListExperiences(ctx)
+	rules, _ := store.GetProjectRules(ctx, "", false)
+	if len(experiences) != 0 || len(rules) != 0 {
+		t.Errorf("expected an empty store after Reset, got %d experiences and %d rules", len(experiences), len(rules))
+	}
+}
+
+func TestInMemoryStore_DeleteAndPurge(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for _, exp := range []struct {
+		pattern string
+		vector  []float32
+	}{
+		{"wrong advice for nil map", []float32{1, 0}},
+		{"nil map write", []float32{0.8, 0.6}},
+	} {
+		if err := store.SaveExperience(ctx, "", exp.pattern, "cause", "solution", nil, exp.vector); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+
+	if err := store.DeleteExperience(ctx, 1); err != nil {
+		t.Fatalf("DeleteExperience failed: %v", err)
+	}
+	if err := store.DeleteExperience(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleting twice to return ErrNotFound, got %v", err)
+	}
+	if _, err := store.GetExperience(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted experience to be hidden, got %v", err)
+	}
+
+	results, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 10, 0, "", false, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 2 {
+		t.Errorf("expected only experience 2 in vector search, got %+v", results)
+	}
+	results, err = store.HybridSearch(ctx, "nil map", []float32{1, 0}, 10, 0.5, "", false)
+	if err != nil {
+		t.Fatalf("HybridSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 2 {
+		t.Errorf("expected only experience 2 in hybrid search, got %+v", results)
+	}
+
+	// Recently deleted experiences are kept until the retention period has passed
+	if purged, _ := store.PurgeDeleted(ctx, time.Hour); purged != 0 {
+		t.Errorf("expected nothing to be purged within the retention period, purged %d", purged)
+	}
+	if purged, _ := store.PurgeDeleted(ctx, 0); purged != 1 {
+		t.Errorf("expected the deleted experience to be purged, purged %d", purged)
+	}
+	if purged, _ := store.PurgeDeleted(ctx, 0); purged != 0 {
+		t.Errorf("expected a purged experience to be purged only once, purged %d", purged)
+	}
+	if _, err := store.GetExperience(ctx, 2); err != nil {
+		t.Errorf("expected experience 2 to survive the purge: %v", err)
+	}
+}
+
+func TestInMemoryStore_ProjectRuleCRUD(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	store.ProjectID = "project-b"
+	otherID, _ := store.AddProjectRule(ctx, "style", "rule of project-b", 1)
+	store.ProjectID = "project-a"
+	first, _ := store.AddProjectRule(ctx, "style", "use gofmt", 1)
+	second, err := store.AddProjectRule(ctx, "errors", "wrap errors", 2)
+	if err != nil {
+		t.Fatalf("AddProjectRule failed: %v", err)
+	}
+
+	if err := store.UpdateProjectRule(ctx, first, "use gofumpt", 3, true); err != nil {
+		t.Fatalf("UpdateProjectRule failed: %v", err)
+	}
+	if err := store.UpdateProjectRule(ctx, second, "wrap errors", 2, false); err != nil {
+		t.Fatalf("UpdateProjectRule failed: %v", err)
+	}
+	rules, _ := store.GetProjectRules(ctx, "project-a", true)
+	if len(rules) != 1 || rules[0] != "use gofumpt" {
+		t.Errorf("expected only the updated active rule, got %v", rules)
+	}
+
+	if err := store.DeleteProjectRule(ctx, first); err != nil {
+		t.Fatalf("DeleteProjectRule failed: %v", err)
+	}
+	if err := store.DeleteProjectRule(ctx, first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleting twice to return ErrNotFound, got %v", err)
+	}
+	if id, _ := store.AddProjectRule(ctx, "style", "new rule", 1); id == first || id == second {
+		t.Errorf("expected a new ID, got %d", id)
+	}
+
+	// Rules of other projects cannot be changed
+	if err := store.UpdateProjectRule(ctx, otherID, "changed", 1, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a rule of another project, got %v", err)
+	}
+	if err := store.DeleteProjectRule(ctx, otherID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a rule of another project, got %v", err)
+	}
+
+	// Shared rules can only be changed by a store without a project
+	store.ProjectID = ""
+	sharedID, _ := store.AddProjectRule(ctx, "style", "shared rule", 1)
+	store.ProjectID = "project-a"
+	if err := store.UpdateProjectRule(ctx, sharedID, "changed", 1, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a shared rule, got %v", err)
+	}
+	if err := store.DeleteProjectRule(ctx, sharedID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a shared rule, got %v", err)
+	}
+	store.ProjectID = ""
+	if err := store.DeleteProjectRule(ctx, sharedID); err != nil {
+		t.Errorf("expected a store without a project to delete the shared rule, got %v", err)
+	}
+}
+
+// testProjectContext checks that store records, replaces and lists project context.
+// Keys start with prefix, so that runs against a shared database do not collide.
+func testProjectContext(t *testing.T, store Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	entry, schema := prefix+"entry_point", prefix+"schema_version"
+
+	if err := store.SetProjectContext(ctx, entry, "cmd/server/main.go"); err != nil {
+		t.Fatalf("SetProjectContext failed: %v", err)
+	}
+	if err := store.SetProjectContext(ctx, schema, "17"); err != nil {
+		t.Fatalf("SetProjectContext failed: %v", err)
+	}
+	// Setting a key again replaces its value
+	if err := store.SetProjectContext(ctx, schema, "18"); err != nil {
+		t.Fatalf("SetProjectContext failed: %v", err)
+	}
+
+	if value, err := store.GetProjectContext(ctx, schema); err != nil || value != "18" {
+		t.Errorf("expected the replaced value, got %q, %v", value, err)
+	}
+	if _, err := store.GetProjectContext(ctx, prefix+"missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown key, got %v", err)
+	}
+
+	entries, err := store.ListProjectContext(ctx)
+	if err != nil {
+		t.Fatalf("ListProjectContext failed: %v", err)
+	}
+	if entries[entry] != "cmd/server/main.go" || entries[schema] != "18" {
+		t.Errorf("expected both keys to be listed, got %v", entries)
+	}
+}
+
+func TestInMemoryStore_ProjectContext(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	store.ProjectID = "project-a"
+	testProjectContext(t, store, "")
+
+	// Each project has its own context
+	store.ProjectID = "project-b"
+	if entries, err := store.ListProjectContext(ctx); err != nil || len(entries) != 0 {
+		t.Errorf("expected no context for another project, got %v, %v", entries, err)
+	}
+	if _, err := store.GetProjectContext(ctx, "entry_point"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a key of another project, got %v", err)
+	}
+}
+
+func TestInMemoryStore_UpsertProjectRule(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	store.ProjectID = "project-a"
+
+	id, err := store.UpsertProjectRule(ctx, "style", "use gofmt", 1)
+	if err != nil {
+		t.Fatalf("UpsertProjectRule failed: %v", err)
+	}
+	if err := store.UpdateProjectRule(ctx, id, "use gofmt", 1, false); err != nil {
+		t.Fatalf("UpdateProjectRule failed: %v", err)
+	}
+	again, err := store.UpsertProjectRule(ctx, "style", "use gofmt", 5)
+	if err != nil {
+		t.Fatalf("UpsertProjectRule failed: %v", err)
+	}
+	if again != id {
+		t.Errorf("expected upserting the same rule to return ID %d, got %d", id, again)
+	}
+	rules, _ := store.ListProjectRules(ctx)
+	if len(rules) != 1 || rules[0].Priority != 5 || !rules[0].IsActive {
+		t.Errorf("expected one active rule with priority 5, got %+v", rules)
+	}
+
+	if _, err := store.AddProjectRule(ctx, "style", "use gofmt", 1); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("expected ErrDuplicateRule for adding the rule again, got %v", err)
+	}
+	other, _ := store.AddProjectRule(ctx, "errors", "use gofmt", 1)
+	if err := store.UpdateProjectRule(ctx, other, "wrap errors", 1, true); err != nil {
+		t.Errorf("UpdateProjectRule failed: %v", err)
+	}
+
+	// The same rule in another project is another rule
+	store.ProjectID = "project-b"
+	if otherID, _ := store.UpsertProjectRule(ctx, "style", "use gofmt", 1); otherID == id {
+		t.Errorf("expected a new rule for another project, got ID %d", otherID)
+	}
+}
+
+func TestInMemoryStore_TagFiltering(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for _, exp := range []struct {
+		pattern string
+		tags    []string
+	}{
+		{"panic in handler", []string{"panic", "nil-pointer"}},
+		{"panic while closing channel", []string{"Panic"}},
+		{"request timed out", []string{"timeout"}},
+		{"untagged failure", nil},
+	} {
+		if err := store.SaveExperience(ctx, "", exp.pattern, "cause", "solution", exp.tags, []float32{1, 0}); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		tags []string
+		want int
+	}{
+		{nil, 4},
+		{[]string{"panic"}, 2},
+		{[]string{"panic", "nil-pointer"}, 1},
+		{[]string{"PANIC", "timeout"}, 0},
+	} {
+		results, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 10, 0.5, "", false, tt.tags)
+		if err != nil {
+			t.Fatalf("SearchSimilarIssues failed: %v", err)
+		}
+		if len(results) != tt.want {
+			t.Errorf("tags %v: expected %d results, got %d", tt.tags, tt.want, len(results))
+		}
+	}
+
+	results, err := store.ListExperiencesByTag(ctx, "Panic", 10)
+	if err != nil {
+		t.Fatalf("ListExperiencesByTag failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ErrorPattern != "panic while closing channel" {
+		t.Errorf("expected both panic experiences, newest first, got %+v", results)
+	}
+	if results, _ := store.ListExperiencesByTag(ctx, "panic", 1); len(results) != 1 {
+		t.Errorf("expected the limit to apply, got %d results", len(results))
+	}
+}
+
+func TestInMemoryStore_Stats(t *testing.T) {
+	ctx := context.Background()
+	store := &InMemoryStore{ProjectID: "billing"}
+	now := time.Now()
+	for _, exp := range []struct {
+		pattern, project string
+		age              time.Duration
+		vector           []float32
+	}{
+		{"fresh failure", "billing", time.Hour, []float32{1, 0, 0}},
+		{"shared failure", "", 25 * time.Hour, []float32{1, 0, 0, 0, 0}},
+		{"last week's failure", "billing", 6*24*time.Hour + time.Hour, nil},
+		{"old failure", "billing", 30 * 24 * time.Hour, []float32{1, 0, 0, 0}},
+		{"other project's failure", "search", time.Hour, []float32{1}},
+	} {
+		err := store.ImportExperience(ctx, Experience{ProjectID: exp.project, ErrorPattern: exp.pattern, OccurredAt: now.Add(-exp.age)}, exp.vector)
+		if err != nil {
+			t.Fatalf("ImportExperience failed: %v", err)
+		}
+	}
+	if _, err := store.AddProjectRule(ctx, "STYLE", "use gofmt", 1); err != nil {
+		t.Fatal(err)
+	}
+	// Deleted experiences are not counted
+	if err := store.SaveExperience(ctx, "", "deleted failure", "cause", "solution", nil, []float32{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := store.FindExperienceByHash(ctx, ContentHash("deleted failure")); err != nil || store.DeleteExperience(ctx, id) != nil {
+		t.Fatalf("failed to delete experience %d: %v", id, err)
+	}
+
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalExperiences != 4 || stats.TotalRules != 1 {
+		t.Errorf("expected 4 experiences and 1 rule of the project, got %d and %d", stats.TotalExperiences, stats.TotalRules)
+	}
+	if !stats.OldestExperience.Equal(now.Add(-30*24*time.Hour)) || !stats.NewestExperience.Equal(now.Add(-time.Hour)) {
+		t.Errorf("unexpected oldest and newest experience: %v, %v", stats.OldestExperience, stats.NewestExperience)
+	}
+	if stats.AverageEmbeddingDimension != 4 {
+		t.Errorf("expected the average dimension of the embedded experiences, got %d", stats.AverageEmbeddingDimension)
+	}
+	if want := [StatsHistogramDays]int64{1, 1, 0, 0, 0, 0, 1}; stats.ByAgeHistogram != want {
+		t.Errorf("expected histogram %v, got %v", want, stats.ByAgeHistogram)
+	}
+	if stats.StorageSizeBytes != 0 || stats.LastOptimizedAt != nil {
+		t.Errorf("expected no storage size or optimization time, got %d and %v", stats.StorageSizeBytes, stats.LastOptimizedAt)
+	}
+}
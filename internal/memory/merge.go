@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// MergeStrategy selects how the content of two experiences is combined by MergeExperiences.
+type MergeStrategy string
+
+const (
+	// MergeKeepPrimary keeps the primary experience and appends the secondary's solution to it.
+	MergeKeepPrimary MergeStrategy = "keep_primary"
+	// MergeLLM asks the LLM to write a combined version of both experiences.
+	MergeLLM MergeStrategy = "llm_merge"
+	// MergeLongestWins keeps the longer text of each field.
+	MergeLongestWins MergeStrategy = "longest_wins"
+)
+
+// Generator defines the interface for text generation.
+// This allows for easier testing by using mock implementations.
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// generatorImpl is the concrete implementation of the Generator interface backed by a genai model.
+type generatorImpl struct {
+	client *genai.Client
+	model  string
+}
+
+// NewGenerator creates a new Generator that produces text with the given model.
+func NewGenerator(ctx context.Context, apiKey, model string) (Generator, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GenAI client: %w", err)
+	}
+
+	return &generatorImpl{client: client, model: model}, nil
+}
+
+// Generate returns the model's text response to prompt.
+func (g *generatorImpl) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := g.client.Models.GenerateContent(ctx, g.model, genai.Text(prompt), nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+
+// ExperienceMerger combines duplicate experiences into one.
+type ExperienceMerger struct {
+	store     Store
+	embedder  Embedder
+	generator Generator // Only required by MergeLLM
+}
+
+// NewExperienceMerger creates an ExperienceMerger. generator may be nil, in which
+// case the llm_merge strategy is unavailable.
+func NewExperienceMerger(store Store, embedder Embedder, generator Generator) *ExperienceMerger {
+	return &ExperienceMerger{store: store, embedder: embedder, generator: generator}
+}
+
+// MergeExperiences folds the experience secondaryID into primaryID using strategy,
+// then soft-deletes secondaryID and stores the combined content on primaryID.
+// The embedding is regenerated only if the error pattern changed. It returns the
+// merged experience.
+func (m *ExperienceMerger) MergeExperiences(ctx context.Context, primaryID, secondaryID int, strategy MergeStrategy) (Experience, error) {
+	if primaryID == secondaryID {
+		return Experience{}, fmt.Errorf("cannot merge experience %d with itself", primaryID)
+	}
+
+	primary, err := m.store.GetExperience(ctx, primaryID)
+	if err != nil {
+		return Experience{}, err
+	}
+	secondary, err := m.store.GetExperience(ctx, secondaryID)
+	if err != nil {
+		return Experience{}, err
+	}
+
+	merged := primary
+	switch strategy {
+	case MergeKeepPrimary:
+		merged.Solution = appendAddendum(primary.Solution, secondary.Solution)
+	case MergeLongestWins:
+		merged.ErrorPattern = longer(primary.ErrorPattern, secondary.ErrorPattern)
+		merged.RootCause = longer(primary.RootCause, secondary.RootCause)
+		merged.Solution = longer(primary.Solution, secondary.Solution)
+	case MergeLLM:
+		if m.generator == nil {
+			return Experience{}, fmt.Errorf("merge strategy %s is not available", strategy)
+		}
+		merged, err = m.llmMerge(ctx, primary, secondary)
+		if err != nil {
+			return Experience{}, err
+		}
+	default:
+		return Experience{}, fmt.Errorf("unknown merge strategy %q", strategy)
+	}
+
+	var vector []float32
+	if merged.ErrorPattern != primary.ErrorPattern {
+		vector, err = m.embedder.Embed(ctx, merged.ErrorPattern)
+		if err != nil {
+			return Experience{}, fmt.Errorf("failed to generate embedding: %w", err)
+		}
+	}
+
+	if err := m.store.MergeExperiences(ctx, primaryID, secondaryID, merged, vector); err != nil {
+		return Experience{}, err
+	}
+	merged.TaskSignature = taskSignature(merged.ErrorPattern)
+	return merged, nil
+}
+
+// mergedContent is the JSON object the LLM is asked to return by llmMerge.
+type mergedContent struct {
+	ErrorPattern string `json:"error_pattern"`
+	RootCause    string `json:"root_cause"`
+	Solution     string `json:"solution"`
+}
+
+// llmMerge asks the generator to write a single experience covering both inputs.
+// Fields the LLM leaves empty keep the primary's content.
+func (m *ExperienceMerger) llmMerge(ctx context.Context, primary, secondary Experience) (Experience, error) {
+	prompt := fmt.Sprintf(`以下两条经验描述的是同一个问题，请将它们合并为一条，保留两者中所有有用的信息，去除重复内容。
+只输出 JSON 对象，格式为 {"error_pattern": "...", "root_cause": "...", "solution": "..."}。
+
+经验一：
+错误模式：%s
+根本原因：%s
+解决方案：%s
+
+经验二：
+错误模式：%s
+根本原因：%s
+解决方案：%s`,
+		primary.ErrorPattern, primary.RootCause, primary.Solution,
+		secondary.ErrorPattern, secondary.RootCause, secondary.Solution)
+
+	text, err := m.generator.Generate(ctx, prompt)
+	if err != nil {
+		return Experience{}, fmt.Errorf("failed to generate merged experience: %w", err)
+	}
+
+	var content mergedContent
+	if err := json.Unmarshal([]byte(StripCodeFence(text)), &content); err != nil {
+		return Experience{}, fmt.Errorf("failed to parse merged experience: %w", err)
+	}
+
+	merged := primary
+	if content.ErrorPattern != "" {
+		merged.ErrorPattern = content.ErrorPattern
+	}
+	if content.RootCause != "" {
+		merged.RootCause = content.RootCause
+	}
+	if content.Solution != "" {
+		merged.Solution = content.Solution
+	}
+	return merged, nil
+}
+
+// appendAddendum appends addendum to text as a separate paragraph, unless it is
+// empty or already contained in text.
+func appendAddendum(text, addendum string) string {
+	addendum = strings.TrimSpace(addendum)
+	if addendum == "" || strings.Contains(text, addendum) {
+		return text
+	}
+	return text + "\n\n补充：" + addendum
+}
+
+// longer returns whichever of a and b has more characters, preferring a on a tie.
+func longer(a, b string) string {
+	if utf8.RuneCountInString(b) > utf8.RuneCountInString(a) {
+		return b
+	}
+	return a
+}
+
+// StripCodeFence removes a surrounding Markdown code fence, such as ```json ... ```,
+// that LLMs often wrap around structured output.
+func StripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMergeExperiences_LongestWins(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	// Each experience has the longer text for some of the fields
+	if err := store.SaveExperience(ctx, "user-a", "nil pointer dereference in handler when config is missing", "config not loaded", "check config before use", nil, []float32{0.1}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	if err := store.SaveExperience(ctx, "user-a", "handler panics on nil config", "config is loaded lazily and may still be nil on the first request", "add a nil check and load the config at startup so handlers never see nil", nil, []float32{0.2}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+
+	embedder := &mockEmbedder{}
+	merger := NewExperienceMerger(store, embedder, nil)
+	merged, err := merger.MergeExperiences(ctx, 1, 2, MergeLongestWins)
+	if err != nil {
+		t.Fatalf("MergeExperiences failed: %v", err)
+	}
+
+	if want := "nil pointer dereference in handler when config is missing"; merged.ErrorPattern != want {
+		t.Errorf("expected pattern %q, got %q", want, merged.ErrorPattern)
+	}
+	if want := "config is loaded lazily and may still be nil on the first request"; merged.RootCause != want {
+		t.Errorf("expected cause %q, got %q", want, merged.RootCause)
+	}
+	if want := "add a nil check and load the config at startup so handlers never see nil"; merged.Solution != want {
+		t.Errorf("expected solution %q, got %q", want, merged.Solution)
+	}
+
+	stored, err := store.GetExperience(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetExperience failed: %v", err)
+	}
+	if stored.ErrorPattern != merged.ErrorPattern || stored.RootCause != merged.RootCause || stored.Solution != merged.Solution {
+		t.Errorf("primary was not updated with merged content: %+v", stored)
+	}
+	if _, err := store.GetExperience(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected secondary to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/easeaico/adk-memory-agent/migrations"
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationLockID is the advisory lock held while migrating, so that agents starting
+// at the same time do not apply the same migration twice.
+const migrationLockID = 7_240_511
+
+// ErrSchemaNotVersioned is returned by Migrate when the database already has the
+// agent's tables but no record of the migrations applied to it, e.g. because they
+// were applied by hand. BaselineMigrations records them.
+var ErrSchemaNotVersioned = errors.New("database schema is not versioned")
+
+// Migration is a change to the database schema.
+type Migration struct {
+	Version int    // Position in the migration sequence, starting at 1
+	Name    string // File name of the migration
+	SQL     string // Statements applying the migration
+}
+
+// MigrationRunner brings the schema of a store's database up to date.
+type MigrationRunner interface {
+	// Migrate applies the pending migrations; it does nothing once the schema is up to date.
+	Migrate(ctx context.Context) error
+}
+
+var _ MigrationRunner = (*PostgresStore)(nil)
+
+// MigrationsTableSQL creates the schema_migrations table, which records the migrations
+// applied to the database, if it does not exist.
+const MigrationsTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);`
+
+// Migrations are the migrations in the migrations directory, ordered by version.
+var Migrations = mustLoadMigrations(migrations.FS)
+
+// Migrate applies the migrations that have not been applied to the database yet, in
+// order and in a single transaction, and records them in the schema_migrations table.
+// Running it again once the schema is up to date does nothing. Returns
+// ErrSchemaNotVersioned for databases that were migrated by hand.
+//
+// The vector index is then rebuilt if its type or parameters differ from those of
+// StoreOptions, which is not recorded as a migration: agents sharing a database must
+// be configured with the same index.
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	if err := migrate(ctx, s.pool, Migrations); err != nil {
+		return err
+	}
+	_, err := ensureVectorIndex(ctx, s.pool, s.vectorIndex)
+	return err
+}
+
+// BaselineMigrations records migrations up to and including version as applied
+// without running them, for databases whose schema was migrated by hand.
+func (s *PostgresStore) BaselineMigrations(ctx context.Context, version int) error {
+	return baselineMigrations(ctx, s.pool, Migrations, version)
+}
+
+// txBeginner starts transactions; it is implemented by *pgxpool.Pool and *pgx.Conn.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// migrate applies the pending migrations of migrations to db. See PostgresStore.Migrate.
+func migrate(ctx context.Context, db txBeginner, migrations []Migration) error {
+	tx, err := beginMigration(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var versioned, hasTables bool
+	err = tx.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL, to_regclass('issue_history') IS NOT NULL`).
+		Scan(&versioned, &hasTables)
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if !versioned {
+		if hasTables {
+			return ErrSchemaNotVersioned
+		}
+		if err := createMigrationsTable(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	var current int
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if _, err := tx.Exec(ctx, m.SQL); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.Name, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// baselineMigrations records the migrations of migrations up to version as applied.
+// See PostgresStore.BaselineMigrations.
+func baselineMigrations(ctx context.Context, db txBeginner, migrations []Migration, version int) error {
+	if version < 1 || version > len(migrations) {
+		return fmt.Errorf("unknown migration version %d, must be between 1 and %d", version, len(migrations))
+	}
+
+	tx, err := beginMigration(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := createMigrationsTable(ctx, tx); err != nil {
+		return err
+	}
+	for _, m := range migrations[:version] {
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING`, m.Version, m.Name)
+		if err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// beginMigration starts a transaction holding the migration lock.
+func beginMigration(ctx context.Context, db txBeginner) (pgx.Tx, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	return tx, nil
+}
+
+// createMigrationsTable creates the schema_migrations table if it does not exist.
+func createMigrationsTable(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, MigrationsTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// loadMigrations reads the *.sql files of fsys as migrations. File names must start
+// with the version followed by "_", and versions must be 1, 2, 3 and so on.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with the version, e.g. 001_init.sql", name)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %s: expected version %d", m.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// mustLoadMigrations is like loadMigrations but panics on error, since the embedded
+// migrations are fixed at build time.
+func mustLoadMigrations(fsys fs.FS) []Migration {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		panic(fmt.Sprintf("invalid migrations: %v", err))
+	}
+	return migrations
+}
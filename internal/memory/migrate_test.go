@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(fstest.MapFS{
+		"002_add_column.sql": {Data: []byte("ALTER TABLE t ADD COLUMN c INT;")},
+		"001_init.sql":       {Data: []byte("CREATE TABLE t (id INT);")},
+		"README.md":          {Data: []byte("not a migration")},
+	})
+	if err != nil {
+		t.Fatalf("loadMigrations failed: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "001_init.sql" || migrations[1].Version != 2 {
+		t.Fatalf("expected migrations 1 and 2 in order, got %+v", migrations)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"gap":         {"001_init.sql": {}, "003_later.sql": {}},
+		"duplicate":   {"001_init.sql": {}, "001_again.sql": {}},
+		"no version":  {"init.sql": {}},
+		"bad version": {"v1_init.sql": {}},
+	} {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// The embedded migrations are numbered without gaps
+	if len(Migrations) == 0 || Migrations[len(Migrations)-1].Version != len(Migrations) {
+		t.Errorf("expected embedded migrations 1 to %d, got %d", len(Migrations), len(Migrations))
+	}
+}
+
+// TestMigrate_Idempotent runs against the database in TEST_DATABASE_URL. The
+// migrations are applied to a new schema that is dropped afterwards.
+func TestMigrate_Idempotent(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close(ctx) }()
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	defer func() { _, _ = conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE") }()
+	// public stays on the path for the pgvector types
+	if _, err := conn.Exec(ctx, "SET search_path TO "+schema+", public"); err != nil {
+		t.Fatalf("failed to set search path: %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := migrate(ctx, conn, Migrations); err != nil {
+			t.Fatalf("run %d: migrate failed: %v", run, err)
+		}
+		var count, version int
+		if err := conn.QueryRow(ctx, "SELECT COUNT(*), MAX(version) FROM schema_migrations").Scan(&count, &version); err != nil {
+			t.Fatalf("failed to read schema_migrations: %v", err)
+		}
+		if count != len(Migrations) || version != len(Migrations) {
+			t.Errorf("run %d: expected %d recorded migrations, got %d up to version %d", run, len(Migrations), count, version)
+		}
+	}
+
+	// A schema migrated by hand must be baselined before it can be migrated
+	if _, err := conn.Exec(ctx, "DROP TABLE schema_migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(ctx, conn, Migrations); !errors.Is(err, ErrSchemaNotVersioned) {
+		t.Fatalf("expected ErrSchemaNotVersioned, got %v", err)
+	}
+	if err := baselineMigrations(ctx, conn, Migrations, len(Migrations)); err != nil {
+		t.Fatalf("baselineMigrations failed: %v", err)
+	}
+	if err := migrate(ctx, conn, Migrations); err != nil {
+		t.Fatalf("expected baselined schema to be up to date, got %v", err)
+	}
+}
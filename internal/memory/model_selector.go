@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLongTextThreshold is the length in characters above which SmartModelSelector
+// treats a text as long when ThresholdChars is not set.
+const DefaultLongTextThreshold = 500
+
+// scriptSampleRunes is the number of leading runes inspected when detecting Chinese text.
+const scriptSampleRunes = 256
+
+// EmbeddingModelSelector chooses the embedding model used for a text.
+type EmbeddingModelSelector interface {
+	SelectModel(text string) string
+}
+
+// SmartModelSelector selects an embedding model based on the language and length of the text.
+// Texts containing Chinese characters use MultilingualModel, texts longer than ThresholdChars
+// use LongModel and everything else uses ShortEnglishModel. Empty model names fall back to
+// EmbeddingModel.
+type SmartModelSelector struct {
+	ShortEnglishModel string // Model for short texts without Chinese characters
+	LongModel         string // Model for texts longer than ThresholdChars
+	MultilingualModel string // Model for texts containing Chinese characters
+	ThresholdChars    int    // Length in characters above which a text is long (default 500)
+}
+
+// SelectModel returns the embedding model to use for text.
+func (s SmartModelSelector) SelectModel(text string) string {
+	threshold := s.ThresholdChars
+	if threshold <= 0 {
+		threshold = DefaultLongTextThreshold
+	}
+
+	switch {
+	case containsHan(text):
+		return modelOrDefault(s.MultilingualModel)
+	case utf8.RuneCountInString(text) > threshold:
+		return modelOrDefault(s.LongModel)
+	default:
+		return modelOrDefault(s.ShortEnglishModel)
+	}
+}
+
+// containsHan reports whether any of the first scriptSampleRunes runes of text is a Han character.
+func containsHan(text string) bool {
+	n := 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+		if n++; n >= scriptSampleRunes {
+			return false
+		}
+	}
+	return false
+}
+
+// modelOrDefault returns model, or EmbeddingModel if model is empty.
+func modelOrDefault(model string) string {
+	if model == "" {
+		return EmbeddingModel
+	}
+	return model
+}
+
+// selectEmbeddingModel returns the model selector picks for text, or EmbeddingModel
+// when selector is nil.
+func selectEmbeddingModel(selector EmbeddingModelSelector, text string) string {
+	if selector == nil {
+		return EmbeddingModel
+	}
+	return selector.SelectModel(text)
+}
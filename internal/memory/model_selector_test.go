@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSmartModelSelector_SelectModel(t *testing.T) {
+	selector := SmartModelSelector{
+		ShortEnglishModel: "short-model",
+		LongModel:         "long-model",
+		MultilingualModel: "multilingual-model",
+		ThresholdChars:    500,
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "short English", text: "nil deref!", want: "short-model"},
+		{name: "long English", text: strings.Repeat("a", 2000), want: "long-model"},
+		{name: "Chinese", text: "空指针异常 in handler", want: "multilingual-model"},
+		{name: "long Chinese", text: strings.Repeat("错误", 1000), want: "multilingual-model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selector.SelectModel(tt.text); got != tt.want {
+				t.Errorf("SelectModel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := (SmartModelSelector{}).SelectModel("short"); got != EmbeddingModel {
+		t.Errorf("expected empty selector to fall back to %q, got %q", EmbeddingModel, got)
+	}
+}
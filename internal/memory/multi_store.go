@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// MultiStore is a Store that sends new experiences and rules to a secondary store as
+// well as the primary one, so that a store being migrated to keeps receiving writes
+// while the existing data is copied over. Reads and every other method go to the
+// primary store only.
+type MultiStore struct {
+	Store // Primary store, the source of truth
+
+	// ReadFromBoth makes SearchSimilarIssues search the secondary store too and merge its
+	// results into those of the primary one. SearchSimilarIssuesPage, whose total is the
+	// primary's count, still searches the primary store only.
+	ReadFromBoth bool
+
+	secondary Store
+}
+
+// NewMultiStore returns a MultiStore writing to primary and secondary.
+func NewMultiStore(primary, secondary Store) *MultiStore {
+	return &MultiStore{Store: primary, secondary: secondary}
+}
+
+// SaveExperience saves the experience in both stores at the same time. A failure of the
+// secondary store is logged and does not fail the call, whose result is the primary's.
+func (s *MultiStore) SaveExperience(ctx context.Context, userID, pattern, cause, solution string, tags []string, vector []float32) error {
+	var secondaryErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		secondaryErr = s.secondary.SaveExperience(ctx, userID, pattern, cause, solution, tags, vector)
+	}()
+	err := s.Store.SaveExperience(ctx, userID, pattern, cause, solution, tags, vector)
+	wg.Wait()
+
+	if secondaryErr != nil {
+		slog.WarnContext(ctx, "failed to save experience in the secondary store", slog.String("pattern", pattern), slog.Any("error", secondaryErr))
+	}
+	return err
+}
+
+// UpsertProjectRule saves the rule in both stores at the same time and returns the ID
+// of the primary store's rule. A failure of the secondary store is logged and does not
+// fail the call.
+func (s *MultiStore) UpsertProjectRule(ctx context.Context, category, content string, priority int) (int, error) {
+	var secondaryErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, secondaryErr = s.secondary.UpsertProjectRule(ctx, category, content, priority)
+	}()
+	id, err := s.Store.UpsertProjectRule(ctx, category, content, priority)
+	wg.Wait()
+
+	if secondaryErr != nil {
+		slog.WarnContext(ctx, "failed to save project rule in the secondary store", slog.String("category", category), slog.Any("error", secondaryErr))
+	}
+	return id, err
+}
+
+// SearchSimilarIssues searches the primary store, and the secondary one as well when
+// ReadFromBoth is set. Merged results are ordered by similarity and cut to limit, and an
+// experience found in both stores, by ID, is returned once as found in the primary. A
+// failure of the secondary store is logged and the primary's results are returned.
+func (s *MultiStore) SearchSimilarIssues(ctx context.Context, query string, queryVector []float32, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, error) {
+	if !s.ReadFromBoth {
+		return s.Store.SearchSimilarIssues(ctx, query, queryVector, limit, minSimilarity, projectID, includeGlobal, tags)
+	}
+
+	var secondary []Experience
+	var secondaryErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		secondary, secondaryErr = s.secondary.SearchSimilarIssues(ctx, query, queryVector, limit, minSimilarity, projectID, includeGlobal, tags)
+	}()
+	experiences, err := s.Store.SearchSimilarIssues(ctx, query, queryVector, limit, minSimilarity, projectID, includeGlobal, tags)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if secondaryErr != nil {
+		slog.WarnContext(ctx, "failed to search the secondary store", slog.Any("error", secondaryErr))
+		return experiences, nil
+	}
+
+	seen := make(map[int]bool, len(experiences))
+	for _, exp := range experiences {
+		seen[exp.ID] = true
+	}
+	for _, exp := range secondary {
+		if !seen[exp.ID] {
+			seen[exp.ID] = true
+			experiences = append(experiences, exp)
+		}
+	}
+	sort.SliceStable(experiences, func(i, j int) bool {
+		return experiences[i].SimilarityScore > experiences[j].SimilarityScore
+	})
+	if limit > 0 && len(experiences) > limit {
+		experiences = experiences[:limit]
+	}
+	return experiences, nil
+}
+
+// Close closes both stores.
+func (s *MultiStore) Close() {
+	s.Store.Close()
+	s.secondary.Close()
+}
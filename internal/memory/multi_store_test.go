@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var errUnavailable = errors.New("secondary store unavailable")
+
+// unavailableStore fails every write and search.
+type unavailableStore struct {
+	InMemoryStore
+}
+
+func (s *unavailableStore) SaveExperience(ctx context.Context, userID, pattern, cause, solution string, tags []string, vector []float32) error {
+	return errUnavailable
+}
+
+func (s *unavailableStore) UpsertProjectRule(ctx context.Context, category, content string, priority int) (int, error) {
+	return 0, errUnavailable
+}
+
+func (s *unavailableStore) SearchSimilarIssues(ctx context.Context, query string, queryVector []float32, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, error) {
+	return nil, errUnavailable
+}
+
+func TestMultiStore_WritesToBoth(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := &InMemoryStore{}, &InMemoryStore{}
+	store := NewMultiStore(primary, secondary)
+
+	if err := store.SaveExperience(ctx, "", "deadlock in worker pool", "cause", "solution", nil, []float32{1, 0}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	if _, err := store.UpsertProjectRule(ctx, "style", "wrap errors with %w", 1); err != nil {
+		t.Fatalf("UpsertProjectRule failed: %v", err)
+	}
+	for name, s := range map[string]*InMemoryStore{"primary": primary, "secondary": secondary} {
+		if experiences, _ := s.ListExperiences(ctx); len(experiences) != 1 {
+			t.Errorf("%s store has %d experiences, want 1", name, len(experiences))
+		}
+		if rules, _ := s.ListProjectRules(ctx); len(rules) != 1 {
+			t.Errorf("%s store has %d rules, want 1", name, len(rules))
+		}
+	}
+
+	// Other writes go to the primary store only
+	if err := store.DeleteExperience(ctx, 1); err != nil {
+		t.Fatalf("DeleteExperience failed: %v", err)
+	}
+	if _, err := secondary.GetExperience(ctx, 1); err != nil {
+		t.Errorf("expected the experience to remain in the secondary store, got %v", err)
+	}
+}
+
+func TestMultiStore_SecondaryFailure(t *testing.T) {
+	ctx := context.Background()
+	primary := &InMemoryStore{}
+	store := NewMultiStore(primary, &unavailableStore{})
+	store.ReadFromBoth = true
+
+	if err := store.SaveExperience(ctx, "", "deadlock in worker pool", "cause", "solution", nil, []float32{1, 0}); err != nil {
+		t.Errorf("SaveExperience failed although the primary store succeeded: %v", err)
+	}
+	if id, err := store.UpsertProjectRule(ctx, "style", "wrap errors with %w", 1); err != nil || id != 1 {
+		t.Errorf("UpsertProjectRule = %d, %v, want the primary store's rule 1", id, err)
+	}
+	results, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 5, 0.5, "", true, nil)
+	if err != nil || len(results) != 1 {
+		t.Errorf("SearchSimilarIssues = %v, %v, want the primary store's experience", results, err)
+	}
+
+	// A failure of the primary store is returned
+	store = NewMultiStore(&unavailableStore{}, primary)
+	if err := store.SaveExperience(ctx, "", "nil map write", "cause", "solution", nil, []float32{0, 1}); !errors.Is(err, errUnavailable) {
+		t.Errorf("SaveExperience error = %v, want the primary store's error", err)
+	}
+}
+
+func TestMultiStore_ReadFromBoth(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := &InMemoryStore{}, &InMemoryStore{}
+	for _, s := range []*InMemoryStore{primary, secondary} {
+		if err := s.SaveExperience(ctx, "", "deadlock in worker pool", "cause", "solution", nil, []float32{1, 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := secondary.SaveExperience(ctx, "", "deadlock in connection pool", "cause", "solution", nil, []float32{0.9, 0.1}); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMultiStore(primary, secondary)
+
+	results, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 5, 0.5, "", true, nil)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("SearchSimilarIssues = %v, %v, want the primary store's experience only", results, err)
+	}
+
+	store.ReadFromBoth = true
+	results, err = store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 5, 0.5, "", true, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 1 || results[1].ID != 2 {
+		t.Errorf("SearchSimilarIssues = %+v, want experiences 1 and 2 once each, most similar first", results)
+	}
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOllamaEmbedModel is the Ollama model used for embeddings when none is configured.
+// It produces 768-dimensional vectors, the size of the embedding column.
+const DefaultOllamaEmbedModel = "nomic-embed-text"
+
+// ollamaEmbedder generates embeddings with a local Ollama server, so that no text is
+// sent to an external API.
+type ollamaEmbedder struct {
+	url    string
+	model  string
+	client *http.Client
+}
+
+// NewOllamaEmbedder creates an Embedder that calls the embeddings API of the Ollama server
+// at host, e.g. "localhost:11434" or "http://ollama:11434", with the given model.
+// DefaultOllamaEmbedModel is used when model is empty.
+func NewOllamaEmbedder(host, model string) Embedder {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	if model == "" {
+		model = DefaultOllamaEmbedModel
+	}
+	return &ollamaEmbedder{
+		url:    strings.TrimRight(host, "/") + "/api/embeddings",
+		model:  model,
+		client: http.DefaultClient,
+	}
+}
+
+// Embed generates a vector embedding for text with the configured Ollama model.
+func (e *ollamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(map[string]string{"model": e.model, "prompt": text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ollama returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	if len(result.Embedding) == 0 {
+		return nil, fmt.Errorf("ollama returned no embedding for model %s", e.model)
+	}
+	return result.Embedding, nil
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaEmbedder(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/embeddings" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got["model"] == "missing" {
+			http.Error(w, `{"error":"model \"missing\" not found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"embedding": []float32{0.5, 0.25, 1}})
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	// The scheme is optional, as in OLLAMA_HOST
+	embedder := NewOllamaEmbedder(strings.TrimPrefix(server.URL, "http://"), "")
+	vector, err := embedder.Embed(ctx, "nil map write")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vector) != 3 || vector[0] != 0.5 || vector[2] != 1 {
+		t.Errorf("expected the vector of the response, got %v", vector)
+	}
+	if got["model"] != DefaultOllamaEmbedModel || got["prompt"] != "nil map write" {
+		t.Errorf("expected the default model and the text as prompt, got %v", got)
+	}
+
+	_, err = NewOllamaEmbedder(server.URL+"/", "missing").Embed(ctx, "nil map write")
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the Ollama error to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SetProjectContext inserts or replaces the value of key for the store's project in the
+// project_context table.
+func (s *PostgresStore) SetProjectContext(ctx context.Context, key, value string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO project_context (project_id, key, value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, key) DO UPDATE SET value = $3, updated_at = NOW()
+	`, s.projectID, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to set project context: %w", err)
+	}
+	return nil
+}
+
+// GetProjectContext returns the value of key for the store's project, or ErrNotFound.
+func (s *PostgresStore) GetProjectContext(ctx context.Context, key string) (string, error) {
+	var value string
+	err := s.pool.QueryRow(ctx, `SELECT value FROM project_context WHERE project_id = $1 AND key = $2`, s.projectID, key).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("project context %q: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get project context: %w", err)
+	}
+	return value, nil
+}
+
+// ListProjectContext returns the values recorded for the store's project, by key.
+func (s *PostgresStore) ListProjectContext(ctx context.Context) (map[string]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT key, value FROM project_context WHERE project_id = $1`, s.projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project context: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan project context: %w", err)
+		}
+		entries[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list project context: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// readOnlyQueryTimeout bounds how long QueryReadOnly lets a query run.
+const readOnlyQueryTimeout = 10 * time.Second
+
+// SQLQuerier runs ad-hoc queries against the database of a store, for diagnostics.
+// It is implemented by PostgresStore.
+type SQLQuerier interface {
+	// QueryReadOnly runs query in a read-only transaction and returns up to maxRows rows
+	// as maps from column name to value, and whether more rows were left out.
+	QueryReadOnly(ctx context.Context, query string, maxRows int) ([]map[string]any, bool, error)
+}
+
+// QueryReadOnly runs query in a read-only transaction with a statement timeout, so
+// that it cannot change the database however it is written, and returns up to maxRows
+// rows. Values are those decoded by pgx, embeddings as their text form.
+func (s *PostgresStore) QueryReadOnly(ctx context.Context, query string, maxRows int) ([]map[string]any, bool, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", readOnlyQueryTimeout.Milliseconds())); err != nil {
+		return nil, false, fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	var results []map[string]any
+	truncated := false
+	for rows.Next() {
+		if len(results) == maxRows {
+			truncated = true
+			break
+		}
+		values, err := rows.Values()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read row: %w", err)
+		}
+		row := make(map[string]any, len(fields))
+		for i, field := range fields {
+			row[field.Name] = values[i]
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to run query: %w", err)
+	}
+	return results, truncated, nil
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// TestPostgresStore_QueryReadOnly runs against the database in TEST_DATABASE_URL, which
+// must have all migrations applied.
+func TestPostgresStore_QueryReadOnly(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	rows, truncated, err := s.QueryReadOnly(ctx, "SELECT n, n * 2 AS twice FROM generate_series(1, 5) AS n", 3)
+	if err != nil {
+		t.Fatalf("QueryReadOnly failed: %v", err)
+	}
+	if len(rows) != 3 || !truncated || rows[2]["twice"] != int32(6) {
+		t.Errorf("QueryReadOnly = %v, %v, want the first 3 rows and truncated", rows, truncated)
+	}
+
+	// The transaction is read-only whatever the query does
+	if _, _, err := s.QueryReadOnly(ctx, "SELECT nextval('issue_history_id_seq')", 1); err == nil {
+		t.Error("expected a query changing a sequence to fail")
+	}
+}
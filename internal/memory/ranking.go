@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"sort"
+	"time"
+)
+
+// Ranking modes of RankExperiences.
+const (
+	RankingSimilarity = "similarity" // Most similar first, the order of the stores' searches
+	RankingRecency    = "recency"    // Most recently occurred first
+	RankingCombined   = "combined"   // Weighted sum of similarity and recency, see RankExperiences
+)
+
+// DefaultRankingAlpha is the weight of similarity against recency in RankingCombined.
+const DefaultRankingAlpha = 0.7
+
+// recencyScaleDays is the age in days at which RecencyScore drops to 1/2.
+const recencyScaleDays = 30
+
+// RecencyScore is 1 for an experience that occurred at now and decreases with its age
+// as 1 / (1 + days / 30), so that a month old experience scores 1/2 and a year old one
+// about 0.08.
+func RecencyScore(occurredAt, now time.Time) float32 {
+	days := max(now.Sub(occurredAt).Hours()/24, 0)
+	return float32(1 / (1 + days/recencyScaleDays))
+}
+
+// RankExperiences orders the results of a search by mode. RankingRecency puts the most
+// recent first; RankingCombined orders by alpha * SimilarityScore + (1-alpha) *
+// RecencyScore, so that an old solution that may no longer apply can be passed by a
+// slightly less similar recent one. Experiences with equal keys keep their order, and
+// any other mode, RankingSimilarity included, leaves experiences as they are.
+func RankExperiences(experiences []Experience, mode string, alpha float32, now time.Time) {
+	switch mode {
+	case RankingRecency:
+		sort.SliceStable(experiences, func(i, j int) bool {
+			return experiences[i].OccurredAt.After(experiences[j].OccurredAt)
+		})
+	case RankingCombined:
+		type scored struct {
+			exp   Experience
+			score float32
+		}
+		ranked := make([]scored, len(experiences))
+		for i, exp := range experiences {
+			ranked[i] = scored{exp, alpha*exp.SimilarityScore + (1-alpha)*RecencyScore(exp.OccurredAt, now)}
+		}
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+		for i := range ranked {
+			experiences[i] = ranked[i].exp
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecencyScore(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, 1},
+		{30 * 24 * time.Hour, 0.5},
+		{90 * 24 * time.Hour, 0.25},
+		{-time.Hour, 1}, // Clock skew does not score above 1
+	} {
+		if got := RecencyScore(now.Add(-tc.age), now); math.Abs(float64(got)-tc.want) > 1e-6 {
+			t.Errorf("RecencyScore(%v old) = %v, want %v", tc.age, got, tc.want)
+		}
+	}
+}
+
+func TestRankExperiences(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	results := func() []Experience {
+		return []Experience{
+			{ID: 1, SimilarityScore: 0.95, OccurredAt: now.AddDate(-3, 0, 0)},
+			{ID: 2, SimilarityScore: 0.9, OccurredAt: now.AddDate(0, 0, -1)},
+			{ID: 3, SimilarityScore: 0.5, OccurredAt: now},
+		}
+	}
+	ids := func(experiences []Experience) []int {
+		var ids []int
+		for _, exp := range experiences {
+			ids = append(ids, exp.ID)
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		mode  string
+		alpha float32
+		want  []int
+	}{
+		{RankingSimilarity, DefaultRankingAlpha, []int{1, 2, 3}},
+		{"", DefaultRankingAlpha, []int{1, 2, 3}},
+		{RankingRecency, DefaultRankingAlpha, []int{3, 2, 1}},
+		// 0.7*0.9 + 0.3*0.97 = 0.92 beats 0.7*0.95 + 0.3*0.03 = 0.67 and 0.7*0.5 + 0.3 = 0.65
+		{RankingCombined, DefaultRankingAlpha, []int{2, 1, 3}},
+		{RankingCombined, 1, []int{1, 2, 3}},
+		{RankingCombined, 0, []int{3, 2, 1}},
+	} {
+		experiences := results()
+		RankExperiences(experiences, tc.mode, tc.alpha, now)
+		if got := ids(experiences); !slices.Equal(got, tc.want) {
+			t.Errorf("RankExperiences(%q, alpha %v) = %v, want %v", tc.mode, tc.alpha, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// maxRecapWords is the length the LLM is asked to keep session recaps under, so that
+// they fit in a ticket comment.
+const maxRecapWords = 400
+
+// maxTranscriptPartBytes is the length at which SummarizeSession cuts each message and
+// tool call of the transcript it sends to the LLM.
+const maxTranscriptPartBytes = 2000
+
+// ErrEmptySession is returned by SummarizeSession for a session without messages.
+var ErrEmptySession = errors.New("the session has no messages to summarize")
+
+// SessionRecap is a summary of a session written by SummarizeSession.
+type SessionRecap struct {
+	ProblemStatement string   `json:"problem_statement"` // Problem the user came with
+	StepsTaken       []string `json:"steps_taken"`       // What was tried, in order
+	Solution         string   `json:"solution"`          // Fix found, empty when unresolved
+	Resolved         bool     `json:"resolved"`          // Whether the problem was solved
+	FollowUpActions  []string `json:"follow_up_actions"` // What remains to be done
+}
+
+// Text formats the recap as plain text for a ticket comment.
+func (r SessionRecap) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "问题：%s\n", r.ProblemStatement)
+	if len(r.StepsTaken) > 0 {
+		sb.WriteString("\n已尝试的步骤：\n")
+		for i, step := range r.StepsTaken {
+			fmt.Fprintf(&sb, "%d. %s\n", i+1, step)
+		}
+	}
+	if r.Resolved {
+		fmt.Fprintf(&sb, "\n解决方案：%s\n", r.Solution)
+	} else {
+		sb.WriteString("\n解决方案：尚未解决\n")
+	}
+	if len(r.FollowUpActions) > 0 {
+		sb.WriteString("\n后续行动：\n")
+		for _, action := range r.FollowUpActions {
+			fmt.Fprintf(&sb, "- %s\n", action)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// SummarizeSession asks generator for a recap of the conversation in history: the problem,
+// the steps taken, the solution if one was found, and follow-up actions. Tool calls and
+// their results are included in the transcript sent to the LLM when includeToolCalls is
+// set; otherwise only the messages of the user and the agent are.
+func SummarizeSession(ctx context.Context, generator Generator, history []*genai.Content, includeToolCalls bool) (SessionRecap, error) {
+	transcript := sessionTranscript(history, includeToolCalls)
+	if transcript == "" {
+		return SessionRecap{}, ErrEmptySession
+	}
+
+	prompt := fmt.Sprintf(`请为下面这次调试会话写一份简短的总结，用于粘贴到工单评论中，总长度不超过 %d 字。
+总结包括：问题描述、已尝试的步骤、找到的解决方案（如果没有解决则留空）以及后续需要的行动。
+只输出 JSON 对象，格式为 {"problem_statement": "...", "steps_taken": ["..."], "solution": "...", "resolved": true, "follow_up_actions": ["..."]}。
+
+会话记录：
+%s`, maxRecapWords, transcript)
+
+	text, err := generator.Generate(ctx, prompt)
+	if err != nil {
+		return SessionRecap{}, fmt.Errorf("failed to summarize session: %w", err)
+	}
+
+	var recap SessionRecap
+	if err := json.Unmarshal([]byte(StripCodeFence(text)), &recap); err != nil {
+		return SessionRecap{}, fmt.Errorf("failed to parse session summary: %w", err)
+	}
+	if recap.ProblemStatement == "" {
+		return SessionRecap{}, errors.New("session summary has no problem statement")
+	}
+	return recap, nil
+}
+
+// sessionTranscript renders the messages of history as a transcript, one line per text,
+// tool call and tool result, leaving out tool calls and results unless includeToolCalls
+// is set, and the model's thoughts. Long parts are cut at maxTranscriptPartBytes.
+func sessionTranscript(history []*genai.Content, includeToolCalls bool) string {
+	var lines []string
+	for _, content := range history {
+		if content == nil {
+			continue
+		}
+		speaker := "用户"
+		if content.Role == genai.RoleModel {
+			speaker = "助手"
+		}
+		for _, part := range content.Parts {
+			switch {
+			case part == nil || part.Thought:
+			case part.Text != "":
+				lines = append(lines, fmt.Sprintf("%s：%s", speaker, truncateBytes(part.Text, maxTranscriptPartBytes)))
+			case part.FunctionCall != nil && includeToolCalls:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				lines = append(lines, fmt.Sprintf("助手调用工具 %s：%s", part.FunctionCall.Name, truncateBytes(string(args), maxTranscriptPartBytes)))
+			case part.FunctionResponse != nil && includeToolCalls:
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				lines = append(lines, fmt.Sprintf("工具 %s 返回：%s", part.FunctionResponse.Name, truncateBytes(string(result), maxTranscriptPartBytes)))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateBytes shortens s to at most limit bytes at a rune boundary, appending "..."
+// when truncated.
+func truncateBytes(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit] + "..."
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/easeaico/adk-memory-agent/internal/retry"
+)
+
+// retryingEmbedder retries requests to the wrapped Embedder that fail with a
+// transient error.
+type retryingEmbedder struct {
+	inner  Embedder
+	policy retry.Policy
+}
+
+// NewRetryingEmbedder wraps inner so that requests failing with a retry.Retryable
+// error, such as a rate limit, are retried according to policy. The result also
+// implements BatchEmbedder, retrying whole batches.
+func NewRetryingEmbedder(inner Embedder, policy retry.Policy) BatchEmbedder {
+	return &retryingEmbedder{inner: inner, policy: policy}
+}
+
+// Embed embeds text with the inner embedder, retrying transient failures.
+func (e *retryingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := e.policy.Do(ctx, func() error {
+		var err error
+		vector, err = e.inner.Embed(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+// BatchEmbed embeds texts with a single EmbedAll call on the inner embedder. A transient
+// failure retries the whole batch.
+func (e *retryingEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := e.policy.Do(ctx, func() error {
+		var err error
+		vectors, err = EmbedAll(ctx, e.inner, texts)
+		return err
+	})
+	return vectors, err
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/retry"
+	"google.golang.org/genai"
+)
+
+// flakyEmbedder fails its first failures requests with a rate limit error.
+type flakyEmbedder struct {
+	failures int
+	calls    int
+}
+
+func (e *flakyEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	if e.calls <= e.failures {
+		return nil, genai.APIError{Code: http.StatusTooManyRequests, Message: "quota exceeded"}
+	}
+	return []float32{float32(len(text)), 1}, nil
+}
+
+func TestRetryingEmbedder(t *testing.T) {
+	ctx := context.Background()
+	inner := &flakyEmbedder{failures: 2}
+	embedder := NewRetryingEmbedder(inner, retry.Policy{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	vector, err := embedder.Embed(ctx, "nil pointer")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if inner.calls != 3 || len(vector) != 2 || vector[0] != 11 {
+		t.Errorf("expected the vector of the third attempt, got %v after %d calls", vector, inner.calls)
+	}
+
+	inner.calls, inner.failures = 0, 3
+	if _, err := embedder.Embed(ctx, "nil pointer"); err == nil || inner.calls != 3 {
+		t.Errorf("expected failure after 3 attempts, got %v after %d calls", err, inner.calls)
+	}
+}
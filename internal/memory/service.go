@@ -8,7 +8,10 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	adkmemory "google.golang.org/adk/memory"
@@ -16,16 +19,25 @@ import (
 	"google.golang.org/genai"
 )
 
+// EmbeddingModel is the name of the default model used to generate text embeddings.
+const EmbeddingModel = "text-embedding-004"
+
+// embeddingDimensions is the size of the embedding column in issue_history. Every model
+// is asked for vectors of this size so that embeddings from different models fit the column.
+const embeddingDimensions = 768
+
 // embedderImpl is the concrete implementation of the Embedder interface.
-// It wraps the genai client for generating text embeddings using Google's
-// text-embedding-004 model to convert text into vector representations for similarity search.
+// It wraps the genai client for generating text embeddings, using the model chosen by
+// its selector to convert text into vector representations for similarity search.
 type embedderImpl struct {
-	client *genai.Client
+	client   *genai.Client
+	selector EmbeddingModelSelector
 }
 
 // NewEmbedder creates a new Embedder implementation with the given GenAI client configuration.
+// selector chooses the model for each text; EmbeddingModel is always used when it is nil.
 // It returns the Embedder interface, allowing for easier testing with mock implementations.
-func NewEmbedder(ctx context.Context, apiKey string) (Embedder, error) {
+func NewEmbedder(ctx context.Context, apiKey string, selector EmbeddingModelSelector) (Embedder, error) {
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey:  apiKey,
 		Backend: genai.BackendGeminiAPI,
@@ -34,20 +46,67 @@ func NewEmbedder(ctx context.Context, apiKey string) (Embedder, error) {
 		return nil, fmt.Errorf("failed to create GenAI client: %w", err)
 	}
 
-	return &embedderImpl{client: client}, nil
+	return &embedderImpl{client: client, selector: selector}, nil
 }
 
-// Embed generates a vector embedding for the given text using the text-embedding-004 model.
+// Embed generates a vector embedding for the given text using the selected model.
 // The returned vector can be used for similarity search in the vector database.
 // Returns an error if the embedding generation fails.
 func (e *embedderImpl) Embed(ctx context.Context, text string) ([]float32, error) {
-	resp, err := e.client.Models.EmbedContent(ctx, "text-embedding-004", genai.Text(text), nil)
+	dimensions := int32(embeddingDimensions)
+	model := selectEmbeddingModel(e.selector, text)
+	resp, err := e.client.Models.EmbedContent(ctx, model, genai.Text(text), &genai.EmbedContentConfig{
+		OutputDimensionality: &dimensions,
+	})
 	if err != nil {
 		return nil, err
 	}
 	return resp.Embeddings[0].Values, nil
 }
 
+// maxEmbedBatch is the largest number of texts sent in a single embedding request.
+const maxEmbedBatch = 100
+
+// BatchEmbed embeds texts with as few requests as possible. Texts are grouped by the
+// model the selector picks for them and sent in chunks of up to maxEmbedBatch.
+func (e *embedderImpl) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	byModel := make(map[string][]int)
+	var models []string
+	for i, text := range texts {
+		model := selectEmbeddingModel(e.selector, text)
+		if _, ok := byModel[model]; !ok {
+			models = append(models, model)
+		}
+		byModel[model] = append(byModel[model], i)
+	}
+
+	dimensions := int32(embeddingDimensions)
+	config := &genai.EmbedContentConfig{OutputDimensionality: &dimensions}
+	vectors := make([][]float32, len(texts))
+	for _, model := range models {
+		indices := byModel[model]
+		for start := 0; start < len(indices); start += maxEmbedBatch {
+			chunk := indices[start:min(start+maxEmbedBatch, len(indices))]
+			contents := make([]*genai.Content, 0, len(chunk))
+			for _, i := range chunk {
+				contents = append(contents, genai.NewContentFromText(texts[i], genai.RoleUser))
+			}
+
+			resp, err := e.client.Models.EmbedContent(ctx, model, contents, config)
+			if err != nil {
+				return nil, err
+			}
+			if len(resp.Embeddings) != len(chunk) {
+				return nil, fmt.Errorf("expected %d embeddings, got %d", len(chunk), len(resp.Embeddings))
+			}
+			for j, i := range chunk {
+				vectors[i] = resp.Embeddings[j].Values
+			}
+		}
+	}
+	return vectors, nil
+}
+
 // Embedder defines the interface for embedding generation.
 // This allows for easier testing by using mock implementations.
 type Embedder interface {
@@ -60,11 +119,42 @@ type Embedder interface {
 type serviceImpl struct {
 	store    Store    // Database store for memory operations
 	embedder Embedder // Embedder for generating query vectors in Search and AddSession
+	minScore float32  // Minimum similarity of the experiences returned by Search
+	project  string   // Project whose experiences, and those shared by all projects, Search returns
+
+	gate QualityGate // Check sessions must pass to be saved by AddSession
 }
 
+// DefaultMinSimilarity is the minimum similarity of search results when none is given.
+const DefaultMinSimilarity float32 = 0.5
+
 // NewService creates a new memory service with the given store and embedder.
-func NewService(embedder Embedder, store Store) adkmemory.Service {
-	return &serviceImpl{store: store, embedder: embedder}
+// Search only returns experiences with a similarity of at least DefaultMinSimilarity,
+// of projectID or shared by all projects.
+func NewService(embedder Embedder, store Store, projectID string) adkmemory.Service {
+	return NewServiceWithQualityGate(embedder, store, projectID, QualityGate{})
+}
+
+// DefaultMinConsolidationScore is the quality score a session must exceed to be saved
+// when QualityGate.MinScore is not set.
+const DefaultMinConsolidationScore float32 = 0.6
+
+// QualityGate configures the check AddSession runs before saving a session: the LLM
+// scores how well the session resolved its problem, from 0 (no resolution) to 1 (a clear,
+// reproducible fix), and only sessions scoring above MinScore are saved.
+type QualityGate struct {
+	Generator Generator // Scores sessions (optional, nil saves every session)
+	MinScore  float32   // Score a session must exceed to be saved (optional, defaults to DefaultMinConsolidationScore)
+	Force     bool      // Save sessions without scoring them
+}
+
+// NewServiceWithQualityGate creates a memory service like NewService whose AddSession
+// only saves the sessions that pass gate.
+func NewServiceWithQualityGate(embedder Embedder, store Store, projectID string, gate QualityGate) adkmemory.Service {
+	if gate.MinScore == 0 {
+		gate.MinScore = DefaultMinConsolidationScore
+	}
+	return &serviceImpl{store: store, embedder: embedder, minScore: DefaultMinSimilarity, project: projectID, gate: gate}
 }
 
 // AddSession implements memory.Service interface.
@@ -114,6 +204,20 @@ func (s *serviceImpl) AddSession(ctx context.Context, sess session.Session) erro
 
 	// Only save if we have both a query and a meaningful response
 	if userQuery != "" && agentResponse != "" && len(agentResponse) > 20 {
+		// Skip the embedding call entirely when the exact same query was already saved
+		if _, err := s.store.FindExperienceByHash(ctx, ContentHash(userQuery)); err == nil {
+			return nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("failed to check for duplicate experience: %w", err)
+		}
+
+		// Only keep sessions that actually resolved their problem
+		if pass, err := s.passesQualityGate(ctx, sess.ID(), userQuery, agentResponse); err != nil {
+			return err
+		} else if !pass {
+			return nil
+		}
+
 		// Generate embedding for the user query
 		queryVector, err := s.embedder.Embed(ctx, userQuery)
 		if err != nil {
@@ -121,9 +225,11 @@ func (s *serviceImpl) AddSession(ctx context.Context, sess session.Session) erro
 		}
 
 		// Save as experience
-		// Use user query as error_pattern, empty string as root_cause, agent response as solution
-		err = s.store.SaveExperience(ctx, userQuery, "", agentResponse, queryVector)
-		if err != nil {
+		// Use user query as error_pattern, empty string as root_cause, agent response as solution,
+		// tagged with the kinds of failure the query mentions
+		// An identical question that was already saved is not an error
+		err = s.store.SaveExperience(ctx, sess.UserID(), userQuery, "", agentResponse, DetectTags(userQuery), queryVector)
+		if err != nil && !errors.Is(err, ErrDuplicateExperience) {
 			return fmt.Errorf("failed to save session to memory: %w", err)
 		}
 	}
@@ -131,6 +237,62 @@ func (s *serviceImpl) AddSession(ctx context.Context, sess session.Session) erro
 	return nil
 }
 
+// passesQualityGate reports whether the session with the given question and final
+// response should be saved, asking the LLM for its quality score unless the gate is
+// disabled or forced. The score and the decision are logged.
+func (s *serviceImpl) passesQualityGate(ctx context.Context, sessionID, query, response string) (bool, error) {
+	if s.gate.Generator == nil {
+		return true, nil
+	}
+	if s.gate.Force {
+		slog.InfoContext(ctx, "saving session without a quality score, consolidation is forced", slog.String("session_id", sessionID))
+		return true, nil
+	}
+
+	score, err := scoreSession(ctx, s.gate.Generator, query, response)
+	if err != nil {
+		return false, err
+	}
+	if score <= s.gate.MinScore {
+		slog.InfoContext(ctx, "skipping session: quality score does not exceed the minimum", slog.String("session_id", sessionID), slog.Float64("score", float64(score)), slog.Float64("min_score", float64(s.gate.MinScore)))
+		return false, nil
+	}
+	slog.InfoContext(ctx, "saving session: quality score exceeds the minimum", slog.String("session_id", sessionID), slog.Float64("score", float64(score)), slog.Float64("min_score", float64(s.gate.MinScore)))
+	return true, nil
+}
+
+// sessionSummary is the JSON object the LLM answers with when scoring a session.
+type sessionSummary struct {
+	QualityScore float32 `json:"quality_score"`
+}
+
+// scoreSession asks generator how well response resolves the problem in query, from 0
+// (no resolution) to 1 (a clear, reproducible fix).
+func scoreSession(ctx context.Context, generator Generator, query, response string) (float32, error) {
+	prompt := fmt.Sprintf(`请评估下面这次会话中问题的解决质量，给出 0 到 1 之间的分数：0 表示问题没有解决，1 表示给出了清晰、可复现的修复方案。
+只输出 JSON 对象，格式为 {"quality_score": 0.0}。
+
+问题：
+%s
+
+回答：
+%s`, query, response)
+
+	text, err := generator.Generate(ctx, prompt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to score session: %w", err)
+	}
+
+	var summary sessionSummary
+	if err := json.Unmarshal([]byte(StripCodeFence(text)), &summary); err != nil {
+		return 0, fmt.Errorf("failed to parse session score: %w", err)
+	}
+	if summary.QualityScore < 0 || summary.QualityScore > 1 {
+		return 0, fmt.Errorf("session score %v is outside 0-1", summary.QualityScore)
+	}
+	return summary.QualityScore, nil
+}
+
 // Search implements memory.Service interface.
 // It performs a vector similarity search based on the query and returns memory entries.
 func (s *serviceImpl) Search(ctx context.Context, req *adkmemory.SearchRequest) (*adkmemory.SearchResponse, error) {
@@ -140,11 +302,12 @@ func (s *serviceImpl) Search(ctx context.Context, req *adkmemory.SearchRequest)
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
-	// Search for similar issues (limit to 10 most relevant)
-	experiences, err := s.store.SearchSimilarIssues(ctx, queryVector, 10)
+	// Search for similar issues (limit to 10 most relevant, skipping unrelated ones)
+	experiences, err := s.store.SearchSimilarIssues(ctx, req.Query, queryVector, 10, s.minScore, s.project, true, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search similar issues: %w", err)
 	}
+	AssignConfidence(experiences)
 
 	// Convert experiences to memory entries
 	memories := make([]adkmemory.Entry, 0, len(experiences))
@@ -160,11 +323,11 @@ func (s *serviceImpl) Search(ctx context.Context, req *adkmemory.SearchRequest)
 		if exp.Solution != "" {
 			parts = append(parts, "解决方案: "+exp.Solution)
 		}
-
-		content := strings.Join(parts, "\n")
-		if content == "" {
+		if len(parts) == 0 {
 			continue
 		}
+		parts = append(parts, "置信度: "+exp.ConfidenceLevel)
+		content := strings.Join(parts, "\n")
 
 		// genai.Text returns []*Content, we need the first one
 		contentParts := genai.Text(content)
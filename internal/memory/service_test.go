@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"iter"
+	"slices"
 	"testing"
 	"time"
 
@@ -13,49 +14,66 @@ import (
 	"google.golang.org/genai"
 )
 
-// mockStore is a mock implementation of Store for testing
+// mockStore wraps InMemoryStore with injectable errors and canned search results for
+// the cases that a working store cannot reproduce.
 type mockStore struct {
-	projectRules      []string
-	savedExperiences  []savedExperience
-	searchResults     []Experience
-	searchError       error
-	saveError         error
-	projectRulesError error
-}
+	InMemoryStore
+
+	searchResults []Experience // Returned by searches instead of the stored experiences when set
+	searchError   error
+	saveError     error
 
-type savedExperience struct {
-	pattern, cause, solution string
-	vector                   []float32
+	searchMinSimilarity float32 // minSimilarity passed to the last SearchSimilarIssues call
+	batchSaves          int     // Number of BatchSaveExperiences calls
 }
 
-func (m *mockStore) GetProjectRules(ctx context.Context) ([]string, error) {
-	if m.projectRulesError != nil {
-		return nil, m.projectRulesError
+func (m *mockStore) SearchSimilarIssues(ctx context.Context, query string, queryVector []float32, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, error) {
+	m.searchMinSimilarity = minSimilarity
+	if m.searchError != nil {
+		return nil, m.searchError
 	}
-	return m.projectRules, nil
+	if m.searchResults != nil {
+		return m.searchResults, nil
+	}
+	return m.InMemoryStore.SearchSimilarIssues(ctx, query, queryVector, limit, minSimilarity, projectID, includeGlobal, tags)
 }
 
-func (m *mockStore) SearchSimilarIssues(ctx context.Context, queryVector []float32, limit int) ([]Experience, error) {
+func (m *mockStore) SearchByUser(ctx context.Context, userID, query string, queryVector []float32, limit int) ([]Experience, error) {
 	if m.searchError != nil {
 		return nil, m.searchError
 	}
-	return m.searchResults, nil
+	if m.searchResults != nil {
+		var results []Experience
+		for _, exp := range m.searchResults {
+			if exp.UserID == userID {
+				results = append(results, exp)
+			}
+		}
+		return results, nil
+	}
+	return m.InMemoryStore.SearchByUser(ctx, userID, query, queryVector, limit)
 }
 
-func (m *mockStore) SaveExperience(ctx context.Context, pattern, cause, solution string, vector []float32) error {
+func (m *mockStore) SaveExperience(ctx context.Context, userID, pattern, cause, solution string, tags []string, vector []float32) error {
 	if m.saveError != nil {
 		return m.saveError
 	}
-	m.savedExperiences = append(m.savedExperiences, savedExperience{
-		pattern:  pattern,
-		cause:    cause,
-		solution: solution,
-		vector:   vector,
-	})
-	return nil
+	return m.InMemoryStore.SaveExperience(ctx, userID, pattern, cause, solution, tags, vector)
+}
+
+func (m *mockStore) BatchSaveExperiences(ctx context.Context, items []ExperienceInput) error {
+	m.batchSaves++
+	if m.saveError != nil {
+		return m.saveError
+	}
+	return m.InMemoryStore.BatchSaveExperiences(ctx, items)
 }
 
-func (m *mockStore) Close() {
+func (m *mockStore) SaveSourcedExperience(ctx context.Context, source, signature, pattern, cause, solution string, vector []float32) error {
+	if m.saveError != nil {
+		return m.saveError
+	}
+	return m.InMemoryStore.SaveSourcedExperience(ctx, source, signature, pattern, cause, solution, vector)
 }
 
 // mockEmbedder is a mock implementation of Embedder for testing
@@ -75,11 +93,21 @@ func (m *mockEmbedder) Embed(ctx context.Context, text string) ([]float32, error
 	return []float32{0.1, 0.2, 0.3}, nil
 }
 
+// storeWith returns a mockStore that already holds the given experiences.
+func storeWith(experiences ...Experience) *mockStore {
+	store := &mockStore{}
+	for _, exp := range experiences {
+		store.insert(exp, nil)
+	}
+	return store
+}
+
 // newTestService creates a serviceImpl for testing with a mock embedder
 func newTestService(store Store, mockEmbed Embedder) *serviceImpl {
 	return &serviceImpl{
 		store:    store,
 		embedder: mockEmbed,
+		minScore: DefaultMinSimilarity,
 	}
 }
 
@@ -171,7 +199,7 @@ func TestService_AddSession(t *testing.T) {
 		wantSaved      bool
 		wantError      bool
 		wantErrorMsg   string
-		checkSavedData func(*testing.T, []savedExperience)
+		checkSavedData func(*testing.T, []storedExperience)
 	}{
 		{
 			name: "successful save with user query and agent response",
@@ -203,19 +231,62 @@ func TestService_AddSession(t *testing.T) {
 			store:     &mockStore{},
 			wantSaved: true,
 			wantError: false,
-			checkSavedData: func(t *testing.T, saved []savedExperience) {
+			checkSavedData: func(t *testing.T, saved []storedExperience) {
 				if len(saved) != 1 {
 					t.Errorf("Expected 1 saved experience, got %d", len(saved))
 					return
 				}
-				if saved[0].pattern != "How to fix this error?" {
-					t.Errorf("Expected pattern 'How to fix this error?', got %q", saved[0].pattern)
+				if saved[0].UserID != "test-user" {
+					t.Errorf("Expected user ID 'test-user', got %q", saved[0].UserID)
+				}
+				if saved[0].ErrorPattern != "How to fix this error?" {
+					t.Errorf("Expected pattern 'How to fix this error?', got %q", saved[0].ErrorPattern)
 				}
-				if saved[0].cause != "" {
-					t.Errorf("Expected empty cause, got %q", saved[0].cause)
+				if saved[0].RootCause != "" {
+					t.Errorf("Expected empty cause, got %q", saved[0].RootCause)
 				}
-				if saved[0].solution != "This is a detailed solution that is longer than 20 characters to meet the requirement." {
-					t.Errorf("Unexpected solution: %q", saved[0].solution)
+				if saved[0].Solution != "This is a detailed solution that is longer than 20 characters to meet the requirement." {
+					t.Errorf("Unexpected solution: %q", saved[0].Solution)
+				}
+			},
+		},
+		{
+			name: "duplicate query is ignored without error",
+			session: &mockSession{
+				id:      "test-session-dup",
+				appName: "test-app",
+				userID:  "test-user",
+				events: []*session.Event{
+					{
+						Author: "user",
+						LLMResponse: model.LLMResponse{
+							Content: &genai.Content{
+								Parts: []*genai.Part{{Text: "How to fix this error?"}},
+							},
+						},
+					},
+					{
+						Author: "assistant",
+						LLMResponse: model.LLMResponse{
+							Content: &genai.Content{
+								Parts: []*genai.Part{{Text: "A newer answer that is also longer than 20 characters."}},
+							},
+						},
+					},
+				},
+				lastTime: time.Now(),
+			},
+			embedder:  &mockEmbedder{embedValue: defaultVector},
+			store:     storeWith(Experience{Source: SourceSession, TaskSignature: "How to fix this error?", ErrorPattern: "How to fix this error?", Solution: "original solution"}),
+			wantSaved: true,
+			wantError: false,
+			checkSavedData: func(t *testing.T, saved []storedExperience) {
+				if len(saved) != 1 {
+					t.Errorf("Expected duplicate to be skipped, got %d saved experiences", len(saved))
+					return
+				}
+				if saved[0].Solution != "original solution" {
+					t.Errorf("Expected original solution to be kept, got %q", saved[0].Solution)
 				}
 			},
 		},
@@ -437,19 +508,55 @@ func TestService_AddSession(t *testing.T) {
 			store:     &mockStore{},
 			wantSaved: true,
 			wantError: false,
-			checkSavedData: func(t *testing.T, saved []savedExperience) {
+			checkSavedData: func(t *testing.T, saved []storedExperience) {
 				if len(saved) != 1 {
 					t.Errorf("Expected 1 saved experience, got %d", len(saved))
 					return
 				}
 				// Note: strings.Join adds a space between parts, so "First part " + "second part" = "First part  second part"
 				expectedPattern := "First part  second part"
-				if saved[0].pattern != expectedPattern {
-					t.Errorf("Expected pattern %q, got %q", expectedPattern, saved[0].pattern)
+				if saved[0].ErrorPattern != expectedPattern {
+					t.Errorf("Expected pattern %q, got %q", expectedPattern, saved[0].ErrorPattern)
 				}
 				expectedSolution := "Response part 1  and part 2 with enough length"
-				if saved[0].solution != expectedSolution {
-					t.Errorf("Expected solution %q, got %q", expectedSolution, saved[0].solution)
+				if saved[0].Solution != expectedSolution {
+					t.Errorf("Expected solution %q, got %q", expectedSolution, saved[0].Solution)
+				}
+			},
+		},
+		{
+			name: "query mentioning failure kinds is tagged",
+			session: &mockSession{
+				id:      "test-session-tags",
+				appName: "test-app",
+				userID:  "test-user",
+				events: []*session.Event{
+					{
+						Author: "user",
+						LLMResponse: model.LLMResponse{
+							Content: &genai.Content{
+								Parts: []*genai.Part{{Text: "panic: runtime error: invalid memory address or nil pointer dereference after context deadline exceeded"}},
+							},
+						},
+					},
+					{
+						Author: "assistant",
+						LLMResponse: model.LLMResponse{
+							Content: &genai.Content{
+								Parts: []*genai.Part{{Text: "Check the error returned by the client before using the response."}},
+							},
+						},
+					},
+				},
+				lastTime: time.Now(),
+			},
+			embedder:  &mockEmbedder{embedValue: defaultVector},
+			store:     &mockStore{},
+			wantSaved: true,
+			checkSavedData: func(t *testing.T, saved []storedExperience) {
+				want := []string{"nil-pointer", "panic", "timeout"}
+				if !slices.Equal(saved[0].Tags, want) {
+					t.Errorf("Expected tags %v, got %v", want, saved[0].Tags)
 				}
 			},
 		},
@@ -477,22 +584,87 @@ func TestService_AddSession(t *testing.T) {
 			}
 
 			if tt.wantSaved {
-				if len(tt.store.savedExperiences) == 0 {
+				if len(tt.store.experiences) == 0 {
 					t.Error("Expected experience to be saved, but none were saved")
 					return
 				}
 				if tt.checkSavedData != nil {
-					tt.checkSavedData(t, tt.store.savedExperiences)
+					tt.checkSavedData(t, tt.store.experiences)
 				}
 			} else {
-				if len(tt.store.savedExperiences) > 0 {
-					t.Errorf("Expected no experience to be saved, but %d were saved", len(tt.store.savedExperiences))
+				if len(tt.store.experiences) > 0 {
+					t.Errorf("Expected no experience to be saved, but %d were saved", len(tt.store.experiences))
 				}
 			}
 		})
 	}
 }
 
+// mockGenerator returns a canned response and counts its calls.
+type mockGenerator struct {
+	response string
+	calls    int
+}
+
+func (m *mockGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	m.calls++
+	return m.response, nil
+}
+
+func TestService_AddSessionQualityGate(t *testing.T) {
+	newSession := func() *mockSession {
+		return &mockSession{
+			id:     "test-session",
+			userID: "test-user",
+			events: []*session.Event{
+				{Author: "user", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("Why does the server panic on startup?", genai.RoleUser)}},
+				{Author: "assistant", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("The config map was nil; initialize it in NewServer before use.", genai.RoleModel)}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		response  string
+		minScore  float32
+		force     bool
+		wantSaved bool
+		wantError bool
+	}{
+		{name: "score above default threshold", response: `{"quality_score": 0.9}`, wantSaved: true},
+		{name: "score below default threshold", response: `{"quality_score": 0.3}`},
+		{name: "score equal to threshold", response: `{"quality_score": 0.6}`},
+		{name: "fenced response", response: "```json\n{\"quality_score\": 0.8}\n```", wantSaved: true},
+		{name: "custom threshold", response: `{"quality_score": 0.5}`, minScore: 0.4, wantSaved: true},
+		{name: "forced", response: `{"quality_score": 0.1}`, force: true, wantSaved: true},
+		{name: "malformed response", response: "no idea", wantError: true},
+		{name: "score out of range", response: `{"quality_score": 7}`, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{}
+			generator := &mockGenerator{response: tt.response}
+			service := NewServiceWithQualityGate(&mockEmbedder{embedValue: []float32{0.1, 0.2, 0.3}}, store, "", QualityGate{
+				Generator: generator,
+				MinScore:  tt.minScore,
+				Force:     tt.force,
+			})
+
+			err := service.AddSession(context.Background(), newSession())
+			if (err != nil) != tt.wantError {
+				t.Fatalf("AddSession error = %v, wantError %v", err, tt.wantError)
+			}
+			if saved := len(store.experiences) == 1; saved != tt.wantSaved {
+				t.Errorf("expected saved %v, got %d experiences", tt.wantSaved, len(store.experiences))
+			}
+			if tt.force && generator.calls != 0 {
+				t.Errorf("expected a forced save not to score the session, got %d calls", generator.calls)
+			}
+		})
+	}
+}
+
 func TestService_Search(t *testing.T) {
 	ctx := context.Background()
 	defaultVector := []float32{0.1, 0.2, 0.3}
@@ -561,6 +733,12 @@ func TestService_Search(t *testing.T) {
 				if !contains(text1, "解决方案: test solution") {
 					t.Errorf("Expected content to contain '解决方案: test solution', got %q", text1)
 				}
+				if !contains(text1, "置信度: high") {
+					t.Errorf("Expected content to contain '置信度: high', got %q", text1)
+				}
+				if text2 := resp.Memories[1].Content.Parts[0].Text; !contains(text2, "置信度: medium") {
+					t.Errorf("Expected content to contain '置信度: medium', got %q", text2)
+				}
 			},
 		},
 		{
@@ -710,6 +888,18 @@ func TestService_Search(t *testing.T) {
 	}
 }
 
+func TestService_SearchMinSimilarity(t *testing.T) {
+	store := &mockStore{}
+	service := newTestService(store, &mockEmbedder{})
+
+	if _, err := service.Search(context.Background(), &adkmemory.SearchRequest{Query: "test query"}); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if store.searchMinSimilarity != DefaultMinSimilarity {
+		t.Errorf("Expected minimum similarity %v, got %v", DefaultMinSimilarity, store.searchMinSimilarity)
+	}
+}
+
 func TestNewService(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -725,7 +915,7 @@ func TestNewService(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := NewService(tt.embedder, tt.store)
+			service := NewService(tt.embedder, tt.store, "")
 
 			if service == nil {
 				t.Error("Expected service, got nil")
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SessionCost is the token usage and estimated API cost of an agent session, recorded
+// when the session ends.
+type SessionCost struct {
+	SessionID       string    `json:"session_id"`
+	ProjectID       string    `json:"project_id"`
+	InputTokens     int64     `json:"input_tokens"`     // Prompt tokens sent to the chat model
+	OutputTokens    int64     `json:"output_tokens"`    // Tokens generated by the chat model
+	EmbeddingCalls  int64     `json:"embedding_calls"`  // Requests to the embedding API
+	EmbeddingTokens int64     `json:"embedding_tokens"` // Estimated tokens of the embedded texts
+	CostUSD         float64   `json:"cost_usd"`         // Estimated cost in US dollars
+	EndedAt         time.Time `json:"ended_at"`
+}
+
+// SessionCostStore records the cost of agent sessions. It is implemented by
+// PostgresStore and InMemoryStore.
+type SessionCostStore interface {
+	// SaveSessionCost records the cost of a session that ended. The project ID of cost
+	// is replaced by the store's, and EndedAt is set to the current time when zero.
+	SaveSessionCost(ctx context.Context, cost SessionCost) error
+	// ListSessionCosts returns up to limit recorded costs of the store's project, most
+	// recent first. Only the costs of sessionID are returned when it is not empty.
+	ListSessionCosts(ctx context.Context, sessionID string, limit int) ([]SessionCost, error)
+}
+
+// SaveSessionCost inserts cost into the session_costs table.
+func (s *PostgresStore) SaveSessionCost(ctx context.Context, cost SessionCost) error {
+	if cost.EndedAt.IsZero() {
+		cost.EndedAt = time.Now()
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO session_costs (session_id, project_id, input_tokens, output_tokens, embedding_calls, embedding_tokens, cost_usd, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, cost.SessionID, s.projectID, cost.InputTokens, cost.OutputTokens, cost.EmbeddingCalls, cost.EmbeddingTokens, cost.CostUSD, cost.EndedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save session cost: %w", err)
+	}
+	return nil
+}
+
+// ListSessionCosts returns the most recent rows of the session_costs table of the
+// store's project, or of every project when the store has none.
+func (s *PostgresStore) ListSessionCosts(ctx context.Context, sessionID string, limit int) ([]SessionCost, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT session_id, project_id, input_tokens, output_tokens, embedding_calls, embedding_tokens, cost_usd, ended_at
+		FROM session_costs
+		WHERE ($1 = '' OR project_id = $1) AND ($2 = '' OR session_id = $2)
+		ORDER BY ended_at DESC, id DESC
+		LIMIT $3
+	`, s.projectID, sessionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session costs: %w", err)
+	}
+	defer rows.Close()
+
+	var costs []SessionCost
+	for rows.Next() {
+		var c SessionCost
+		if err := rows.Scan(&c.SessionID, &c.ProjectID, &c.InputTokens, &c.OutputTokens, &c.EmbeddingCalls, &c.EmbeddingTokens, &c.CostUSD, &c.EndedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session cost: %w", err)
+		}
+		costs = append(costs, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session costs: %w", err)
+	}
+	return costs, nil
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// SessionDiff is the difference between two runs of an investigation, written by
+// DiffSessions.
+type SessionDiff struct {
+	NewToolCalls        []string `json:"new_tool_calls"`        // Calls made by B but not by A, e.g. `read_file {"path":"main.go"}`
+	DroppedToolCalls    []string `json:"dropped_tool_calls"`    // Calls made by A but not by B
+	NewExperiencesSaved int      `json:"new_experiences_saved"` // Experiences saved by B but not by A
+	SummaryDiff         string   `json:"summary_diff"`          // How the conclusions differ, empty when either session has none
+}
+
+// DiffSessions compares the saved histories of sessionA and sessionB: the tool calls
+// only one of them made, identified by tool name and arguments, the experiences B
+// saved that A did not, and, written by generator, how the last answers of the agent
+// in each session differ.
+func DiffSessions(ctx context.Context, store SessionStore, generator Generator, sessionA, sessionB string) (*SessionDiff, error) {
+	historyA, err := store.LoadSession(ctx, sessionA)
+	if err != nil {
+		return nil, err
+	}
+	historyB, err := store.LoadSession(ctx, sessionB)
+	if err != nil {
+		return nil, err
+	}
+
+	callsA, callsB := toolCalls(historyA), toolCalls(historyB)
+	diff := &SessionDiff{
+		NewToolCalls:        missingFrom(callsB, callsA),
+		DroppedToolCalls:    missingFrom(callsA, callsB),
+		NewExperiencesSaved: len(missingFrom(savedExperiences(historyB), savedExperiences(historyA))),
+	}
+
+	answerA, answerB := lastAnswer(historyA), lastAnswer(historyB)
+	if answerA == "" || answerB == "" {
+		return diff, nil
+	}
+	prompt := fmt.Sprintf(`同一个问题先后进行了两次调试会话，下面是两次会话中助手的最终回答。
+请简要说明第二次的结论与第一次有何不同：新发现了什么、推翻了什么、哪些保持不变。只输出说明文字，不超过 %d 字。
+
+第一次会话的回答：
+%s
+
+第二次会话的回答：
+%s`, maxRecapWords, truncateBytes(answerA, maxTranscriptPartBytes), truncateBytes(answerB, maxTranscriptPartBytes))
+	text, err := generator.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare session answers: %w", err)
+	}
+	diff.SummaryDiff = strings.TrimSpace(text)
+	return diff, nil
+}
+
+// toolCalls returns the distinct tool calls of history as "name args", in the order
+// they were first made.
+func toolCalls(history []*genai.Content) []string {
+	var calls []string
+	for _, content := range history {
+		if content == nil {
+			continue
+		}
+		for _, part := range content.Parts {
+			if part != nil && part.FunctionCall != nil {
+				calls = append(calls, formatToolCall(part.FunctionCall))
+			}
+		}
+	}
+	return missingFrom(calls, nil)
+}
+
+// formatToolCall renders call as its name followed by its arguments as JSON, whose
+// keys are sorted, so that identical calls render identically.
+func formatToolCall(call *genai.FunctionCall) string {
+	if len(call.Args) == 0 {
+		return call.Name
+	}
+	args, _ := json.Marshal(call.Args)
+	return call.Name + " " + string(args)
+}
+
+// savedExperiences returns the calls to save_experience in history that succeeded,
+// pairing the calls and responses of the tool in order.
+func savedExperiences(history []*genai.Content) []string {
+	var calls, saved []string
+	responses := 0
+	for _, content := range history {
+		if content == nil {
+			continue
+		}
+		for _, part := range content.Parts {
+			switch {
+			case part == nil:
+			case part.FunctionCall != nil && part.FunctionCall.Name == "save_experience":
+				calls = append(calls, formatToolCall(part.FunctionCall))
+			case part.FunctionResponse != nil && part.FunctionResponse.Name == "save_experience":
+				if responses < len(calls) && part.FunctionResponse.Response["success"] == true {
+					saved = append(saved, calls[responses])
+				}
+				responses++
+			}
+		}
+	}
+	return saved
+}
+
+// lastAnswer returns the text of the last message of the model in history, leaving out
+// its thoughts.
+func lastAnswer(history []*genai.Content) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		content := history[i]
+		if content == nil || content.Role != genai.RoleModel {
+			continue
+		}
+		var texts []string
+		for _, part := range content.Parts {
+			if part != nil && !part.Thought && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) > 0 {
+			return strings.Join(texts, "\n")
+		}
+	}
+	return ""
+}
+
+// missingFrom returns the distinct elements of items that are not in other, in order.
+func missingFrom(items, other []string) []string {
+	seen := make(map[string]bool, len(items)+len(other))
+	for _, item := range other {
+		seen[item] = true
+	}
+	var missing []string
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
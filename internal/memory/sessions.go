@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/genai"
+)
+
+func init() {
+	// Function call arguments and responses are map[string]any, whose values may be
+	// nested maps and lists
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// SessionStore saves the history of agent conversations, so that a conversation can be
+// resumed after the process restarts. It is implemented by PostgresStore and
+// InMemoryStore.
+type SessionStore interface {
+	// SaveSession replaces the saved history of session id, creating it if needed.
+	SaveSession(ctx context.Context, id string, history []*genai.Content) error
+	// LoadSession returns the saved history of session id, or ErrNotFound.
+	LoadSession(ctx context.Context, id string) ([]*genai.Content, error)
+	// ListSessions returns the saved sessions, most recently updated first.
+	ListSessions(ctx context.Context) ([]SessionInfo, error)
+}
+
+// SessionInfo describes a saved session.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"` // When the history was last saved
+}
+
+// encodeHistory gob-encodes the turns of a conversation.
+func encodeHistory(history []*genai.Content) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(history); err != nil {
+		return nil, fmt.Errorf("failed to encode session history: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeHistory decodes turns encoded by encodeHistory.
+func decodeHistory(data []byte) ([]*genai.Content, error) {
+	var history []*genai.Content
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&history); err != nil {
+		return nil, fmt.Errorf("failed to decode session history: %w", err)
+	}
+	return history, nil
+}
+
+// SaveSession replaces the history of session id in the sessions table.
+func (s *PostgresStore) SaveSession(ctx context.Context, id string, history []*genai.Content) error {
+	data, err := encodeHistory(history)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO sessions (id, history, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (id) DO UPDATE SET history = EXCLUDED.history, updated_at = EXCLUDED.updated_at
+	`, id, data)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// LoadSession returns the history of session id from the sessions table.
+func (s *PostgresStore) LoadSession(ctx context.Context, id string) ([]*genai.Content, error) {
+	var data []byte
+	err := s.pool.QueryRow(ctx, `SELECT history FROM sessions WHERE id = $1`, id).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("session %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	return decodeHistory(data)
+}
+
+// ListSessions returns the sessions in the sessions table, most recently updated first.
+func (s *PostgresStore) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	rows, err := s.pool.Query(ctx, `SELECT id, updated_at FROM sessions ORDER BY updated_at DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionInfo
+	for rows.Next() {
+		var info SessionInfo
+		if err := rows.Scan(&info.ID, &info.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,312 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// testSessionStore checks that store saves, replaces and lists sessions. Session IDs
+// start with prefix, so that runs against a shared database do not collide.
+func testSessionStore(t *testing.T, store SessionStore, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+
+	history := []*genai.Content{
+		genai.NewContentFromText("why does the handler panic?", genai.RoleUser),
+		{Role: genai.RoleModel, Parts: []*genai.Part{
+			genai.NewPartFromFunctionCall("search_past_issues", map[string]any{
+				"query": "nil map write",
+				"tags":  []any{"panic"},
+				"opts":  map[string]any{"limit": float64(3)},
+			}),
+		}},
+		{Role: genai.RoleUser, Parts: []*genai.Part{
+			genai.NewPartFromFunctionResponse("search_past_issues", map[string]any{"success": true}),
+		}},
+		genai.NewContentFromText("The map is never initialized.", genai.RoleModel),
+	}
+	first, second := prefix+"-first", prefix+"-second"
+
+	if err := store.SaveSession(ctx, first, history[:2]); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if err := store.SaveSession(ctx, second, history[:1]); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	// Saving again replaces the history
+	if err := store.SaveSession(ctx, first, history); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	got, err := store.LoadSession(ctx, first)
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, history) {
+		t.Errorf("expected the saved history back, got %+v", got)
+	}
+	if _, err := store.LoadSession(ctx, prefix+"-missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown session, got %v", err)
+	}
+
+	sessions, err := store.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	var ids []string
+	for _, s := range sessions {
+		if s.ID == first || s.ID == second {
+			ids = append(ids, s.ID)
+		}
+	}
+	if !reflect.DeepEqual(ids, []string{first, second}) {
+		t.Errorf("expected the most recently saved session first, got %v", ids)
+	}
+}
+
+func TestInMemoryStore_Sessions(t *testing.T) {
+	testSessionStore(t, NewInMemoryStore(), "test")
+}
+
+// TestPostgresStore_Sessions runs against the database in TEST_DATABASE_URL, which must
+// have all migrations applied.
+func TestPostgresStore_Sessions(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	prefix := fmt.Sprintf("session-test-%d", time.Now().UnixNano())
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM sessions WHERE id LIKE $1", prefix+"%")
+	}()
+	testSessionStore(t, s, prefix)
+}
+
+// testSessionCostStore checks that store records session costs and lists them most
+// recent first. Session IDs start with prefix, so that runs against a shared database
+// do not collide.
+func testSessionCostStore(t *testing.T, store SessionCostStore, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	first, second := prefix+"-first", prefix+"-second"
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for i, cost := range []SessionCost{
+		{SessionID: first, InputTokens: 100, OutputTokens: 10, EmbeddingCalls: 1, EmbeddingTokens: 5, CostUSD: 0.01},
+		{SessionID: second, InputTokens: 200, CostUSD: 0.02},
+		{SessionID: first, InputTokens: 300, CostUSD: 0.03},
+	} {
+		cost.EndedAt = start.Add(time.Duration(i) * time.Minute)
+		if err := store.SaveSessionCost(ctx, cost); err != nil {
+			t.Fatalf("SaveSessionCost failed: %v", err)
+		}
+	}
+
+	costs, err := store.ListSessionCosts(ctx, first, 10)
+	if err != nil {
+		t.Fatalf("ListSessionCosts failed: %v", err)
+	}
+	if len(costs) != 2 || costs[0].InputTokens != 300 || costs[1].InputTokens != 100 {
+		t.Fatalf("expected both costs of the session, most recent first, got %+v", costs)
+	}
+	if c := costs[1]; c.OutputTokens != 10 || c.EmbeddingCalls != 1 || c.EmbeddingTokens != 5 || c.CostUSD != 0.01 || !c.EndedAt.Equal(start) {
+		t.Errorf("expected the saved cost back, got %+v", c)
+	}
+	if costs, _ := store.ListSessionCosts(ctx, "", 1); len(costs) != 1 || costs[0].SessionID != first {
+		t.Errorf("expected the limit to keep the most recent cost, got %+v", costs)
+	}
+}
+
+func TestInMemoryStore_SessionCosts(t *testing.T) {
+	store := NewInMemoryStore()
+	store.ProjectID = "payments"
+	testSessionCostStore(t, store, "test")
+
+	// Costs are listed for the store's project only
+	store.ProjectID = "billing"
+	if costs, err := store.ListSessionCosts(context.Background(), "", 10); err != nil || len(costs) != 0 {
+		t.Errorf("expected no costs of another project, got %+v, %v", costs, err)
+	}
+}
+
+// TestPostgresStore_SessionCosts runs against the database in TEST_DATABASE_URL, which
+// must have all migrations applied.
+func TestPostgresStore_SessionCosts(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("cost-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() { _, _ = s.pool.Exec(ctx, "DELETE FROM session_costs WHERE project_id = $1", project) }()
+	testSessionCostStore(t, s, project)
+}
+
+// FuzzDecodeHistory checks that decodeHistory returns an error, not a panic, for saved
+// session data that is corrupt or was not written by encodeHistory.
+func FuzzDecodeHistory(f *testing.F) {
+	for _, history := range [][]*genai.Content{
+		nil,
+		{genai.NewContentFromText("why does the handler panic?", genai.RoleUser)},
+		{{Role: genai.RoleModel, Parts: []*genai.Part{
+			genai.NewPartFromFunctionCall("read_file_content", map[string]any{"filepath": "main.go", "opts": []any{1.5, "x"}}),
+		}}},
+	} {
+		data, err := encodeHistory(history)
+		if err != nil {
+			f.Fatalf("encodeHistory failed: %v", err)
+		}
+		f.Add(data)
+		f.Add(data[:len(data)/2])
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		history, err := decodeHistory(data)
+		if err != nil {
+			return
+		}
+		// Whatever decodes must survive another round trip
+		again, err := encodeHistory(history)
+		if err != nil {
+			t.Fatalf("encodeHistory failed for decoded history: %v", err)
+		}
+		if _, err := decodeHistory(again); err != nil {
+			t.Fatalf("decodeHistory failed for re-encoded history: %v", err)
+		}
+	})
+}
+
+// TestHistoryRoundTrip checks that decodeHistory(encodeHistory(h)) == h for histories
+// of random text turns.
+func TestHistoryRoundTrip(t *testing.T) {
+	roundTrip := func(texts []string, fromModel []bool) bool {
+		history := make([]*genai.Content, len(texts))
+		for i, text := range texts {
+			role := genai.Role(genai.RoleUser)
+			if i < len(fromModel) && fromModel[i] {
+				role = genai.RoleModel
+			}
+			history[i] = genai.NewContentFromText(text, role)
+		}
+		data, err := encodeHistory(history)
+		if err != nil {
+			return false
+		}
+		got, err := decodeHistory(data)
+		if err != nil {
+			return false
+		}
+		if len(history) == 0 {
+			return len(got) == 0
+		}
+		return reflect.DeepEqual(got, history)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiffSessions(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	call := func(name string, args map[string]any) *genai.Content {
+		return &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{genai.NewPartFromFunctionCall(name, args)}}
+	}
+	response := func(name string, success bool) *genai.Content {
+		return &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{genai.NewPartFromFunctionResponse(name, map[string]any{"success": success})}}
+	}
+	question := genai.NewContentFromText("why does the handler panic?", genai.RoleUser)
+	first := []*genai.Content{
+		question,
+		call("search_past_issues", map[string]any{"query": "nil map write"}),
+		response("search_past_issues", true),
+		call("read_file", map[string]any{"path": "handler.go"}),
+		response("read_file", true),
+		call("save_experience", map[string]any{"error_pattern": "nil map write"}),
+		response("save_experience", true),
+		genai.NewContentFromText("The map is never initialized.", genai.RoleModel),
+	}
+	second := []*genai.Content{
+		question,
+		call("read_file", map[string]any{"path": "handler.go"}),
+		response("read_file", true),
+		call("read_file", map[string]any{"path": "server.go"}),
+		response("read_file", true),
+		call("read_file", map[string]any{"path": "server.go"}),
+		response("read_file", true),
+		call("save_experience", map[string]any{"error_pattern": "nil map write"}),
+		response("save_experience", true),
+		call("save_experience", map[string]any{"error_pattern": "handler registered twice"}),
+		response("save_experience", true),
+		call("save_experience", map[string]any{"error_pattern": "rejected"}),
+		response("save_experience", false),
+		{Role: genai.RoleModel, Parts: []*genai.Part{{Text: "thinking", Thought: true}, {Text: "NewServer registers the handler before creating the map."}}},
+	}
+	if err := store.SaveSession(ctx, "a", first); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if err := store.SaveSession(ctx, "b", second); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	generator := &mockGenerator{response: "  The second session found the registration order.\n"}
+	diff, err := DiffSessions(ctx, store, generator, "a", "b")
+	if err != nil {
+		t.Fatalf("DiffSessions failed: %v", err)
+	}
+	want := &SessionDiff{
+		NewToolCalls: []string{
+			`read_file {"path":"server.go"}`,
+			`save_experience {"error_pattern":"handler registered twice"}`,
+			`save_experience {"error_pattern":"rejected"}`,
+		},
+		DroppedToolCalls:    []string{`search_past_issues {"query":"nil map write"}`},
+		NewExperiencesSaved: 1,
+		SummaryDiff:         "The second session found the registration order.",
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("expected %+v, got %+v", want, diff)
+	}
+	if generator.calls != 1 {
+		t.Errorf("expected the answers to be compared once, got %d calls", generator.calls)
+	}
+
+	// Without a final answer there is nothing to compare
+	if err := store.SaveSession(ctx, "unanswered", second[:3]); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	diff, err = DiffSessions(ctx, store, generator, "a", "unanswered")
+	if err != nil {
+		t.Fatalf("DiffSessions failed: %v", err)
+	}
+	if diff.SummaryDiff != "" || generator.calls != 1 {
+		t.Errorf("expected no summary without an answer, got %q", diff.SummaryDiff)
+	}
+	if _, err := DiffSessions(ctx, store, generator, "a", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown session, got %v", err)
+	}
+}
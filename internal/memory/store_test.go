@@ -1,7 +1,14 @@
 package memory
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 	"unicode/utf8"
 )
 
@@ -227,3 +234,992 @@ func TestSaveExperience_OldVsNewBehavior(t *testing.T) {
 	t.Logf("Old (broken): %q (%d bytes, %d runes, valid: %v)", oldSignature, len(oldSignature), utf8.RuneCountInString(oldSignature), utf8.ValidString(oldSignature))
 	t.Logf("New (fixed): %q (%d bytes, %d runes, valid: %v)", newSignature, len(newSignature), actualRunes, utf8.ValidString(newSignature))
 }
+
+// TestPostgresStore_AutoOptimize verifies that indexes are optimized once every
+// optimizeEvery saved experiences and that the time is reported by Stats.
+func TestPostgresStore_AutoOptimize(t *testing.T) {
+	calls := 0
+	s := &PostgresStore{}
+	s.optimize = func(ctx context.Context) error {
+		calls++
+		return nil
+	}
+
+	for i := 0; i < optimizeEvery+1; i++ {
+		s.afterSave(context.Background())
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected OptimizeIndexes to be called once, got %d", calls)
+	}
+	if s.lastOptimizedAt.Load() == nil {
+		t.Error("Expected LastOptimizedAt to be recorded")
+	}
+}
+
+// TestPostgresStore_Stats runs against the database in TEST_DATABASE_URL, which must
+// have all migrations applied.
+func TestPostgresStore_Stats(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("stats-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+	}()
+
+	before, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	vector := make([]float32, embeddingDimensions)
+	vector[0] = 1
+	for i, age := range []time.Duration{time.Hour, 3 * 24 * time.Hour} {
+		exp := Experience{ProjectID: project, ErrorPattern: fmt.Sprintf("stats failure %d", i), OccurredAt: time.Now().Add(-age)}
+		if err := s.ImportExperience(ctx, exp, vector); err != nil {
+			t.Fatalf("ImportExperience failed: %v", err)
+		}
+	}
+
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalExperiences != before.TotalExperiences+2 {
+		t.Errorf("expected 2 more experiences, got %d then %d", before.TotalExperiences, stats.TotalExperiences)
+	}
+	if stats.ByAgeHistogram[0] != before.ByAgeHistogram[0]+1 || stats.ByAgeHistogram[3] != before.ByAgeHistogram[3]+1 {
+		t.Errorf("expected one more experience on days 0 and 3, got %v then %v", before.ByAgeHistogram, stats.ByAgeHistogram)
+	}
+	if stats.AverageEmbeddingDimension != embeddingDimensions || stats.StorageSizeBytes <= 0 {
+		t.Errorf("expected dimension %d and a storage size, got %d and %d", embeddingDimensions, stats.AverageEmbeddingDimension, stats.StorageSizeBytes)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if ContentHash("nil map write") != ContentHash("nil map write") {
+		t.Error("Expected identical patterns to have the same hash")
+	}
+	if ContentHash("nil map write") == ContentHash("nil map read") {
+		t.Error("Expected different patterns to have different hashes")
+	}
+	if got := len(ContentHash("")); got != 64 {
+		t.Errorf("Expected a 64 character hex digest, got %d characters", got)
+	}
+
+	var err error = &ErrExperienceAlreadyExists{ExistingID: 7}
+	if !errors.Is(err, ErrDuplicateExperience) {
+		t.Error("Expected ErrExperienceAlreadyExists to match ErrDuplicateExperience")
+	}
+	err = &ErrExperienceMerged{ExistingID: 7, Similarity: 0.98}
+	if !errors.Is(err, ErrDuplicateExperience) {
+		t.Error("Expected ErrExperienceMerged to match ErrDuplicateExperience")
+	}
+}
+
+// TestPostgresStore_SaveExperienceDeduplication runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_SaveExperienceDeduplication(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	pattern := fmt.Sprintf("dedup test pattern %d", time.Now().UnixNano())
+	hash := ContentHash(pattern)
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE hash = $1", hash)
+	}()
+
+	vector := make([]float32, embeddingDimensions)
+	vector[0], vector[1] = 1, 0.5
+	if err := s.SaveExperience(ctx, "", pattern, "cause", "solution", nil, vector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	firstID, err := s.FindExperienceByHash(ctx, hash)
+	if err != nil {
+		t.Fatalf("FindExperienceByHash failed: %v", err)
+	}
+
+	// A different pattern with the same embedding is merged into the first experience
+	err = s.SaveExperience(ctx, "", pattern+" again", "cause", "solution", nil, vector)
+	var merged *ErrExperienceMerged
+	if !errors.As(err, &merged) || merged.ExistingID != firstID || merged.Similarity < 0.99 {
+		t.Fatalf("Expected ErrExperienceMerged for id %d, got %v", firstID, err)
+	}
+	if _, err := s.FindExperienceByHash(ctx, ContentHash(pattern+" again")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the merged experience not to be inserted, got %v", err)
+	}
+
+	var rows, frequency int
+	err = s.pool.QueryRow(ctx, "SELECT COUNT(*), MAX(frequency) FROM issue_history WHERE hash = $1", hash).Scan(&rows, &frequency)
+	if err != nil {
+		t.Fatalf("failed to count experiences: %v", err)
+	}
+	if rows != 1 || frequency != 2 {
+		t.Errorf("Expected 1 row with frequency 2, got %d rows with frequency %d", rows, frequency)
+	}
+}
+
+// TestPostgresStore_UpsertExperience runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_UpsertExperience(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("upsert-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+	}()
+
+	// Both patterns share the 50-rune task signature but are different experiences
+	prefix := strings.Repeat("p", 50)
+	first, second := prefix+" first", prefix+" second"
+	for i, pattern := range []string{first, second} {
+		vector := make([]float32, embeddingDimensions)
+		vector[i] = 1
+		if err := s.SaveExperience(ctx, "", pattern, "cause", "solution", nil, vector); err != nil {
+			t.Fatalf("SaveExperience(%q) failed: %v", pattern, err)
+		}
+	}
+	firstID, err := s.FindExperienceByHash(ctx, ContentHash(first))
+	if err != nil {
+		t.Fatalf("FindExperienceByHash failed: %v", err)
+	}
+	secondID, err := s.FindExperienceByHash(ctx, ContentHash(second))
+	if err != nil {
+		t.Fatalf("FindExperienceByHash failed: %v", err)
+	}
+
+	vector := make([]float32, embeddingDimensions)
+	vector[2] = 1
+	var exists *ErrExperienceAlreadyExists
+	err = s.SaveExperience(ctx, "", first, "other cause", "other solution", nil, vector)
+	if !errors.As(err, &exists) || exists.ExistingID != firstID {
+		t.Fatalf("Expected ErrExperienceAlreadyExists for id %d, got %v", firstID, err)
+	}
+
+	if err := s.UpsertExperience(ctx, first, "new cause", "new solution", vector); err != nil {
+		t.Fatalf("UpsertExperience failed: %v", err)
+	}
+	updated, err := s.GetExperience(ctx, firstID)
+	if err != nil {
+		t.Fatalf("GetExperience failed: %v", err)
+	}
+	if updated.RootCause != "new cause" || updated.Solution != "new solution" {
+		t.Errorf("Expected the upsert to overwrite the experience, got %+v", updated)
+	}
+	history, err := s.GetExperienceHistory(ctx, firstID)
+	if err != nil {
+		t.Fatalf("GetExperienceHistory failed: %v", err)
+	}
+	if len(history) != 1 || history[0].Solution != "solution" {
+		t.Errorf("Expected the overwritten content as version 1, got %+v", history)
+	}
+	untouched, err := s.GetExperience(ctx, secondID)
+	if err != nil {
+		t.Fatalf("GetExperience failed: %v", err)
+	}
+	if untouched.Solution != "solution" {
+		t.Errorf("Expected the experience sharing the task signature to be untouched, got %+v", untouched)
+	}
+}
+
+// TestPostgresStore_ExperienceHistory runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_ExperienceHistory(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("history-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+	}()
+
+	vector := make([]float32, embeddingDimensions)
+	vector[3] = 1
+	pattern := "history test pattern " + project
+	if err := s.SaveExperience(ctx, "", pattern, "original cause", "original solution", nil, vector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	id, err := s.FindExperienceByHash(ctx, ContentHash(pattern))
+	if err != nil {
+		t.Fatalf("FindExperienceByHash failed: %v", err)
+	}
+
+	for _, update := range []string{"second", "third"} {
+		if err := s.UpdateExperience(ctx, id, update+" cause", update+" solution"); err != nil {
+			t.Fatalf("UpdateExperience(%s) failed: %v", update, err)
+		}
+	}
+	history, err := s.GetExperienceHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("GetExperienceHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 versions after 2 updates, got %+v", history)
+	}
+	if history[0].Version != 1 || history[0].RootCause != "original cause" || history[0].Solution != "original solution" {
+		t.Errorf("Expected version 1 to hold the original content, got %+v", history[0])
+	}
+	if history[1].Version != 2 || history[1].RootCause != "second cause" || history[1].Solution != "second solution" {
+		t.Errorf("Expected version 2 to hold the first update, got %+v", history[1])
+	}
+
+	if err := s.RevertExperienceTo(ctx, id, 1); err != nil {
+		t.Fatalf("RevertExperienceTo failed: %v", err)
+	}
+	reverted, err := s.GetExperience(ctx, id)
+	if err != nil {
+		t.Fatalf("GetExperience failed: %v", err)
+	}
+	if reverted.RootCause != "original cause" || reverted.Solution != "original solution" {
+		t.Errorf("Expected the original content after the revert, got %+v", reverted)
+	}
+	history, err = s.GetExperienceHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("GetExperienceHistory failed: %v", err)
+	}
+	if len(history) != 3 || history[2].Version != 3 || history[2].Solution != "third solution" {
+		t.Errorf("Expected the reverted content to be kept as version 3, got %+v", history)
+	}
+
+	if err := s.RevertExperienceTo(ctx, id, 42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown version, got %v", err)
+	}
+}
+
+func TestPostgresStore_SearchRejectsWrongDimensions(t *testing.T) {
+	// The check happens before the database is queried
+	s := &PostgresStore{}
+	ctx := context.Background()
+	vector := make([]float32, 1024)
+
+	if _, err := s.SearchSimilarIssues(ctx, "query", vector, 3, 0.5, "", true, nil); !errors.Is(err, ErrEmbeddingDimensions) {
+		t.Errorf("SearchSimilarIssues: expected ErrEmbeddingDimensions, got %v", err)
+	}
+	if _, err := s.SearchByUser(ctx, "user", "query", vector, 3); !errors.Is(err, ErrEmbeddingDimensions) {
+		t.Errorf("SearchByUser: expected ErrEmbeddingDimensions, got %v", err)
+	}
+	if _, err := s.HybridSearch(ctx, "query", vector, 3, 0.5, "", true); !errors.Is(err, ErrEmbeddingDimensions) {
+		t.Errorf("HybridSearch: expected ErrEmbeddingDimensions, got %v", err)
+	}
+}
+
+// prefixModelSelector picks "model-b" for texts starting with "b" and "model-a" for all others.
+type prefixModelSelector struct{}
+
+func (prefixModelSelector) SelectModel(text string) string {
+	if strings.HasPrefix(text, "b") {
+		return "model-b"
+	}
+	return "model-a"
+}
+
+// TestPostgresStore_EmbeddingModelFilter runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_EmbeddingModelFilter(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("model-filter-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+	}()
+	s.SetEmbeddingModelSelector(prefixModelSelector{})
+
+	// Identical vectors from different models are neither near duplicates nor search results of each other
+	vector := make([]float32, embeddingDimensions)
+	vector[0] = 1
+	for _, pattern := range []string{"a timeout in the handler", "b timeout in the handler"} {
+		if err := s.SaveExperience(ctx, "user-a", pattern, "cause", "solution", nil, vector); err != nil {
+			t.Fatalf("SaveExperience(%q) failed: %v", pattern, err)
+		}
+	}
+
+	results, err := s.SearchSimilarIssues(ctx, "b query", vector, 10, 0.5, project, false, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ErrorPattern != "b timeout in the handler" {
+		t.Errorf("Expected only the experience embedded with model-b, got %+v", results)
+	}
+	results, err = s.SearchByUser(ctx, "user-a", "a query", vector, 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ErrorPattern != "a timeout in the handler" {
+		t.Errorf("Expected only the experience embedded with model-a, got %+v", results)
+	}
+	results, err = s.HybridSearch(ctx, "b timeout", vector, 10, 0.5, project, false)
+	if err != nil {
+		t.Fatalf("HybridSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ErrorPattern != "b timeout in the handler" {
+		t.Errorf("Expected only the experience embedded with model-b, got %+v", results)
+	}
+}
+
+// TestPostgresStore_ExpiredExperiences runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_ExpiredExperiences(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	project := fmt.Sprintf("expiry-test-%d", suffix)
+	otherProject := fmt.Sprintf("expiry-test-other-%d", suffix)
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{MaxAgeDays: 30, ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	other, err := NewPostgresStore(ctx, databaseURL, StoreOptions{MaxAgeDays: 30, ProjectID: otherProject})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer other.Close()
+
+	fresh := fmt.Sprintf("fresh expiry test pattern %d", suffix)
+	stale := fmt.Sprintf("stale expiry test pattern %d", suffix)
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = ANY($1)", []string{project, otherProject})
+	}()
+
+	// Orthogonal vectors so that the two experiences are not deduplicated
+	freshVector := make([]float32, embeddingDimensions)
+	freshVector[0] = 1
+	staleVector := make([]float32, embeddingDimensions)
+	staleVector[1] = 1
+	if err := s.SaveExperience(ctx, "", fresh, "cause", "solution", nil, freshVector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	if err := s.SaveExperience(ctx, "", stale, "cause", "solution", nil, staleVector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	// Expired rows that are not session experiences of the store's project must survive the purge
+	if err := other.SaveExperience(ctx, "", stale, "cause", "solution", nil, staleVector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	if err := s.SaveSourcedExperience(ctx, SourceCodebaseIndex, "stale.go", stale, "cause", "solution", staleVector); err != nil {
+		t.Fatalf("SaveSourcedExperience failed: %v", err)
+	}
+	if _, err := s.pool.Exec(ctx, "UPDATE issue_history SET occurred_at = NOW() - INTERVAL '60 days' WHERE hash = $1 AND project_id = ANY($2)", ContentHash(stale), []string{project, otherProject}); err != nil {
+		t.Fatalf("failed to back-date experience: %v", err)
+	}
+
+	for _, vector := range [][]float32{freshVector, staleVector} {
+		results, err := s.SearchSimilarIssues(ctx, "", vector, 10, 0.99, "", false, nil)
+		if err != nil {
+			t.Fatalf("SearchSimilarIssues failed: %v", err)
+		}
+		for _, exp := range results {
+			if exp.ErrorPattern == stale {
+				t.Errorf("Expected expired experience to be excluded from search")
+			}
+		}
+	}
+
+	deleted, err := s.PurgeExpiredExperiences(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpiredExperiences failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected only the expired session experience of %s to be purged, deleted %d", project, deleted)
+	}
+	if _, err := s.FindExperienceByHash(ctx, ContentHash(fresh)); err != nil {
+		t.Errorf("Expected fresh experience to survive the purge: %v", err)
+	}
+	if _, err := other.FindExperienceByHash(ctx, ContentHash(stale)); err != nil {
+		t.Errorf("Expected the expired experience of another project to survive the purge: %v", err)
+	}
+	if exists, err := s.HasTaskSignature(ctx, "stale.go"); err != nil || !exists {
+		t.Errorf("Expected the expired codebase index entry to survive the purge: %v", err)
+	}
+}
+
+// TestPostgresStore_DeleteExperience runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_DeleteExperience(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	pattern := fmt.Sprintf("soft delete test pattern %d", time.Now().UnixNano())
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE hash = $1", ContentHash(pattern))
+	}()
+
+	vector := make([]float32, embeddingDimensions)
+	vector[2] = 1
+	if err := s.SaveExperience(ctx, "", pattern, "cause", "solution", nil, vector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	id, err := s.FindExperienceByHash(ctx, ContentHash(pattern))
+	if err != nil {
+		t.Fatalf("FindExperienceByHash failed: %v", err)
+	}
+
+	if vectors, err := s.GetEmbeddings(ctx, []int{id}); err != nil || len(vectors[id]) != embeddingDimensions || vectors[id][2] != 1 {
+		t.Errorf("Expected the embedding of experience %d, got %v (%v)", id, len(vectors[id]), err)
+	}
+
+	if err := s.DeleteExperience(ctx, id); err != nil {
+		t.Fatalf("DeleteExperience failed: %v", err)
+	}
+	if vectors, err := s.GetEmbeddings(ctx, []int{id}); err != nil || len(vectors) != 0 {
+		t.Errorf("Expected no embedding of a deleted experience, got %d (%v)", len(vectors), err)
+	}
+	if err := s.DeleteExperience(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleting twice to return ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetExperience(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleted experience to be hidden, got %v", err)
+	}
+	results, err := s.SearchSimilarIssues(ctx, "", vector, 10, 0.99, "", true, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	for _, exp := range results {
+		if exp.ID == id {
+			t.Errorf("Expected deleted experience to be excluded from search")
+		}
+	}
+
+	// Merging into a deleted experience must fail and keep the other one
+	other := pattern + " merged"
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE hash = $1", ContentHash(other))
+	}()
+	if err := s.SaveExperience(ctx, "", other, "cause", "solution", nil, vector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	otherID, err := s.FindExperienceByHash(ctx, ContentHash(other))
+	if err != nil {
+		t.Fatalf("FindExperienceByHash failed: %v", err)
+	}
+	if err := s.MergeExperiences(ctx, id, otherID, Experience{ErrorPattern: pattern}, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected merging into a deleted experience to return ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetExperience(ctx, otherID); err != nil {
+		t.Errorf("Expected the merged experience to be kept after a failed merge, got %v", err)
+	}
+
+	// The row is kept until the retention period has passed
+	var rows int
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM issue_history WHERE id = $1", id).Scan(&rows); err != nil || rows != 1 {
+		t.Fatalf("Expected the soft-deleted row to be kept, got %d rows (%v)", rows, err)
+	}
+	if _, err := s.PurgeDeleted(ctx, 0); err != nil {
+		t.Fatalf("PurgeDeleted failed: %v", err)
+	}
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM issue_history WHERE id = $1", id).Scan(&rows); err != nil || rows != 0 {
+		t.Errorf("Expected the row to be purged, got %d rows (%v)", rows, err)
+	}
+}
+
+// TestPostgresStore_Reconnect runs against the database in TEST_DATABASE_URL.
+func TestPostgresStore_Reconnect(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{MaxConns: 2, MinConns: 1, MaxConnIdleTime: time.Minute})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	if got := s.pool.Config().MaxConns; got != 2 {
+		t.Errorf("Expected the pool to be limited to 2 connections, got %d", got)
+	}
+	if err := s.Ping(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if err := s.Reconnect(ctx); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	if _, err := s.ListProjectRules(ctx); err != nil {
+		t.Errorf("Expected the store to work after reconnecting, got %v", err)
+	}
+}
+
+// TestPostgresStore_ProjectRuleCRUD runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_ProjectRuleCRUD(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("rule-crud-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() { _, _ = s.pool.Exec(ctx, "DELETE FROM project_rules WHERE project_id = $1", project) }()
+
+	id, err := s.AddProjectRule(ctx, "style", "use gofmt", 1)
+	if err != nil {
+		t.Fatalf("AddProjectRule failed: %v", err)
+	}
+	if err := s.UpdateProjectRule(ctx, id, "use gofumpt", 2, false); err != nil {
+		t.Fatalf("UpdateProjectRule failed: %v", err)
+	}
+	rules, err := s.ListProjectRules(ctx)
+	if err != nil {
+		t.Fatalf("ListProjectRules failed: %v", err)
+	}
+	found := false
+	for _, rule := range rules {
+		if rule.ID == id {
+			found = true
+			if rule.RuleContent != "use gofumpt" || rule.Priority != 2 || rule.IsActive {
+				t.Errorf("Expected the updated rule, got %+v", rule)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("Expected rule %d to be listed", id)
+	}
+
+	if err := s.DeleteProjectRule(ctx, id); err != nil {
+		t.Fatalf("DeleteProjectRule failed: %v", err)
+	}
+	if err := s.DeleteProjectRule(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleting twice to return ErrNotFound, got %v", err)
+	}
+	if err := s.UpdateProjectRule(ctx, id, "x", 1, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected updating a deleted rule to return ErrNotFound, got %v", err)
+	}
+}
+
+// TestPostgresStore_ProjectContext runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_ProjectContext(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("context-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() { _, _ = s.pool.Exec(ctx, "DELETE FROM project_context WHERE project_id = $1", project) }()
+
+	testProjectContext(t, s, "")
+}
+
+// TestPostgresStore_UpsertProjectRule runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_UpsertProjectRule(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("rule-upsert-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() { _, _ = s.pool.Exec(ctx, "DELETE FROM project_rules WHERE project_id = $1", project) }()
+
+	id, err := s.UpsertProjectRule(ctx, "style", "use gofmt", 1)
+	if err != nil {
+		t.Fatalf("UpsertProjectRule failed: %v", err)
+	}
+	if err := s.UpdateProjectRule(ctx, id, "use gofmt", 1, false); err != nil {
+		t.Fatalf("UpdateProjectRule failed: %v", err)
+	}
+	again, err := s.UpsertProjectRule(ctx, "style", "use gofmt", 5)
+	if err != nil {
+		t.Fatalf("UpsertProjectRule failed: %v", err)
+	}
+	if again != id {
+		t.Errorf("Expected upserting the same rule to return ID %d, got %d", id, again)
+	}
+
+	var count, priority int
+	var isActive bool
+	err = s.pool.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(priority), BOOL_AND(is_active) FROM project_rules
+		WHERE project_id = $1 AND category = 'style' AND rule_content = 'use gofmt'
+	`, project).Scan(&count, &priority, &isActive)
+	if err != nil {
+		t.Fatalf("Failed to count rules: %v", err)
+	}
+	if count != 1 || priority != 5 || !isActive {
+		t.Errorf("Expected one active row with priority 5, got %d rows, priority %d, active %v", count, priority, isActive)
+	}
+
+	if _, err := s.AddProjectRule(ctx, "style", "use gofmt", 1); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("Expected ErrDuplicateRule for adding the rule again, got %v", err)
+	}
+}
+
+// TestPostgresStore_ProjectIsolation runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_ProjectIsolation(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	projectA := fmt.Sprintf("project-a-%d", suffix)
+	projectB := fmt.Sprintf("project-b-%d", suffix)
+	pattern := fmt.Sprintf("project isolation test pattern %d", suffix)
+	vector := make([]float32, embeddingDimensions)
+	vector[0] = 1
+
+	for _, project := range []string{projectA, projectB} {
+		s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+		if err != nil {
+			t.Fatalf("NewPostgresStore failed: %v", err)
+		}
+		defer s.Close()
+		defer func() {
+			_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+		}()
+		// The same pattern is saved for both projects; duplicates are only detected within a project
+		if err := s.SaveExperience(ctx, "", pattern, "cause", "solution for "+project, nil, vector); err != nil {
+			t.Fatalf("SaveExperience for %s failed: %v", project, err)
+		}
+	}
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: projectA})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	results, err := s.SearchSimilarIssues(ctx, "", vector, 10, 0.99, projectA, false, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ProjectID != projectA {
+		t.Errorf("Expected only the experience of %s, got %+v", projectA, results)
+	}
+}
+
+// TestPostgresStore_ProjectScopedIDs runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_ProjectScopedIDs(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	projectA := fmt.Sprintf("project-a-%d", suffix)
+	projectB := fmt.Sprintf("project-b-%d", suffix)
+	stores := make(map[string]*PostgresStore)
+	ids := make(map[string]int)
+	for i, project := range []string{projectA, projectB} {
+		s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+		if err != nil {
+			t.Fatalf("NewPostgresStore failed: %v", err)
+		}
+		defer s.Close()
+		defer func() {
+			_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+			_, _ = s.pool.Exec(ctx, "DELETE FROM project_rules WHERE project_id = $1", project)
+		}()
+		stores[project] = s
+
+		pattern := fmt.Sprintf("scoped id test pattern %s", project)
+		vector := make([]float32, embeddingDimensions)
+		vector[i] = 1
+		if err := s.SaveExperience(ctx, "user-"+projectA, pattern, "cause", "solution", nil, vector); err != nil {
+			t.Fatalf("SaveExperience for %s failed: %v", project, err)
+		}
+		if ids[project], err = s.FindExperienceByHash(ctx, ContentHash(pattern)); err != nil {
+			t.Fatalf("FindExperienceByHash failed: %v", err)
+		}
+		if _, err := s.AddProjectRule(ctx, "style", "rule for "+project, 1); err != nil {
+			t.Fatalf("AddProjectRule failed: %v", err)
+		}
+	}
+	if err := stores[projectB].UpdateExperience(ctx, ids[projectB], "new cause", "new solution"); err != nil {
+		t.Fatalf("UpdateExperience failed: %v", err)
+	}
+
+	s, other := stores[projectA], ids[projectB]
+	for name, op := range map[string]func() error{
+		"GetExperience":      func() error { _, err := s.GetExperience(ctx, other); return err },
+		"UpdateExperience":   func() error { return s.UpdateExperience(ctx, other, "cause", "solution") },
+		"DeleteExperience":   func() error { return s.DeleteExperience(ctx, other) },
+		"RevertExperienceTo": func() error { return s.RevertExperienceTo(ctx, other, 1) },
+		"MergeExperiences": func() error {
+			return s.MergeExperiences(ctx, ids[projectA], other, Experience{ErrorPattern: "merged"}, nil)
+		},
+		"MergeIntoOtherProject": func() error {
+			return s.MergeExperiences(ctx, other, ids[projectA], Experience{ErrorPattern: "merged"}, nil)
+		},
+	} {
+		if err := op(); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: Expected ErrNotFound for an experience of another project, got %v", name, err)
+		}
+	}
+	if history, err := s.GetExperienceHistory(ctx, other); err != nil || len(history) != 0 {
+		t.Errorf("Expected no history of an experience of another project, got %+v, %v", history, err)
+	}
+	if exp, err := stores[projectB].GetExperience(ctx, other); err != nil || exp.Solution != "new solution" {
+		t.Errorf("Expected the experience of %s to be untouched, got %+v, %v", projectB, exp, err)
+	}
+
+	experiences, err := s.ListExperiences(ctx)
+	if err != nil {
+		t.Fatalf("ListExperiences failed: %v", err)
+	}
+	for _, exp := range experiences {
+		if exp.ProjectID == projectB {
+			t.Errorf("Expected ListExperiences to skip %s, got %+v", projectB, exp)
+		}
+	}
+	vector := make([]float32, embeddingDimensions)
+	vector[1] = 1
+	results, err := s.SearchByUser(ctx, "user-"+projectA, "", vector, 10)
+	if err != nil {
+		t.Fatalf("SearchByUser failed: %v", err)
+	}
+	for _, exp := range results {
+		if exp.ProjectID == projectB {
+			t.Errorf("Expected SearchByUser to skip %s, got %+v", projectB, exp)
+		}
+	}
+	rules, err := s.ListProjectRules(ctx)
+	if err != nil {
+		t.Fatalf("ListProjectRules failed: %v", err)
+	}
+	for _, rule := range rules {
+		if rule.ProjectID == projectB {
+			t.Errorf("Expected ListProjectRules to skip %s, got %+v", projectB, rule)
+		}
+	}
+}
+
+// TestPostgresStore_HybridSearch runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_HybridSearch(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+
+	code := fmt.Sprintf("EHYBRID%d", time.Now().UnixNano())
+	pattern := "read tcp: " + code
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE hash = $1", ContentHash(pattern))
+	}()
+
+	vector := make([]float32, embeddingDimensions)
+	vector[0] = 1
+	if err := s.SaveExperience(ctx, "", pattern, "cause", "solution", nil, vector); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+
+	results, err := s.HybridSearch(ctx, code, nil, 5, 0, "", false)
+	if err != nil {
+		t.Fatalf("keyword HybridSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ErrorPattern != pattern {
+		t.Errorf("Expected only the experience containing %s, got %+v", code, results)
+	}
+	if score := results[0].SimilarityScore; len(results) == 1 && (score <= 0 || score >= 1) {
+		t.Errorf("Expected the keyword score to be scaled to (0, 1), got %v", score)
+	}
+
+	results, err = s.HybridSearch(ctx, code, vector, 1, 0.5, "", false)
+	if err != nil {
+		t.Fatalf("hybrid HybridSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ErrorPattern != pattern {
+		t.Errorf("Expected the matching experience to rank first, got %+v", results)
+	}
+	if score := results[0].SimilarityScore; len(results) == 1 && (score <= 0.5 || score >= 1) {
+		t.Errorf("Expected an identical vector and a keyword match to score in (0.5, 1), got %v", score)
+	}
+}
+
+// TestPostgresStore_TagFiltering runs against the database in
+// TEST_DATABASE_URL, which must have all migrations applied.
+func TestPostgresStore_TagFiltering(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("tag-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() { _, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project) }()
+
+	// Orthogonal vectors so that the experiences are not deduplicated
+	for i, exp := range []struct {
+		pattern string
+		tags    []string
+	}{
+		{"panic in handler", []string{"panic", "nil-pointer"}},
+		{"panic while closing channel", []string{"Panic"}},
+		{"request timed out", []string{"timeout"}},
+	} {
+		vector := make([]float32, embeddingDimensions)
+		vector[i] = 1
+		if err := s.SaveExperience(ctx, "", exp.pattern, "cause", "solution", exp.tags, vector); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+	}
+
+	query := make([]float32, embeddingDimensions)
+	query[0], query[1], query[2] = 1, 1, 1
+	for _, tt := range []struct {
+		tags []string
+		want int
+	}{
+		{nil, 3},
+		{[]string{"panic"}, 2},
+		{[]string{"panic", "nil-pointer"}, 1},
+		{[]string{"panic", "timeout"}, 0},
+	} {
+		results, err := s.SearchSimilarIssues(ctx, "", query, 10, 0.1, project, false, tt.tags)
+		if err != nil {
+			t.Fatalf("SearchSimilarIssues failed: %v", err)
+		}
+		if len(results) != tt.want {
+			t.Errorf("tags %v: expected %d results, got %d", tt.tags, tt.want, len(results))
+		}
+	}
+
+	results, err := s.ListExperiencesByTag(ctx, "PANIC", 10)
+	if err != nil {
+		t.Fatalf("ListExperiencesByTag failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected both panic experiences, got %+v", results)
+	}
+}
+
+// TestPostgresStore_SearchSimilarIssuesPage runs against the database in TEST_DATABASE_URL,
+// which must have all migrations applied.
+func TestPostgresStore_SearchSimilarIssuesPage(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	project := fmt.Sprintf("page-test-%d", time.Now().UnixNano())
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{ProjectID: project})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	defer func() {
+		_, _ = s.pool.Exec(ctx, "DELETE FROM issue_history WHERE project_id = $1", project)
+	}()
+
+	// The i-th experience saved is the i-th most similar to query of 10, and no two are
+	// near duplicates of each other
+	query := make([]float32, embeddingDimensions)
+	query[0] = 1
+	var ids []int
+	for i := 1; i <= 10; i++ {
+		vector := make([]float32, embeddingDimensions)
+		vector[0], vector[i] = 1, 0.3+float32(i)/10
+		if err := s.SaveExperience(ctx, "", fmt.Sprintf("page test error %d", i), "cause", "solution", nil, vector); err != nil {
+			t.Fatalf("SaveExperience failed: %v", err)
+		}
+		results, err := s.SearchSimilarIssues(ctx, "", vector, 1, 0.99, project, false, nil)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("failed to find saved experience %d: %v", i, err)
+		}
+		ids = append(ids, results[0].ID)
+	}
+
+	results, total, err := s.SearchSimilarIssuesPage(ctx, "", query, 4, 4, 0.5, project, false, nil)
+	if err != nil {
+		t.Fatalf("SearchSimilarIssuesPage failed: %v", err)
+	}
+	var got []int
+	for _, exp := range results {
+		got = append(got, exp.ID)
+	}
+	if !slices.Equal(got, ids[4:8]) || total != 10 {
+		t.Errorf("expected %v of 10, got %v of %d", ids[4:8], got, total)
+	}
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+)
+
+// tagKeywords maps the tags added by DetectTags to the phrases that indicate them.
+var tagKeywords = map[string][]string{
+	"panic":         {"panic", "goroutine stack"},
+	"nil-pointer":   {"nil pointer", "nil map", "null pointer", "segmentation violation"},
+	"timeout":       {"timeout", "timed out", "deadline exceeded"},
+	"deadlock":      {"deadlock", "all goroutines are asleep"},
+	"race":          {"data race", "race condition"},
+	"out-of-memory": {"out of memory", "oom"},
+	"index-range":   {"index out of range", "slice bounds out of range"},
+	"connection":    {"connection refused", "connection reset", "broken pipe"},
+	"permission":    {"permission denied", "access denied"},
+}
+
+// NormalizeTags returns tags lower-cased and trimmed, without empty entries or
+// duplicates, in sorted order. Tags are stored and compared in this form.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// DetectTags returns the tags whose keywords occur in text, ignoring case, such as
+// "panic" for a stack trace or "timeout" for "context deadline exceeded".
+func DetectTags(text string) []string {
+	text = strings.ToLower(text)
+	var tags []string
+	for tag, keywords := range tagKeywords {
+		if slices.ContainsFunc(keywords, func(keyword string) bool { return containsWord(text, keyword) }) {
+			tags = append(tags, tag)
+		}
+	}
+	return NormalizeTags(tags)
+}
+
+// hasAllTags reports whether every tag of required is in tags. Both must be normalized.
+func hasAllTags(tags, required []string) bool {
+	for _, tag := range required {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsWord reports whether keyword occurs in text without being part of a longer
+// word, so that "oom" does not match "room".
+func containsWord(text, keyword string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], keyword)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(keyword)
+		if (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// isWordByte reports whether b is an ASCII letter or digit.
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
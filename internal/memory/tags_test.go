@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Timeout", "panic", "", "PANIC", "deadlock "})
+	want := []string{"deadlock", "panic", "timeout"}
+	if !slices.Equal(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+	if got := NormalizeTags(nil); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty, non-nil slice for no tags, got %#v", got)
+	}
+}
+
+func TestDetectTags(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"panic: assignment to entry in nil map", []string{"nil-pointer", "panic"}},
+		{"Get \"http://api\": context deadline exceeded", []string{"timeout"}},
+		{"fatal error: all goroutines are asleep - deadlock!", []string{"deadlock"}},
+		{"the meeting room booking is slow", []string{}},
+		{"Request Timed Out", []string{"timeout"}},
+	}
+	for _, tt := range tests {
+		if got := DetectTags(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("DetectTags(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
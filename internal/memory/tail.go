@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// LogTail reads the lines appended to a file since the previous read. It is used both by
+// StartBackgroundIngestion and by the watch_file tool, which keeps one per session and
+// watched file. A LogTail with only Path set reads the file from its start.
+type LogTail struct {
+	Path   string      // File to read
+	File   os.FileInfo // The file read last, to tell when it is replaced
+	Offset int64       // Offset of the first byte not read yet
+}
+
+// LogLine is a complete line read by LogTail.
+type LogLine struct {
+	Text string // The line without its line ending
+	End  int64  // Offset in the file after the line's newline
+}
+
+// ReadLines returns the complete lines appended to the file since the previous call,
+// without line endings, reading at most maxBytes. A last line without a newline is left
+// for a later call, unless it fills a whole read. A file truncated below the offset read
+// up to, or replaced by another file, e.g. by log rotation, is read again from its start.
+// A file that does not exist, e.g. between rotation and the creation of the new file,
+// has no lines.
+func (t *LogTail) ReadLines(maxBytes int64) ([]LogLine, error) {
+	f, err := os.Open(t.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < t.Offset || (t.File != nil && !os.SameFile(t.File, info)) {
+		t.Offset = 0 // Truncated or replaced by a new file
+	}
+	t.File = info
+	if info.Size() == t.Offset {
+		return nil, nil
+	}
+
+	buf := make([]byte, min(info.Size()-t.Offset, maxBytes))
+	n, err := f.ReadAt(buf, t.Offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	buf = buf[:n]
+	if end := bytes.LastIndexByte(buf, '\n'); end >= 0 {
+		buf = buf[:end+1]
+	} else if int64(n) < maxBytes {
+		return nil, nil
+	}
+
+	var lines []LogLine
+	for start := 0; start < len(buf); {
+		end := bytes.IndexByte(buf[start:], '\n')
+		next := start + end + 1
+		if end < 0 {
+			end, next = len(buf)-start, len(buf)
+		}
+		text := bytes.TrimSuffix(buf[start:start+end], []byte("\r"))
+		lines = append(lines, LogLine{Text: string(text), End: t.Offset + int64(next)})
+		start = next
+	}
+	t.Offset += int64(len(buf))
+	return lines, nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLogTail_ReadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	tail := &LogTail{Path: path}
+
+	// A missing file has no lines yet
+	if lines, err := tail.ReadLines(1 << 10); err != nil || lines != nil {
+		t.Fatalf("ReadLines = %v, %v, want no lines for a missing file", lines, err)
+	}
+
+	// Line endings are removed and the incomplete last line is left for later
+	if err := os.WriteFile(path, []byte("first\r\n\nsecond\npart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := tail.ReadLines(1 << 10)
+	want := []LogLine{{Text: "first", End: 7}, {Text: "", End: 8}, {Text: "second", End: 15}}
+	if err != nil || !reflect.DeepEqual(lines, want) {
+		t.Fatalf("ReadLines = %v, %v, want %v", lines, err, want)
+	}
+	appendFile(t, path, "ial\n")
+	lines, err = tail.ReadLines(1 << 10)
+	want = []LogLine{{Text: "partial", End: 23}}
+	if err != nil || !reflect.DeepEqual(lines, want) {
+		t.Fatalf("ReadLines = %v, %v, want %v", lines, err, want)
+	}
+
+	// A line longer than a read is returned in pieces
+	appendFile(t, path, "0123456789\n")
+	lines, err = tail.ReadLines(4)
+	want = []LogLine{{Text: "0123", End: 27}}
+	if err != nil || !reflect.DeepEqual(lines, want) {
+		t.Fatalf("ReadLines = %v, %v, want %v", lines, err, want)
+	}
+	lines, err = tail.ReadLines(1 << 10)
+	want = []LogLine{{Text: "456789", End: 34}}
+	if err != nil || !reflect.DeepEqual(lines, want) {
+		t.Fatalf("ReadLines = %v, %v, want %v", lines, err, want)
+	}
+
+	// A file replaced by a longer one is read from its start
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("a new file longer than 34 bytes, after rotation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err = tail.ReadLines(1 << 10)
+	if err != nil || len(lines) != 1 || lines[0].Text != "a new file longer than 34 bytes, after rotation" {
+		t.Fatalf("ReadLines = %v, %v, want the line of the new file", lines, err)
+	}
+}
@@ -0,0 +1,340 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedStore records a "store.<method>" span for every call to the wrapped Store.
+type tracedStore struct {
+	store  Store
+	tracer trace.Tracer
+}
+
+// NewTracedStore wraps store so that each method call is recorded as a span named
+// "store.<method>". Methods returning rows record their number as store.rows_returned.
+func NewTracedStore(store Store, tracer trace.Tracer) Store {
+	return &tracedStore{store: store, tracer: tracer}
+}
+
+func (s *tracedStore) GetProjectRules(ctx context.Context, projectID string, includeGlobal bool) ([]string, error) {
+	ctx, span := s.tracer.Start(ctx, "store.GetProjectRules")
+	rules, err := s.store.GetProjectRules(ctx, projectID, includeGlobal)
+	endStoreSpan(span, len(rules), err)
+	return rules, err
+}
+
+func (s *tracedStore) ListProjectRules(ctx context.Context) ([]ProjectRule, error) {
+	ctx, span := s.tracer.Start(ctx, "store.ListProjectRules")
+	rules, err := s.store.ListProjectRules(ctx)
+	endStoreSpan(span, len(rules), err)
+	return rules, err
+}
+
+func (s *tracedStore) UpdateProjectRule(ctx context.Context, id int, content string, priority int, isActive bool) error {
+	ctx, span := s.tracer.Start(ctx, "store.UpdateProjectRule", trace.WithAttributes(attribute.Int("store.rule_id", id)))
+	err := s.store.UpdateProjectRule(ctx, id, content, priority, isActive)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) DeleteProjectRule(ctx context.Context, id int) error {
+	ctx, span := s.tracer.Start(ctx, "store.DeleteProjectRule", trace.WithAttributes(attribute.Int("store.rule_id", id)))
+	err := s.store.DeleteProjectRule(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) AddProjectRule(ctx context.Context, category, content string, priority int) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "store.AddProjectRule")
+	id, err := s.store.AddProjectRule(ctx, category, content, priority)
+	endSpan(span, err)
+	return id, err
+}
+
+func (s *tracedStore) UpsertProjectRule(ctx context.Context, category, content string, priority int) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "store.UpsertProjectRule")
+	id, err := s.store.UpsertProjectRule(ctx, category, content, priority)
+	endSpan(span, err)
+	return id, err
+}
+
+func (s *tracedStore) SetProjectContext(ctx context.Context, key, value string) error {
+	ctx, span := s.tracer.Start(ctx, "store.SetProjectContext")
+	err := s.store.SetProjectContext(ctx, key, value)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) GetProjectContext(ctx context.Context, key string) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "store.GetProjectContext")
+	value, err := s.store.GetProjectContext(ctx, key)
+	endSpan(span, err)
+	return value, err
+}
+
+func (s *tracedStore) ListProjectContext(ctx context.Context) (map[string]string, error) {
+	ctx, span := s.tracer.Start(ctx, "store.ListProjectContext")
+	entries, err := s.store.ListProjectContext(ctx)
+	endStoreSpan(span, len(entries), err)
+	return entries, err
+}
+
+func (s *tracedStore) SearchSimilarIssues(ctx context.Context, query string, queryVector []float32, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, error) {
+	ctx, span := s.tracer.Start(ctx, "store.SearchSimilarIssues", trace.WithAttributes(
+		attribute.Int("store.limit", limit),
+		attribute.Float64("store.min_similarity", float64(minSimilarity)),
+	))
+	experiences, err := s.store.SearchSimilarIssues(ctx, query, queryVector, limit, minSimilarity, projectID, includeGlobal, tags)
+	endStoreSpan(span, len(experiences), err)
+	return experiences, err
+}
+
+func (s *tracedStore) SearchSimilarIssuesPage(ctx context.Context, query string, queryVector []float32, offset, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]Experience, int64, error) {
+	ctx, span := s.tracer.Start(ctx, "store.SearchSimilarIssuesPage", trace.WithAttributes(
+		attribute.Int("store.offset", offset),
+		attribute.Int("store.limit", limit),
+		attribute.Float64("store.min_similarity", float64(minSimilarity)),
+	))
+	experiences, total, err := s.store.SearchSimilarIssuesPage(ctx, query, queryVector, offset, limit, minSimilarity, projectID, includeGlobal, tags)
+	endStoreSpan(span, len(experiences), err)
+	return experiences, total, err
+}
+
+func (s *tracedStore) SearchByUser(ctx context.Context, userID, query string, queryVector []float32, limit int) ([]Experience, error) {
+	ctx, span := s.tracer.Start(ctx, "store.SearchByUser", trace.WithAttributes(attribute.Int("store.limit", limit)))
+	experiences, err := s.store.SearchByUser(ctx, userID, query, queryVector, limit)
+	endStoreSpan(span, len(experiences), err)
+	return experiences, err
+}
+
+func (s *tracedStore) HybridSearch(ctx context.Context, query string, queryVector []float32, limit int, alpha float32, projectID string, includeGlobal bool) ([]Experience, error) {
+	ctx, span := s.tracer.Start(ctx, "store.HybridSearch", trace.WithAttributes(
+		attribute.Int("store.limit", limit),
+		attribute.Float64("store.alpha", float64(alpha)),
+	))
+	experiences, err := s.store.HybridSearch(ctx, query, queryVector, limit, alpha, projectID, includeGlobal)
+	endStoreSpan(span, len(experiences), err)
+	return experiences, err
+}
+
+func (s *tracedStore) SaveExperience(ctx context.Context, userID, pattern, cause, solution string, tags []string, vector []float32) error {
+	ctx, span := s.tracer.Start(ctx, "store.SaveExperience")
+	err := s.store.SaveExperience(ctx, userID, pattern, cause, solution, tags, vector)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) BatchSaveExperiences(ctx context.Context, items []ExperienceInput) error {
+	ctx, span := s.tracer.Start(ctx, "store.BatchSaveExperiences", trace.WithAttributes(attribute.Int("store.batch_size", len(items))))
+	err := s.store.BatchSaveExperiences(ctx, items)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) UpsertExperience(ctx context.Context, pattern, cause, solution string, vector []float32) error {
+	ctx, span := s.tracer.Start(ctx, "store.UpsertExperience")
+	err := s.store.UpsertExperience(ctx, pattern, cause, solution, vector)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) SaveSourcedExperience(ctx context.Context, source, signature, pattern, cause, solution string, vector []float32) error {
+	ctx, span := s.tracer.Start(ctx, "store.SaveSourcedExperience", trace.WithAttributes(attribute.String("store.source", source)))
+	err := s.store.SaveSourcedExperience(ctx, source, signature, pattern, cause, solution, vector)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) ReplaceSourcedExperiences(ctx context.Context, source, signaturePrefix, signature string, items []ExperienceInput) error {
+	ctx, span := s.tracer.Start(ctx, "store.ReplaceSourcedExperiences", trace.WithAttributes(attribute.String("store.source", source), attribute.Int("store.batch_size", len(items))))
+	err := s.store.ReplaceSourcedExperiences(ctx, source, signaturePrefix, signature, items)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) ImportExperience(ctx context.Context, exp Experience, vector []float32) error {
+	ctx, span := s.tracer.Start(ctx, "store.ImportExperience")
+	err := s.store.ImportExperience(ctx, exp, vector)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) HasTaskSignature(ctx context.Context, signature string) (bool, error) {
+	ctx, span := s.tracer.Start(ctx, "store.HasTaskSignature")
+	exists, err := s.store.HasTaskSignature(ctx, signature)
+	endSpan(span, err)
+	return exists, err
+}
+
+func (s *tracedStore) FindExperienceByHash(ctx context.Context, hash string) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "store.FindExperienceByHash")
+	id, err := s.store.FindExperienceByHash(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		span.End() // Not finding a duplicate is the normal case, not a failure
+		return id, err
+	}
+	endSpan(span, err)
+	return id, err
+}
+
+func (s *tracedStore) UpdateExperience(ctx context.Context, id int, cause, solution string) error {
+	ctx, span := s.tracer.Start(ctx, "store.UpdateExperience", trace.WithAttributes(attribute.Int("store.experience_id", id)))
+	err := s.store.UpdateExperience(ctx, id, cause, solution)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) GetExperienceHistory(ctx context.Context, id int) ([]ExperienceVersion, error) {
+	ctx, span := s.tracer.Start(ctx, "store.GetExperienceHistory", trace.WithAttributes(attribute.Int("store.experience_id", id)))
+	versions, err := s.store.GetExperienceHistory(ctx, id)
+	endStoreSpan(span, len(versions), err)
+	return versions, err
+}
+
+func (s *tracedStore) RevertExperienceTo(ctx context.Context, id, version int) error {
+	ctx, span := s.tracer.Start(ctx, "store.RevertExperienceTo", trace.WithAttributes(attribute.Int("store.experience_id", id)))
+	err := s.store.RevertExperienceTo(ctx, id, version)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) GetExperience(ctx context.Context, id int) (Experience, error) {
+	ctx, span := s.tracer.Start(ctx, "store.GetExperience", trace.WithAttributes(attribute.Int("store.experience_id", id)))
+	exp, err := s.store.GetExperience(ctx, id)
+	endSpan(span, err)
+	return exp, err
+}
+
+func (s *tracedStore) MergeExperiences(ctx context.Context, primaryID, secondaryID int, merged Experience, vector []float32) error {
+	ctx, span := s.tracer.Start(ctx, "store.MergeExperiences", trace.WithAttributes(attribute.Int("store.experience_id", primaryID)))
+	err := s.store.MergeExperiences(ctx, primaryID, secondaryID, merged, vector)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) CompactExperiences(ctx context.Context, similarityThreshold float32) (CompactionReport, error) {
+	ctx, span := s.tracer.Start(ctx, "store.CompactExperiences")
+	report, err := s.store.CompactExperiences(ctx, similarityThreshold)
+	endSpan(span, err)
+	return report, err
+}
+
+func (s *tracedStore) PurgeExpiredExperiences(ctx context.Context) (int64, error) {
+	ctx, span := s.tracer.Start(ctx, "store.PurgeExpiredExperiences")
+	deleted, err := s.store.PurgeExpiredExperiences(ctx)
+	span.SetAttributes(attribute.Int64("store.rows_deleted", deleted))
+	endSpan(span, err)
+	return deleted, err
+}
+
+func (s *tracedStore) DeleteExperience(ctx context.Context, id int) error {
+	ctx, span := s.tracer.Start(ctx, "store.DeleteExperience", trace.WithAttributes(attribute.Int("store.experience_id", id)))
+	err := s.store.DeleteExperience(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, span := s.tracer.Start(ctx, "store.PurgeDeleted")
+	deleted, err := s.store.PurgeDeleted(ctx, olderThan)
+	span.SetAttributes(attribute.Int64("store.rows_deleted", deleted))
+	endSpan(span, err)
+	return deleted, err
+}
+
+func (s *tracedStore) ListExperiences(ctx context.Context) ([]Experience, error) {
+	ctx, span := s.tracer.Start(ctx, "store.ListExperiences")
+	experiences, err := s.store.ListExperiences(ctx)
+	endStoreSpan(span, len(experiences), err)
+	return experiences, err
+}
+
+func (s *tracedStore) ListExperiencesByTag(ctx context.Context, tag string, limit int) ([]Experience, error) {
+	ctx, span := s.tracer.Start(ctx, "store.ListExperiencesByTag", trace.WithAttributes(
+		attribute.String("store.tag", tag),
+		attribute.Int("store.limit", limit),
+	))
+	experiences, err := s.store.ListExperiencesByTag(ctx, tag, limit)
+	endStoreSpan(span, len(experiences), err)
+	return experiences, err
+}
+
+func (s *tracedStore) GetEmbeddings(ctx context.Context, ids []int) (map[int][]float32, error) {
+	ctx, span := s.tracer.Start(ctx, "store.GetEmbeddings", trace.WithAttributes(
+		attribute.Int("store.ids", len(ids)),
+	))
+	vectors, err := s.store.GetEmbeddings(ctx, ids)
+	endStoreSpan(span, len(vectors), err)
+	return vectors, err
+}
+
+func (s *tracedStore) Stats(ctx context.Context) (*MemoryStats, error) {
+	ctx, span := s.tracer.Start(ctx, "store.Stats")
+	stats, err := s.store.Stats(ctx)
+	endSpan(span, err)
+	return stats, err
+}
+
+func (s *tracedStore) Ping(ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "store.Ping")
+	err := s.store.Ping(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) Close() {
+	s.store.Close()
+}
+
+// tracedEmbedder records an "embedding.Embed" or "embedding.BatchEmbed" span for every
+// request to the wrapped Embedder.
+type tracedEmbedder struct {
+	inner  Embedder
+	tracer trace.Tracer
+}
+
+// NewTracedEmbedder wraps inner so that each request is recorded as a span carrying its
+// latency as embedding.latency_ms. The result also implements BatchEmbedder, passing
+// batches to inner in a single request when inner supports it.
+func NewTracedEmbedder(inner Embedder, tracer trace.Tracer) BatchEmbedder {
+	return &tracedEmbedder{inner: inner, tracer: tracer}
+}
+
+func (e *tracedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	ctx, span := e.tracer.Start(ctx, "embedding.Embed", trace.WithAttributes(attribute.Int("embedding.text_length", len(text))))
+	start := time.Now()
+	vector, err := e.inner.Embed(ctx, text)
+	span.SetAttributes(attribute.Int64("embedding.latency_ms", time.Since(start).Milliseconds()))
+	endSpan(span, err)
+	return vector, err
+}
+
+func (e *tracedEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, span := e.tracer.Start(ctx, "embedding.BatchEmbed", trace.WithAttributes(attribute.Int("embedding.batch_size", len(texts))))
+	start := time.Now()
+	vectors, err := EmbedAll(ctx, e.inner, texts)
+	span.SetAttributes(attribute.Int64("embedding.latency_ms", time.Since(start).Milliseconds()))
+	endSpan(span, err)
+	return vectors, err
+}
+
+// endStoreSpan records the number of returned rows on span and ends it.
+func endStoreSpan(span trace.Span, rows int, err error) {
+	span.SetAttributes(attribute.Int("store.rows_returned", rows))
+	endSpan(span, err)
+}
+
+// endSpan marks span as failed if err is not nil and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestTracer returns a tracer whose spans are recorded synchronously in the returned exporter.
+func newTestTracer() (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	exporter := tracetest.NewInMemoryExporter()
+	return exporter, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter)))
+}
+
+// spanAttribute returns the value of the attribute key of span, or false if it is not set.
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracedStore(t *testing.T) {
+	ctx := context.Background()
+	exporter, provider := newTestTracer()
+	store := NewTracedStore(NewInMemoryStore(), provider.Tracer("test"))
+
+	if err := store.SaveExperience(ctx, "", "nil pointer", "cause", "solution", nil, []float32{1, 0}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	if _, err := store.SearchSimilarIssues(ctx, "", []float32{1, 0}, 5, 0.5, "", false, nil); err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+	if _, err := store.GetExperience(ctx, 42); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for i, name := range []string{"store.SaveExperience", "store.SearchSimilarIssues", "store.GetExperience"} {
+		if spans[i].Name != name {
+			t.Errorf("span %d: expected %s, got %s", i, name, spans[i].Name)
+		}
+	}
+	if rows, ok := spanAttribute(spans[1], "store.rows_returned"); !ok || rows.AsInt64() != 1 {
+		t.Errorf("expected store.rows_returned 1, got %v", rows)
+	}
+	if spans[2].Status.Code != codes.Error {
+		t.Errorf("expected the failed lookup to be marked as an error, got %v", spans[2].Status)
+	}
+}
+
+func TestTracedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	exporter, provider := newTestTracer()
+	embedder := NewTracedEmbedder(&mockEmbedder{}, provider.Tracer("test"))
+
+	if _, err := embedder.Embed(ctx, "text"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if _, err := embedder.BatchEmbed(ctx, []string{"a", "b"}); err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "embedding.Embed" || spans[1].Name != "embedding.BatchEmbed" {
+		t.Fatalf("expected embedding.Embed and embedding.BatchEmbed spans, got %+v", spans)
+	}
+	for _, span := range spans {
+		if _, ok := spanAttribute(span, "embedding.latency_ms"); !ok {
+			t.Errorf("%s: expected embedding.latency_ms to be set", span.Name)
+		}
+	}
+	if size, _ := spanAttribute(spans[1], "embedding.batch_size"); size.AsInt64() != 2 {
+		t.Errorf("expected embedding.batch_size 2, got %v", size)
+	}
+}
@@ -9,22 +9,57 @@ import "time"
 // along with a vector embedding for similarity search.
 type Experience struct {
 	ID              int       // Unique identifier in the database
+	UserID          string    // ID of the user whose session produced this experience (empty if unknown)
+	ProjectID       string    // Project the experience was learned in (empty for experiences shared by all projects)
+	Source          string    // Origin of the experience, SourceSession or SourceCodebaseIndex
 	TaskSignature   string    // Short signature (first 50 chars) for quick identification
 	ErrorPattern    string    // Description of the error or problem pattern
 	RootCause       string    // Root cause analysis of the issue
 	Solution        string    // Solution or fix that resolved the issue
 	SimilarityScore float32   // Similarity score when returned from search (0-1, higher is more similar)
+	ConfidenceLevel string    // ConfidenceHigh, ConfidenceMedium or ConfidenceLow for similarity search results, see AssignConfidence
+	Frequency       int       // Number of times the issue was encountered, set by GetExperience
+	Tags            []string  // Normalized labels such as "panic" or "timeout", see NormalizeTags
 	OccurredAt      time.Time // Timestamp when the issue was encountered and resolved
 }
 
+// Known values of Experience.Source.
+const (
+	SourceSession       = "session"        // Learned from a chat session or saved by the agent
+	SourceCodebaseIndex = "codebase_index" // Ingested from source code by IndexCodebase
+)
+
+// ExperienceInput is a new experience passed to Store.BatchSaveExperiences.
+type ExperienceInput struct {
+	UserID   string    // ID of the user the experience came from (may be empty)
+	Pattern  string    // Description of the error or problem pattern
+	Cause    string    // Root cause analysis of the issue
+	Solution string    // Solution or fix that resolved the issue
+	Tags     []string  // Labels of the experience, see NormalizeTags
+	Vector   []float32 // Embedding of Pattern, filled in by SaveExperienceBatch
+}
+
 // ProjectRule represents a semantic memory entry - a project rule or constraint.
 // These rules are injected into the system prompt to guide agent behavior
 // and enforce project-specific coding standards and practices.
 type ProjectRule struct {
 	ID          int       // Unique identifier in the database
+	ProjectID   string    // Project the rule applies to (empty for rules shared by all projects)
 	Category    string    // Category or type of rule (e.g., "naming", "error_handling")
 	RuleContent string    // The actual rule text to be included in the system prompt
 	Priority    int       // Priority level (higher values take precedence)
 	IsActive    bool      // Whether this rule is currently active
 	CreatedAt   time.Time // Timestamp when the rule was created
 }
+
+// ExperienceVersion is a historical snapshot of an Experience, recorded
+// every time the experience is updated so that earlier versions can be
+// inspected or restored.
+type ExperienceVersion struct {
+	ExperienceID int       // ID of the experience this version belongs to
+	Version      int       // Version number, starting from 1 for the original content
+	ErrorPattern string    // Error pattern at the time of this version
+	RootCause    string    // Root cause at the time of this version
+	Solution     string    // Solution at the time of this version
+	UpdatedAt    time.Time // Timestamp when this version was superseded
+}
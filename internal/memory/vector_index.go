@@ -0,0 +1,346 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Types of the vector index on issue_history.embedding, see StoreOptions.IndexType.
+//
+// IVFFlat partitions the vectors into lists around centroids computed when the index is
+// built, and searches the lists nearest to the query. It builds quickly and stays small,
+// but the centroids only fit the rows present at build time: on a small or fast-growing
+// table, searches miss neighbours until the index is rebuilt. The pgvector documentation
+// recommends about rows/1000 lists.
+//
+// HNSW links the vectors into a layered proximity graph that is updated on every
+// insert, so it needs no training data and recalls well from the first row. It is
+// slower to build and uses more memory; m is the number of links per vector and
+// ef_construction the size of the candidate list while building, and raising either
+// improves recall at the cost of build time and size.
+//
+// Without an index every search scans the whole table, which is exact and fine for a
+// few thousand experiences.
+const (
+	IndexTypeIVFFlat = "ivfflat"
+	IndexTypeHNSW    = "hnsw"
+	IndexTypeNone    = "none"
+)
+
+// Defaults of the vector index parameters, those of pgvector for HNSW.
+const (
+	DefaultIVFFlatLists       = 100
+	DefaultHNSWM              = 16
+	DefaultHNSWEfConstruction = 64
+)
+
+// vectorIndexName is the name of the vector index, the one Postgres gave the index
+// created by the first migration.
+const vectorIndexName = "issue_history_embedding_idx"
+
+// vectorIndex is the type and parameters of the vector index. Only the parameters of
+// Type are set.
+type vectorIndex struct {
+	Type           string
+	Lists          int // IVFFlat
+	M              int // HNSW
+	EfConstruction int // HNSW
+}
+
+// newVectorIndex returns the vector index configured by opts, with defaults for unset
+// parameters, or an error for an unknown type or a negative parameter.
+func newVectorIndex(opts StoreOptions) (vectorIndex, error) {
+	if opts.IVFFlatLists < 0 || opts.HNSWM < 0 || opts.HNSWEfConstruction < 0 {
+		return vectorIndex{}, errors.New("vector index parameters must not be negative")
+	}
+	switch strings.ToLower(opts.IndexType) {
+	case "", IndexTypeIVFFlat:
+		return vectorIndex{Type: IndexTypeIVFFlat, Lists: positiveOr(opts.IVFFlatLists, DefaultIVFFlatLists)}, nil
+	case IndexTypeHNSW:
+		ix := vectorIndex{
+			Type:           IndexTypeHNSW,
+			M:              positiveOr(opts.HNSWM, DefaultHNSWM),
+			EfConstruction: positiveOr(opts.HNSWEfConstruction, DefaultHNSWEfConstruction),
+		}
+		// pgvector rejects a candidate list shorter than twice the links
+		if ix.EfConstruction < 2*ix.M {
+			return vectorIndex{}, fmt.Errorf("HNSW ef_construction (%d) must be at least twice m (%d)", ix.EfConstruction, ix.M)
+		}
+		return ix, nil
+	case IndexTypeNone:
+		return vectorIndex{Type: IndexTypeNone}, nil
+	default:
+		return vectorIndex{}, fmt.Errorf("unknown vector index type %q, must be %s, %s or %s", opts.IndexType, IndexTypeIVFFlat, IndexTypeHNSW, IndexTypeNone)
+	}
+}
+
+// positiveOr returns n if it is positive and def otherwise.
+func positiveOr(n, def int) int {
+	if n > 0 {
+		return n
+	}
+	return def
+}
+
+// createSQL returns the statement creating ix, or "" for IndexTypeNone.
+func (ix vectorIndex) createSQL() string {
+	var with string
+	switch ix.Type {
+	case IndexTypeIVFFlat:
+		with = fmt.Sprintf("lists = %d", ix.Lists)
+	case IndexTypeHNSW:
+		with = fmt.Sprintf("m = %d, ef_construction = %d", ix.M, ix.EfConstruction)
+	default:
+		return ""
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON issue_history USING %s (embedding vector_cosine_ops) WITH (%s)", vectorIndexName, ix.Type, with)
+}
+
+// indexDefPattern matches the definition of the vector index as reported by pg_indexes,
+// e.g. "CREATE INDEX ... USING hnsw (embedding vector_cosine_ops) WITH (m='16', ef_construction='64')".
+var indexDefPattern = regexp.MustCompile(`USING (\w+) \(embedding vector_cosine_ops\)(?: WITH \((.*)\))?$`)
+
+// parseVectorIndexDef returns the vector index defined by def, as reported by
+// pg_indexes, and false when def is not an index this package creates.
+func parseVectorIndexDef(def string) (vectorIndex, bool) {
+	m := indexDefPattern.FindStringSubmatch(def)
+	if m == nil {
+		return vectorIndex{}, false
+	}
+	params := make(map[string]int)
+	if m[2] != "" {
+		for _, param := range strings.Split(m[2], ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			n, err := strconv.Atoi(strings.Trim(value, "'"))
+			if err != nil {
+				return vectorIndex{}, false
+			}
+			params[key] = n
+		}
+	}
+
+	// Parameters left out of the definition have pgvector's defaults
+	switch m[1] {
+	case IndexTypeIVFFlat:
+		return vectorIndex{Type: IndexTypeIVFFlat, Lists: positiveOr(params["lists"], DefaultIVFFlatLists)}, true
+	case IndexTypeHNSW:
+		return vectorIndex{
+			Type:           IndexTypeHNSW,
+			M:              positiveOr(params["m"], DefaultHNSWM),
+			EfConstruction: positiveOr(params["ef_construction"], DefaultHNSWEfConstruction),
+		}, true
+	default:
+		return vectorIndex{}, false
+	}
+}
+
+// indexDB runs the statements of ensureVectorIndex and dropLeftoverIndexes; it is
+// implemented by *pgxpool.Pool and *pgxpool.Conn.
+type indexDB interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// ensureVectorIndex drops the vector index of db and creates want instead, unless the
+// index already has the type and parameters of want. It reports whether it changed the
+// index.
+func ensureVectorIndex(ctx context.Context, db indexDB, want vectorIndex) (bool, error) {
+	current := vectorIndex{Type: IndexTypeNone}
+	var def string
+	err := db.QueryRow(ctx, `SELECT indexdef FROM pg_indexes WHERE tablename = 'issue_history' AND indexname = $1`, vectorIndexName).Scan(&def)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return false, fmt.Errorf("failed to read vector index: %w", err)
+	default:
+		var ok bool
+		if current, ok = parseVectorIndexDef(def); !ok {
+			current = vectorIndex{Type: "unknown"} // Created by hand, always replaced
+		}
+	}
+	if current == want {
+		return false, nil
+	}
+
+	if _, err := db.Exec(ctx, "DROP INDEX IF EXISTS "+vectorIndexName); err != nil {
+		return false, fmt.Errorf("failed to drop vector index: %w", err)
+	}
+	if stmt := want.createSQL(); stmt != "" {
+		if _, err := db.Exec(ctx, stmt); err != nil {
+			return true, fmt.Errorf("failed to create %s vector index: %w", want.Type, err)
+		}
+		if _, err := db.Exec(ctx, recordReindexedRowsSQL); err != nil {
+			return true, fmt.Errorf("failed to record reindexed rows: %w", err)
+		}
+	}
+	slog.InfoContext(ctx, "replaced vector index", slog.String("from", current.Type), slog.String("to", want.Type))
+	return true, nil
+}
+
+// recordReindexedRowsSQL records the current number of embedded experiences as the
+// number the vector index was built from, see ReindexIfNeeded.
+const recordReindexedRowsSQL = `UPDATE embedding_meta SET reindexed_rows = (SELECT COUNT(*) FROM issue_history WHERE embedding IS NOT NULL)`
+
+// reindexGrowth is the growth of the number of embedded experiences since the IVFFlat
+// index was last built above which ReindexIfNeeded rebuilds it.
+const reindexGrowth = 0.2
+
+// reindexAdvisable reports whether an IVFFlat index built when the table had lastRows
+// embedded experiences should be rebuilt now that it has rows. An index built on an
+// empty table, as by the first migration, has no meaningful lists at all.
+func reindexAdvisable(rows, lastRows int64) bool {
+	return rows > 0 && float64(rows) > float64(lastRows)*(1+reindexGrowth)
+}
+
+// reindexLockID is the advisory lock held while rebuilding the vector index, so that
+// agents sharing the database do not rebuild it at the same time.
+const reindexLockID = 7_240_512
+
+// errReindexRunning is returned by Reindex when another agent is rebuilding the index.
+var errReindexRunning = errors.New("the vector index is already being rebuilt")
+
+// leftoverIndexTimeout bounds the removal of the indexes an interrupted rebuild leaves
+// behind, which runs after the context of the rebuild may have been cancelled.
+const leftoverIndexTimeout = time.Minute
+
+// ReindexIfNeeded rebuilds the IVFFlat vector index when the number of embedded
+// experiences has grown by more than 20% since it was last built. The lists of an
+// IVFFlat index are computed from the rows present when it is built, so rows added
+// later make searches miss neighbours. It does nothing for other index types, whose
+// graph is kept up to date on every insert, when the index does not exist, or when
+// another agent is already rebuilding it.
+func (s *PostgresStore) ReindexIfNeeded(ctx context.Context) error {
+	_, err := s.reindexIfNeeded(ctx)
+	return err
+}
+
+// reindexIfNeeded implements ReindexIfNeeded and reports whether it rebuilt the index.
+func (s *PostgresStore) reindexIfNeeded(ctx context.Context) (bool, error) {
+	if s.vectorIndex.Type != IndexTypeIVFFlat {
+		return false, nil
+	}
+	var rows, lastRows int64
+	var exists bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM issue_history WHERE embedding IS NOT NULL),
+		       COALESCE((SELECT reindexed_rows FROM embedding_meta ORDER BY updated_at DESC LIMIT 1), 0),
+		       EXISTS (SELECT 1 FROM pg_stat_user_indexes WHERE relname = 'issue_history' AND indexrelname = $1)
+	`, vectorIndexName).Scan(&rows, &lastRows, &exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check vector index: %w", err)
+	}
+	if !exists || !reindexAdvisable(rows, lastRows) {
+		return false, nil
+	}
+	err = s.reindex(ctx, rows)
+	if errors.Is(err, errReindexRunning) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// reindexInBackground runs ReindexIfNeeded in a goroutine, unless one is still running,
+// so that a rebuild, which can take minutes on a large table, does not hold up the save
+// that triggered it. The rebuild does not use the context of the save and is only
+// cancelled by Close. Failures are logged.
+func (s *PostgresStore) reindexInBackground() {
+	if !s.reindexing.CompareAndSwap(false, true) {
+		return
+	}
+	ctx := s.background
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.backgroundWG.Add(1)
+	go func() {
+		defer s.backgroundWG.Done()
+		defer s.reindexing.Store(false)
+		if err := s.ReindexIfNeeded(ctx); err != nil {
+			slog.WarnContext(ctx, "failed to rebuild vector index", slog.Any("error", err))
+		}
+	}()
+}
+
+// Reindex rebuilds the vector index regardless of how much the table has grown, without
+// blocking searches and saves, and records the number of embedded experiences it was
+// built with. It fails when another agent is already rebuilding the index.
+func (s *PostgresStore) Reindex(ctx context.Context) error {
+	if s.vectorIndex.Type == IndexTypeNone {
+		return errors.New("there is no vector index to rebuild")
+	}
+	var rows int64
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM issue_history WHERE embedding IS NOT NULL`).Scan(&rows); err != nil {
+		return fmt.Errorf("failed to count embedded experiences: %w", err)
+	}
+	return s.reindex(ctx, rows)
+}
+
+// reindex rebuilds the vector index, which was built from rows embedded experiences,
+// holding reindexLockID on the connection it runs on; it returns errReindexRunning when
+// the lock is taken. REINDEX CONCURRENTLY cannot run in a transaction, so the count is
+// recorded afterwards. An interrupted rebuild leaves an invalid copy of the index
+// behind, which is dropped.
+func (s *PostgresStore) reindex(ctx context.Context, rows int64) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, reindexLockID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to acquire reindex lock: %w", err)
+	}
+	if !locked {
+		return errReindexRunning
+	}
+	defer func() {
+		// The lock belongs to the session, close the connection if it cannot be released
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), leftoverIndexTimeout)
+		defer cancel()
+		if _, unlockErr := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, reindexLockID); unlockErr != nil {
+			_ = conn.Conn().Close(unlockCtx)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, "REINDEX INDEX CONCURRENTLY "+vectorIndexName); err != nil {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), leftoverIndexTimeout)
+		defer cancel()
+		if dropErr := dropLeftoverIndexes(cleanupCtx, conn); dropErr != nil {
+			slog.WarnContext(ctx, "failed to drop indexes left by an interrupted rebuild", slog.Any("error", dropErr))
+		}
+		return fmt.Errorf("failed to rebuild vector index: %w", err)
+	}
+	if _, err := conn.Exec(ctx, `UPDATE embedding_meta SET reindexed_rows = $1`, rows); err != nil {
+		return fmt.Errorf("failed to record reindexed rows: %w", err)
+	}
+	slog.InfoContext(ctx, "rebuilt vector index", slog.String("type", s.vectorIndex.Type), slog.Int64("rows", rows))
+	return nil
+}
+
+// dropLeftoverIndexes drops the copies of the vector index REINDEX CONCURRENTLY creates,
+// named with a _ccnew or _ccold suffix, which are left invalid when it is interrupted.
+func dropLeftoverIndexes(ctx context.Context, db indexDB) error {
+	var names []string
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(array_agg(indexname), '{}') FROM pg_indexes
+		WHERE tablename = 'issue_history' AND indexname ~ ('^' || $1 || '_cc(new|old)[0-9]*$')
+	`, vectorIndexName).Scan(&names)
+	if err != nil {
+		return fmt.Errorf("failed to list leftover indexes: %w", err)
+	}
+	for _, name := range names {
+		if _, err := db.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", name, err)
+		}
+	}
+	return nil
+}
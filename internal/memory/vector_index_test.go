@@ -0,0 +1,250 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// captureDB is an indexDB that reports def as the definition of the vector index, or
+// no index when def is empty, and leftovers as the indexes left by interrupted
+// rebuilds, and records the statements it is asked to execute.
+type captureDB struct {
+	def       string
+	leftovers []string
+	execs     []string
+}
+
+func (db *captureDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return captureRow{def: db.def, leftovers: db.leftovers}
+}
+
+func (db *captureDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.execs = append(db.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+// captureRow is the pg_indexes row of captureDB.
+type captureRow struct {
+	def       string
+	leftovers []string
+}
+
+func (r captureRow) Scan(dest ...any) error {
+	if names, ok := dest[0].(*[]string); ok {
+		*names = r.leftovers
+		return nil
+	}
+	if r.def == "" {
+		return pgx.ErrNoRows
+	}
+	*dest[0].(*string) = r.def
+	return nil
+}
+
+// Index definitions as reported by pg_indexes.
+const (
+	ivfflatDef = "CREATE INDEX issue_history_embedding_idx ON public.issue_history USING ivfflat (embedding vector_cosine_ops) WITH (lists='100')"
+	hnswDef    = "CREATE INDEX issue_history_embedding_idx ON public.issue_history USING hnsw (embedding vector_cosine_ops) WITH (m='16', ef_construction='64')"
+)
+
+func TestEnsureVectorIndex(t *testing.T) {
+	const drop = "DROP INDEX IF EXISTS issue_history_embedding_idx"
+
+	tests := []struct {
+		name    string
+		opts    StoreOptions
+		current string
+		want    []string // Statements executed
+	}{
+		{
+			name:    "default ivfflat already built",
+			current: ivfflatDef,
+		},
+		{
+			name:    "ivfflat with other lists",
+			opts:    StoreOptions{IndexType: "ivfflat", IVFFlatLists: 500},
+			current: ivfflatDef,
+			want:    []string{drop, "CREATE INDEX issue_history_embedding_idx ON issue_history USING ivfflat (embedding vector_cosine_ops) WITH (lists = 500)", recordReindexedRowsSQL},
+		},
+		{
+			name:    "ivfflat to hnsw",
+			opts:    StoreOptions{IndexType: "hnsw"},
+			current: ivfflatDef,
+			want:    []string{drop, "CREATE INDEX issue_history_embedding_idx ON issue_history USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 64)", recordReindexedRowsSQL},
+		},
+		{
+			name:    "hnsw already built",
+			opts:    StoreOptions{IndexType: "HNSW", HNSWM: 16},
+			current: hnswDef,
+		},
+		{
+			name:    "hnsw with other parameters",
+			opts:    StoreOptions{IndexType: "hnsw", HNSWM: 32, HNSWEfConstruction: 128},
+			current: hnswDef,
+			want:    []string{drop, "CREATE INDEX issue_history_embedding_idx ON issue_history USING hnsw (embedding vector_cosine_ops) WITH (m = 32, ef_construction = 128)", recordReindexedRowsSQL},
+		},
+		{
+			name:    "none drops the index",
+			opts:    StoreOptions{IndexType: "none"},
+			current: hnswDef,
+			want:    []string{drop},
+		},
+		{
+			name: "none without index",
+			opts: StoreOptions{IndexType: "none"},
+		},
+		{
+			name: "missing index is created",
+			want: []string{drop, "CREATE INDEX issue_history_embedding_idx ON issue_history USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100)", recordReindexedRowsSQL},
+		},
+		{
+			name:    "index created by hand is replaced",
+			current: "CREATE INDEX issue_history_embedding_idx ON public.issue_history USING ivfflat (embedding vector_l2_ops) WITH (lists='100')",
+			want:    []string{drop, "CREATE INDEX issue_history_embedding_idx ON issue_history USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100)", recordReindexedRowsSQL},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := newVectorIndex(tt.opts)
+			if err != nil {
+				t.Fatalf("newVectorIndex failed: %v", err)
+			}
+			db := &captureDB{def: tt.current}
+			changed, err := ensureVectorIndex(context.Background(), db, index)
+			if err != nil {
+				t.Fatalf("ensureVectorIndex failed: %v", err)
+			}
+			if !reflect.DeepEqual(db.execs, tt.want) {
+				t.Errorf("expected statements %q, got %q", tt.want, db.execs)
+			}
+			if changed != (len(tt.want) > 0) {
+				t.Errorf("expected changed to be %v, got %v", len(tt.want) > 0, changed)
+			}
+		})
+	}
+}
+
+func TestDropLeftoverIndexes(t *testing.T) {
+	db := &captureDB{leftovers: []string{"issue_history_embedding_idx_ccnew", "issue_history_embedding_idx_ccnew1"}}
+	if err := dropLeftoverIndexes(context.Background(), db); err != nil {
+		t.Fatalf("dropLeftoverIndexes failed: %v", err)
+	}
+	want := []string{
+		`DROP INDEX CONCURRENTLY IF EXISTS "issue_history_embedding_idx_ccnew"`,
+		`DROP INDEX CONCURRENTLY IF EXISTS "issue_history_embedding_idx_ccnew1"`,
+	}
+	if !reflect.DeepEqual(db.execs, want) {
+		t.Errorf("expected statements %q, got %q", want, db.execs)
+	}
+}
+
+func TestReindexAdvisable(t *testing.T) {
+	tests := []struct {
+		rows, lastRows int64
+		want           bool
+	}{
+		{rows: 0, lastRows: 0, want: false},
+		{rows: 10, lastRows: 0, want: true}, // Built on an empty table
+		{rows: 1000, lastRows: 1000, want: false},
+		{rows: 1200, lastRows: 1000, want: false},
+		{rows: 1201, lastRows: 1000, want: true},
+		{rows: 500, lastRows: 1000, want: false},
+	}
+	for _, tt := range tests {
+		if got := reindexAdvisable(tt.rows, tt.lastRows); got != tt.want {
+			t.Errorf("reindexAdvisable(%d, %d) = %v, want %v", tt.rows, tt.lastRows, got, tt.want)
+		}
+	}
+}
+
+// TestPostgresStore_Reindex runs against the database in TEST_DATABASE_URL, which must
+// have all migrations applied.
+func TestPostgresStore_Reindex(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	s, err := NewPostgresStore(ctx, databaseURL, StoreOptions{IndexType: IndexTypeIVFFlat})
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	defer s.Close()
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	if err := s.Reindex(ctx); err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	var rows, lastRows int64
+	err = s.pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM issue_history WHERE embedding IS NOT NULL),
+		       COALESCE((SELECT reindexed_rows FROM embedding_meta LIMIT 1), 0)
+	`).Scan(&rows, &lastRows)
+	if err != nil {
+		t.Fatalf("failed to read reindexed rows: %v", err)
+	}
+	if rows > 0 && lastRows != rows {
+		t.Errorf("expected Reindex to record %d rows, got %d", rows, lastRows)
+	}
+
+	// The table has not grown since
+	rebuilt, err := s.reindexIfNeeded(ctx)
+	if err != nil {
+		t.Fatalf("reindexIfNeeded failed: %v", err)
+	}
+	if rebuilt {
+		t.Error("expected no rebuild right after Reindex")
+	}
+
+	// Only one agent rebuilds the index at a time
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, reindexLockID); err != nil {
+		t.Fatalf("failed to take the reindex lock: %v", err)
+	}
+	if err := s.Reindex(ctx); !errors.Is(err, errReindexRunning) {
+		t.Errorf("expected errReindexRunning while another agent holds the lock, got %v", err)
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, reindexLockID); err != nil {
+		t.Fatalf("failed to release the reindex lock: %v", err)
+	}
+
+	// The copy an interrupted rebuild leaves behind is dropped
+	if _, err := s.pool.Exec(ctx, `CREATE INDEX issue_history_embedding_idx_ccnew ON issue_history (id)`); err != nil {
+		t.Fatalf("failed to create leftover index: %v", err)
+	}
+	if err := dropLeftoverIndexes(ctx, s.pool); err != nil {
+		t.Fatalf("dropLeftoverIndexes failed: %v", err)
+	}
+	var leftovers int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM pg_indexes WHERE indexname LIKE 'issue_history_embedding_idx_cc%'`).Scan(&leftovers); err != nil {
+		t.Fatalf("failed to count leftover indexes: %v", err)
+	}
+	if leftovers != 0 {
+		t.Errorf("expected no leftover index, got %d", leftovers)
+	}
+}
+
+func TestNewVectorIndex_Invalid(t *testing.T) {
+	for _, opts := range []StoreOptions{
+		{IndexType: "diskann"},
+		{IVFFlatLists: -1},
+		{IndexType: "hnsw", HNSWM: 48},
+	} {
+		if _, err := newVectorIndex(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultMaxToolResults is the number of recent tool results kept per session by default.
+const DefaultMaxToolResults = 10
+
+// ToolCallRecord is a tool call made during a session and the result it returned.
+type ToolCallRecord struct {
+	ToolName string         `json:"tool_name"`
+	Args     map[string]any `json:"args"`
+	Result   map[string]any `json:"result"`
+	CalledAt time.Time      `json:"called_at"`
+}
+
+// AgentContext is the working memory of a single agent session: short-lived state
+// that only matters while the session is active and is never written to the store.
+// All methods are safe for concurrent use and for use on a nil *AgentContext.
+type AgentContext struct {
+	SessionID string // ID of the session this context belongs to
+
+	mu             sync.Mutex
+	readFiles      []string
+	toolResults    []ToolCallRecord
+	maxToolResults int
+	history        []*genai.Content   // Conversation of the latest model request
+	watchTails     map[string]LogTail // Path -> position up to which watch_file returned the lines of the file
+	lastUsed       time.Time          // When WorkingMemory.Session last returned the context, guarded by WorkingMemory.mu
+}
+
+// RecordReadFile remembers that the file at path was read during the session.
+// Paths are recorded once, in the order they were first read.
+func (c *AgentContext) RecordReadFile(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.readFiles {
+		if p == path {
+			return
+		}
+	}
+	c.readFiles = append(c.readFiles, path)
+}
+
+// ReadFiles returns the paths of the files read during the session.
+func (c *AgentContext) ReadFiles() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.readFiles...)
+}
+
+// RecordToolResult appends a tool call to the session's recent tool results,
+// discarding the oldest record once the configured maximum is exceeded.
+func (c *AgentContext) RecordToolResult(record ToolCallRecord) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.toolResults = append(c.toolResults, record)
+	if limit := c.maxToolResults; limit > 0 && len(c.toolResults) > limit {
+		c.toolResults = append([]ToolCallRecord(nil), c.toolResults[len(c.toolResults)-limit:]...)
+	}
+}
+
+// RecentToolResults returns up to n of the most recent tool calls, oldest first.
+// All recorded calls are returned when n is not positive.
+func (c *AgentContext) RecentToolResults(n int) []ToolCallRecord {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := c.toolResults
+	if n > 0 && len(results) > n {
+		results = results[len(results)-n:]
+	}
+	return append([]ToolCallRecord(nil), results...)
+}
+
+// SetSessionHistory remembers contents as the conversation of the session so far, as
+// sent to the model, replacing the previously remembered one.
+func (c *AgentContext) SetSessionHistory(contents []*genai.Content) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = append([]*genai.Content(nil), contents...)
+}
+
+// SessionHistory returns the conversation of the session last set by SetSessionHistory.
+// The contents are shared and must not be modified.
+func (c *AgentContext) SessionHistory() []*genai.Content {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*genai.Content(nil), c.history...)
+}
+
+// WatchTail returns the position up to which the lines of the file at path were
+// returned by watch_file during the session, and false when the file was not watched.
+func (c *AgentContext) WatchTail(path string) (LogTail, bool) {
+	if c == nil {
+		return LogTail{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tail, ok := c.watchTails[path]
+	return tail, ok
+}
+
+// SetWatchTail records the position up to which the lines of the file at path were
+// returned by watch_file.
+func (c *AgentContext) SetWatchTail(path string, tail LogTail) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchTails == nil {
+		c.watchTails = make(map[string]LogTail)
+	}
+	c.watchTails[path] = tail
+}
+
+// WorkingMemory holds the AgentContext of every active session, keyed by session ID.
+type WorkingMemory struct {
+	mu             sync.Mutex
+	contexts       map[string]*AgentContext
+	maxToolResults int
+}
+
+// NewWorkingMemory creates an empty WorkingMemory whose sessions keep at most
+// maxToolResults recent tool results. DefaultMaxToolResults is used when
+// maxToolResults is not positive.
+func NewWorkingMemory(maxToolResults int) *WorkingMemory {
+	if maxToolResults <= 0 {
+		maxToolResults = DefaultMaxToolResults
+	}
+	return &WorkingMemory{contexts: make(map[string]*AgentContext), maxToolResults: maxToolResults}
+}
+
+// Session returns the AgentContext of the given session, creating it on first use.
+// It returns nil when called on a nil *WorkingMemory, which disables working memory.
+func (w *WorkingMemory) Session(sessionID string) *AgentContext {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c, ok := w.contexts[sessionID]
+	if !ok {
+		c = &AgentContext{SessionID: sessionID, maxToolResults: w.maxToolResults}
+		w.contexts[sessionID] = c
+	}
+	c.lastUsed = time.Now()
+	return c
+}
+
+// Release discards the AgentContext of the given session.
+func (w *WorkingMemory) Release(sessionID string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.contexts, sessionID)
+}
+
+// ReleaseIdle discards the AgentContext of every session that has not been used for
+// maxIdle, and returns the IDs of the sessions discarded. Sessions are not ended
+// explicitly, so this is what bounds the memory held for past sessions.
+func (w *WorkingMemory) ReleaseIdle(maxIdle time.Duration) []string {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var released []string
+	for sessionID, c := range w.contexts {
+		if time.Since(c.lastUsed) >= maxIdle {
+			delete(w.contexts, sessionID)
+			released = append(released, sessionID)
+		}
+	}
+	return released
+}
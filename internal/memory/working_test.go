@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkingMemory_ReleaseIdle(t *testing.T) {
+	wm := NewWorkingMemory(0)
+	wm.Session("idle").RecordReadFile("main.go")
+	wm.Session("active").RecordReadFile("go.mod")
+
+	if released := wm.ReleaseIdle(time.Hour); len(released) != 0 {
+		t.Errorf("expected recently used sessions to be kept, released %v", released)
+	}
+
+	wm.mu.Lock()
+	wm.contexts["idle"].lastUsed = time.Now().Add(-2 * time.Hour)
+	wm.mu.Unlock()
+	if released := wm.ReleaseIdle(time.Hour); len(released) != 1 || released[0] != "idle" {
+		t.Errorf("expected the idle session to be released, released %v", released)
+	}
+	if files := wm.Session("idle").ReadFiles(); len(files) != 0 {
+		t.Errorf("expected a fresh context for the released session, got %v", files)
+	}
+	if files := wm.Session("active").ReadFiles(); len(files) != 1 {
+		t.Errorf("expected the active session to keep its context, got %v", files)
+	}
+
+	var disabled *WorkingMemory
+	if released := disabled.ReleaseIdle(0); len(released) != 0 {
+		t.Errorf("expected a nil WorkingMemory to release nothing, got %v", released)
+	}
+}
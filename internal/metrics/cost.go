@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/genai"
+)
+
+// CostRates are the prices, in US dollars per million tokens, used to estimate the cost
+// of a session.
+type CostRates struct {
+	InputPerMillion     float64 // Prompt tokens of the chat model
+	OutputPerMillion    float64 // Tokens generated by the chat model, including thoughts
+	EmbeddingPerMillion float64 // Tokens of the embedded texts
+}
+
+// DefaultCostRates are the list prices of gemini-2.0-flash and text-embedding-004.
+var DefaultCostRates = CostRates{InputPerMillion: 0.10, OutputPerMillion: 0.40, EmbeddingPerMillion: 0.10}
+
+// SessionUsage is the API usage of a session accumulated by CostTracker.
+type SessionUsage struct {
+	InputTokens     int64
+	OutputTokens    int64
+	EmbeddingCalls  int64
+	EmbeddingTokens int64 // Estimated as a quarter of the length of the embedded texts
+}
+
+// Cost returns the estimated cost of u in US dollars.
+func (u SessionUsage) Cost(rates CostRates) float64 {
+	return (float64(u.InputTokens)*rates.InputPerMillion +
+		float64(u.OutputTokens)*rates.OutputPerMillion +
+		float64(u.EmbeddingTokens)*rates.EmbeddingPerMillion) / 1e6
+}
+
+// CostTracker accumulates the tokens sent to and generated by the chat model, and the
+// embedding requests, of each session until it ends, then logs and records its
+// estimated cost. It is safe for concurrent use.
+type CostTracker struct {
+	rates CostRates
+	store memory.SessionCostStore // Where ended sessions are recorded, nil to only log them
+
+	mu       sync.Mutex
+	sessions map[string]*SessionUsage
+}
+
+// NewCostTracker creates a CostTracker pricing usage at rates and recording the cost of
+// ended sessions in store, which may be nil.
+func NewCostTracker(rates CostRates, store memory.SessionCostStore) *CostTracker {
+	return &CostTracker{rates: rates, store: store, sessions: make(map[string]*SessionUsage)}
+}
+
+// AddUsage adds the token counts of a model response to the usage of sessionID.
+func (t *CostTracker) AddUsage(sessionID string, usage *genai.GenerateContentResponseUsageMetadata) {
+	if usage == nil {
+		return
+	}
+	t.update(sessionID, func(u *SessionUsage) {
+		u.InputTokens += int64(usage.PromptTokenCount) + int64(usage.ToolUsePromptTokenCount)
+		u.OutputTokens += int64(usage.CandidatesTokenCount) + int64(usage.ThoughtsTokenCount)
+	})
+}
+
+// AddEmbedding adds an embedding request for texts to the usage of sessionID.
+func (t *CostTracker) AddEmbedding(sessionID string, texts ...string) {
+	t.update(sessionID, func(u *SessionUsage) {
+		u.EmbeddingCalls++
+		for _, text := range texts {
+			u.EmbeddingTokens += int64((len(text) + 3) / 4)
+		}
+	})
+}
+
+// Usage returns the usage of sessionID accumulated so far.
+func (t *CostTracker) Usage(sessionID string) SessionUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if u, ok := t.sessions[sessionID]; ok {
+		return *u
+	}
+	return SessionUsage{}
+}
+
+// EndSession logs the usage and estimated cost of sessionID, records them in the store
+// and forgets the session. Sessions without usage are ignored.
+func (t *CostTracker) EndSession(ctx context.Context, sessionID string) error {
+	t.mu.Lock()
+	u, ok := t.sessions[sessionID]
+	delete(t.sessions, sessionID)
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	cost := u.Cost(t.rates)
+	slog.InfoContext(ctx, "session cost",
+		slog.String("session_id", sessionID),
+		slog.Int64("input_tokens", u.InputTokens),
+		slog.Int64("output_tokens", u.OutputTokens),
+		slog.Int64("embedding_calls", u.EmbeddingCalls),
+		slog.Float64("cost_usd", cost),
+	)
+	if t.store == nil {
+		return nil
+	}
+	return t.store.SaveSessionCost(ctx, memory.SessionCost{
+		SessionID:       sessionID,
+		InputTokens:     u.InputTokens,
+		OutputTokens:    u.OutputTokens,
+		EmbeddingCalls:  u.EmbeddingCalls,
+		EmbeddingTokens: u.EmbeddingTokens,
+		CostUSD:         cost,
+	})
+}
+
+// EndAll ends every session with usage, see EndSession, e.g. when the agent shuts down.
+func (t *CostTracker) EndAll(ctx context.Context) error {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.sessions))
+	for id := range t.sessions {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+
+	var errs []error
+	for _, id := range ids {
+		errs = append(errs, t.EndSession(ctx, id))
+	}
+	return errors.Join(errs...)
+}
+
+// update calls f with the usage of sessionID, creating it if needed.
+func (t *CostTracker) update(sessionID string, f func(u *SessionUsage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.sessions[sessionID]
+	if !ok {
+		u = &SessionUsage{}
+		t.sessions[sessionID] = u
+	}
+	f(u)
+}
+
+// InstrumentEmbedder wraps embedder so that each request is added to the usage of the
+// session of its context. Only requests made by tools, whose context carries the
+// session ID, are counted. Wrap the embedder below any cache, so that cached embeddings
+// are not counted.
+func (t *CostTracker) InstrumentEmbedder(embedder memory.Embedder) memory.BatchEmbedder {
+	return &costEmbedder{inner: embedder, t: t}
+}
+
+// costEmbedder adds the requests to the wrapped Embedder to the usage tracked by t.
+type costEmbedder struct {
+	inner memory.Embedder
+	t     *CostTracker
+}
+
+func (e *costEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector, err := e.inner.Embed(ctx, text)
+	if err == nil {
+		e.record(ctx, text)
+	}
+	return vector, err
+}
+
+func (e *costEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := memory.EmbedAll(ctx, e.inner, texts)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := e.inner.(memory.BatchEmbedder); ok {
+		e.record(ctx, texts...)
+	} else {
+		// EmbedAll made one request per text
+		for _, text := range texts {
+			e.record(ctx, text)
+		}
+	}
+	return vectors, nil
+}
+
+// record adds a request for texts to the session of ctx, if any.
+func (e *costEmbedder) record(ctx context.Context, texts ...string) {
+	if session, ok := ctx.(interface{ SessionID() string }); ok {
+		e.t.AddEmbedding(session.SessionID(), texts...)
+	}
+}
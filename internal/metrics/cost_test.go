@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/genai"
+)
+
+// sessionContext is a context carrying a session ID, like the context of a tool call.
+type sessionContext struct {
+	context.Context
+	id string
+}
+
+func (c sessionContext) SessionID() string { return c.id }
+
+func TestSessionUsage_Cost(t *testing.T) {
+	usage := SessionUsage{InputTokens: 2_000_000, OutputTokens: 500_000, EmbeddingCalls: 3, EmbeddingTokens: 1_000_000}
+	rates := CostRates{InputPerMillion: 0.10, OutputPerMillion: 0.40, EmbeddingPerMillion: 0.02}
+
+	// 2 * 0.10 + 0.5 * 0.40 + 1 * 0.02
+	if got := usage.Cost(rates); math.Abs(got-0.42) > 1e-9 {
+		t.Errorf("expected $0.42, got $%v", got)
+	}
+	if got := (SessionUsage{}).Cost(DefaultCostRates); got != 0 {
+		t.Errorf("expected no cost without usage, got $%v", got)
+	}
+}
+
+func TestCostTracker_AccumulatesPerSession(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewInMemoryStore()
+	tracker := NewCostTracker(CostRates{InputPerMillion: 1, OutputPerMillion: 2, EmbeddingPerMillion: 4}, store)
+
+	tracker.AddUsage("a", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 20})
+	tracker.AddUsage("a", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 150, CandidatesTokenCount: 10, ThoughtsTokenCount: 5})
+	tracker.AddUsage("a", nil)
+	tracker.AddUsage("b", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 7})
+
+	embedder := tracker.InstrumentEmbedder(fixedEmbedder{})
+	if _, err := embedder.Embed(sessionContext{ctx, "a"}, "12345678"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if _, err := embedder.Embed(ctx, "not made by a tool"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	// fixedEmbedder has no BatchEmbed, so each text is a request of its own
+	if _, err := embedder.BatchEmbed(sessionContext{ctx, "a"}, []string{"abcd", "efghi"}); err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+
+	want := SessionUsage{InputTokens: 250, OutputTokens: 35, EmbeddingCalls: 3, EmbeddingTokens: 2 + 1 + 2}
+	if got := tracker.Usage("a"); got != want {
+		t.Errorf("expected usage %+v, got %+v", want, got)
+	}
+	if got := tracker.Usage("b"); got != (SessionUsage{InputTokens: 7}) {
+		t.Errorf("expected the usage of each session to be separate, got %+v", got)
+	}
+
+	if err := tracker.EndSession(ctx, "a"); err != nil {
+		t.Fatalf("EndSession failed: %v", err)
+	}
+	if got := tracker.Usage("a"); got != (SessionUsage{}) {
+		t.Errorf("expected an ended session to be forgotten, got %+v", got)
+	}
+	if err := tracker.EndSession(ctx, "unknown"); err != nil {
+		t.Fatalf("EndSession failed: %v", err)
+	}
+	costs, err := store.ListSessionCosts(ctx, "", 10)
+	if err != nil {
+		t.Fatalf("ListSessionCosts failed: %v", err)
+	}
+	if len(costs) != 1 {
+		t.Fatalf("expected only the session with usage to be recorded, got %+v", costs)
+	}
+	got := costs[0]
+	wantCost := (250*1 + 35*2 + 5*4) / 1e6
+	if got.SessionID != "a" || got.InputTokens != 250 || got.OutputTokens != 35 || got.EmbeddingCalls != 3 || math.Abs(got.CostUSD-wantCost) > 1e-12 {
+		t.Errorf("unexpected recorded cost %+v, want $%v", got, wantCost)
+	}
+
+	if err := tracker.EndAll(ctx); err != nil {
+		t.Fatalf("EndAll failed: %v", err)
+	}
+	if costs, _ := store.ListSessionCosts(ctx, "b", 10); len(costs) != 1 || costs[0].InputTokens != 7 {
+		t.Errorf("expected EndAll to record the remaining session, got %+v", costs)
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// HealthCheckTimeout bounds each check of HealthHandler.
+const HealthCheckTimeout = 5 * time.Second
+
+// healthCheckText is embedded by HealthHandler to check the embedder.
+const healthCheckText = "health check"
+
+// Pinger checks that a dependency is reachable, e.g. memory.Store.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthChecks are the dependencies checked by HealthHandler.
+type HealthChecks struct {
+	Store    Pinger          // Pinged
+	Embedder memory.Embedder // Asked to embed a short text
+	WorkDir  string          // Must be a readable directory
+}
+
+// HealthStatus is the response body of HealthHandler.
+type HealthStatus struct {
+	Status string            `json:"status"` // "ok", or "unavailable" when a check failed
+	Checks map[string]string `json:"checks"` // "ok" or the error of each check, by component
+}
+
+// HealthHandler returns an HTTP handler checking the store, the embedder and the
+// working directory of checks at the same time, each within HealthCheckTimeout. It
+// responds with 200 and a HealthStatus when all of them pass, and with 503 and the
+// errors of the failing components otherwise.
+func HealthHandler(checks HealthChecks) http.Handler {
+	components := map[string]func(ctx context.Context) error{
+		"store": checks.Store.Ping,
+		"embedder": func(ctx context.Context) error {
+			_, err := checks.Embedder.Embed(ctx, healthCheckText)
+			return err
+		},
+		"workdir": func(ctx context.Context) error {
+			return checkReadableDir(checks.WorkDir)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := HealthStatus{Status: "ok", Checks: make(map[string]string, len(components))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range components {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(r.Context(), HealthCheckTimeout)
+				defer cancel()
+				result := "ok"
+				if err := check(ctx); err != nil {
+					result = err.Error()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				status.Checks[name] = result
+				if result != "ok" {
+					status.Status = "unavailable"
+				}
+			}()
+		}
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
+
+// checkReadableDir returns an error unless dir is a directory whose entries can be listed.
+func checkReadableDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
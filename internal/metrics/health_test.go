@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pinger returns err from Ping.
+type pinger struct{ err error }
+
+func (p pinger) Ping(ctx context.Context) error { return p.err }
+
+// fixedEmbedder embeds every text as the same vector.
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+// checkHealth requests /healthz from a server for checks and returns the response
+// status code and body.
+func checkHealth(t *testing.T, checks HealthChecks) (int, HealthStatus) {
+	t.Helper()
+	server := httptest.NewServer(HealthHandler(checks))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("health check request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var status HealthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode health status: %v", err)
+	}
+	return resp.StatusCode, status
+}
+
+func TestHealthHandler(t *testing.T) {
+	checks := HealthChecks{Store: pinger{}, Embedder: fixedEmbedder{}, WorkDir: t.TempDir()}
+	code, status := checkHealth(t, checks)
+	if code != http.StatusOK || status.Status != "ok" || len(status.Checks) != 3 {
+		t.Errorf("expected 200 with three passing checks, got %d %+v", code, status)
+	}
+
+	checks.Store = pinger{err: errors.New("connection refused")}
+	checks.WorkDir = "/nonexistent/work/dir"
+	code, status = checkHealth(t, checks)
+	if code != http.StatusServiceUnavailable || status.Status != "unavailable" {
+		t.Errorf("expected 503, got %d %+v", code, status)
+	}
+	if status.Checks["store"] != "connection refused" || status.Checks["workdir"] == "ok" || status.Checks["embedder"] != "ok" {
+		t.Errorf("expected the store and workdir checks to fail, got %+v", status.Checks)
+	}
+}
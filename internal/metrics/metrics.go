@@ -0,0 +1,216 @@
+// Package metrics exposes Prometheus metrics describing the health of the agent.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/adk/model"
+)
+
+// DefaultPort is the port the metrics server listens on when METRICS_PORT is not set.
+const DefaultPort = 9090
+
+// shutdownTimeout bounds how long Serve waits for in-flight scrapes on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// CacheStatser reports the statistics of an embedding cache, e.g. *memory.CachedEmbedder.
+type CacheStatser interface {
+	CacheStats() memory.CacheStats
+}
+
+// Metrics holds the collectors of the agent and the registry they are registered in.
+type Metrics struct {
+	registry             *prometheus.Registry
+	vectorSearchDuration prometheus.Histogram
+	experienceSaves      prometheus.Counter
+	llmCallDuration      prometheus.Histogram
+}
+
+// New creates the agent's metrics in a new registry. The embedding cache counters are
+// read from cache on every scrape; they stay at zero when cache is nil.
+func New(cache CacheStatser) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		vectorSearchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "vector_search_duration_seconds",
+			Help:    "Duration of vector similarity searches in the experience store.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		experienceSaves: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "experience_save_total",
+			Help: "Number of experiences saved to the store.",
+		}),
+		llmCallDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "llm_call_duration_seconds",
+			Help:    "Duration of LLM calls, until the last response is received.",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 40, 80},
+		}),
+	}
+
+	cacheStats := func() memory.CacheStats {
+		if cache == nil {
+			return memory.CacheStats{}
+		}
+		return cache.CacheStats()
+	}
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "embedding_cache_hit_total",
+			Help: "Number of embeddings answered from the embedding cache.",
+		}, func() float64 { return float64(cacheStats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "embedding_cache_miss_total",
+			Help: "Number of embeddings requested from the embedding API.",
+		}, func() float64 { return float64(cacheStats().Misses) }),
+		m.vectorSearchDuration,
+		m.experienceSaves,
+		m.llmCallDuration,
+	)
+	return m
+}
+
+// Handler returns an HTTP handler serving the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve serves the metrics at /metrics on addr until ctx is cancelled, then shuts the
+// server down gracefully. When health is not nil it is served at /healthz as well.
+// It returns nil after a shutdown caused by ctx.
+func (m *Metrics) Serve(ctx context.Context, addr string, health http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	if health != nil {
+		mux.Handle("/healthz", health)
+	}
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down metrics server: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
+
+// InstrumentStore wraps store so that vector searches are timed and saved experiences
+// are counted. Other methods are passed through unchanged.
+func (m *Metrics) InstrumentStore(store memory.Store) memory.Store {
+	return &instrumentedStore{Store: store, m: m}
+}
+
+// InstrumentLLM wraps llm so that the duration of each call is recorded.
+func (m *Metrics) InstrumentLLM(llm model.LLM) model.LLM {
+	return &instrumentedLLM{LLM: llm, m: m}
+}
+
+// instrumentedStore updates the store metrics of m around calls to the embedded Store.
+type instrumentedStore struct {
+	memory.Store
+	m *Metrics
+}
+
+func (s *instrumentedStore) SearchSimilarIssues(ctx context.Context, query string, queryVector []float32, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]memory.Experience, error) {
+	defer s.m.observeSince(s.m.vectorSearchDuration, time.Now())
+	return s.Store.SearchSimilarIssues(ctx, query, queryVector, limit, minSimilarity, projectID, includeGlobal, tags)
+}
+
+func (s *instrumentedStore) SearchSimilarIssuesPage(ctx context.Context, query string, queryVector []float32, offset, limit int, minSimilarity float32, projectID string, includeGlobal bool, tags []string) ([]memory.Experience, int64, error) {
+	defer s.m.observeSince(s.m.vectorSearchDuration, time.Now())
+	return s.Store.SearchSimilarIssuesPage(ctx, query, queryVector, offset, limit, minSimilarity, projectID, includeGlobal, tags)
+}
+
+func (s *instrumentedStore) SearchByUser(ctx context.Context, userID, query string, queryVector []float32, limit int) ([]memory.Experience, error) {
+	defer s.m.observeSince(s.m.vectorSearchDuration, time.Now())
+	return s.Store.SearchByUser(ctx, userID, query, queryVector, limit)
+}
+
+func (s *instrumentedStore) HybridSearch(ctx context.Context, query string, queryVector []float32, limit int, alpha float32, projectID string, includeGlobal bool) ([]memory.Experience, error) {
+	defer s.m.observeSince(s.m.vectorSearchDuration, time.Now())
+	return s.Store.HybridSearch(ctx, query, queryVector, limit, alpha, projectID, includeGlobal)
+}
+
+func (s *instrumentedStore) SaveExperience(ctx context.Context, userID, pattern, cause, solution string, tags []string, vector []float32) error {
+	err := s.Store.SaveExperience(ctx, userID, pattern, cause, solution, tags, vector)
+	s.m.countSaves(1, err)
+	return err
+}
+
+func (s *instrumentedStore) BatchSaveExperiences(ctx context.Context, items []memory.ExperienceInput) error {
+	err := s.Store.BatchSaveExperiences(ctx, items)
+	s.m.countSaves(len(items), err)
+	return err
+}
+
+func (s *instrumentedStore) ImportExperience(ctx context.Context, exp memory.Experience, vector []float32) error {
+	err := s.Store.ImportExperience(ctx, exp, vector)
+	s.m.countSaves(1, err)
+	return err
+}
+
+func (s *instrumentedStore) UpsertExperience(ctx context.Context, pattern, cause, solution string, vector []float32) error {
+	err := s.Store.UpsertExperience(ctx, pattern, cause, solution, vector)
+	s.m.countSaves(1, err)
+	return err
+}
+
+func (s *instrumentedStore) SaveSourcedExperience(ctx context.Context, source, signature, pattern, cause, solution string, vector []float32) error {
+	err := s.Store.SaveSourcedExperience(ctx, source, signature, pattern, cause, solution, vector)
+	s.m.countSaves(1, err)
+	return err
+}
+
+func (s *instrumentedStore) ReplaceSourcedExperiences(ctx context.Context, source, signaturePrefix, signature string, items []memory.ExperienceInput) error {
+	err := s.Store.ReplaceSourcedExperiences(ctx, source, signaturePrefix, signature, items)
+	s.m.countSaves(len(items), err)
+	return err
+}
+
+// instrumentedLLM records the duration of calls to the embedded LLM in m.
+type instrumentedLLM struct {
+	model.LLM
+	m *Metrics
+}
+
+func (l *instrumentedLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		defer l.m.observeSince(l.m.llmCallDuration, time.Now())
+		for resp, err := range l.LLM.GenerateContent(ctx, req, stream) {
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
+
+// observeSince records the time elapsed since start in h.
+func (m *Metrics) observeSince(h prometheus.Histogram, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// countSaves adds n saved experiences unless the save failed.
+func (m *Metrics) countSaves(n int, err error) {
+	if err == nil {
+		m.experienceSaves.Add(float64(n))
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+)
+
+// fakeCache reports fixed cache statistics.
+type fakeCache struct{ stats memory.CacheStats }
+
+func (c fakeCache) CacheStats() memory.CacheStats { return c.stats }
+
+// scrape returns the body served by the metrics handler of m.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to scrape metrics: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := New(fakeCache{memory.CacheStats{Hits: 3, Misses: 2}})
+	store := m.InstrumentStore(memory.NewInMemoryStore())
+
+	if err := store.SaveExperience(ctx, "", "nil pointer", "cause", "solution", nil, []float32{1}); err != nil {
+		t.Fatalf("SaveExperience failed: %v", err)
+	}
+	// Duplicates are rejected and must not be counted
+	_ = store.SaveExperience(ctx, "", "nil pointer", "cause", "solution", nil, []float32{1})
+	if _, err := store.SearchSimilarIssues(ctx, "", []float32{1}, 3, 0.5, "", false, nil); err != nil {
+		t.Fatalf("SearchSimilarIssues failed: %v", err)
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"embedding_cache_hit_total 3",
+		"embedding_cache_miss_total 2",
+		"vector_search_duration_seconds_count 1",
+		"experience_save_total 1",
+		"llm_call_duration_seconds_count 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestServe_StopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New(nil).Serve(ctx, "127.0.0.1:0", nil) }()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package otel configures OpenTelemetry tracing for the agent.
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracerName is the instrumentation scope of the spans recorded by the agent.
+const TracerName = "github.com/easeaico/adk-memory-agent"
+
+// serviceName is reported as service.name on every exported span.
+const serviceName = "legacy-code-hunter"
+
+// NewTracerProvider returns a tracer provider that batches spans to the OTLP/HTTP
+// endpoint, e.g. "http://localhost:4318". When endpoint is empty it returns a no-op
+// provider, so tracing costs nothing unless it is configured.
+// The returned shutdown function flushes pending spans and must be called on exit.
+func NewTracerProvider(ctx context.Context, endpoint string) (trace.TracerProvider, func(context.Context) error, error) {
+	if endpoint == "" {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	return provider, provider.Shutdown, nil
+}
@@ -0,0 +1,20 @@
+package otel
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewTracerProvider_NoEndpoint(t *testing.T) {
+	provider, shutdown, err := NewTracerProvider(context.Background(), "")
+	if err != nil {
+		t.Fatalf("NewTracerProvider failed: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "test")
+	defer span.End()
+	if span.IsRecording() {
+		t.Error("expected a no-op span when no endpoint is configured")
+	}
+}
@@ -0,0 +1,85 @@
+// Package retry retries calls to external APIs that fail with transient errors.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// DefaultMaxAttempts is the number of attempts made when Policy.MaxAttempts is not set.
+	DefaultMaxAttempts = 3
+	// DefaultInitialDelay is the delay before the first retry when Policy.InitialDelay is not set.
+	DefaultInitialDelay = 500 * time.Millisecond
+	// maxDelay caps the delay between two attempts.
+	maxDelay = 30 * time.Second
+)
+
+// Policy configures how often and how quickly a call is retried.
+type Policy struct {
+	MaxAttempts  int           // Attempts made in total, including the first (default 3)
+	InitialDelay time.Duration // Delay before the first retry, doubled for every further one (default 500ms)
+}
+
+// Do calls fn until it succeeds, returns an error that is not Retryable, or has been
+// called maxAttempts times, and returns its last error. See Policy.Do.
+func Do(ctx context.Context, maxAttempts int, fn func() error) error {
+	return Policy{MaxAttempts: maxAttempts}.Do(ctx, fn)
+}
+
+// Do calls fn until it succeeds, returns an error that is not Retryable, or has been
+// called p.MaxAttempts times, and returns its last error. Between attempts it waits an
+// exponentially growing delay, capped at 30 seconds, of which a random half is
+// jitter so that clients failing together do not retry together. It stops early with
+// ctx's error when ctx is done.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+	delay := p.InitialDelay
+	if delay <= 0 {
+		delay = DefaultInitialDelay
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt == attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+
+		timer := time.NewTimer(delay/2 + rand.N(delay/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, maxDelay)
+	}
+}
+
+// Retryable reports whether err is likely to be transient: an API error with HTTP
+// status 429 or 5xx, a timeout, or a network error. Other API errors, such as 400 for
+// an invalid request, are permanent.
+func Retryable(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// fastPolicy retries without waiting noticeably.
+var fastPolicy = Policy{MaxAttempts: 3, InitialDelay: time.Millisecond}
+
+func TestDo_SucceedsOnThirdAttempt(t *testing.T) {
+	calls := 0
+	var result string
+	err := fastPolicy.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return genai.APIError{Code: http.StatusServiceUnavailable, Message: "overloaded"}
+		}
+		result = "embedding"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if calls != 3 || result != "embedding" {
+		t.Errorf("expected the result of the third attempt, got %q after %d calls", result, calls)
+	}
+}
+
+func TestDo_StopsOnPermanentError(t *testing.T) {
+	calls := 0
+	err := fastPolicy.Do(context.Background(), func() error {
+		calls++
+		return genai.APIError{Code: http.StatusBadRequest, Message: "invalid argument"}
+	})
+	if calls != 1 {
+		t.Errorf("expected a single attempt for a permanent error, got %d", calls)
+	}
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		t.Errorf("expected the API error to be returned, got %v", err)
+	}
+}
+
+func TestDo_GivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), 2, func() error {
+		calls++
+		return genai.APIError{Code: http.StatusTooManyRequests}
+	})
+	if calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+	if !Retryable(err) {
+		t.Errorf("expected the last error to be wrapped, got %v", err)
+	}
+}
+
+func TestDo_StopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Policy{MaxAttempts: 5, InitialDelay: time.Hour}.Do(ctx, func() error {
+		calls++
+		cancel()
+		return genai.APIError{Code: http.StatusServiceUnavailable}
+	})
+	if calls != 1 || err == nil {
+		t.Errorf("expected to stop after the context was cancelled, got %d calls and %v", calls, err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{genai.APIError{Code: http.StatusTooManyRequests}, true},
+		{genai.APIError{Code: http.StatusServiceUnavailable}, true},
+		{fmt.Errorf("embed: %w", genai.APIError{Code: http.StatusInternalServerError}), true},
+		{genai.APIError{Code: http.StatusBadRequest}, false},
+		{genai.APIError{Code: http.StatusForbidden}, false},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("invalid response"), false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/audit"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// auditHandler wraps handler so that every call is recorded in logger, whether it
+// succeeds or not. Failures to write the audit log are logged and do not fail the call.
+func auditHandler[TArgs, TResults any](logger *audit.Logger, name string, handler functiontool.Func[TArgs, TResults]) functiontool.Func[TArgs, TResults] {
+	return func(ctx tool.Context, args TArgs) (TResults, error) {
+		start := time.Now()
+		result, err := handler(ctx, args)
+
+		entry := audit.Entry{
+			Timestamp:  start,
+			ToolName:   name,
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if ctx != nil {
+			entry.SessionID, entry.UserID = ctx.SessionID(), ctx.UserID()
+		}
+		if argsJSON, jsonErr := json.Marshal(args); jsonErr == nil {
+			entry.ArgsJSON = string(argsJSON)
+		}
+		entry.Success = err == nil
+		if resultJSON, jsonErr := json.Marshal(result); jsonErr == nil {
+			sum := sha256.Sum256(resultJSON)
+			entry.ResultHash = hex.EncodeToString(sum[:])
+			// Tools report failures in the Success field of their result
+			var reported struct {
+				Success *bool `json:"success"`
+			}
+			if json.Unmarshal(resultJSON, &reported) == nil && reported.Success != nil {
+				entry.Success = entry.Success && *reported.Success
+			}
+		}
+
+		if logErr := logger.Log(entry); logErr != nil {
+			slog.WarnContext(commandContext(ctx), "failed to audit tool call", slog.String("tool", name), slog.Any("error", logErr))
+		}
+		return result, err
+	}
+}
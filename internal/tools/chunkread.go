@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultChunkSize is the chunk size of chunk_read_file when ChunkSize is not set.
+	defaultChunkSize = 8000
+	// defaultChunkOverlap is the overlap of chunk_read_file when OverlapSize is not set.
+	defaultChunkOverlap = 200
+)
+
+// Markers around the text a chunk repeats from the previous one.
+const (
+	overlapStartMarker = "// [overlap from previous chunk]"
+	overlapEndMarker   = "// [end of overlap]"
+)
+
+// ChunkReadFileArgs is the input for chunk_read_file tool.
+type ChunkReadFileArgs struct {
+	Filepath    string `json:"filepath"`               // Path to the file to read (relative to WorkDir, absolute, or "@alias/path" in a mount)
+	ChunkSize   int    `json:"chunk_size,omitempty"`   // Maximum bytes of file content per chunk (default 8000, at most 50000)
+	OverlapSize int    `json:"overlap_size,omitempty"` // Bytes of each chunk repeated at the start of the next (default 200, at most half of ChunkSize)
+	ChunkIndex  int    `json:"chunk_index,omitempty"`  // Chunk to return, starting at 0
+}
+
+// ChunkReadFileResult is the output for chunk_read_file tool.
+type ChunkReadFileResult struct {
+	Success     bool   `json:"success"`                // Whether the operation succeeded
+	Data        string `json:"data,omitempty"`         // Content of the chunk, with the overlap marked
+	ChunkIndex  int    `json:"chunk_index"`            // Index of the returned chunk
+	TotalChunks int    `json:"total_chunks,omitempty"` // Number of chunks of the file with these sizes
+	StartOffset int    `json:"start_offset"`           // Byte offset in the file of the chunk's first byte
+	EndOffset   int    `json:"end_offset,omitempty"`   // Byte offset in the file after the chunk's last byte
+	Error       string `json:"error,omitempty"`        // Error message if the operation failed
+}
+
+// chunkBounds is the byte range of a chunk in the file, and the start of the part
+// repeated from the previous chunk.
+type chunkBounds struct {
+	start, overlapEnd, end int
+}
+
+// createChunkReadFileTool creates the chunk_read_file tool.
+// This tool splits a file too large for the model's context into chunks of about
+// ChunkSize bytes, each starting with the last OverlapSize bytes of the previous one
+// so that code cut at a chunk boundary can be read as a whole, and returns one of them.
+func createChunkReadFileTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ChunkReadFileArgs) (ChunkReadFileResult, error) {
+		if args.Filepath == "" {
+			return ChunkReadFileResult{Success: false, Error: "filepath is required"}, nil
+		}
+		size := args.ChunkSize
+		if size <= 0 {
+			size = defaultChunkSize
+		}
+		if size > maxReadBytes {
+			return ChunkReadFileResult{Success: false, Error: fmt.Sprintf("chunk_size must be at most %d", maxReadBytes)}, nil
+		}
+		overlap := args.OverlapSize
+		if overlap <= 0 {
+			overlap = min(defaultChunkOverlap, size/2)
+		}
+		if overlap > size/2 {
+			return ChunkReadFileResult{Success: false, Error: fmt.Sprintf("overlap_size must be at most half of chunk_size (%d)", size/2)}, nil
+		}
+
+		absPath, err := resolvePath(cfg, args.Filepath)
+		if err != nil {
+			return ChunkReadFileResult{Success: false, Error: err.Error()}, nil
+		}
+		if err := checkExtension(cfg, absPath); err != nil {
+			return ChunkReadFileResult{Success: false, Error: err.Error()}, nil
+		}
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return ChunkReadFileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+		}
+
+		chunks := splitChunks(content, size, overlap)
+		if args.ChunkIndex < 0 || args.ChunkIndex >= len(chunks) {
+			return ChunkReadFileResult{Success: false, TotalChunks: len(chunks), Error: fmt.Sprintf("chunk_index %d is out of range: the file has %d chunks, numbered from 0", args.ChunkIndex, len(chunks))}, nil
+		}
+		absWorkDir, _ := filepath.Abs(cfg.WorkDir)
+		if strings.HasPrefix(args.Filepath, mountPrefix) {
+			sessionContext(cfg, ctx).RecordReadFile(args.Filepath)
+		} else if relPath, err := filepath.Rel(absWorkDir, absPath); err == nil {
+			sessionContext(cfg, ctx).RecordReadFile(relPath)
+		}
+
+		chunk := chunks[args.ChunkIndex]
+		return ChunkReadFileResult{
+			Success:     true,
+			Data:        chunkText(content, chunk),
+			ChunkIndex:  args.ChunkIndex,
+			TotalChunks: len(chunks),
+			StartOffset: chunk.start,
+			EndOffset:   chunk.end,
+		}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "chunk_read_file",
+		Description: "分块读取放不进上下文的大文件。文件被切分为约 chunk_size 字节（默认 8000，最大 50000）的块，块尽量在行尾切分，每块开头重复上一块末尾约 overlap_size 字节（默认 200），重复部分以 " + overlapStartMarker + " 和 " + overlapEndMarker + " 标出。返回第 chunk_index 块（从 0 开始）和总块数 total_chunks，依次递增 chunk_index 即可读完整个文件。" + mountsDescription(cfg),
+	}, handler)
+}
+
+// splitChunks splits content into chunks of at most size bytes, each but the first
+// starting with at most overlap bytes of the end of the previous one. Chunks end after a
+// newline, and overlaps start at the beginning of a line, unless there is no newline in
+// range; neither cuts a UTF-8 sequence. overlap must be at most half of size.
+func splitChunks(content []byte, size, overlap int) []chunkBounds {
+	chunks := []chunkBounds{{}}
+	for {
+		c := &chunks[len(chunks)-1]
+		c.end = len(content)
+		if c.end-c.start > size {
+			// Leave more than overlap bytes after the previous overlap so the next chunk advances
+			c.end = lineCut(content, c.overlapEnd+max(overlap, 1), c.start+size)
+		}
+		if c.end == len(content) {
+			return chunks
+		}
+		next := c.end
+		if overlap > 0 {
+			next = lineStart(content, c.end-overlap, c.end)
+		}
+		chunks = append(chunks, chunkBounds{start: next, overlapEnd: c.end})
+	}
+}
+
+// lineCut returns the position after the last newline in content[low:high], or high
+// moved back to the start of a UTF-8 sequence when there is none.
+func lineCut(content []byte, low, high int) int {
+	for i := high; i > low; i-- {
+		if content[i-1] == '\n' {
+			return i
+		}
+	}
+	for high > low && !utf8.RuneStart(content[high]) {
+		high--
+	}
+	return high
+}
+
+// lineStart returns the first position in content[low:high] that starts a line, or
+// low moved forward to the start of a UTF-8 sequence when there is none.
+func lineStart(content []byte, low, high int) int {
+	for i := low; i < high; i++ {
+		if content[i-1] == '\n' {
+			return i
+		}
+	}
+	for low < high && !utf8.RuneStart(content[low]) {
+		low++
+	}
+	return low
+}
+
+// chunkText returns the text of chunk with its overlap, if any, between
+// overlapStartMarker and overlapEndMarker lines.
+func chunkText(content []byte, chunk chunkBounds) string {
+	if chunk.overlapEnd <= chunk.start {
+		return string(content[chunk.start:chunk.end])
+	}
+	var sb strings.Builder
+	sb.WriteString(overlapStartMarker + "\n")
+	sb.Write(content[chunk.start:chunk.overlapEnd])
+	if content[chunk.overlapEnd-1] != '\n' {
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(overlapEndMarker + "\n")
+	sb.Write(content[chunk.overlapEnd:chunk.end])
+	return sb.String()
+}
@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// maxCodeSearchMatches is the number of declarations returned by code_search.
+	maxCodeSearchMatches = 50
+	// maxSignatureLength is the longest signature returned in full; longer ones are truncated.
+	maxSignatureLength = 300
+)
+
+// Declaration kinds accepted by code_search.
+const (
+	declKindFunc      = "func"
+	declKindType      = "type"
+	declKindVar       = "var"
+	declKindConst     = "const"
+	declKindInterface = "interface"
+)
+
+// errCodeSearchLimit stops the directory walk once enough declarations have been collected.
+var errCodeSearchLimit = errors.New("code search limit reached")
+
+// CodeSearchArgs is the input for code_search tool.
+type CodeSearchArgs struct {
+	Symbol string `json:"symbol"`         // Name of the declared identifier, e.g. "NewServer"; methods are found by their name
+	Kind   string `json:"kind,omitempty"` // func, type (including interfaces), interface, var or const; empty for all kinds
+}
+
+// Declaration is a Go declaration found by code_search.
+type Declaration struct {
+	File      string `json:"file"`      // File path relative to WorkDir
+	Line      int    `json:"line"`      // Line of the declared name
+	Kind      string `json:"kind"`      // func, type, interface, var or const
+	Signature string `json:"signature"` // Declaration without body, e.g. "func (s *Server) Start(ctx context.Context) error"
+}
+
+// CodeSearchResult is the output for code_search tool.
+type CodeSearchResult struct {
+	Success   bool          `json:"success"`             // Whether the operation succeeded
+	Matches   []Declaration `json:"matches,omitempty"`   // Matching declarations in file and line order
+	Truncated bool          `json:"truncated,omitempty"` // Whether more declarations exist than were returned
+	Error     string        `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createCodeSearchTool creates the code_search tool.
+// This tool parses the Go files under the working directory and returns the
+// declarations of a symbol, which tells the agent what a name is and where it is
+// defined rather than every place it occurs. Hidden directories and files that do
+// not parse are skipped, and files are subject to the same extension checks as
+// read_file_content.
+func createCodeSearchTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args CodeSearchArgs) (CodeSearchResult, error) {
+		if args.Symbol == "" {
+			return CodeSearchResult{Success: false, Error: "symbol is required"}, nil
+		}
+		switch args.Kind {
+		case "", declKindFunc, declKindType, declKindVar, declKindConst, declKindInterface:
+		default:
+			return CodeSearchResult{Success: false, Error: fmt.Sprintf("unknown kind %q, expected func, type, var, const or interface", args.Kind)}, nil
+		}
+
+		root, err := filepath.Abs(cfg.WorkDir)
+		if err != nil {
+			return CodeSearchResult{Success: false, Error: fmt.Sprintf("invalid working directory: %v", err)}, nil
+		}
+
+		matches := []Declaration{}
+		truncated := false
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root {
+					return err
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || !strings.HasSuffix(d.Name(), ".go") {
+				return nil
+			}
+			if err := checkExtension(cfg, path); err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				rel = path
+			}
+			for _, decl := range findDeclarations(path, filepath.ToSlash(rel), args.Symbol, args.Kind) {
+				if len(matches) >= maxCodeSearchMatches {
+					truncated = true
+					return errCodeSearchLimit
+				}
+				matches = append(matches, decl)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errCodeSearchLimit) {
+			if errors.Is(err, ErrUnsupportedFileType) {
+				return CodeSearchResult{Success: false, Error: err.Error()}, nil
+			}
+			return CodeSearchResult{Success: false, Error: fmt.Sprintf("failed to search files: %v", err)}, nil
+		}
+
+		return CodeSearchResult{Success: true, Matches: matches, Truncated: truncated}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "code_search",
+		Description: "解析工作目录中的 Go 文件，查找某个符号的声明位置和签名。kind 可选 func、type、var、const 或 interface（仅接口类型），不填则查找所有类型的声明。与 grep_in_files 不同，只返回定义而非所有出现的位置，适合理解遗留代码的结构。最多返回 50 条结果。",
+	}, handler)
+}
+
+// findDeclarations parses the Go file at path and returns its top-level declarations of
+// symbol of the given kind, or of any kind when kind is empty. Files that do not parse
+// or cannot be read have no declarations.
+func findDeclarations(path, rel, symbol, kind string) []Declaration {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var found []Declaration
+	add := func(name *ast.Ident, declKind, signature string) {
+		found = append(found, Declaration{
+			File:      rel,
+			Line:      fset.Position(name.Pos()).Line,
+			Kind:      declKind,
+			Signature: truncateString(signature, maxSignatureLength),
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name == symbol && (kind == "" || kind == declKindFunc) {
+				add(d.Name, declKindFunc, funcSignature(fset, content, d))
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					declKind := declKindType
+					if _, ok := s.Type.(*ast.InterfaceType); ok {
+						declKind = declKindInterface
+					}
+					if s.Name.Name == symbol && (kind == "" || kind == declKindType || kind == declKind) {
+						add(s.Name, declKind, typeSignature(s))
+					}
+				case *ast.ValueSpec:
+					declKind := d.Tok.String()
+					if kind != "" && kind != declKind {
+						continue
+					}
+					for _, name := range s.Names {
+						if name.Name == symbol {
+							add(name, declKind, valueSignature(fset, declKind, name, s))
+						}
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// typeSignature returns the declaration of ts without the fields of structs or the
+// methods of interfaces, e.g. "type Server struct" or "type ID = int".
+func typeSignature(ts *ast.TypeSpec) string {
+	name := ts.Name.Name
+	if ts.TypeParams != nil {
+		params := make([]string, 0, len(ts.TypeParams.List))
+		for _, field := range ts.TypeParams.List {
+			for _, n := range field.Names {
+				params = append(params, n.Name+" "+types.ExprString(field.Type))
+			}
+		}
+		name += "[" + strings.Join(params, ", ") + "]"
+	}
+	switch ts.Type.(type) {
+	case *ast.StructType:
+		return "type " + name + " struct"
+	case *ast.InterfaceType:
+		return "type " + name + " interface"
+	}
+	if ts.Assign.IsValid() {
+		return "type " + name + " = " + types.ExprString(ts.Type)
+	}
+	return "type " + name + " " + types.ExprString(ts.Type)
+}
+
+// valueSignature returns the declaration of name in the var or const spec s, e.g.
+// "var ErrNotFound = errors.New(\"not found\")" or "const maxRetries int".
+func valueSignature(fset *token.FileSet, declKind string, name *ast.Ident, s *ast.ValueSpec) string {
+	signature := declKind + " " + name.Name
+	if s.Type != nil {
+		signature += " " + types.ExprString(s.Type)
+	}
+	for i, n := range s.Names {
+		if n == name && i < len(s.Values) {
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, fset, s.Values[i]); err == nil {
+				signature += " = " + buf.String()
+			}
+		}
+	}
+	return signature
+}
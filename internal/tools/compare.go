@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// maxCompareDiffBytes caps the size of the diff returned by compare_files.
+	maxCompareDiffBytes = 30 * 1024
+	// maxCompareFileSize is the largest file compare_files reads.
+	maxCompareFileSize = 1 << 20
+	// maxCompareEdits is the largest number of added and removed lines compare_files
+	// looks for; the diff algorithm needs memory quadratic in it.
+	maxCompareEdits = 2000
+	// defaultContextLines is the number of unchanged lines shown around each change.
+	defaultContextLines = 3
+	// maxContextLines is the largest number of context lines compare_files accepts.
+	maxContextLines = 20
+)
+
+// CompareFilesArgs is the input for compare_files tool.
+type CompareFilesArgs struct {
+	FileA        string `json:"file_a"`                  // Original file (relative to WorkDir or absolute)
+	FileB        string `json:"file_b"`                  // Changed file (relative to WorkDir or absolute)
+	ContextLines int    `json:"context_lines,omitempty"` // Unchanged lines shown around each change (default 3, at most 20)
+}
+
+// CompareResult is the output for compare_files tool.
+type CompareResult struct {
+	Success      bool   `json:"success"`             // Whether the operation succeeded
+	Diff         string `json:"diff,omitempty"`      // Unified diff from FileA to FileB, empty when the files are equal
+	AddedLines   int    `json:"added_lines"`         // Number of lines only in FileB
+	RemovedLines int    `json:"removed_lines"`       // Number of lines only in FileA
+	Truncated    bool   `json:"truncated,omitempty"` // Whether the diff was cut at 30KB
+	Error        string `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createCompareFilesTool creates the compare_files tool.
+// This tool returns a unified diff between two files, such as two versions of a
+// config file or a generated file, which git_diff cannot compare. Both paths are
+// subject to the same checks as read_file_content.
+func createCompareFilesTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args CompareFilesArgs) (CompareResult, error) {
+		if args.FileA == "" || args.FileB == "" {
+			return CompareResult{Success: false, Error: "file_a and file_b are required"}, nil
+		}
+		if args.ContextLines < 0 {
+			return CompareResult{Success: false, Error: "context_lines must not be negative"}, nil
+		}
+		contextLines := args.ContextLines
+		if contextLines == 0 {
+			contextLines = defaultContextLines
+		}
+		contextLines = min(contextLines, maxContextLines)
+
+		a, err := readCompareFile(cfg, args.FileA)
+		if err != nil {
+			return CompareResult{Success: false, Error: err.Error()}, nil
+		}
+		b, err := readCompareFile(cfg, args.FileB)
+		if err != nil {
+			return CompareResult{Success: false, Error: err.Error()}, nil
+		}
+
+		ops, ok := diffLines(splitLines(a), splitLines(b), maxCompareEdits)
+		if !ok {
+			return CompareResult{Success: false, Error: fmt.Sprintf("files differ in more than %d lines", maxCompareEdits)}, nil
+		}
+		result := CompareResult{Success: true}
+		for _, op := range ops {
+			switch op.kind {
+			case '+':
+				result.AddedLines++
+			case '-':
+				result.RemovedLines++
+			}
+		}
+		diff := unifiedDiff(args.FileA, args.FileB, ops, contextLines)
+		result.Diff = diff
+		if len(diff) > maxCompareDiffBytes {
+			result.Diff = truncateString(diff, maxCompareDiffBytes)
+			result.Truncated = true
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "compare_files",
+		Description: "比较工作目录中的两个文件（如配置文件或生成文件的两个版本），返回从 file_a 到 file_b 的统一 diff 以及新增、删除的行数。context_lines 为每处修改前后显示的未修改行数（默认 3，最大 20）。diff 最多返回 30KB。",
+	}, handler)
+}
+
+// readCompareFile reads the file at path for compare_files, after the checks of
+// read_file_content.
+func readCompareFile(cfg ToolsConfig, path string) (string, error) {
+	absPath, err := resolvePath(cfg, path)
+	if err != nil {
+		return "", err
+	}
+	if err := checkExtension(cfg, absPath); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("file %s does not exist", path)
+		}
+		return "", fmt.Errorf("failed to stat file: %v", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxCompareFileSize {
+		return "", fmt.Errorf("file %s too large: %d bytes exceeds the limit of %d bytes", path, info.Size(), maxCompareFileSize)
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	return string(content), nil
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	line string // Line with its line ending, if any
+}
+
+// diffLines returns the shortest edit script turning lines a into lines b, using
+// Myers' algorithm. It returns false when more than maxEdits lines would have to be
+// added or removed.
+func diffLines(a, b []string, maxEdits int) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	// v[k+offset] is the furthest x reached on diagonal k = x-y. trace[d] holds the
+	// diagonals -d-1..d+1 of v as they were before round d, for backtracking.
+	offset := maxEdits + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Down: add b[y]
+			} else {
+				x = v[offset+k-1] + 1 // Right: remove a[x]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(trace, a, b), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrackDiff walks the trace of diffLines back from the end of a and b and returns
+// the edit script in order.
+func backtrackDiff(trace [][]int, a, b []string) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: ' ', line: a[x-1]})
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{kind: '+', line: b[y-1]})
+			} else {
+				ops = append(ops, diffOp{kind: '-', line: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedDiff formats ops as a unified diff from nameA to nameB, with contextLines
+// unchanged lines around each change. Changes closer than twice contextLines share a
+// hunk. It returns "" when ops has no changes.
+func unifiedDiff(nameA, nameB string, ops []diffOp, contextLines int) string {
+	// lineA[i] and lineB[i] are the numbers of lines of a and b before ops[i]
+	lineA, lineB := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		lineA[i+1], lineB[i+1] = lineA[i], lineB[i]
+		if op.kind != '+' {
+			lineA[i+1]++
+		}
+		if op.kind != '-' {
+			lineB[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(i-contextLines, 0)
+		end := i // One past the last change of the hunk
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*contextLines {
+				break
+			}
+			end = next
+		}
+		stop := min(end+contextLines, len(ops))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(lineA[start], lineA[stop]-lineA[start]),
+			hunkRange(lineB[start], lineB[stop]-lineB[start]))
+		for _, op := range ops[start:stop] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of count lines after the first before lines of a file
+// for a hunk header, as diff -u does: an empty range names the line before it.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
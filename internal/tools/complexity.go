@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// complexityWarnThreshold is the cyclomatic complexity above which code_complexity
+// warns about a function.
+const complexityWarnThreshold = 10
+
+// CodeComplexityArgs is the input for code_complexity tool.
+type CodeComplexityArgs struct {
+	Filepath     string `json:"filepath"`                // Go file to analyze (relative to WorkDir or absolute)
+	FunctionName string `json:"function_name,omitempty"` // Function to analyze, "Type.Method" for methods; all functions when empty
+}
+
+// FunctionComplexity is the cyclomatic complexity of a function.
+type FunctionComplexity struct {
+	Name       string `json:"name"`       // Function name, "Type.Method" for methods
+	Complexity int    `json:"complexity"` // Cyclomatic complexity, 1 for straight-line code
+	File       string `json:"file"`       // File path as given in the arguments
+	StartLine  int    `json:"start_line"` // Line of the func keyword
+}
+
+// ComplexityResult is the output for code_complexity tool.
+type ComplexityResult struct {
+	Success   bool                 `json:"success"`             // Whether the operation succeeded
+	Functions []FunctionComplexity `json:"functions,omitempty"` // Functions, most complex first
+	Warnings  []string             `json:"warnings,omitempty"`  // Functions with a complexity above 10
+	Error     string               `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createCodeComplexityTool creates the code_complexity tool.
+// This tool computes the cyclomatic complexity of the functions of a Go file, so that
+// the agent can point at the functions most likely to hide bugs.
+func createCodeComplexityTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args CodeComplexityArgs) (ComplexityResult, error) {
+		if args.Filepath == "" {
+			return ComplexityResult{Success: false, Error: "filepath is required"}, nil
+		}
+		absPath, err := resolvePath(cfg, args.Filepath)
+		if err != nil {
+			return ComplexityResult{Success: false, Error: err.Error()}, nil
+		}
+		if filepath.Ext(absPath) != ".go" {
+			return ComplexityResult{Success: false, Error: "filepath must be a .go file"}, nil
+		}
+
+		functions, err := functionComplexities(absPath, args.Filepath)
+		if err != nil {
+			return ComplexityResult{Success: false, Error: err.Error()}, nil
+		}
+		if args.FunctionName != "" {
+			var named []FunctionComplexity
+			for _, fn := range functions {
+				if fn.Name == args.FunctionName {
+					named = append(named, fn)
+				}
+			}
+			if len(named) == 0 {
+				return ComplexityResult{Success: false, Error: fmt.Sprintf("function %s not found in %s", args.FunctionName, args.Filepath)}, nil
+			}
+			functions = named
+		}
+
+		result := ComplexityResult{Success: true, Functions: functions}
+		for _, fn := range functions {
+			if fn.Complexity > complexityWarnThreshold {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s has a cyclomatic complexity of %d, above %d", fn.Name, fn.Complexity, complexityWarnThreshold))
+			}
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "code_complexity",
+		Description: "计算 Go 文件中函数的圈复杂度（按 if、for、case、&&、|| 计数），按复杂度从高到低返回。可用 function_name 指定单个函数（方法写作 Type.Method）。复杂度超过 10 的函数会给出警告，这些函数往往是遗留代码中最可能藏有 bug 的地方。",
+	}, handler)
+}
+
+// functionComplexities parses the Go file at path and returns the cyclomatic complexity
+// of each of its functions, most complex first. Functions of equal complexity keep their
+// order in the file. name is the path reported in the results.
+func functionComplexities(path, name string) ([]FunctionComplexity, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+	}
+
+	functions := []FunctionComplexity{}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		funcName := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			funcName = memory.ReceiverName(fn.Recv.List[0].Type) + "." + funcName
+		}
+		functions = append(functions, FunctionComplexity{
+			Name:       funcName,
+			Complexity: cyclomaticComplexity(fn.Body),
+			File:       name,
+			StartLine:  fset.Position(fn.Pos()).Line,
+		})
+	}
+	sort.SliceStable(functions, func(i, j int) bool {
+		return functions[i].Complexity > functions[j].Complexity
+	})
+	return functions, nil
+}
+
+// cyclomaticComplexity returns 1 plus the number of decision points in body: if, for
+// and range statements, non-default case and select clauses, and && and || operators.
+// Function literals count towards the enclosing function.
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"fmt"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultDebugSearchLimit is the number of experiences find_similar_experiences
+	// returns when Limit is not set.
+	defaultDebugSearchLimit = 10
+	// maxDebugSearchLimit is the largest number of experiences find_similar_experiences returns.
+	maxDebugSearchLimit = 50
+	// debugSolutionBytes is the length at which find_similar_experiences cuts solutions.
+	debugSolutionBytes = 200
+)
+
+// FindSimilarExperiencesArgs is the input for find_similar_experiences tool.
+type FindSimilarExperiencesArgs struct {
+	Query         string  `json:"query"`                    // Text to embed and search for
+	Limit         int     `json:"limit,omitempty"`          // Maximum number of experiences (default 10, at most 50)
+	MinSimilarity float32 `json:"min_similarity,omitempty"` // Minimum cosine similarity, 0-1 (default 0, everything)
+}
+
+// SimilarExperience is an experience returned by find_similar_experiences.
+type SimilarExperience struct {
+	ID         int     `json:"id"`         // Experience ID
+	Similarity float32 `json:"similarity"` // Raw cosine similarity to the query
+	Pattern    string  `json:"pattern"`    // Error pattern
+	Solution   string  `json:"solution"`   // First 200 bytes of the solution
+}
+
+// FindSimilarExperiencesResult is the output for find_similar_experiences tool.
+type FindSimilarExperiencesResult struct {
+	Success     bool                `json:"success"`               // Whether the operation succeeded
+	Experiences []SimilarExperience `json:"experiences,omitempty"` // Experiences, most similar first
+	Error       string              `json:"error,omitempty"`       // Error message if the operation failed
+}
+
+// createFindSimilarExperiencesTool creates the find_similar_experiences tool.
+// This debug tool shows what the vector search returns for a query, with the raw
+// similarity scores, bypassing the thresholds, clustering and formatting of
+// search_past_issues. BuildTools only includes it when cfg.DebugTools is set.
+func createFindSimilarExperiencesTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args FindSimilarExperiencesArgs) (FindSimilarExperiencesResult, error) {
+		if args.Query == "" {
+			return FindSimilarExperiencesResult{Success: false, Error: "query is required"}, nil
+		}
+		if args.MinSimilarity < 0 || args.MinSimilarity > 1 {
+			return FindSimilarExperiencesResult{Success: false, Error: fmt.Sprintf("invalid min_similarity %v: must be between 0 and 1", args.MinSimilarity)}, nil
+		}
+		limit := args.Limit
+		if limit <= 0 {
+			limit = defaultDebugSearchLimit
+		}
+		limit = min(limit, maxDebugSearchLimit)
+
+		embedding, err := cfg.Embedder.Embed(ctx, args.Query)
+		if err != nil {
+			return FindSimilarExperiencesResult{Success: false, Error: fmt.Sprintf("failed to generate embedding: %v", err)}, nil
+		}
+		experiences, err := cfg.Store.SearchSimilarIssues(ctx, args.Query, embedding, limit, args.MinSimilarity, cfg.ProjectID, true, nil)
+		if err != nil {
+			return FindSimilarExperiencesResult{Success: false, Error: fmt.Sprintf("failed to search issues: %v", err)}, nil
+		}
+
+		result := FindSimilarExperiencesResult{Success: true, Experiences: []SimilarExperience{}}
+		for _, exp := range experiences {
+			result.Experiences = append(result.Experiences, SimilarExperience{
+				ID:         exp.ID,
+				Similarity: exp.SimilarityScore,
+				Pattern:    exp.ErrorPattern,
+				Solution:   truncateString(exp.Solution, debugSolutionBytes),
+			})
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "find_similar_experiences",
+		Description: "调试工具：直接对查询文本生成向量并在经验库中检索，返回每条经验的 ID、原始余弦相似度、错误模式和截断后的解决方案（前 200 字节），用于检查向量检索的结果。",
+	}, handler)
+}
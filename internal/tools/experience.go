@@ -0,0 +1,436 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ListExperiencesByTagArgs is the input for list_experiences_by_tag tool.
+type ListExperiencesByTagArgs struct {
+	Tag string `json:"tag"` // Tag the listed experiences must carry, e.g. "panic"
+}
+
+// ListExperiencesByTagResult is the output for list_experiences_by_tag tool.
+type ListExperiencesByTagResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    any    `json:"data,omitempty"`  // Array of experiences or message if none carry the tag
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// experiencesByTagLimit is the largest number of experiences returned by list_experiences_by_tag.
+const experiencesByTagLimit = 20
+
+// ExperienceVersionsArgs is the input for experience_versions tool.
+type ExperienceVersionsArgs struct {
+	ID int `json:"id"` // ID of the experience whose history should be shown
+}
+
+// ExperienceVersionsResult is the output for experience_versions tool.
+type ExperienceVersionsResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    any    `json:"data,omitempty"`  // Array of historical versions, oldest first
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// RevertExperienceArgs is the input for revert_experience tool.
+type RevertExperienceArgs struct {
+	ID      int `json:"id"`      // ID of the experience to roll back
+	Version int `json:"version"` // Historical version number to restore
+}
+
+// RevertExperienceResult is the output for revert_experience tool.
+type RevertExperienceResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// UpdateExperienceArgs is the input for update_experience tool.
+type UpdateExperienceArgs struct {
+	ID        int    `json:"id"`                   // ID of the experience to correct
+	RootCause string `json:"root_cause,omitempty"` // Corrected root cause (empty keeps the current one)
+	Solution  string `json:"solution,omitempty"`   // Corrected solution (empty keeps the current one)
+}
+
+// UpdateExperienceResult is the output for update_experience tool.
+type UpdateExperienceResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// GetExperienceByIDArgs is the input for get_experience_by_id tool.
+type GetExperienceByIDArgs struct {
+	ID int `json:"id"` // ID of the experience, as returned by search_past_issues
+}
+
+// GetExperienceResult is the output for get_experience_by_id tool.
+type GetExperienceResult struct {
+	Success      bool   `json:"success"`                 // Whether the operation succeeded
+	ID           int    `json:"id,omitempty"`            // ID of the experience
+	ErrorPattern string `json:"error_pattern,omitempty"` // Description of the error or problem pattern
+	RootCause    string `json:"root_cause,omitempty"`    // Root cause analysis of the issue
+	Solution     string `json:"solution,omitempty"`      // Solution or fix that resolved the issue
+	Source       string `json:"source,omitempty"`        // Origin of the experience, "session" or "codebase_index"
+	UserID       string `json:"user_id,omitempty"`       // User whose session produced the experience
+	ProjectID    string `json:"project_id,omitempty"`    // Project the experience was learned in, empty if shared
+	Frequency    int    `json:"frequency,omitempty"`     // Number of times the issue was encountered
+	OccurredAt   string `json:"occurred_at,omitempty"`   // When the issue was last encountered, in RFC 3339 format
+	Error        string `json:"error,omitempty"`         // Error message if the operation failed
+}
+
+// createGetExperienceByIDTool creates the get_experience_by_id tool.
+// This tool returns every stored field of one experience, such as when it was last
+// encountered and how often, which search_past_issues does not include.
+func createGetExperienceByIDTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args GetExperienceByIDArgs) (GetExperienceResult, error) {
+		if args.ID <= 0 {
+			return GetExperienceResult{Success: false, Error: "id must be a positive integer"}, nil
+		}
+
+		exp, err := cfg.Store.GetExperience(ctx, args.ID)
+		if err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return GetExperienceResult{Success: false, Error: fmt.Sprintf("experience %d not found", args.ID)}, nil
+			}
+			return GetExperienceResult{Success: false, Error: fmt.Sprintf("failed to load experience: %v", err)}, nil
+		}
+
+		return GetExperienceResult{
+			Success:      true,
+			ID:           exp.ID,
+			ErrorPattern: exp.ErrorPattern,
+			RootCause:    exp.RootCause,
+			Solution:     exp.Solution,
+			Source:       exp.Source,
+			UserID:       exp.UserID,
+			ProjectID:    exp.ProjectID,
+			Frequency:    exp.Frequency,
+			OccurredAt:   exp.OccurredAt.Format(time.RFC3339),
+		}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "get_experience_by_id",
+		Description: "按 ID 获取一条经验的完整信息，包括错误模式、根本原因、解决方案、最近出现时间和出现次数。在 search_past_issues 返回 ID 后用于查看详情。",
+	}, handler)
+}
+
+// createListExperiencesByTagTool creates the list_experiences_by_tag tool.
+// This tool lists the most recent experiences carrying a tag, so the agent can browse
+// all known issues of one kind, such as every recorded deadlock, without a query text.
+func createListExperiencesByTagTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ListExperiencesByTagArgs) (ListExperiencesByTagResult, error) {
+		if strings.TrimSpace(args.Tag) == "" {
+			return ListExperiencesByTagResult{Success: false, Error: "tag is required"}, nil
+		}
+
+		experiences, err := cfg.Store.ListExperiencesByTag(ctx, args.Tag, experiencesByTagLimit)
+		if err != nil {
+			return ListExperiencesByTagResult{Success: false, Error: fmt.Sprintf("failed to list experiences: %v", err)}, nil
+		}
+
+		if len(experiences) == 0 {
+			return ListExperiencesByTagResult{Success: true, Data: fmt.Sprintf("没有带有标签 %q 的经验。", args.Tag)}, nil
+		}
+
+		results := make([]map[string]any, 0, len(experiences))
+		for _, exp := range experiences {
+			results = append(results, map[string]any{
+				"id":       exp.ID,
+				"pattern":  exp.ErrorPattern,
+				"cause":    exp.RootCause,
+				"solution": exp.Solution,
+				"tags":     exp.Tags,
+			})
+		}
+
+		return ListExperiencesByTagResult{Success: true, Data: results}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "list_experiences_by_tag",
+		Description: "列出带有指定标签（如 panic、nil-pointer、timeout、deadlock）的最近经验，用于浏览同一类问题的历史解决方案。",
+	}, handler)
+}
+
+// createExperienceVersionsTool creates the experience_versions tool.
+// This tool lets the agent inspect how a stored experience changed over time,
+// listing every previous version recorded before an update.
+func createExperienceVersionsTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ExperienceVersionsArgs) (ExperienceVersionsResult, error) {
+		if args.ID <= 0 {
+			return ExperienceVersionsResult{Success: false, Error: "id must be a positive integer"}, nil
+		}
+
+		versions, err := cfg.Store.GetExperienceHistory(ctx, args.ID)
+		if err != nil {
+			return ExperienceVersionsResult{Success: false, Error: fmt.Sprintf("failed to load experience history: %v", err)}, nil
+		}
+
+		if len(versions) == 0 {
+			return ExperienceVersionsResult{Success: true, Data: "该经验没有历史版本。"}, nil
+		}
+
+		results := make([]map[string]any, 0, len(versions))
+		for _, v := range versions {
+			results = append(results, map[string]any{
+				"version":    v.Version,
+				"pattern":    v.ErrorPattern,
+				"cause":      v.RootCause,
+				"solution":   v.Solution,
+				"updated_at": v.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+
+		return ExperienceVersionsResult{Success: true, Data: results}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "experience_versions",
+		Description: "查看指定经验的历史版本，用于了解经验内容是如何被修改的。",
+	}, handler)
+}
+
+// createRevertExperienceTool creates the revert_experience tool.
+// This tool rolls an experience back to one of its historical versions,
+// for example when a later correction turned out to be wrong.
+func createRevertExperienceTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args RevertExperienceArgs) (RevertExperienceResult, error) {
+		if args.ID <= 0 || args.Version <= 0 {
+			return RevertExperienceResult{Success: false, Error: "id and version must be positive integers"}, nil
+		}
+
+		if err := cfg.Store.RevertExperienceTo(ctx, args.ID, args.Version); err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return RevertExperienceResult{Success: false, Error: fmt.Sprintf("experience %d has no version %d", args.ID, args.Version)}, nil
+			}
+			return RevertExperienceResult{Success: false, Error: fmt.Sprintf("failed to revert experience: %v", err)}, nil
+		}
+
+		return RevertExperienceResult{Success: true, Data: fmt.Sprintf("经验 #%d 已回滚到版本 %d。", args.ID, args.Version)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "revert_experience",
+		Description: "将指定经验回滚到某个历史版本。",
+	}, handler)
+}
+
+// createUpdateExperienceTool creates the update_experience tool.
+// This tool corrects the root cause or solution of a stored experience in place.
+// The error pattern, and therefore its embedding, is left unchanged, and the previous
+// content is kept as a historical version.
+func createUpdateExperienceTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args UpdateExperienceArgs) (UpdateExperienceResult, error) {
+		if args.ID <= 0 {
+			return UpdateExperienceResult{Success: false, Error: "id must be a positive integer"}, nil
+		}
+		if args.RootCause == "" && args.Solution == "" {
+			return UpdateExperienceResult{Success: false, Error: "root_cause or solution is required"}, nil
+		}
+
+		// Confirm the experience exists, and keep the fields that are not being corrected
+		existing, err := cfg.Store.GetExperience(ctx, args.ID)
+		if err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return UpdateExperienceResult{Success: false, Error: fmt.Sprintf("experience %d not found", args.ID)}, nil
+			}
+			return UpdateExperienceResult{Success: false, Error: fmt.Sprintf("failed to load experience: %v", err)}, nil
+		}
+		cause, solution := existing.RootCause, existing.Solution
+		if args.RootCause != "" {
+			cause = args.RootCause
+		}
+		if args.Solution != "" {
+			solution = args.Solution
+		}
+
+		if err := cfg.Store.UpdateExperience(ctx, args.ID, cause, solution); err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return UpdateExperienceResult{Success: false, Error: fmt.Sprintf("experience %d not found", args.ID)}, nil
+			}
+			return UpdateExperienceResult{Success: false, Error: fmt.Sprintf("failed to update experience: %v", err)}, nil
+		}
+
+		return UpdateExperienceResult{Success: true, Data: fmt.Sprintf("经验 #%d 已更新，旧内容已保存为历史版本。", args.ID)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "update_experience",
+		Description: "修正已保存经验的根本原因或解决方案，不改变错误模式。发现某条经验的解决方案部分有误时使用，旧内容会保存为历史版本。",
+	}, handler)
+}
+
+// DeleteExperienceArgs is the input for delete_experience tool.
+type DeleteExperienceArgs struct {
+	ID     int    `json:"id"`     // ID of the incorrect experience to delete
+	Reason string `json:"reason"` // Why the experience is wrong, kept in the log
+}
+
+// DeleteExperienceResult is the output for delete_experience tool.
+type DeleteExperienceResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// createDeleteExperienceTool creates the delete_experience tool.
+// This tool removes an experience that is wrong as a whole, so that it is no longer
+// suggested. The experience is only soft-deleted and can be recovered from the database
+// until it is purged.
+func createDeleteExperienceTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args DeleteExperienceArgs) (DeleteExperienceResult, error) {
+		if args.ID <= 0 {
+			return DeleteExperienceResult{Success: false, Error: "id must be a positive integer"}, nil
+		}
+		if args.Reason == "" {
+			return DeleteExperienceResult{Success: false, Error: "reason is required"}, nil
+		}
+
+		if err := cfg.Store.DeleteExperience(ctx, args.ID); err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return DeleteExperienceResult{Success: false, Error: fmt.Sprintf("experience %d not found", args.ID)}, nil
+			}
+			return DeleteExperienceResult{Success: false, Error: fmt.Sprintf("failed to delete experience: %v", err)}, nil
+		}
+		slog.InfoContext(ctx, "deleted experience", slog.Int("id", args.ID), slog.String("reason", args.Reason))
+
+		return DeleteExperienceResult{Success: true, Data: fmt.Sprintf("经验 #%d 已删除，不会再出现在搜索结果中。", args.ID)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "delete_experience",
+		Description: "删除一条整体错误的经验，使其不再出现在搜索结果中。需要说明删除原因。仅部分有误时请使用 update_experience 修正。",
+	}, handler)
+}
+
+// MergeExperiencesArgs is the input for merge_experiences tool.
+type MergeExperiencesArgs struct {
+	PrimaryID   int    `json:"primary_id"`         // ID of the experience to keep
+	SecondaryID int    `json:"secondary_id"`       // ID of the duplicate experience to fold in and delete
+	Strategy    string `json:"strategy,omitempty"` // keep_primary (default), llm_merge or longest_wins
+}
+
+// MergeExperiencesResult is the output for merge_experiences tool.
+type MergeExperiencesResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    any    `json:"data,omitempty"`  // The merged experience
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// createMergeExperiencesTool creates the merge_experiences tool.
+// This tool combines two experiences describing the same problem into the primary one
+// and deletes the secondary, keeping the knowledge base free of duplicates.
+func createMergeExperiencesTool(cfg ToolsConfig) (tool.Tool, error) {
+	merger := memory.NewExperienceMerger(cfg.Store, cfg.Embedder, cfg.Generator)
+
+	handler := func(ctx tool.Context, args MergeExperiencesArgs) (MergeExperiencesResult, error) {
+		if args.PrimaryID <= 0 || args.SecondaryID <= 0 {
+			return MergeExperiencesResult{Success: false, Error: "primary_id and secondary_id must be positive integers"}, nil
+		}
+		strategy := memory.MergeStrategy(args.Strategy)
+		if strategy == "" {
+			strategy = memory.MergeKeepPrimary
+		}
+
+		merged, err := merger.MergeExperiences(ctx, args.PrimaryID, args.SecondaryID, strategy)
+		if err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return MergeExperiencesResult{Success: false, Error: err.Error()}, nil
+			}
+			return MergeExperiencesResult{Success: false, Error: fmt.Sprintf("failed to merge experiences: %v", err)}, nil
+		}
+
+		return MergeExperiencesResult{Success: true, Data: map[string]any{
+			"id":       merged.ID,
+			"pattern":  merged.ErrorPattern,
+			"cause":    merged.RootCause,
+			"solution": merged.Solution,
+		}}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "merge_experiences",
+		Description: "将两条描述同一问题的经验合并到主经验中，并删除次经验。策略：keep_primary（保留主经验，追加次经验的解决方案）、llm_merge（由模型撰写合并版本）、longest_wins（每个字段取较长的内容）。",
+	}, handler)
+}
+
+// UserSearchArgs is the input for search_personal_history tool.
+type UserSearchArgs struct {
+	Query string `json:"query"` // Description of the problem to search for
+}
+
+// UserSearchResult is the output for search_personal_history tool.
+type UserSearchResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    any    `json:"data,omitempty"`  // Search results (array of experiences) or message if none found
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// createSearchPersonalHistoryTool creates the search_personal_history tool.
+// This tool searches only the experiences recorded in a single user's past sessions,
+// which allows the agent to give personalized answers based on what the user has
+// run into before. The user is always the one owning the current session, so one
+// user's history cannot be read from another user's session.
+func createSearchPersonalHistoryTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args UserSearchArgs) (UserSearchResult, error) {
+		if args.Query == "" {
+			return UserSearchResult{Success: false, Error: "query is required"}, nil
+		}
+
+		userID := userIDFromContext(ctx)
+		if userID == "" {
+			return UserSearchResult{Success: false, Error: "personal history requires a session with a user"}, nil
+		}
+
+		embedding, err := cfg.Embedder.Embed(ctx, args.Query)
+		if err != nil {
+			return UserSearchResult{Success: false, Error: fmt.Sprintf("failed to generate embedding: %v", err)}, nil
+		}
+
+		experiences, err := cfg.Store.SearchByUser(ctx, userID, args.Query, embedding, 5)
+		if err != nil {
+			return UserSearchResult{Success: false, Error: fmt.Sprintf("failed to search user history: %v", err)}, nil
+		}
+
+		if len(experiences) == 0 {
+			return UserSearchResult{Success: true, Data: "该用户没有相关的历史问题。"}, nil
+		}
+
+		results := make([]map[string]any, 0, len(experiences))
+		for _, exp := range experiences {
+			results = append(results, map[string]any{
+				"id":         exp.ID,
+				"user_id":    exp.UserID,
+				"pattern":    exp.ErrorPattern,
+				"cause":      exp.RootCause,
+				"solution":   exp.Solution,
+				"similarity": fmt.Sprintf("%.2f%%", exp.SimilarityScore*100),
+			})
+		}
+
+		return UserSearchResult{Success: true, Data: results}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "search_personal_history",
+		Description: "搜索当前用户过去会话中记录的相似问题，用于提供个性化的帮助。",
+	}, handler)
+}
+
+// userIDFromContext returns the ID of the user owning the current session,
+// or an empty string when the tool is invoked without a session.
+func userIDFromContext(ctx tool.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	return ctx.UserID()
+}
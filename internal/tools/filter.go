@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"log/slog"
+	"strings"
+
+	"google.golang.org/adk/tool"
+)
+
+// ToolEnablementRule disables a set of tools whenever an active project rule
+// contains RuleKeyword (case-insensitive substring match).
+type ToolEnablementRule struct {
+	RuleKeyword   string   // Keyword searched for in the content of active project rules
+	DisabledTools []string // Names of the tools to disable when the keyword is present
+}
+
+// DefaultToolEnablementRules are always applied in addition to configured rules.
+// They may only name tools built by BuildTools.
+var DefaultToolEnablementRules = []ToolEnablementRule{
+	{RuleKeyword: "No external HTTP", DisabledTools: []string{"read_url"}},
+}
+
+// ParseToolEnablementRules parses rule definitions of the form
+// "keyword => tool_a, tool_b". Definitions without a keyword or without
+// any tool names are ignored.
+func ParseToolEnablementRules(rules []string) []ToolEnablementRule {
+	var parsed []ToolEnablementRule
+	for _, def := range rules {
+		keyword, toolList, ok := strings.Cut(def, "=>")
+		if !ok {
+			continue
+		}
+		keyword = strings.TrimSpace(keyword)
+
+		var disabled []string
+		for _, name := range strings.Split(toolList, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				disabled = append(disabled, name)
+			}
+		}
+
+		if keyword == "" || len(disabled) == 0 {
+			continue
+		}
+		parsed = append(parsed, ToolEnablementRule{RuleKeyword: keyword, DisabledTools: disabled})
+	}
+	return parsed
+}
+
+// filterTools removes every tool disabled by an enablement rule whose keyword
+// appears in one of the active project rules. Disabled tools are logged together
+// with the project rule that triggered them.
+func filterTools(tools []tool.Tool, activeRules []string, enablementRules []ToolEnablementRule) []tool.Tool {
+	disabled := make(map[string]string)
+	for _, er := range enablementRules {
+		keyword := strings.ToLower(er.RuleKeyword)
+		for _, rule := range activeRules {
+			if !strings.Contains(strings.ToLower(rule), keyword) {
+				continue
+			}
+			for _, name := range er.DisabledTools {
+				if _, seen := disabled[name]; !seen {
+					disabled[name] = rule
+				}
+			}
+			break
+		}
+	}
+
+	if len(disabled) == 0 {
+		return tools
+	}
+
+	filtered := make([]tool.Tool, 0, len(tools))
+	for _, t := range tools {
+		if rule, ok := disabled[t.Name()]; ok {
+			slog.Info("tool disabled by project rule", slog.String("tool", t.Name()), slog.String("rule", rule))
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return filtered
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// maxGitDiffBytes caps the size of the diff returned by git_diff.
+const maxGitDiffBytes = 20 * 1024
+
+// GitDiffArgs is the input for git_diff tool.
+type GitDiffArgs struct {
+	FilePath string `json:"file_path,omitempty"` // File or directory to diff (relative to WorkDir or absolute, empty for all tracked files)
+	Staged   bool   `json:"staged,omitempty"`    // Diff staged changes instead of unstaged ones
+}
+
+// GitDiffResult is the output for git_diff tool.
+type GitDiffResult struct {
+	Success      bool     `json:"success"`                 // Whether the operation succeeded
+	Diff         string   `json:"diff,omitempty"`          // Unified diff, empty when nothing changed
+	FilesChanged []string `json:"files_changed,omitempty"` // Paths of the changed files, relative to the repository root
+	Truncated    bool     `json:"truncated,omitempty"`     // Whether the diff was cut at 20KB
+	Error        string   `json:"error,omitempty"`         // Error message if the operation failed
+}
+
+// createGitDiffTool creates the git_diff tool.
+// This tool returns the uncommitted changes in the working directory, so the agent
+// can check what changed when investigating a regression.
+func createGitDiffTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args GitDiffArgs) (GitDiffResult, error) {
+		if _, err := exec.LookPath("git"); err != nil {
+			return GitDiffResult{Success: false, Error: "git is not installed"}, nil
+		}
+
+		gitArgs := []string{"diff", "--no-color", "--no-ext-diff"}
+		if args.Staged {
+			gitArgs = append(gitArgs, "--cached")
+		}
+		if args.FilePath != "" {
+			// Security check: ensure path is within working directory
+			absPath, err := SafeAbsPath(args.FilePath, cfg.WorkDir)
+			if err != nil {
+				return GitDiffResult{Success: false, Error: err.Error()}, nil
+			}
+			gitArgs = append(gitArgs, "--", absPath)
+		}
+
+		cmd := exec.CommandContext(commandContext(ctx), "git", gitArgs...)
+		cmd.Dir, _ = filepath.Abs(cfg.WorkDir)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return GitDiffResult{Success: false, Error: fmt.Sprintf("git diff failed: %s", strings.TrimSpace(stderr.String()))}, nil
+			}
+			return GitDiffResult{Success: false, Error: fmt.Sprintf("failed to run git: %v", err)}, nil
+		}
+
+		diff := stdout.String()
+		result := GitDiffResult{Success: true, Diff: diff, FilesChanged: parseDiffFiles(diff)}
+		if len(diff) > maxGitDiffBytes {
+			result.Diff = truncateString(diff, maxGitDiffBytes)
+			result.Truncated = true
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "git_diff",
+		Description: "返回工作目录中未提交的修改（git diff），可指定单个文件或只看已暂存的修改。用于排查回归问题时查看最近改动了哪些代码。",
+	}, handler)
+}
+
+// commandContext returns the context that commands started by a tool run in. Tools
+// invoked outside an agent session, e.g. in tests, have no tool context.
+func commandContext(ctx tool.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// parseDiffFiles returns the paths named in the "diff --git a/<path> b/<path>" headers
+// of a unified diff, using the new path for renamed files.
+func parseDiffFiles(diff string) []string {
+	var files []string
+	for _, line := range strings.Split(diff, "\n") {
+		header, ok := strings.CutPrefix(line, "diff --git ")
+		if !ok {
+			continue
+		}
+		if i := strings.LastIndex(header, " b/"); i >= 0 {
+			files = append(files, header[i+len(" b/"):])
+		}
+	}
+	return files
+}
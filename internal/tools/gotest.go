@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultGoTestTimeout is passed to go test -timeout when RunGoTestsArgs.Timeout is not set.
+	defaultGoTestTimeout = 60 * time.Second
+	// goTestGracePeriod is how long go test may run past its -timeout before it is killed,
+	// leaving it time to report the timed out test.
+	goTestGracePeriod = 30 * time.Second
+	// maxGoTestOutputBytes caps the test and build output returned by run_go_tests.
+	maxGoTestOutputBytes = 100 * 1024
+)
+
+// RunGoTestsArgs is the input for run_go_tests tool.
+type RunGoTestsArgs struct {
+	Package    string `json:"package,omitempty"`     // Package pattern relative to WorkDir, e.g. "./internal/..." (default "./...")
+	TestFilter string `json:"test_filter,omitempty"` // Regular expression selecting the tests to run (go test -run)
+	Timeout    string `json:"timeout,omitempty"`     // Maximum duration of the run, e.g. "2m" (default "60s")
+	Race       bool   `json:"race,omitempty"`        // Enable the race detector
+}
+
+// FailedTest is a test that failed in a run_go_tests run.
+type FailedTest struct {
+	Name   string `json:"name"`   // Test name, including the package, e.g. "example.com/pkg.TestParse"
+	Output string `json:"output"` // Output printed by the test
+}
+
+// TestRunResult is the output for run_go_tests tool.
+type TestRunResult struct {
+	Success     bool         `json:"success"`                // Whether go test ran; failing tests are reported in FailedTests
+	Passed      int          `json:"passed"`                 // Number of passed tests, including subtests
+	Failed      int          `json:"failed"`                 // Number of failed tests, including subtests
+	Skipped     int          `json:"skipped"`                // Number of skipped tests, including subtests
+	FailedTests []FailedTest `json:"failed_tests,omitempty"` // Failed tests with their output
+	Output      string       `json:"output,omitempty"`       // Build errors and output of failed packages outside any test
+	Truncated   bool         `json:"truncated,omitempty"`    // Whether output was cut at 100KB
+	Error       string       `json:"error,omitempty"`        // Error message if go test could not be run
+}
+
+// createRunGoTestsTool creates the run_go_tests tool.
+// This tool runs the project's existing Go tests and reports which ones fail and why,
+// so the agent can check its understanding of a bug against the test suite.
+func createRunGoTestsTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args RunGoTestsArgs) (TestRunResult, error) {
+		pkg := args.Package
+		if pkg == "" {
+			pkg = "./..."
+		}
+		// Security check: only packages inside the working directory may be tested
+		if strings.HasPrefix(pkg, "/") || strings.HasPrefix(pkg, "-") || strings.Contains(strings.TrimSuffix(pkg, "..."), "..") {
+			return TestRunResult{Success: false, Error: fmt.Sprintf("invalid package %q: must be a path inside the working directory", pkg)}, nil
+		}
+
+		timeout := defaultGoTestTimeout
+		if args.Timeout != "" {
+			d, err := time.ParseDuration(args.Timeout)
+			if err != nil || d <= 0 {
+				return TestRunResult{Success: false, Error: fmt.Sprintf("invalid timeout %q", args.Timeout)}, nil
+			}
+			timeout = d
+		}
+
+		goArgs := []string{"test", "-json", "-timeout", timeout.String()}
+		if args.Race {
+			goArgs = append(goArgs, "-race")
+		}
+		if args.TestFilter != "" {
+			goArgs = append(goArgs, "-run", args.TestFilter)
+		}
+		// No "--" before pkg: go test would pass it on to the test binary instead of
+		// selecting the package. pkg cannot be taken for a flag, see the check above.
+		goArgs = append(goArgs, pkg)
+
+		runCtx, cancel := context.WithTimeout(commandContext(ctx), timeout+goTestGracePeriod)
+		defer cancel()
+		cmd := exec.CommandContext(runCtx, "go", goArgs...)
+		cmd.Dir, _ = filepath.Abs(cfg.WorkDir)
+		stderr := &cappedBuffer{limit: maxGoTestOutputBytes}
+		cmd.Stderr = stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return TestRunResult{Success: false, Error: fmt.Sprintf("failed to run go test: %v", err)}, nil
+		}
+		if err := cmd.Start(); err != nil {
+			return TestRunResult{Success: false, Error: fmt.Sprintf("failed to run go test: %v", err)}, nil
+		}
+
+		p := newTestEventParser()
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			p.parseLine(scanner.Bytes())
+		}
+		waitErr := cmd.Wait()
+
+		if runCtx.Err() != nil {
+			return TestRunResult{Success: false, Error: fmt.Sprintf("go test did not finish within %s", timeout+goTestGracePeriod)}, nil
+		}
+		var exitErr *exec.ExitError
+		if waitErr != nil && !errors.As(waitErr, &exitErr) {
+			return TestRunResult{Success: false, Error: fmt.Sprintf("failed to run go test: %v", waitErr)}, nil
+		}
+
+		result := p.result()
+		result.Output = stderr.String() + result.Output
+		result.Truncated = result.Truncated || stderr.truncated
+		if waitErr != nil && p.events == 0 {
+			// go test failed before running anything, e.g. because the pattern matched no packages
+			return TestRunResult{Success: false, Output: result.Output, Error: fmt.Sprintf("go test failed: %v", waitErr)}, nil
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "run_go_tests",
+		Description: "在工作目录中运行 go test，返回通过、失败和跳过的测试数量，以及失败测试的输出。可按包和测试名过滤。用于运行现有测试套件、验证对问题的判断。",
+	}, handler)
+}
+
+// testEvent is an event printed by go test -json.
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// testEventParser accumulates the events of a go test -json run.
+type testEventParser struct {
+	res     TestRunResult
+	outputs map[string]*strings.Builder // Output of running tests and packages, keyed by package and test name
+	bytes   int                         // Output bytes kept so far
+	events  int                         // JSON events parsed
+}
+
+func newTestEventParser() *testEventParser {
+	return &testEventParser{outputs: make(map[string]*strings.Builder)}
+}
+
+// parseLine records a line of go test -json output. Lines that are not JSON events,
+// such as the output of failed builds on older Go versions, are kept as output.
+func (p *testEventParser) parseLine(line []byte) {
+	var ev testEvent
+	if err := json.Unmarshal(line, &ev); err != nil || ev.Action == "" {
+		if p.reserve(len(line) + 1) {
+			p.res.Output += string(line) + "\n"
+		}
+		return
+	}
+	p.events++
+
+	key := ev.Package + "\x00" + ev.Test
+	switch ev.Action {
+	case "output":
+		if !p.reserve(len(ev.Output)) {
+			return
+		}
+		out, ok := p.outputs[key]
+		if !ok {
+			out = &strings.Builder{}
+			p.outputs[key] = out
+		}
+		out.WriteString(ev.Output)
+	case "build-output":
+		if p.reserve(len(ev.Output)) {
+			p.res.Output += ev.Output
+		}
+	case "pass", "skip", "fail":
+		out := p.outputs[key]
+		delete(p.outputs, key)
+		if ev.Test == "" {
+			// Package results; keep the output of failed packages that is not part of a test
+			if ev.Action == "fail" && out != nil {
+				p.res.Output += out.String()
+			}
+			return
+		}
+		switch ev.Action {
+		case "pass":
+			p.res.Passed++
+		case "skip":
+			p.res.Skipped++
+		case "fail":
+			p.res.Failed++
+			failed := FailedTest{Name: ev.Package + "." + ev.Test}
+			if out != nil {
+				failed.Output = out.String()
+			}
+			p.res.FailedTests = append(p.res.FailedTests, failed)
+		}
+	}
+}
+
+// reserve reports whether n more bytes of output may be kept, and records that the
+// output was truncated if not.
+func (p *testEventParser) reserve(n int) bool {
+	if p.bytes+n > maxGoTestOutputBytes {
+		p.res.Truncated = true
+		return false
+	}
+	p.bytes += n
+	return true
+}
+
+// result returns the result of the events parsed so far.
+func (p *testEventParser) result() TestRunResult {
+	res := p.res
+	res.Success = true
+	return res
+}
+
+// cappedBuffer is an io.Writer that keeps the first limit bytes written to it.
+type cappedBuffer struct {
+	buf       strings.Builder
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(data []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(data) > room {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(data[:room])
+		}
+		return len(data), nil
+	}
+	b.buf.Write(data)
+	return len(data), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultGrepResults is the number of matches returned by grep_in_files when MaxResults is not set.
+	defaultGrepResults = 100
+	// maxGrepBytes caps the total size of the file paths and lines returned by grep_in_files.
+	maxGrepBytes = 50 * 1024
+	// maxGrepLineLength is the longest line returned in full; longer lines are truncated.
+	maxGrepLineLength = 500
+)
+
+// errGrepLimit stops the directory walk once enough matches have been collected.
+var errGrepLimit = errors.New("grep limit reached")
+
+// GrepArgs is the input for grep_in_files tool.
+type GrepArgs struct {
+	Pattern       string `json:"pattern"`                  // Regular expression to search for
+	Path          string `json:"path,omitempty"`           // Directory or file to search (relative to WorkDir or absolute, empty for WorkDir)
+	FileGlob      string `json:"file_glob,omitempty"`      // Only search files whose name matches this glob, e.g. "*.go"
+	CaseSensitive bool   `json:"case_sensitive,omitempty"` // Match case exactly (default false)
+	MaxResults    int    `json:"max_results,omitempty"`    // Maximum number of matches to return (default 100)
+}
+
+// Match is a line matched by grep_in_files.
+type Match struct {
+	File       string `json:"file"`        // File path relative to WorkDir
+	LineNumber int    `json:"line_number"` // 1-based line number
+	Line       string `json:"line"`        // Matching line, truncated if very long
+}
+
+// GrepResult is the output for grep_in_files tool.
+type GrepResult struct {
+	Success   bool    `json:"success"`             // Whether the operation succeeded
+	Matches   []Match `json:"matches,omitempty"`   // Matching lines in file and line order
+	Truncated bool    `json:"truncated,omitempty"` // Whether more matches exist than were returned
+	Error     string  `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createGrepTool creates the grep_in_files tool.
+// This tool searches every file under a directory for a regular expression, so the
+// agent can find where an error message or symbol is used without reading files one
+// at a time. Hidden directories, binary files and symlinks are skipped.
+func createGrepTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args GrepArgs) (GrepResult, error) {
+		if args.Pattern == "" {
+			return GrepResult{Success: false, Error: "pattern is required"}, nil
+		}
+		expr := args.Pattern
+		if !args.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return GrepResult{Success: false, Error: fmt.Sprintf("invalid pattern: %v", err)}, nil
+		}
+		if args.FileGlob != "" {
+			if _, err := filepath.Match(args.FileGlob, ""); err != nil {
+				return GrepResult{Success: false, Error: fmt.Sprintf("invalid file_glob: %v", err)}, nil
+			}
+		}
+		limit := args.MaxResults
+		if limit <= 0 {
+			limit = defaultGrepResults
+		}
+
+		// Security check: ensure path is within working directory
+		root, err := SafeAbsPath(args.Path, cfg.WorkDir)
+		if err != nil {
+			return GrepResult{Success: false, Error: err.Error()}, nil
+		}
+		absWorkDir, _ := filepath.Abs(cfg.WorkDir)
+
+		g := &grepper{re: re, limit: limit, matches: []Match{}}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root {
+					return err
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(absWorkDir, path)
+			if err != nil {
+				rel = path
+			}
+			rel = filepath.ToSlash(rel)
+			if args.FileGlob != "" && !matchesGlob(args.FileGlob, rel) {
+				return nil
+			}
+			return g.grepFile(path, rel)
+		})
+		if err != nil && !errors.Is(err, errGrepLimit) {
+			return GrepResult{Success: false, Error: fmt.Sprintf("failed to search files: %v", err)}, nil
+		}
+
+		return GrepResult{Success: true, Matches: g.matches, Truncated: g.truncated}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "grep_in_files",
+		Description: "在工作目录的文件中按正则表达式搜索，返回匹配行的文件路径和行号。用于查找某个错误信息或符号在哪里出现或被使用，无需逐个读取文件。",
+	}, handler)
+}
+
+// grepper collects matches across files until the result or byte limit is reached.
+type grepper struct {
+	re        *regexp.Regexp
+	limit     int
+	matches   []Match
+	bytes     int
+	truncated bool
+}
+
+// grepFile appends the lines of the file at path that match g.re. It returns
+// errGrepLimit once no more matches can be returned. Binary files and files that
+// cannot be read are skipped.
+func (g *grepper) grepFile(path, rel string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(f)
+	head, _ := reader.Peek(mimeSniffLen)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	lineNumber := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			lineNumber++
+			line = strings.TrimRight(line, "\r\n")
+			if g.re.MatchString(line) {
+				if len(line) > maxGrepLineLength {
+					line = truncateString(line, maxGrepLineLength) + "..."
+				}
+				size := len(rel) + len(line)
+				if len(g.matches) >= g.limit || g.bytes+size > maxGrepBytes {
+					g.truncated = true
+					return errGrepLimit
+				}
+				g.bytes += size
+				g.matches = append(g.matches, Match{File: rel, LineNumber: lineNumber, Line: line})
+			}
+		}
+		if err != nil {
+			// io.EOF, or a read error that ends the search of this file
+			return nil
+		}
+	}
+}
+
+// matchesGlob reports whether the slash-separated path rel matches glob. Globs without
+// a "/" are matched against the file name only, so "*.go" matches Go files at any depth.
+func matchesGlob(glob, rel string) bool {
+	name := rel
+	if !strings.Contains(glob, "/") {
+		name = filepath.Base(rel)
+	}
+	ok, _ := filepath.Match(glob, name)
+	return ok
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/adk/tool"
+)
+
+// SessionLimits counts the calls a session made to the tools limited by
+// ToolsConfig.MaxSearchCallsPerSession and ToolsConfig.MaxSaveCallsPerSession.
+type SessionLimits struct {
+	mu       sync.Mutex
+	searches int // Calls to search_past_issues
+	saves    int // Calls to save_experience
+}
+
+// SessionLimiter holds the SessionLimits of every session, keyed by session ID. It is
+// safe for concurrent use.
+type SessionLimiter struct {
+	sessions sync.Map // Session ID -> *SessionLimits
+}
+
+// NewSessionLimiter creates a SessionLimiter without any recorded calls.
+func NewSessionLimiter() *SessionLimiter {
+	return &SessionLimiter{}
+}
+
+// ResetSessionLimits discards the calls recorded for the given session, e.g. when it ends.
+func (l *SessionLimiter) ResetSessionLimits(sessionID string) {
+	if l == nil {
+		return
+	}
+	l.sessions.Delete(sessionID)
+}
+
+// session returns the SessionLimits of the given session, creating them on first use.
+func (l *SessionLimiter) session(sessionID string) *SessionLimits {
+	limits, _ := l.sessions.LoadOrStore(sessionID, &SessionLimits{})
+	return limits.(*SessionLimits)
+}
+
+// allowSearch records a search_past_issues call of the session the tool is invoked in.
+// It returns a rate limit error instead, without recording the call, when the session
+// already made cfg.MaxSearchCallsPerSession calls.
+func allowSearch(cfg ToolsConfig, ctx tool.Context) error {
+	return allowCall(cfg, ctx, cfg.MaxSearchCallsPerSession, "search_past_issues", func(s *SessionLimits) *int { return &s.searches })
+}
+
+// allowSave records a save_experience call like allowSearch, up to
+// cfg.MaxSaveCallsPerSession calls per session.
+func allowSave(cfg ToolsConfig, ctx tool.Context) error {
+	return allowCall(cfg, ctx, cfg.MaxSaveCallsPerSession, "save_experience", func(s *SessionLimits) *int { return &s.saves })
+}
+
+// allowCall records a call to toolName in the counter of the session's limits returned
+// by count, unless it already reached limit. A limit of zero, a nil limiter and calls
+// outside a session are not limited.
+func allowCall(cfg ToolsConfig, ctx tool.Context, limit int, toolName string, count func(*SessionLimits) *int) error {
+	if limit <= 0 || cfg.Limiter == nil || ctx == nil {
+		return nil
+	}
+	limits := cfg.Limiter.session(ctx.SessionID())
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	n := count(limits)
+	if *n >= limit {
+		return fmt.Errorf("rate limit exceeded: %s may be called at most %d times per session", toolName, limit)
+	}
+	*n++
+	return nil
+}
@@ -0,0 +1,282 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ApplyPatchArgs is the input for apply_patch tool.
+type ApplyPatchArgs struct {
+	Filepath string `json:"filepath"` // File to patch (relative to WorkDir or absolute)
+	Patch    string `json:"patch"`    // Unified diff of the file, e.g. as printed by git diff
+}
+
+// ApplyPatchResult is the output for apply_patch tool.
+type ApplyPatchResult struct {
+	Success      bool   `json:"success"`                // Whether every hunk applied and the file was written
+	HunksApplied int    `json:"hunks_applied"`          // Number of hunks that matched the file
+	HunksFailed  int    `json:"hunks_failed"`           // Number of hunks that did not match the file
+	FailedHunks  []int  `json:"failed_hunks,omitempty"` // Numbers of the hunks that did not match, starting at 1
+	Data         string `json:"data,omitempty"`         // Success message if the operation succeeded
+	Error        string `json:"error,omitempty"`        // Error message if the operation failed
+}
+
+// hunk is one "@@ -a,b +c,d @@" section of a unified diff.
+type hunk struct {
+	header   string   // The "@@" line
+	oldStart int      // First line replaced by the hunk, starting at 1 (0 for an empty file)
+	old      []string // Context and removed lines, each with its line ending
+	new      []string // Context and added lines, each with its line ending
+}
+
+// createApplyPatchTool creates the apply_patch tool.
+// This tool changes part of a file by applying a unified diff, instead of rewriting the
+// whole file with write_file_content. A hunk whose lines moved applies at the nearest
+// position where they match, ignoring trailing whitespace if need be. The file is only
+// written when every hunk applies.
+func createApplyPatchTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ApplyPatchArgs) (ApplyPatchResult, error) {
+		if args.Filepath == "" {
+			return ApplyPatchResult{Success: false, Error: "filepath is required"}, nil
+		}
+		hunks, err := parseUnifiedDiff(args.Patch)
+		if err != nil {
+			return ApplyPatchResult{Success: false, Error: err.Error()}, nil
+		}
+
+		// Security check: ensure path is within working directory
+		absPath, err := SafeAbsPath(args.Filepath, cfg.WorkDir)
+		if err != nil {
+			return ApplyPatchResult{Success: false, Error: err.Error()}, nil
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return ApplyPatchResult{Success: false, Error: fmt.Sprintf("file %s does not exist, use write_file_content to create it", args.Filepath)}, nil
+			}
+			return ApplyPatchResult{Success: false, Error: fmt.Sprintf("failed to stat file: %v", err)}, nil
+		}
+		if info.IsDir() {
+			return ApplyPatchResult{Success: false, Error: fmt.Sprintf("%s is a directory", args.Filepath)}, nil
+		}
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return ApplyPatchResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+		}
+
+		patched, failed := applyHunks(splitLines(string(content)), hunks)
+		result := ApplyPatchResult{HunksApplied: len(hunks) - len(failed), HunksFailed: len(failed), FailedHunks: failed}
+		if len(failed) > 0 {
+			headers := make([]string, len(failed))
+			for i, n := range failed {
+				headers[i] = fmt.Sprintf("#%d %s", n, hunks[n-1].header)
+			}
+			result.Error = fmt.Sprintf("%d of %d hunks did not match the file, which was left unchanged: %s", len(failed), len(hunks), strings.Join(headers, "; "))
+			return result, nil
+		}
+
+		data := strings.Join(patched, "")
+		if len(data) > maxWriteSize {
+			result.Error = fmt.Sprintf("patched file too large: %d bytes exceeds the limit of %d bytes", len(data), maxWriteSize)
+			return result, nil
+		}
+		if err := writeFileAtomic(absPath, []byte(data), info.Mode().Perm()); err != nil {
+			result.Error = err.Error()
+			return result, nil
+		}
+
+		result.Success = true
+		result.Data = fmt.Sprintf("Successfully applied %d hunks to %s", len(hunks), args.Filepath)
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "apply_patch",
+		Description: "将统一 diff 格式（unified diff）的补丁应用到工作目录中的一个文件，只修改补丁涉及的部分。行号有偏移时会就近匹配；只要有一个 hunk 无法匹配，文件就保持不变并返回失败的 hunk。",
+	}, handler)
+}
+
+// parseUnifiedDiff parses the hunks of a unified diff of a single file. File headers
+// such as "diff --git", "---" and "+++" are skipped.
+func parseUnifiedDiff(patch string) ([]hunk, error) {
+	var hunks []hunk
+	var current *hunk
+	oldLeft, newLeft, files := 0, 0, 0
+	var last byte // Kind of the previous hunk line: ' ', '-' or '+'
+	for _, line := range splitLines(patch) {
+		text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if current != nil && (oldLeft > 0 || newLeft > 0) {
+			switch {
+			case strings.HasPrefix(text, " ") || text == "":
+				body := strings.TrimPrefix(line, " ")
+				if text == "" {
+					body = line // An empty context line whose leading space was stripped
+				}
+				current.old = append(current.old, body)
+				current.new = append(current.new, body)
+				oldLeft--
+				newLeft--
+				last = ' '
+			case strings.HasPrefix(text, "-"):
+				current.old = append(current.old, line[1:])
+				oldLeft--
+				last = '-'
+			case strings.HasPrefix(text, "+"):
+				current.new = append(current.new, line[1:])
+				newLeft--
+				last = '+'
+			case strings.HasPrefix(text, `\`):
+				trimLastLineEnding(current, last)
+			default:
+				return nil, fmt.Errorf("invalid patch: unexpected line %q in hunk %s", text, current.header)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("invalid patch: hunk %s has more lines than its header declares", current.header)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			h, oldLen, newLen, err := parseHunkHeader(text)
+			if err != nil {
+				return nil, err
+			}
+			hunks = append(hunks, h)
+			current = &hunks[len(hunks)-1]
+			oldLeft, newLeft = oldLen, newLen
+		case strings.HasPrefix(text, `\`) && current != nil:
+			trimLastLineEnding(current, last)
+		case strings.HasPrefix(text, "+++ "):
+			if files++; files > 1 {
+				return nil, errors.New("patch changes more than one file, apply the patch of each file separately")
+			}
+		}
+	}
+
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("invalid patch: hunk %s is truncated", current.header)
+	}
+	if len(hunks) == 0 {
+		return nil, errors.New("patch contains no hunks")
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader parses a "@@ -oldStart,oldLen +newStart,newLen @@" line. A missing
+// length means one line.
+func parseHunkHeader(line string) (h hunk, oldLen, newLen int, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return hunk{}, 0, 0, fmt.Errorf("invalid hunk header %q", line)
+	}
+	oldStart, oldLen, ok1 := parseRange(fields[1][1:])
+	_, newLen, ok2 := parseRange(fields[2][1:])
+	if !ok1 || !ok2 {
+		return hunk{}, 0, 0, fmt.Errorf("invalid hunk header %q", line)
+	}
+	return hunk{header: strings.Join(fields[:4], " "), oldStart: oldStart}, oldLen, newLen, nil
+}
+
+// parseRange parses the "start,length" of a hunk header.
+func parseRange(s string) (start, length int, ok bool) {
+	startText, lengthText, hasLength := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	length = 1
+	if hasLength {
+		if length, err = strconv.Atoi(lengthText); err != nil || length < 0 {
+			return 0, 0, false
+		}
+	}
+	return start, length, true
+}
+
+// trimLastLineEnding handles a "\ No newline at end of file" marker by removing the line
+// ending of the line before it, which is of the given kind.
+func trimLastLineEnding(h *hunk, kind byte) {
+	trim := func(lines []string) {
+		if n := len(lines); n > 0 {
+			lines[n-1] = strings.TrimSuffix(strings.TrimSuffix(lines[n-1], "\n"), "\r")
+		}
+	}
+	if kind != '+' {
+		trim(h.old)
+	}
+	if kind != '-' {
+		trim(h.new)
+	}
+}
+
+// applyHunks applies hunks to lines in order and returns the patched lines together with
+// the numbers of the hunks that did not match, starting at 1.
+func applyHunks(lines []string, hunks []hunk) ([]string, []int) {
+	var failed []int
+	offset, minPos := 0, 0 // Shift of the lines caused by earlier hunks, and where the next may start
+	for i, h := range hunks {
+		expected := max(h.oldStart-1, 0) + offset
+		if len(h.old) == 0 && h.oldStart > 0 {
+			expected = h.oldStart + offset // Pure insertions are placed after line oldStart
+		}
+		pos := findHunk(lines, h.old, expected, minPos)
+		if pos < 0 {
+			failed = append(failed, i+1)
+			continue
+		}
+		patched := make([]string, 0, len(lines)-len(h.old)+len(h.new))
+		patched = append(patched, lines[:pos]...)
+		patched = append(patched, h.new...)
+		patched = append(patched, lines[pos+len(h.old):]...)
+		lines = patched
+		offset += len(h.new) - len(h.old) + pos - expected
+		minPos = pos + len(h.new)
+	}
+	return lines, failed
+}
+
+// findHunk returns the position at or after minPos closest to expected where old matches
+// lines, or -1. Exact matches are preferred over ones that ignore trailing whitespace.
+func findHunk(lines, old []string, expected, minPos int) int {
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t\r\n") == strings.TrimRight(b, " \t\r\n") },
+	} {
+		matches := func(pos int) bool {
+			if pos < minPos || pos+len(old) > len(lines) {
+				return false
+			}
+			for j, line := range old {
+				if !equal(lines[pos+j], line) {
+					return false
+				}
+			}
+			return true
+		}
+		for delta := 0; expected-delta >= minPos || expected+delta <= len(lines); delta++ {
+			if matches(expected - delta) {
+				return expected - delta
+			}
+			if matches(expected + delta) {
+				return expected + delta
+			}
+		}
+	}
+	return -1
+}
+
+// splitLines splits s into lines that keep their line endings.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// maxContextKeyRunes is the longest key accepted by set_project_context.
+	maxContextKeyRunes = 100
+	// maxContextValueRunes is the longest value accepted by set_project_context, which
+	// keeps each entry from taking over the system prompt.
+	maxContextValueRunes = 1000
+)
+
+// SetProjectContextArgs is the input for set_project_context tool.
+type SetProjectContextArgs struct {
+	Key   string `json:"key"`   // Name of the fact, e.g. "entry_point" or "ci_pipeline_url"
+	Value string `json:"value"` // The fact, replacing any previous value of Key
+}
+
+// SetProjectContextResult is the output for set_project_context tool.
+type SetProjectContextResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// GetProjectContextArgs is the input for get_project_context tool.
+type GetProjectContextArgs struct {
+	Key string `json:"key"` // Name of the fact to look up
+}
+
+// GetProjectContextResult is the output for get_project_context tool.
+type GetProjectContextResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Value   string `json:"value,omitempty"` // Value recorded under the key
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// ListProjectContextArgs is the input for list_project_context tool.
+type ListProjectContextArgs struct{}
+
+// ListProjectContextResult is the output for list_project_context tool.
+type ListProjectContextResult struct {
+	Success bool              `json:"success"`           // Whether the operation succeeded
+	Context map[string]string `json:"context,omitempty"` // Every recorded fact, by key
+	Error   string            `json:"error,omitempty"`   // Error message if the operation failed
+}
+
+// createSetProjectContextTool creates the set_project_context tool.
+// This tool records a fact about the project, such as its entry point or the version of
+// its database schema, which is then included in the system prompt of every session.
+// Since it reaches the system prompt, the text is checked by cfg.DetectInjection first.
+func createSetProjectContextTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args SetProjectContextArgs) (SetProjectContextResult, error) {
+		key, value := strings.TrimSpace(args.Key), strings.TrimSpace(args.Value)
+		if key == "" || value == "" {
+			return SetProjectContextResult{Success: false, Error: "key and value are required"}, nil
+		}
+		if utf8.RuneCountInString(key) > maxContextKeyRunes || strings.ContainsAny(key, "\r\n") {
+			return SetProjectContextResult{Success: false, Error: fmt.Sprintf("key must be a single line of at most %d characters", maxContextKeyRunes)}, nil
+		}
+		if utf8.RuneCountInString(value) > maxContextValueRunes {
+			return SetProjectContextResult{Success: false, Error: fmt.Sprintf("value must be at most %d characters", maxContextValueRunes)}, nil
+		}
+		if cfg.DetectInjection != nil {
+			if injected, reason := cfg.DetectInjection(key + "\n" + value); injected {
+				return SetProjectContextResult{Success: false, Error: fmt.Sprintf("project context rejected as a possible prompt injection (%s)", reason)}, nil
+			}
+		}
+
+		if err := cfg.Store.SetProjectContext(ctx, key, value); err != nil {
+			return SetProjectContextResult{Success: false, Error: err.Error()}, nil
+		}
+		promptChanged(ctx, cfg)
+		return SetProjectContextResult{Success: true, Data: fmt.Sprintf("已记录项目上下文 %s", key)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "set_project_context",
+		Description: "记录项目级的背景信息，例如入口文件（entry_point）、数据库 schema 版本、CI 流水线地址。key 相同时覆盖原值。记录的信息会加入之后所有会话的系统提示中。",
+	}, handler)
+}
+
+// createGetProjectContextTool creates the get_project_context tool.
+// This tool returns the fact recorded under a key by set_project_context.
+func createGetProjectContextTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args GetProjectContextArgs) (GetProjectContextResult, error) {
+		if args.Key == "" {
+			return GetProjectContextResult{Success: false, Error: "key is required"}, nil
+		}
+		value, err := cfg.Store.GetProjectContext(ctx, args.Key)
+		if errors.Is(err, memory.ErrNotFound) {
+			return GetProjectContextResult{Success: false, Error: fmt.Sprintf("no project context recorded under %q", args.Key)}, nil
+		}
+		if err != nil {
+			return GetProjectContextResult{Success: false, Error: err.Error()}, nil
+		}
+		return GetProjectContextResult{Success: true, Value: value}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "get_project_context",
+		Description: "读取 set_project_context 以指定 key 记录的项目背景信息。",
+	}, handler)
+}
+
+// createListProjectContextTool creates the list_project_context tool.
+// This tool returns every fact recorded by set_project_context for the project.
+func createListProjectContextTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ListProjectContextArgs) (ListProjectContextResult, error) {
+		entries, err := cfg.Store.ListProjectContext(ctx)
+		if err != nil {
+			return ListProjectContextResult{Success: false, Error: err.Error()}, nil
+		}
+		return ListProjectContextResult{Success: true, Context: entries}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "list_project_context",
+		Description: "列出本项目记录的所有项目背景信息（key 到 value 的映射）。",
+	}, handler)
+}
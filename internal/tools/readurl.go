@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultURLBytes is the number of bytes of text read_url returns when MaxBytes is not set.
+	defaultURLBytes = 20000
+	// maxURLBytes is the largest number of bytes of text read_url returns.
+	maxURLBytes = 100000
+	// maxURLBodyBytes caps the response body read by read_url, before tags are stripped.
+	maxURLBodyBytes = 2 << 20
+	// urlTimeout is the time read_url allows for the whole request, body included.
+	urlTimeout = 10 * time.Second
+	// urlUserAgent is the User-Agent header of read_url requests.
+	urlUserAgent = "legacy-code-hunter/1.0 (+read_url)"
+)
+
+// ErrPrivateAddress is returned by read_url for URLs that resolve to a loopback, private
+// or link-local address, so that the agent cannot be used to reach internal services.
+var ErrPrivateAddress = errors.New("access denied: URL resolves to a private address")
+
+// ReadURLArgs is the input for read_url tool.
+type ReadURLArgs struct {
+	URL      string `json:"url"`                 // http:// or https:// URL to fetch
+	MaxBytes int    `json:"max_bytes,omitempty"` // Maximum number of bytes of text to return (default 20000, at most 100000)
+}
+
+// ReadURLResult is the output for read_url tool.
+type ReadURLResult struct {
+	Success   bool   `json:"success"`             // Whether the operation succeeded
+	Data      string `json:"data,omitempty"`      // Text of the page, with HTML tags, scripts and styles removed
+	Truncated bool   `json:"truncated,omitempty"` // Whether the text was cut at MaxBytes
+	Error     string `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createReadURLTool creates the read_url tool.
+// This tool fetches a web page, such as a GitHub issue or a Stack Overflow answer
+// about an error, and returns its text. Connections to loopback, private and
+// link-local addresses are refused, including after redirects.
+func createReadURLTool(cfg ToolsConfig) (tool.Tool, error) {
+	dialer := &net.Dialer{Timeout: urlTimeout, Control: denyPrivateAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would make the dialed address say nothing about the target
+	transport.DialContext = dialer.DialContext
+	return newReadURLTool(cfg, &http.Client{Transport: transport, Timeout: urlTimeout})
+}
+
+// newReadURLTool creates the read_url tool fetching pages with client.
+func newReadURLTool(cfg ToolsConfig, client *http.Client) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ReadURLArgs) (ReadURLResult, error) {
+		u, err := url.Parse(args.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ReadURLResult{Success: false, Error: "url must be an absolute http:// or https:// URL"}, nil
+		}
+		maxBytes := args.MaxBytes
+		if maxBytes <= 0 {
+			maxBytes = defaultURLBytes
+		}
+		maxBytes = min(maxBytes, maxURLBytes)
+
+		text, err := fetchURLText(commandContext(ctx), client, u.String())
+		if err != nil {
+			if errors.Is(err, ErrPrivateAddress) {
+				return ReadURLResult{Success: false, Error: ErrPrivateAddress.Error()}, nil
+			}
+			return ReadURLResult{Success: false, Error: err.Error()}, nil
+		}
+
+		result := ReadURLResult{Success: true, Data: text}
+		if len(text) > maxBytes {
+			result.Data, result.Truncated = truncateString(text, maxBytes), true
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "read_url",
+		Description: "获取 http:// 或 https:// 网页（如 GitHub issue、Stack Overflow 问答）并返回去除 HTML 标签后的正文，用于查找错误信息对应的解决方案。每次最多返回 max_bytes 字节（默认 20000，最大 100000）。不能访问内网地址。",
+	}, handler)
+}
+
+// fetchURLText fetches rawURL with client and returns its body as text, stripping the
+// markup of HTML pages. Responses other than 2xx are errors.
+func fetchURLText(ctx context.Context, client *http.Client, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %v", err)
+	}
+	req.Header.Set("User-Agent", urlUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch url: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to fetch url: unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxURLBodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return string(body), nil
+	}
+	return htmlText(string(body)), nil
+}
+
+// skippedElements are the HTML elements whose content is not text shown to readers.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Iframe: true,
+}
+
+// blockElements are the HTML elements that start a new line of text.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Pre: true, atom.Blockquote: true, atom.Section: true, atom.Article: true,
+	atom.Header: true, atom.Footer: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+}
+
+// htmlText returns the text of the HTML document doc without its tags. Runs of
+// whitespace are collapsed, and block elements such as paragraphs start new lines.
+func htmlText(doc string) string {
+	var text strings.Builder
+	newline := func() {
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteByte('\n')
+		}
+	}
+
+	skipDepth := 0
+	z := html.NewTokenizer(strings.NewReader(doc))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF, or malformed markup that ends the document
+			return strings.TrimSpace(text.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if skippedElements[tok.DataAtom] && tok.Type == html.StartTagToken {
+				skipDepth++
+			}
+			if blockElements[tok.DataAtom] {
+				newline()
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			if skippedElements[tok.DataAtom] && skipDepth > 0 {
+				skipDepth--
+			}
+			if blockElements[tok.DataAtom] {
+				newline()
+			}
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			words := strings.Fields(string(z.Text()))
+			if len(words) == 0 {
+				continue
+			}
+			if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+				text.WriteByte(' ')
+			}
+			text.WriteString(strings.Join(words, " "))
+		}
+	}
+}
+
+// denyPrivateAddress is a net.Dialer Control function refusing connections to loopback,
+// private, link-local and unspecified addresses. It sees the address after DNS
+// resolution, so a public host name that resolves to an internal address is refused too.
+func denyPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
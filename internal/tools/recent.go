@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// RecentToolResultsArgs is the input for get_recent_tool_results tool.
+type RecentToolResultsArgs struct {
+	Limit int `json:"limit,omitempty"` // Maximum number of results to return (default: all remembered results)
+}
+
+// RecentToolResultsResult is the output for get_recent_tool_results tool.
+type RecentToolResultsResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    any    `json:"data,omitempty"`  // Array of recent tool calls, oldest first
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// createRecentToolResultsTool creates the get_recent_tool_results tool.
+// This tool returns the tool calls made earlier in the session together with their
+// results, so the agent can refer back to them without re-reading the conversation.
+func createRecentToolResultsTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args RecentToolResultsArgs) (RecentToolResultsResult, error) {
+		records := sessionContext(cfg, ctx).RecentToolResults(args.Limit)
+		if len(records) == 0 {
+			return RecentToolResultsResult{Success: true, Data: "本次会话中还没有工具调用记录。"}, nil
+		}
+
+		results := make([]map[string]any, 0, len(records))
+		for _, r := range records {
+			results = append(results, map[string]any{
+				"tool_name": r.ToolName,
+				"args":      r.Args,
+				"result":    r.Result,
+				"called_at": r.CalledAt.Format(time.RFC3339),
+			})
+		}
+
+		return RecentToolResultsResult{Success: true, Data: results}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "get_recent_tool_results",
+		Description: "获取本次会话中最近的工具调用及其结果，用于回顾之前的搜索或读取结果，而无需重新执行。",
+	}, handler)
+}
+
+// maxRecordedStringLen is the number of bytes of each string argument or result value
+// kept in working memory. File contents and long search results are cut off after it.
+const maxRecordedStringLen = 2000
+
+// RecordToolResultCallback returns an after-tool callback that records every tool call
+// and its result in the session's working memory. Long strings are truncated in the
+// record, see truncateStrings; the tool result itself is not modified.
+func RecordToolResultCallback(wm *memory.WorkingMemory) func(ctx tool.Context, t tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+	return func(ctx tool.Context, t tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+		if t.Name() == "get_recent_tool_results" || wm == nil || ctx == nil {
+			return nil, nil
+		}
+		if err != nil {
+			result = map[string]any{"success": false, "error": err.Error()}
+		}
+		wm.Session(ctx.SessionID()).RecordToolResult(memory.ToolCallRecord{
+			ToolName: t.Name(),
+			Args:     truncateStrings(args).(map[string]any),
+			Result:   truncateStrings(result).(map[string]any),
+			CalledAt: time.Now(),
+		})
+		return nil, nil
+	}
+}
+
+// truncateStrings returns a copy of v in which strings longer than maxRecordedStringLen
+// bytes are cut off at a rune boundary and marked as truncated. Maps and slices are
+// copied recursively; other values are returned as they are.
+func truncateStrings(v any) any {
+	switch v := v.(type) {
+	case string:
+		if len(v) <= maxRecordedStringLen {
+			return v
+		}
+		end := maxRecordedStringLen
+		for end > 0 && !utf8.RuneStart(v[end]) {
+			end--
+		}
+		return v[:end] + "...(truncated)"
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		copied := make(map[string]any, len(v))
+		for key, value := range v {
+			copied[key] = truncateStrings(value)
+		}
+		return copied
+	case []any:
+		if v == nil {
+			return v
+		}
+		copied := make([]any, len(v))
+		for i, value := range v {
+			copied[i] = truncateStrings(value)
+		}
+		return copied
+	}
+	return v
+}
@@ -0,0 +1,387 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// defaultRuleResults is the number of rules returned by query_rules when MaxResults is not set.
+const defaultRuleResults = 5
+
+// QueryRulesArgs is the input for query_rules tool.
+type QueryRulesArgs struct {
+	Question   string `json:"question"`              // Natural language question about the project rules
+	MaxResults int    `json:"max_results,omitempty"` // Maximum number of rules to return (default 5)
+}
+
+// RuleMatch is a project rule returned by query_rules together with its relevance.
+type RuleMatch struct {
+	ID       int     `json:"id"`       // Rule ID
+	Category string  `json:"category"` // Rule category
+	Content  string  `json:"content"`  // Rule text
+	Score    float32 `json:"score"`    // Cosine similarity between the question and the rule
+}
+
+// QueryRulesResult is the output for query_rules tool.
+type QueryRulesResult struct {
+	Success bool        `json:"success"`         // Whether the operation succeeded
+	Rules   []RuleMatch `json:"rules,omitempty"` // Matching rules, most relevant first
+	Error   string      `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// ruleEmbeddingCache caches the embedding of each rule's content by rule ID.
+// An entry is recomputed whenever the content of its rule changes, and entries
+// of rules that are no longer active are dropped.
+type ruleEmbeddingCache struct {
+	mu      sync.Mutex
+	entries map[int]cachedRuleEmbedding
+}
+
+// cachedRuleEmbedding is the embedding of a rule along with the content it was computed from.
+type cachedRuleEmbedding struct {
+	content string
+	vector  []float32
+}
+
+// vectors returns the embedding of every rule, embedding only rules that are new or changed.
+func (c *ruleEmbeddingCache) vectors(ctx context.Context, embedder Embedder, rules []memory.ProjectRule) (map[int][]float32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make(map[int]cachedRuleEmbedding, len(rules))
+	vectors := make(map[int][]float32, len(rules))
+	for _, rule := range rules {
+		entry, ok := c.entries[rule.ID]
+		if !ok || entry.content != rule.RuleContent {
+			vector, err := embedder.Embed(ctx, rule.RuleContent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to embed rule %d: %w", rule.ID, err)
+			}
+			entry = cachedRuleEmbedding{content: rule.RuleContent, vector: vector}
+		}
+		entries[rule.ID] = entry
+		vectors[rule.ID] = entry.vector
+	}
+	c.entries = entries
+	return vectors, nil
+}
+
+// createQueryRulesTool creates the query_rules tool.
+// This tool answers questions about the project rules by semantic search, so the agent
+// does not need to read every rule. Rule embeddings are cached between calls.
+func createQueryRulesTool(cfg ToolsConfig) (tool.Tool, error) {
+	cache := &ruleEmbeddingCache{}
+
+	handler := func(ctx tool.Context, args QueryRulesArgs) (QueryRulesResult, error) {
+		if args.Question == "" {
+			return QueryRulesResult{Success: false, Error: "question is required"}, nil
+		}
+		limit := args.MaxResults
+		if limit <= 0 {
+			limit = defaultRuleResults
+		}
+
+		allRules, err := cfg.Store.ListProjectRules(ctx)
+		if err != nil {
+			return QueryRulesResult{Success: false, Error: fmt.Sprintf("failed to load project rules: %v", err)}, nil
+		}
+		var rules []memory.ProjectRule
+		for _, rule := range allRules {
+			if rule.IsActive {
+				rules = append(rules, rule)
+			}
+		}
+
+		questionVector, err := cfg.Embedder.Embed(ctx, args.Question)
+		if err != nil {
+			return QueryRulesResult{Success: false, Error: fmt.Sprintf("failed to generate embedding: %v", err)}, nil
+		}
+
+		ruleVectors, err := cache.vectors(ctx, cfg.Embedder, rules)
+		if err != nil {
+			return QueryRulesResult{Success: false, Error: err.Error()}, nil
+		}
+
+		matches := make([]RuleMatch, 0, len(rules))
+		for _, rule := range rules {
+			matches = append(matches, RuleMatch{
+				ID:       rule.ID,
+				Category: rule.Category,
+				Content:  rule.RuleContent,
+				Score:    memory.CosineSimilarity(questionVector, ruleVectors[rule.ID]),
+			})
+		}
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+
+		return QueryRulesResult{Success: true, Rules: matches}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "query_rules",
+		Description: "用自然语言查询项目规范，例如“关于错误处理有哪些规定？”，返回语义上最相关的规则。",
+	}, handler)
+}
+
+// AddProjectRuleArgs is the input for add_project_rule tool.
+type AddProjectRuleArgs struct {
+	Category    string `json:"category"`           // Rule category, e.g. "error_handling"
+	RuleContent string `json:"rule_content"`       // Rule text, as it should appear in the system prompt
+	Priority    int    `json:"priority,omitempty"` // Higher priorities are listed first (default 0)
+}
+
+// AddProjectRuleResult is the output for add_project_rule tool.
+type AddProjectRuleResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	ID      int    `json:"id,omitempty"`    // ID of the new or updated rule
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// ListProjectRulesArgs is the input for list_project_rules tool.
+type ListProjectRulesArgs struct{}
+
+// ProjectRuleInfo is a project rule returned by list_project_rules.
+type ProjectRuleInfo struct {
+	ID       int    `json:"id"`               // Rule ID
+	Category string `json:"category"`         // Rule category
+	Content  string `json:"content"`          // Rule text
+	Priority int    `json:"priority"`         // Higher priorities are listed first
+	IsActive bool   `json:"is_active"`        // Whether the rule is included in the system prompt
+	Shared   bool   `json:"shared,omitempty"` // Whether the rule applies to all projects
+
+	// AddedSinceStartup is set for rules created after the agent started, which reached the
+	// system prompt through a rule tool rather than at startup.
+	AddedSinceStartup bool `json:"added_since_startup,omitempty"`
+}
+
+// ListProjectRulesResult is the output for list_project_rules tool.
+type ListProjectRulesResult struct {
+	Success bool              `json:"success"`         // Whether the operation succeeded
+	Rules   []ProjectRuleInfo `json:"rules"`           // Rules of the project and shared rules, highest priority first
+	Count   int               `json:"count"`           // Number of rules listed
+	Active  int               `json:"active"`          // Number of listed rules included in the system prompt
+	Error   string            `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// UpdateProjectRuleArgs is the input for update_project_rule tool.
+type UpdateProjectRuleArgs struct {
+	ID          int    `json:"id"`                     // ID of the rule to change
+	RuleContent string `json:"rule_content,omitempty"` // New rule text (empty keeps the current one)
+	Priority    *int   `json:"priority,omitempty"`     // New priority (omitted keeps the current one)
+	IsActive    *bool  `json:"is_active,omitempty"`    // Whether the rule is active (omitted keeps the current state)
+}
+
+// UpdateProjectRuleResult is the output for update_project_rule tool.
+type UpdateProjectRuleResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// DeleteProjectRuleArgs is the input for delete_project_rule tool.
+type DeleteProjectRuleArgs struct {
+	ID int `json:"id"` // ID of the rule to delete
+}
+
+// DeleteProjectRuleResult is the output for delete_project_rule tool.
+type DeleteProjectRuleResult struct {
+	Success bool   `json:"success"`         // Whether the operation succeeded
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
+	Error   string `json:"error,omitempty"` // Error message if the operation failed
+}
+
+// projectRules returns the rules of cfg.ProjectID and the rules shared by all projects.
+func projectRules(ctx context.Context, cfg ToolsConfig) ([]memory.ProjectRule, error) {
+	rules, err := cfg.Store.ListProjectRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project rules: %w", err)
+	}
+	var visible []memory.ProjectRule
+	for _, rule := range rules {
+		if cfg.ProjectID == "" || rule.ProjectID == cfg.ProjectID || rule.ProjectID == "" {
+			visible = append(visible, rule)
+		}
+	}
+	return visible, nil
+}
+
+// checkRuleContent returns an error message if content is rejected by cfg.DetectInjection.
+func checkRuleContent(cfg ToolsConfig, content string) string {
+	if cfg.DetectInjection == nil {
+		return ""
+	}
+	if injected, reason := cfg.DetectInjection(content); injected {
+		return fmt.Sprintf("rule_content rejected as a possible prompt injection (%s)", reason)
+	}
+	return ""
+}
+
+// editableRule returns the rule with the given ID that the rule tools may change, or an
+// error message. Rules shared by all projects are read-only for an agent with a project.
+func editableRule(ctx context.Context, cfg ToolsConfig, id int) (memory.ProjectRule, string) {
+	rules, err := projectRules(ctx, cfg)
+	if err != nil {
+		return memory.ProjectRule{}, err.Error()
+	}
+	i := slices.IndexFunc(rules, func(rule memory.ProjectRule) bool { return rule.ID == id })
+	if i < 0 {
+		return memory.ProjectRule{}, fmt.Sprintf("project rule %d not found", id)
+	}
+	if rules[i].ProjectID != cfg.ProjectID {
+		return memory.ProjectRule{}, fmt.Sprintf("project rule %d is shared by all projects and cannot be changed", id)
+	}
+	return rules[i], ""
+}
+
+// promptChanged notifies cfg.PromptChanged, if set, that a tool changed the project rules
+// or context, which are part of the system prompt.
+func promptChanged(ctx context.Context, cfg ToolsConfig) {
+	if cfg.PromptChanged != nil {
+		cfg.PromptChanged(ctx)
+	}
+}
+
+// createAddProjectRuleTool creates the add_project_rule tool.
+// This tool records a new rule for the project, e.g. a convention the user asked the
+// agent to follow from now on. Adding a rule the project already has updates its
+// priority and reactivates it instead of duplicating it.
+func createAddProjectRuleTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args AddProjectRuleArgs) (AddProjectRuleResult, error) {
+		if args.Category == "" || args.RuleContent == "" {
+			return AddProjectRuleResult{Success: false, Error: "category and rule_content are required"}, nil
+		}
+		if msg := checkRuleContent(cfg, args.RuleContent); msg != "" {
+			return AddProjectRuleResult{Success: false, Error: msg}, nil
+		}
+
+		id, err := cfg.Store.UpsertProjectRule(ctx, args.Category, args.RuleContent, args.Priority)
+		if err != nil {
+			return AddProjectRuleResult{Success: false, Error: fmt.Sprintf("failed to add project rule: %v", err)}, nil
+		}
+		promptChanged(ctx, cfg)
+
+		return AddProjectRuleResult{Success: true, ID: id}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "add_project_rule",
+		Description: "为当前项目新增一条项目规范，之后的对话会在系统提示中遵守它。仅在用户明确要求记住某条约定时使用。若同一分类下已有相同规范，则更新其优先级并重新启用，不会重复添加。",
+	}, handler)
+}
+
+// createListProjectRulesTool creates the list_project_rules tool.
+// This tool lists every rule of the project, including inactive ones, with the IDs
+// needed to update or delete them.
+func createListProjectRulesTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ListProjectRulesArgs) (ListProjectRulesResult, error) {
+		rules, err := projectRules(ctx, cfg)
+		if err != nil {
+			return ListProjectRulesResult{Success: false, Error: err.Error()}, nil
+		}
+
+		infos := make([]ProjectRuleInfo, 0, len(rules))
+		active := 0
+		for _, rule := range rules {
+			infos = append(infos, ProjectRuleInfo{
+				ID:                rule.ID,
+				Category:          rule.Category,
+				Content:           rule.RuleContent,
+				Priority:          rule.Priority,
+				IsActive:          rule.IsActive,
+				Shared:            rule.ProjectID == "",
+				AddedSinceStartup: !cfg.StartedAt.IsZero() && rule.CreatedAt.After(cfg.StartedAt),
+			})
+			if rule.IsActive {
+				active++
+			}
+		}
+		return ListProjectRulesResult{Success: true, Rules: infos, Count: len(infos), Active: active}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "list_project_rules",
+		Description: "列出当前项目的全部项目规范（包括未启用的和所有项目共享的），返回 ID、类别、内容、优先级、启用状态、总数和启用数，并标出启动后才添加的规范。可用于排查规范配置问题。",
+	}, handler)
+}
+
+// createUpdateProjectRuleTool creates the update_project_rule tool.
+// This tool changes the text or priority of a rule, or deactivates it without losing it.
+func createUpdateProjectRuleTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args UpdateProjectRuleArgs) (UpdateProjectRuleResult, error) {
+		if args.ID <= 0 {
+			return UpdateProjectRuleResult{Success: false, Error: "id must be a positive integer"}, nil
+		}
+
+		if msg := checkRuleContent(cfg, args.RuleContent); msg != "" {
+			return UpdateProjectRuleResult{Success: false, Error: msg}, nil
+		}
+
+		// Keep the fields that are not being changed
+		rule, msg := editableRule(ctx, cfg, args.ID)
+		if msg != "" {
+			return UpdateProjectRuleResult{Success: false, Error: msg}, nil
+		}
+		if args.RuleContent != "" {
+			rule.RuleContent = args.RuleContent
+		}
+		if args.Priority != nil {
+			rule.Priority = *args.Priority
+		}
+		if args.IsActive != nil {
+			rule.IsActive = *args.IsActive
+		}
+
+		if err := cfg.Store.UpdateProjectRule(ctx, rule.ID, rule.RuleContent, rule.Priority, rule.IsActive); err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return UpdateProjectRuleResult{Success: false, Error: fmt.Sprintf("project rule %d not found", args.ID)}, nil
+			}
+			return UpdateProjectRuleResult{Success: false, Error: fmt.Sprintf("failed to update project rule: %v", err)}, nil
+		}
+		promptChanged(ctx, cfg)
+
+		return UpdateProjectRuleResult{Success: true, Data: fmt.Sprintf("项目规范 #%d 已更新。", args.ID)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "update_project_rule",
+		Description: "修改一条项目规范的内容、优先级或启用状态，未提供的字段保持不变。停用规范请将 is_active 设为 false。所有项目共享的规范是只读的。",
+	}, handler)
+}
+
+// createDeleteProjectRuleTool creates the delete_project_rule tool.
+// This tool permanently deletes a rule that no longer applies.
+func createDeleteProjectRuleTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args DeleteProjectRuleArgs) (DeleteProjectRuleResult, error) {
+		if args.ID <= 0 {
+			return DeleteProjectRuleResult{Success: false, Error: "id must be a positive integer"}, nil
+		}
+		if _, msg := editableRule(ctx, cfg, args.ID); msg != "" {
+			return DeleteProjectRuleResult{Success: false, Error: msg}, nil
+		}
+
+		if err := cfg.Store.DeleteProjectRule(ctx, args.ID); err != nil {
+			if errors.Is(err, memory.ErrNotFound) {
+				return DeleteProjectRuleResult{Success: false, Error: fmt.Sprintf("project rule %d not found", args.ID)}, nil
+			}
+			return DeleteProjectRuleResult{Success: false, Error: fmt.Sprintf("failed to delete project rule: %v", err)}, nil
+		}
+		promptChanged(ctx, cfg)
+
+		return DeleteProjectRuleResult{Success: true, Data: fmt.Sprintf("项目规范 #%d 已删除。", args.ID)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "delete_project_rule",
+		Description: "永久删除一条不再适用的项目规范。若只是暂时不需要，请用 update_project_rule 停用。所有项目共享的规范是只读的。",
+	}, handler)
+}
@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrPathOutsideWorkDir is returned by SafeAbsPath when a path resolves to a location
+// outside the working directory.
+var ErrPathOutsideWorkDir = errors.New("access denied: path is outside working directory")
+
+// ErrSymlinkLoop is returned by SafeAbsPath when resolving a path follows more than
+// ErrUnsupportedFileType is returned by read_file_content for files whose extension is
+// not in ToolsConfig.AllowedExtensions, or is in ToolsConfig.DeniedExtensions.
+var ErrUnsupportedFileType = errors.New("unsupported file type")
+
+// DefaultAllowedExtensions are the extensions read_file_content reads when
+// ToolsConfig.AllowedExtensions is nil: source code, documentation and configuration,
+// and files without an extension such as Makefile.
+var DefaultAllowedExtensions = []string{
+	"", ".go", ".mod", ".sum", ".py", ".js", ".jsx", ".ts", ".tsx", ".java", ".kt", ".rs",
+	".c", ".h", ".cc", ".cpp", ".hpp", ".cs", ".rb", ".php", ".swift", ".scala", ".sql",
+	".proto", ".md", ".txt", ".rst", ".yaml", ".yml", ".json", ".toml", ".ini", ".cfg",
+	".xml", ".html", ".css", ".sh", ".bash", ".tmpl", ".csv",
+}
+
+// DefaultDeniedExtensions are the extensions read_file_content refuses when
+// ToolsConfig.DeniedExtensions is nil, because such files usually hold secrets.
+var DefaultDeniedExtensions = []string{".env", ".key", ".pem", ".p12", ".pfx"}
+
+// maxSymlinkDepth symlinks, which in practice means the links form a loop.
+var ErrSymlinkLoop = errors.New("too many levels of symbolic links")
+
+// maxSymlinkDepth is the number of symlinks followed while resolving a single path,
+// the same limit as the Linux kernel's.
+const maxSymlinkDepth = 40
+
+// SafeAbsPath resolves path against workDir and verifies that the result stays within
+// workDir. Symlinks are resolved before the check, so a link inside workDir that points
+// elsewhere is rejected. For paths that do not exist yet, the deepest existing ancestor
+// is resolved instead, so a new file cannot be created through a symlinked directory
+// that leads out of workDir. Resolving fails with ErrSymlinkLoop after maxSymlinkDepth
+// links. It returns the absolute path, without resolving symlinks.
+func SafeAbsPath(path, workDir string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %v", err)
+	}
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %v", err)
+	}
+
+	// Check the path as written first, which also rejects traversal like "../x"
+	// and "/home/user/work-evil" bypassing "/home/user/work"
+	if !withinDir(absWorkDir, absPath) {
+		return "", ErrPathOutsideWorkDir
+	}
+
+	resolvedWorkDir, err := evalSymlinks(absWorkDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %w", err)
+	}
+	resolvedPath, err := evalExistingSymlinks(absPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	if !withinDir(resolvedWorkDir, resolvedPath) {
+		return "", ErrPathOutsideWorkDir
+	}
+
+	return absPath, nil
+}
+
+// evalExistingSymlinks resolves the symlinks in path like evalSymlinks. When path
+// does not exist, it resolves the deepest existing ancestor and appends the missing
+// components to it. A dangling symlink on the way is an error, because writing through
+// it would create its target wherever it points.
+func evalExistingSymlinks(path string) (string, error) {
+	existing := path
+	var missing []string
+	for {
+		resolved, err := evalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if info, lerr := os.Lstat(existing); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%s is a dangling symlink", existing)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+}
+
+// evalSymlinks resolves the symlinks in the absolute path like filepath.EvalSymlinks, but
+// gives up with ErrSymlinkLoop once more than maxSymlinkDepth links have been followed.
+func evalSymlinks(path string) (string, error) {
+	root := filepath.VolumeName(path) + string(filepath.Separator)
+	resolved := root
+	pending := strings.Split(path[len(root):], string(filepath.Separator))
+	links := 0
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, name)
+		info, err := os.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinkDepth {
+			return "", fmt.Errorf("%s: %w", path, ErrSymlinkLoop)
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			resolved = filepath.VolumeName(target) + string(filepath.Separator)
+			target = target[len(resolved):]
+		}
+		pending = append(strings.Split(target, string(filepath.Separator)), pending...)
+	}
+	return resolved, nil
+}
+
+// mountPrefix starts paths that refer to a mounted directory, as in "@lib/util/strings.go".
+const mountPrefix = "@"
+
+// resolvePath resolves a path given to a tool like SafeAbsPath. Paths of the form
+// "@alias/rest" are resolved against the directory mounted as alias in cfg.Mounts
+// instead of cfg.WorkDir, and must stay within that directory.
+func resolvePath(cfg ToolsConfig, path string) (string, error) {
+	aliasPath, ok := strings.CutPrefix(path, mountPrefix)
+	if !ok {
+		return SafeAbsPath(path, cfg.WorkDir)
+	}
+	alias, rest, _ := strings.Cut(aliasPath, "/")
+	dir, ok := cfg.Mounts[alias]
+	if !ok {
+		return "", fmt.Errorf("access denied: no directory is mounted as %s%s", mountPrefix, alias)
+	}
+	absPath, err := SafeAbsPath(rest, dir)
+	if errors.Is(err, ErrPathOutsideWorkDir) {
+		return "", fmt.Errorf("access denied: path is outside mounted directory %s%s", mountPrefix, alias)
+	}
+	return absPath, err
+}
+
+// mountsDescription lists the mounted directories for the descriptions of the tools that
+// accept "@alias/path", or returns "" when nothing is mounted.
+func mountsDescription(cfg ToolsConfig) string {
+	if len(cfg.Mounts) == 0 {
+		return ""
+	}
+	aliases := make([]string, 0, len(cfg.Mounts))
+	for alias := range cfg.Mounts {
+		aliases = append(aliases, mountPrefix+alias+"/")
+	}
+	slices.Sort(aliases)
+	return " 其他代码仓库挂载在以下前缀下，可用「前缀+相对路径」访问：" + strings.Join(aliases, "、") + "。"
+}
+
+// withinDir reports whether the absolute path p is dir or lies below it.
+func withinDir(dir, p string) bool {
+	relPath, err := filepath.Rel(dir, p)
+	return err == nil && !strings.HasPrefix(relPath, "..") && !filepath.IsAbs(relPath)
+}
+
+// checkExtension returns ErrUnsupportedFileType when the extension of path is denied by
+// cfg, or not allowed by it. Extensions are compared case-insensitively.
+func checkExtension(cfg ToolsConfig, path string) error {
+	allowed := cfg.AllowedExtensions
+	if allowed == nil {
+		allowed = DefaultAllowedExtensions
+	}
+	return checkExtensionIn(cfg, path, allowed)
+}
+
+// checkExtensionIn is checkExtension with the allowed extensions of a tool other than
+// read_file_content; an empty list allows all extensions.
+func checkExtensionIn(cfg ToolsConfig, path string, allowed []string) error {
+	denied := cfg.DeniedExtensions
+	if denied == nil {
+		denied = DefaultDeniedExtensions
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	matches := func(e string) bool { return strings.ToLower(e) == ext }
+	if slices.ContainsFunc(denied, matches) {
+		return fmt.Errorf("%w: %s files are not readable", ErrUnsupportedFileType, ext)
+	}
+	if len(allowed) > 0 && !slices.ContainsFunc(allowed, matches) {
+		if ext == "" {
+			return fmt.Errorf("%w: files without an extension are not readable", ErrUnsupportedFileType)
+		}
+		return fmt.Errorf("%w: %s files are not readable", ErrUnsupportedFileType, ext)
+	}
+	return nil
+}
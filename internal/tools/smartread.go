@@ -0,0 +1,370 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Intents accepted by the smart_read_file tool.
+const (
+	IntentUnderstandStructure = "understand_structure"
+	IntentFindBug             = "find_bug"
+	IntentReadFull            = "read_full"
+	IntentSearchSymbol        = "search_symbol"
+)
+
+// Strategies reported by the smart_read_file tool.
+const (
+	StrategyFullRead         = "full_read"
+	StrategyASTSummary       = "ast_summary"
+	StrategyChunked          = "chunked"
+	StrategySymbolDefinition = "symbol_definition"
+)
+
+// smallFileLimit is the size below which find_bug reads the whole file.
+const smallFileLimit = 5 * 1024
+
+// maxReadSize is the maximum number of bytes of file content returned by a read.
+const maxReadSize = 10000
+
+// SmartReadArgs is the input for smart_read_file tool.
+type SmartReadArgs struct {
+	Filepath string `json:"filepath"`         // Path to the file to read (relative to WorkDir or absolute)
+	Intent   string `json:"intent"`           // One of understand_structure, find_bug, read_full, search_symbol
+	Symbol   string `json:"symbol,omitempty"` // Symbol to look up for search_symbol, or to focus chunks on for find_bug
+}
+
+// SmartReadResult is the output for smart_read_file tool.
+type SmartReadResult struct {
+	Success  bool   `json:"success"`            // Whether the operation succeeded
+	Strategy string `json:"strategy,omitempty"` // Strategy used to read the file
+	Content  any    `json:"content,omitempty"`  // File content, AST summary, chunks or symbol definition
+	Error    string `json:"error,omitempty"`    // Error message if the operation failed
+}
+
+// goChunk is a contiguous range of top-level declarations of a Go file.
+type goChunk struct {
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+// createSmartReadTool creates the smart_read_file tool.
+// Based on the caller's intent and the file's size and type it picks the most useful
+// way of reading the file: the whole content, a structural AST summary, the chunks
+// of a large Go file, or the definition of a single symbol.
+func createSmartReadTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args SmartReadArgs) (SmartReadResult, error) {
+		if args.Filepath == "" {
+			return SmartReadResult{Success: false, Error: "filepath is required"}, nil
+		}
+
+		absPath, err := SafeAbsPath(args.Filepath, cfg.WorkDir)
+		if err != nil {
+			return SmartReadResult{Success: false, Error: err.Error()}, nil
+		}
+
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return SmartReadResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+		}
+
+		return smartRead(absPath, content, args)
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "smart_read_file",
+		Description: "根据阅读意图自动选择读取方式：understand_structure 返回 Go 文件的结构摘要，find_bug 读取小文件全文或大文件的分块，read_full 读取全文，search_symbol 返回指定符号的定义。",
+	}, handler)
+}
+
+// smartRead dispatches a read of content to the strategy matching args.Intent.
+func smartRead(path string, content []byte, args SmartReadArgs) (SmartReadResult, error) {
+	isGo := strings.HasSuffix(path, ".go")
+
+	switch args.Intent {
+	case IntentUnderstandStructure:
+		if !isGo {
+			return fullRead(content), nil
+		}
+		summary, err := summarizeGoSource(path, content)
+		if err != nil {
+			return SmartReadResult{Success: false, Error: err.Error()}, nil
+		}
+		return SmartReadResult{Success: true, Strategy: StrategyASTSummary, Content: summary}, nil
+
+	case IntentFindBug:
+		if len(content) < smallFileLimit || !isGo {
+			return fullRead(content), nil
+		}
+		chunks := chunkGoSource(path, content, maxReadSize/2)
+		if args.Symbol != "" {
+			chunks = filterChunks(chunks, args.Symbol)
+		}
+		return SmartReadResult{Success: true, Strategy: StrategyChunked, Content: limitChunks(chunks, maxReadSize)}, nil
+
+	case IntentReadFull:
+		return fullRead(content), nil
+
+	case IntentSearchSymbol:
+		if args.Symbol == "" {
+			return SmartReadResult{Success: false, Error: "symbol is required for search_symbol"}, nil
+		}
+		if !isGo {
+			return SmartReadResult{Success: false, Error: "search_symbol is only supported for Go files"}, nil
+		}
+		def, err := findGoDefinition(path, content, args.Symbol)
+		if err != nil {
+			return SmartReadResult{Success: false, Error: err.Error()}, nil
+		}
+		return SmartReadResult{Success: true, Strategy: StrategySymbolDefinition, Content: def}, nil
+
+	default:
+		return SmartReadResult{Success: false, Error: fmt.Sprintf("unknown intent %q", args.Intent)}, nil
+	}
+}
+
+// fullRead returns the file content, truncated to maxReadSize bytes.
+func fullRead(content []byte) SmartReadResult {
+	s := string(content)
+	if len(s) > maxReadSize {
+		s = truncateString(s, maxReadSize) + "\n... (truncated)"
+	}
+	return SmartReadResult{Success: true, Strategy: StrategyFullRead, Content: s}
+}
+
+// summarizeGoSource returns the package name, imports, types and function
+// signatures declared in a Go source file.
+func summarizeGoSource(path string, content []byte) (map[string]any, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go file: %w", err)
+	}
+
+	var imports, types, funcs []string
+	for _, imp := range f.Imports {
+		imports = append(imports, strings.Trim(imp.Path.Value, `"`))
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			funcs = append(funcs, fmt.Sprintf("%d: %s", fset.Position(d.Pos()).Line, funcSignature(fset, content, d)))
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				types = append(types, fmt.Sprintf("%d: type %s %s", fset.Position(ts.Pos()).Line, ts.Name.Name, typeKind(ts)))
+			}
+		}
+	}
+
+	return map[string]any{
+		"package":   f.Name.Name,
+		"imports":   imports,
+		"types":     types,
+		"functions": funcs,
+	}, nil
+}
+
+// chunkGoSource splits a Go file into chunks of consecutive top-level declarations,
+// each at most maxBytes long unless a single declaration is larger. Doc comments are
+// kept with their declaration. Files that cannot be parsed are split by lines.
+func chunkGoSource(path string, content []byte, maxBytes int) []goChunk {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return chunkLines(content, maxBytes)
+	}
+
+	var chunks []goChunk
+	start := 0 // byte offset where the current chunk begins
+	last := 0  // byte offset of the end of the last declaration added to the current chunk
+	for _, decl := range f.Decls {
+		declStart := fset.Position(declPos(decl)).Offset
+		declEnd := fset.Position(decl.End()).Offset
+		if last > start && declEnd-start > maxBytes {
+			chunks = append(chunks, newChunk(content, start, last))
+			start = declStart
+		}
+		last = declEnd
+	}
+	if last > start {
+		chunks = append(chunks, newChunk(content, start, last))
+	}
+	return chunks
+}
+
+// chunkLines splits content into chunks of whole lines, each at most maxBytes long
+// unless a single line is larger.
+func chunkLines(content []byte, maxBytes int) []goChunk {
+	var chunks []goChunk
+	start, lineStart := 0, 0
+	for i, b := range content {
+		if b != '\n' {
+			continue
+		}
+		if i+1-start > maxBytes && lineStart > start {
+			chunks = append(chunks, newChunk(content, start, lineStart))
+			start = lineStart
+		}
+		lineStart = i + 1
+	}
+	if len(content) > start {
+		chunks = append(chunks, newChunk(content, start, len(content)))
+	}
+	return chunks
+}
+
+// newChunk creates the chunk covering content[start:end].
+func newChunk(content []byte, start, end int) goChunk {
+	return goChunk{
+		StartLine: strings.Count(string(content[:start]), "\n") + 1,
+		EndLine:   strings.Count(string(content[:end]), "\n") + 1,
+		Text:      string(content[start:end]),
+	}
+}
+
+// filterChunks returns the chunks mentioning symbol, or all chunks if none do.
+func filterChunks(chunks []goChunk, symbol string) []goChunk {
+	var matched []goChunk
+	for _, c := range chunks {
+		if strings.Contains(c.Text, symbol) {
+			matched = append(matched, c)
+		}
+	}
+	if len(matched) == 0 {
+		return chunks
+	}
+	return matched
+}
+
+// limitChunks returns the leading chunks whose combined text fits in maxBytes,
+// always keeping at least the first chunk.
+func limitChunks(chunks []goChunk, maxBytes int) []goChunk {
+	total := 0
+	for i, c := range chunks {
+		total += len(c.Text)
+		if total > maxBytes && i > 0 {
+			return chunks[:i]
+		}
+	}
+	return chunks
+}
+
+// findGoDefinition returns the source of the top-level declaration named symbol,
+// including its doc comment. Methods match either "Name" or "Type.Name".
+func findGoDefinition(path string, content []byte, symbol string) (map[string]any, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go file: %w", err)
+	}
+
+	for _, decl := range f.Decls {
+		if !declaresSymbol(decl, symbol) {
+			continue
+		}
+		start := fset.Position(declPos(decl))
+		end := fset.Position(decl.End())
+		return map[string]any{
+			"symbol":     symbol,
+			"start_line": start.Line,
+			"end_line":   end.Line,
+			"source":     string(content[start.Offset:end.Offset]),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("symbol %s not found in %s", symbol, filepath.Base(path))
+}
+
+// declaresSymbol reports whether decl declares symbol.
+func declaresSymbol(decl ast.Decl, symbol string) bool {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Name.Name == symbol {
+			return true
+		}
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return memory.ReceiverName(d.Recv.List[0].Type)+"."+d.Name.Name == symbol
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == symbol {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					if name.Name == symbol {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// declPos returns the start of decl, including its doc comment.
+func declPos(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
+
+// typeKind returns a short description of a type declaration, e.g. "struct" or "interface".
+// Aliases ("type A = B") are "alias"; types defined from another named type ("type ID int")
+// are "named".
+func typeKind(ts *ast.TypeSpec) string {
+	if ts.Assign.IsValid() {
+		return "alias"
+	}
+	switch t := ts.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.FuncType:
+		return "func"
+	case *ast.MapType:
+		return "map"
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "array"
+		}
+		return "slice"
+	case *ast.ChanType:
+		return "chan"
+	case *ast.StarExpr:
+		return "pointer"
+	}
+	return "named"
+}
+
+// funcSignature returns the source text of a function declaration without its doc comment and body.
+func funcSignature(fset *token.FileSet, content []byte, fn *ast.FuncDecl) string {
+	start := fset.Position(fn.Pos()).Offset
+	end := fset.Position(fn.Type.End()).Offset
+	return string(content[start:end])
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// maxSQLRows is the number of rows execute_sql returns.
+const maxSQLRows = 100
+
+// forbiddenSQLKeyword matches the statements that change the database or its schema.
+// Whole words only, so that columns such as created_at and updated_at are allowed.
+var forbiddenSQLKeyword = regexp.MustCompile(`(?i)\b(DROP|DELETE|UPDATE|INSERT|CREATE|ALTER|ATTACH|TRUNCATE|GRANT|COPY)\b`)
+
+// ExecuteSQLArgs is the input for execute_sql tool.
+type ExecuteSQLArgs struct {
+	Query string `json:"query"` // A single SELECT statement
+}
+
+// ExecuteSQLResult is the output for execute_sql tool.
+type ExecuteSQLResult struct {
+	Success   bool             `json:"success"`             // Whether the operation succeeded
+	Rows      []map[string]any `json:"rows,omitempty"`      // Rows returned, at most 100, as column name to value
+	Truncated bool             `json:"truncated,omitempty"` // Whether the query returned more rows than those given
+	Error     string           `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createExecuteSQLTool creates the execute_sql tool.
+// This debug tool runs an ad-hoc SELECT statement against the memory database, e.g. to
+// inspect issue_history or project_rules. Queries that are not a single SELECT, or
+// mention a statement changing the database, are rejected before they reach it, and
+// cfg.SQL runs the rest in a read-only transaction. Every query is logged, and recorded
+// in the audit log when there is one. BuildTools only includes it when cfg.DebugTools
+// is set and cfg.SQL is not nil.
+func createExecuteSQLTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ExecuteSQLArgs) (ExecuteSQLResult, error) {
+		if cfg.SQL == nil {
+			return ExecuteSQLResult{Success: false, Error: "execute_sql requires a SQL database"}, nil
+		}
+		query, err := validateSelectQuery(args.Query)
+		if err != nil {
+			return ExecuteSQLResult{Success: false, Error: err.Error()}, nil
+		}
+
+		slog.InfoContext(commandContext(ctx), "executing diagnostic SQL query", slog.String("tool", "execute_sql"), slog.String("query", query))
+		rows, truncated, err := cfg.SQL.QueryReadOnly(commandContext(ctx), query, maxSQLRows)
+		if err != nil {
+			return ExecuteSQLResult{Success: false, Error: err.Error()}, nil
+		}
+		return ExecuteSQLResult{Success: true, Rows: rows, Truncated: truncated}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "execute_sql",
+		Description: "调试工具：对记忆数据库执行只读 SELECT 查询（如查看 issue_history、project_rules 表），最多返回 100 行，每行为列名到值的映射。只允许单条 SELECT 语句，包含 DROP、DELETE、UPDATE、INSERT、CREATE、ALTER 等关键词的查询会被拒绝。",
+	}, handler)
+}
+
+// validateSelectQuery checks that query is a single SELECT statement mentioning none of
+// the statements that change the database, and returns it without surrounding
+// whitespace and trailing semicolon.
+func validateSelectQuery(query string) (string, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	if !strings.HasPrefix(strings.ToUpper(query), "SELECT") {
+		return "", fmt.Errorf("only SELECT queries are allowed")
+	}
+	if strings.Contains(query, ";") {
+		return "", fmt.Errorf("only a single statement is allowed")
+	}
+	if keyword := forbiddenSQLKeyword.FindString(query); keyword != "" {
+		return "", fmt.Errorf("query must not contain %s", strings.ToUpper(keyword))
+	}
+	return query, nil
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// SummarizeSessionArgs is the input for summarize_session tool.
+type SummarizeSessionArgs struct {
+	IncludeToolCalls bool `json:"include_tool_calls,omitempty"` // Show the tool calls and results of the session to the summarizer (default false)
+}
+
+// SummarizeSessionResult is the output for summarize_session tool.
+type SummarizeSessionResult struct {
+	Success          bool   `json:"success"`                     // Whether the operation succeeded
+	Summary          string `json:"summary,omitempty"`           // Recap of the session to paste into a ticket comment
+	ProblemStatement string `json:"problem_statement,omitempty"` // Problem the session was about
+	Resolved         bool   `json:"resolved"`                    // Whether the problem was solved
+	Error            string `json:"error,omitempty"`             // Error message if the operation failed
+}
+
+// createSummarizeSessionTool creates the summarize_session tool.
+// This tool asks cfg.Generator for a recap of the current session, read from the
+// session's working memory, see RecordSessionHistoryCallback. The request for the recap
+// is sent to the generator directly, so it does not become part of the conversation.
+func createSummarizeSessionTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args SummarizeSessionArgs) (SummarizeSessionResult, error) {
+		if cfg.Generator == nil {
+			return SummarizeSessionResult{Success: false, Error: "summarize_session requires a text generator"}, nil
+		}
+
+		history := sessionContext(cfg, ctx).SessionHistory()
+		recap, err := memory.SummarizeSession(ctx, cfg.Generator, history, args.IncludeToolCalls)
+		if err != nil {
+			if errors.Is(err, memory.ErrEmptySession) {
+				return SummarizeSessionResult{Success: false, Error: err.Error()}, nil
+			}
+			return SummarizeSessionResult{Success: false, Error: fmt.Sprintf("failed to summarize session: %v", err)}, nil
+		}
+		return SummarizeSessionResult{
+			Success:          true,
+			Summary:          recap.Text(),
+			ProblemStatement: recap.ProblemStatement,
+			Resolved:         recap.Resolved,
+		}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "summarize_session",
+		Description: "总结本次会话：问题描述、已尝试的步骤、找到的解决方案（或尚未解决）以及后续行动，结果简短（不超过 400 字），可直接粘贴到工单评论中。include_tool_calls 为 true 时总结也会参考工具调用及其结果。",
+	}, handler)
+}
+
+// RecordSessionHistoryCallback returns a before-model callback that keeps the
+// conversation of every model request in the session's working memory, for
+// summarize_session. The request itself is not modified.
+func RecordSessionHistoryCallback(wm *memory.WorkingMemory) llmagent.BeforeModelCallback {
+	return func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+		if wm == nil || ctx == nil || req == nil {
+			return nil, nil
+		}
+		wm.Session(ctx.SessionID()).SetSessionHistory(req.Contents)
+		return nil, nil
+	}
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// goVetTimeout bounds the go vet run of validate_go_syntax.
+const goVetTimeout = 60 * time.Second
+
+// ValidateGoSyntaxArgs is the input for validate_go_syntax tool.
+type ValidateGoSyntaxArgs struct {
+	Code     string `json:"code,omitempty"`     // Go source to check, a complete file with its package clause; takes precedence over filepath
+	Filepath string `json:"filepath,omitempty"` // Go file to check (relative to WorkDir or absolute), also checked with go vet
+}
+
+// SyntaxError is a syntax error found by validate_go_syntax.
+type SyntaxError struct {
+	Line    int    `json:"line"`    // Line of the error, starting at 1
+	Column  int    `json:"column"`  // Column of the error in bytes, starting at 1
+	Message string `json:"message"` // Error message of the Go parser
+}
+
+// ValidateResult is the output for validate_go_syntax tool.
+type ValidateResult struct {
+	Success     bool          `json:"success"`                // Whether the code could be checked
+	Valid       bool          `json:"valid"`                  // Whether the code is syntactically valid Go
+	Errors      []SyntaxError `json:"errors,omitempty"`       // Syntax errors, in source order
+	VetWarnings []string      `json:"vet_warnings,omitempty"` // Diagnostics of go vet about the file
+	Error       string        `json:"error,omitempty"`        // Error message if the operation failed
+}
+
+// createValidateGoSyntaxTool creates the validate_go_syntax tool.
+// This tool parses Go code given inline or read from a file and reports every syntax
+// error with its position, so the agent can check code before writing it. Files are
+// also checked with go vet.
+func createValidateGoSyntaxTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ValidateGoSyntaxArgs) (ValidateResult, error) {
+		if args.Code != "" {
+			syntaxErrors := goSyntaxErrors("code.go", args.Code)
+			return ValidateResult{Success: true, Valid: len(syntaxErrors) == 0, Errors: syntaxErrors}, nil
+		}
+		if args.Filepath == "" {
+			return ValidateResult{Success: false, Error: "code or filepath is required"}, nil
+		}
+		absPath, err := resolvePath(cfg, args.Filepath)
+		if err != nil {
+			return ValidateResult{Success: false, Error: err.Error()}, nil
+		}
+		if filepath.Ext(absPath) != ".go" {
+			return ValidateResult{Success: false, Error: "filepath must be a .go file"}, nil
+		}
+
+		syntaxErrors, err := goFileSyntaxErrors(absPath)
+		if err != nil {
+			return ValidateResult{Success: false, Error: err.Error()}, nil
+		}
+		result := ValidateResult{Success: true, Valid: len(syntaxErrors) == 0, Errors: syntaxErrors}
+		if result.Valid {
+			// go vet type checks the package, which fails on syntax errors
+			result.VetWarnings = goVetWarnings(commandContext(ctx), absPath)
+		}
+		return result, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "validate_go_syntax",
+		Description: "检查 Go 代码是否有语法错误，返回每个错误的行号、列号和信息。可通过 code 传入完整的 Go 源文件（包含 package 声明），或通过 filepath 指定文件；两者都提供时检查 code。检查文件时若语法正确，还会对文件所在的包运行 go vet，并在 vet_warnings 中返回关于该文件的警告。写入代码前可用它确认代码能够解析。",
+	}, handler)
+}
+
+// goSyntaxErrors parses src as a Go file named filename and returns its syntax errors.
+func goSyntaxErrors(filename, src string) []SyntaxError {
+	_, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.AllErrors)
+	return syntaxErrorsOf(err)
+}
+
+// goFileSyntaxErrors parses the Go file at path and returns its syntax errors. It fails
+// only when the file cannot be read.
+func goFileSyntaxErrors(path string) ([]SyntaxError, error) {
+	_, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.AllErrors)
+	var list scanner.ErrorList
+	if err != nil && !errors.As(err, &list) {
+		return nil, fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+	}
+	return syntaxErrorsOf(err), nil
+}
+
+// syntaxErrorsOf converts the error returned by parser.ParseFile to syntax errors.
+func syntaxErrorsOf(err error) []SyntaxError {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return nil
+	}
+	syntaxErrors := make([]SyntaxError, 0, len(list))
+	for _, e := range list {
+		syntaxErrors = append(syntaxErrors, SyntaxError{Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg})
+	}
+	return syntaxErrors
+}
+
+// goVetWarnings runs go vet on the package of the Go file at path and returns the
+// diagnostics about that file. When go vet fails without reporting on any file, e.g.
+// because the directory is not part of a module, the failure is returned as a warning.
+func goVetWarnings(ctx context.Context, path string) []string {
+	vetCtx, cancel := context.WithTimeout(ctx, goVetTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(vetCtx, "go", "vet", ".")
+	cmd.Dir = filepath.Dir(path)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	var warnings []string
+	diagnosed := false
+	prefix := filepath.Base(path) + ":"
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(line), "vet: "), "./")
+		diagnosed = diagnosed || strings.Contains(line, ".go:")
+		if strings.HasPrefix(line, prefix) {
+			warnings = append(warnings, line)
+		}
+	}
+	switch {
+	case vetCtx.Err() != nil:
+		return []string{fmt.Sprintf("go vet did not finish within %s", goVetTimeout)}
+	case !diagnosed:
+		return []string{fmt.Sprintf("go vet failed: %s", strings.TrimSpace(string(output)))}
+	}
+	return warnings
+}
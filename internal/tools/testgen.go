@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// maxTestContextBytes caps the existing test file included in the prompt of generate_unit_test.
+const maxTestContextBytes = 20000
+
+// GenerateUnitTestArgs is the input for generate_unit_test tool.
+type GenerateUnitTestArgs struct {
+	Filepath     string `json:"filepath"`      // Go file declaring the function (relative to WorkDir or absolute)
+	FunctionName string `json:"function_name"` // Function to test, "Type.Method" for methods
+}
+
+// GenerateUnitTestResult is the output for generate_unit_test tool.
+type GenerateUnitTestResult struct {
+	Success  bool   `json:"success"`             // Whether the operation succeeded
+	TestCode string `json:"test_code,omitempty"` // Generated test, not written to disk
+	TestFile string `json:"test_file,omitempty"` // Test file the code belongs in, as given in the arguments
+	Error    string `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// createGenerateUnitTestTool creates the generate_unit_test tool.
+// This tool asks cfg.Generator for a table-driven test of a Go function, giving it the
+// function's signature and source and the tests already in the package's test file so
+// that test names do not collide. The test is returned for the agent to review and
+// write with write_file_content.
+func createGenerateUnitTestTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args GenerateUnitTestArgs) (GenerateUnitTestResult, error) {
+		if args.Filepath == "" || args.FunctionName == "" {
+			return GenerateUnitTestResult{Success: false, Error: "filepath and function_name are required"}, nil
+		}
+		if cfg.Generator == nil {
+			return GenerateUnitTestResult{Success: false, Error: "generate_unit_test requires a text generator"}, nil
+		}
+		absPath, err := resolvePath(cfg, args.Filepath)
+		if err != nil {
+			return GenerateUnitTestResult{Success: false, Error: err.Error()}, nil
+		}
+		if filepath.Ext(absPath) != ".go" || strings.HasSuffix(absPath, "_test.go") {
+			return GenerateUnitTestResult{Success: false, Error: "filepath must be a .go file other than a test file"}, nil
+		}
+
+		prompt, err := unitTestPrompt(absPath, args.FunctionName)
+		if err != nil {
+			return GenerateUnitTestResult{Success: false, Error: err.Error()}, nil
+		}
+		text, err := cfg.Generator.Generate(commandContext(ctx), prompt)
+		if err != nil {
+			return GenerateUnitTestResult{Success: false, Error: fmt.Sprintf("failed to generate test: %v", err)}, nil
+		}
+		code := memory.StripCodeFence(text)
+		if !strings.Contains(code, "func Test") {
+			return GenerateUnitTestResult{Success: false, Error: "the generated code contains no test function"}, nil
+		}
+		return GenerateUnitTestResult{
+			Success:  true,
+			TestCode: code,
+			TestFile: strings.TrimSuffix(args.Filepath, ".go") + "_test.go",
+		}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "generate_unit_test",
+		Description: "为 Go 文件中的函数（方法写作 Type.Method）生成表驱动测试。会读取函数签名、参数和返回值类型以及源码，并参考同名 _test.go 文件中已有的测试以避免重名。生成的测试只作为 test_code 返回，不会写入磁盘；检查后可用 write_file_content 写入 test_file。",
+	}, handler)
+}
+
+// unitTestPrompt builds the prompt asking for a table-driven test of the function named
+// name in the Go file at path. It fails when the file does not declare the function.
+func unitTestPrompt(path, name string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Go file: %v", err)
+	}
+	var fn *ast.FuncDecl
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.FuncDecl); ok && declaresSymbol(d, name) {
+			fn = d
+			break
+		}
+	}
+	if fn == nil {
+		return "", fmt.Errorf("function %s not found in %s", name, filepath.Base(path))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "请为下面 package %s 中的 Go 函数 %s 编写表驱动测试（table-driven test），覆盖正常情况、边界情况和错误情况。\n\n", f.Name.Name, name)
+	fmt.Fprintf(&sb, "函数签名：%s\n", funcSignature(fset, content, fn))
+	fmt.Fprintf(&sb, "参数：%s\n", fieldTypes(fn.Type.Params))
+	fmt.Fprintf(&sb, "返回值：%s\n\n", fieldTypes(fn.Type.Results))
+	start, end := fset.Position(declPos(fn)).Offset, fset.Position(fn.End()).Offset
+	fmt.Fprintf(&sb, "函数源码：\n```go\n%s\n```\n\n", content[start:end])
+
+	testPath := strings.TrimSuffix(path, ".go") + "_test.go"
+	if existing, err := os.ReadFile(testPath); err == nil {
+		fmt.Fprintf(&sb, "测试文件 %s 已存在，新测试的函数名不能与其中已有的函数重复", filepath.Base(testPath))
+		if names := testFuncNames(existing); len(names) > 0 {
+			fmt.Fprintf(&sb, "（已有：%s）", strings.Join(names, ", "))
+		}
+		fmt.Fprintf(&sb, "。只输出要追加到该文件的测试函数，不要输出 package 声明。现有内容：\n```go\n%s\n```\n\n", truncateString(string(existing), maxTestContextBytes))
+	} else {
+		fmt.Fprintf(&sb, "测试文件 %s 还不存在，请输出包含 package 声明和 import 的完整测试文件。\n\n", filepath.Base(testPath))
+	}
+	sb.WriteString("只输出 Go 代码，不要解释。")
+	return sb.String(), nil
+}
+
+// fieldTypes formats a parameter or result list as "name type" pairs, "无" when empty.
+func fieldTypes(fields *ast.FieldList) string {
+	if fields == nil || len(fields.List) == 0 {
+		return "无"
+	}
+	var parts []string
+	for _, field := range fields.List {
+		typ := types.ExprString(field.Type)
+		if len(field.Names) == 0 {
+			parts = append(parts, typ)
+		}
+		for _, n := range field.Names {
+			parts = append(parts, n.Name+" "+typ)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// testFuncNames returns the names of the top-level functions of the Go test file src,
+// or nil if it does not parse.
+func testFuncNames(src []byte) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			names = append(names, fn.Name.Name)
+		}
+	}
+	return names
+}
@@ -4,14 +4,25 @@
 package tools
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/easeaico/adk-memory-agent/internal/audit"
 	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"github.com/google/jsonschema-go/jsonschema"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 )
@@ -26,54 +37,204 @@ type Embedder interface {
 // ToolsConfig holds dependencies and configuration for creating agent tools.
 // All fields are required for proper tool initialization.
 type ToolsConfig struct {
-	Store    memory.Store // Database store for memory operations
-	Embedder Embedder     // Embedder for generating query vectors
-	WorkDir  string       // Working directory for file operations (used for path resolution)
+	Store     memory.Store      // Database store for memory operations
+	Embedder  Embedder          // Embedder for generating query vectors
+	WorkDir   string            // Working directory for file operations (used for path resolution)
+	Mounts    map[string]string // Additional directories by alias, read as "@alias/path" by read_file_content and list_directory (optional)
+	ProjectID string            // Project whose experiences, and those shared by all projects, search_past_issues returns
+
+	AllowedExtensions []string // File extensions read_file_content reads, "" for files without one (optional, nil uses DefaultAllowedExtensions, empty allows all)
+	DeniedExtensions  []string // File extensions read_file_content never reads, even when allowed (optional, nil uses DefaultDeniedExtensions)
+
+	ProjectRules        []string             // Active project rules, used to decide which tools are enabled
+	ToolEnablementRules []ToolEnablementRule // Additional rules mapping project rule keywords to disabled tools
+
+	PromptChanged func(ctx context.Context) // Called after a tool changed a project rule or the project context, which are part of the system prompt (optional)
+	StartedAt     time.Time                 // When the agent started; list_project_rules marks the rules created since (optional)
+
+	// DetectInjection reports whether text looks like a prompt injection attempt, and why.
+	// Rule text given to add_project_rule and update_project_rule is checked with it before
+	// it is stored, since rules become part of the system prompt (optional, nil disables the check).
+	DetectInjection func(text string) (bool, string)
+
+	WorkingMemory *memory.WorkingMemory // Per-session working memory (optional, nil disables tracking)
+	Generator     memory.Generator      // Text generator for llm_merge (optional, nil disables that strategy)
+
+	MinSimilarity  float32 // Default minimum similarity for search_past_issues (optional, defaults to memory.DefaultMinSimilarity)
+	MaxResultCount int     // Number of experiences returned by search_past_issues (optional, defaults to 3)
+
+	MaxSearchCallsPerSession int             // Calls to search_past_issues allowed per session (optional, 0 disables the limit)
+	MaxSaveCallsPerSession   int             // Calls to save_experience allowed per session (optional, 0 disables the limit)
+	Limiter                  *SessionLimiter // Per-session call counts for the limits above (optional, BuildTools creates one when nil)
+
+	DebugTools bool              // Whether to add find_similar_experiences, which shows raw vector search results, and execute_sql (optional)
+	SQL        memory.SQLQuerier // Database execute_sql queries (optional, nil leaves execute_sql out)
+
+	Tracer   trace.Tracer  // Tracer recording a span per tool call (optional, nil disables tracing)
+	AuditLog *audit.Logger // Log recording every tool call with its arguments and outcome (optional, nil disables auditing)
+}
+
+// sessionContext returns the working memory of the session the tool is invoked in,
+// or nil when working memory is disabled or the tool runs outside a session.
+func sessionContext(cfg ToolsConfig, ctx tool.Context) *memory.AgentContext {
+	if cfg.WorkingMemory == nil || ctx == nil {
+		return nil
+	}
+	return cfg.WorkingMemory.Session(ctx.SessionID())
 }
 
 // --- Tool Input/Output Structs ---
 
 // SearchPastIssuesArgs is the input for search_past_issues tool.
 type SearchPastIssuesArgs struct {
-	ErrorDescription string `json:"error_description"` // Description of the error or problem to search for
+	ErrorDescription string   `json:"error_description"`         // Description of the error or problem to search for
+	MinSimilarity    float32  `json:"min_similarity,omitempty"`  // Minimum similarity of returned issues, 0-1 (default 0.5, vector mode only)
+	SearchMode       string   `json:"search_mode,omitempty"`     // vector (default), keyword or hybrid
+	Tags             []string `json:"tags,omitempty"`            // Only return issues carrying all of these tags (vector mode only)
+	ClusterResults   bool     `json:"cluster_results,omitempty"` // Return only the best match of each group of near-duplicate issues
+	RankingMode      string   `json:"ranking_mode,omitempty"`    // similarity (default), recency or combined
+	Offset           int      `json:"offset,omitempty"`          // Number of most similar issues to skip, to page through the results (vector mode with similarity ranking and without clustering only)
 }
 
 // SearchPastIssuesResult is the output for search_past_issues tool.
 type SearchPastIssuesResult struct {
-	Success bool   `json:"success"`         // Whether the operation succeeded
-	Data    any    `json:"data,omitempty"`  // Search results (array of experiences) or message if none found
-	Error   string `json:"error,omitempty"` // Error message if the operation failed
+	Success    bool   `json:"success"`               // Whether the operation succeeded
+	Data       any    `json:"data,omitempty"`        // Search results (array of experiences) or message if none found
+	TotalCount int64  `json:"total_count,omitempty"` // Number of issues matching the search, set when a page after the first one is requested
+	Offset     int    `json:"offset,omitempty"`      // Number of issues skipped before Data
+	HasMore    bool   `json:"has_more,omitempty"`    // Whether there are matching issues after Data
+	Error      string `json:"error,omitempty"`       // Error message if the operation failed
 }
 
 // ReadFileArgs is the input for read_file_content tool.
 type ReadFileArgs struct {
-	Filepath string `json:"filepath"` // Path to the file to read (relative to WorkDir or absolute)
+	Filepath     string `json:"filepath"`                // Path to the file to read (relative to WorkDir, absolute, or "@alias/path" in a mount)
+	MetadataOnly bool   `json:"metadata_only,omitempty"` // Return only file metadata, without reading the content
+	Offset       int    `json:"offset,omitempty"`        // Byte offset to start reading at (default 0), e.g. the next_offset of the previous read
+	MaxBytes     int    `json:"max_bytes,omitempty"`     // Maximum number of bytes to read (default 10000, at most 50000)
 }
 
 // ReadFileResult is the output for read_file_content tool.
 type ReadFileResult struct {
+	Success    bool          `json:"success"`               // Whether the operation succeeded
+	Data       string        `json:"data,omitempty"`        // File contents from Offset, at most MaxBytes
+	HasMore    bool          `json:"has_more,omitempty"`    // Whether the file continues after Data
+	NextOffset int           `json:"next_offset,omitempty"` // Offset to read the rest of the file from, set when HasMore is
+	Metadata   *FileMetadata `json:"metadata,omitempty"`    // File metadata, set only when MetadataOnly is requested
+	Error      string        `json:"error,omitempty"`       // Error message if the operation failed
+}
+
+const (
+	// defaultReadBytes is the number of bytes read_file_content returns when MaxBytes is not set.
+	defaultReadBytes = 10000
+	// maxReadBytes is the largest number of bytes read_file_content returns in one call.
+	maxReadBytes = 50000
+)
+
+// FileMetadata describes a file without its content.
+type FileMetadata struct {
+	Size      int64     `json:"size"`       // File size in bytes
+	ModTime   time.Time `json:"mod_time"`   // Last modification time
+	Mode      string    `json:"mode"`       // Permission bits, e.g. "-rw-r--r--"
+	MIMEType  string    `json:"mime_type"`  // MIME type sniffed from the beginning of the file
+	LineCount int       `json:"line_count"` // Estimated number of lines (size / 80 bytes)
+}
+
+// averageLineLength is the line length in bytes used to estimate FileMetadata.LineCount.
+const averageLineLength = 80
+
+// defaultSearchResults is the number of experiences returned by search_past_issues
+// when ToolsConfig.MaxResultCount is not set.
+const defaultSearchResults = 3
+
+// Search modes accepted by search_past_issues.
+const (
+	searchModeVector  = "vector"
+	searchModeKeyword = "keyword"
+	searchModeHybrid  = "hybrid"
+)
+
+// resultClusterEps is the cosine distance within which search_past_issues treats two
+// results as near-duplicates when cluster_results is set.
+const resultClusterEps = 0.05
+
+// clusterOverfetch is how many times the usual number of results search_past_issues
+// fetches when cluster_results is set, so that enough remain after collapsing.
+const clusterOverfetch = 5
+
+// rankingOverfetch is how many times the usual number of results search_past_issues
+// fetches when ranking by recency, so that recent results ranked below the limit by
+// similarity can move up.
+const rankingOverfetch = 5
+
+// hybridSearchAlpha is the weight of vector similarity against keyword match in the
+// hybrid search mode of search_past_issues.
+const hybridSearchAlpha = 0.5
+
+// mimeSniffLen is the number of leading bytes inspected to detect a file's MIME type.
+const mimeSniffLen = 512
+
+// OverwriteMode selects how write_file_content combines new content with an existing file.
+type OverwriteMode string
+
+const (
+	OverwriteReplace OverwriteMode = "replace" // Replace the whole file (default)
+	OverwriteAppend  OverwriteMode = "append"  // Add the content after the existing content
+	OverwritePrepend OverwriteMode = "prepend" // Add the content before the existing content
+)
+
+// maxWriteSize is the largest content in bytes accepted by write_file_content.
+const maxWriteSize = 1 << 20
+
+// WriteFileArgs is the input for write_file_content tool.
+type WriteFileArgs struct {
+	Filepath          string        `json:"filepath"`                       // Path to the file to write (relative to WorkDir or absolute)
+	Content           string        `json:"content"`                        // Content to write
+	CreateIfNotExists bool          `json:"create_if_not_exists,omitempty"` // Create the file (and missing parent directories) if it does not exist
+	Mode              OverwriteMode `json:"mode,omitempty"`                 // replace (default), append or prepend
+}
+
+// WriteFileResult is the output for write_file_content tool.
+type WriteFileResult struct {
 	Success bool   `json:"success"`         // Whether the operation succeeded
-	Data    string `json:"data,omitempty"`  // File contents (truncated if > 10000 bytes)
+	Data    string `json:"data,omitempty"`  // Success message if the operation succeeded
 	Error   string `json:"error,omitempty"` // Error message if the operation failed
 }
 
 // ListDirectoryArgs is the input for list_directory tool.
 type ListDirectoryArgs struct {
-	Path string `json:"path"` // Directory path to list (relative to WorkDir or absolute, empty for WorkDir)
+	Path       string `json:"path"`                  // Directory path to list (relative to WorkDir, absolute, or "@alias/path" in a mount; empty for WorkDir)
+	Depth      int    `json:"depth,omitempty"`       // Levels of subdirectories to list below Path (0 for Path's entries only, at most 5)
+	ShowHidden bool   `json:"show_hidden,omitempty"` // List entries whose name starts with "." (default false)
 }
 
 // ListDirectoryResult is the output for list_directory tool.
 type ListDirectoryResult struct {
-	Success bool   `json:"success"`         // Whether the operation succeeded
-	Data    any    `json:"data,omitempty"`  // Array of directory entries with name, isDir, and size
-	Error   string `json:"error,omitempty"` // Error message if the operation failed
+	Success   bool       `json:"success"`             // Whether the operation succeeded
+	Data      []TreeNode `json:"data,omitempty"`      // Entries of the directory, with the children of subdirectories up to Depth
+	Truncated bool       `json:"truncated,omitempty"` // Whether entries were left out because of the 2000 entry cap
+	Error     string     `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// listDirectoryOutputSchema is the output schema of list_directory, see treeNodeDefs.
+var listDirectoryOutputSchema = &jsonschema.Schema{
+	Type: "object",
+	Defs: treeNodeDefs(),
+	Properties: map[string]*jsonschema.Schema{
+		"success":   {Type: "boolean"},
+		"data":      {Type: "array", Items: &jsonschema.Schema{Ref: "#/$defs/TreeNode"}},
+		"truncated": {Type: "boolean"},
+		"error":     {Type: "string"},
+	},
+	Required: []string{"success"},
 }
 
 // SaveExperienceArgs is the input for save_experience tool.
 type SaveExperienceArgs struct {
-	ErrorPattern string `json:"error_pattern"` // Description of the error or problem pattern
-	RootCause    string `json:"root_cause"`    // Root cause analysis of the issue
-	Solution     string `json:"solution"`      // Solution or fix that resolved the issue
+	ErrorPattern string   `json:"error_pattern"`  // Description of the error or problem pattern
+	RootCause    string   `json:"root_cause"`     // Root cause analysis of the issue
+	Solution     string   `json:"solution"`       // Solution or fix that resolved the issue
+	Tags         []string `json:"tags,omitempty"` // Labels such as "panic" or "timeout" used to filter searches
 }
 
 // SaveExperienceResult is the output for save_experience tool.
@@ -85,7 +246,7 @@ type SaveExperienceResult struct {
 
 // ListFilesArgs is the input for list_files tool.
 type ListFilesArgs struct {
-	Path string `json:"path"` // Directory path to list (relative to WorkDir or absolute, empty for WorkDir)
+	Path string `json:"path"` // Directory path to list (relative to WorkDir, absolute, or "@alias/path" in a mount; empty for WorkDir)
 }
 
 // ListFilesResult is the output for list_files tool.
@@ -100,52 +261,181 @@ type ListFilesResult struct {
 // createSearchPastIssuesTool creates the search_past_issues tool.
 // This tool allows the agent to search for similar past issues using vector similarity.
 // It generates an embedding for the error description and searches the database
-// for the top 3 most similar experiences.
+// for the most similar experiences (3 unless MaxResultCount is set) that reach the
+// minimum similarity. The keyword and hybrid modes also match exact terms such as
+// error codes against the stored error patterns. Plain vector searches can be paged
+// through with Offset; pages after the first one report the total number of matching
+// experiences.
 func createSearchPastIssuesTool(cfg ToolsConfig) (tool.Tool, error) {
 	handler := func(ctx tool.Context, args SearchPastIssuesArgs) (SearchPastIssuesResult, error) {
 		if args.ErrorDescription == "" {
 			return SearchPastIssuesResult{Success: false, Error: "error_description is required"}, nil
 		}
+		switch args.SearchMode {
+		case "", searchModeVector, searchModeKeyword, searchModeHybrid:
+		default:
+			return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("invalid search_mode %q: must be vector, keyword or hybrid", args.SearchMode)}, nil
+		}
+		if args.MinSimilarity < 0 || args.MinSimilarity > 1 {
+			return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("invalid min_similarity %v: must be between 0 and 1", args.MinSimilarity)}, nil
+		}
+		switch args.RankingMode {
+		case "", memory.RankingSimilarity, memory.RankingRecency, memory.RankingCombined:
+		default:
+			return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("invalid ranking_mode %q: must be similarity, recency or combined", args.RankingMode)}, nil
+		}
+		reranked := args.RankingMode == memory.RankingRecency || args.RankingMode == memory.RankingCombined
+		vectorMode := args.SearchMode == "" || args.SearchMode == searchModeVector
+		// Clustering and re-ranking reorder the fetched results, so only plain vector
+		// searches page through them in the store
+		paged := vectorMode && !reranked && !args.ClusterResults
+		if args.Offset < 0 {
+			return SearchPastIssuesResult{Success: false, Error: "offset must not be negative"}, nil
+		}
+		if args.Offset > 0 && !paged {
+			return SearchPastIssuesResult{Success: false, Error: "offset is only supported in vector mode with similarity ranking and without cluster_results"}, nil
+		}
+		if err := allowSearch(cfg, ctx); err != nil {
+			return SearchPastIssuesResult{Success: false, Error: err.Error()}, nil
+		}
 
-		// Generate embedding for the query
-		embedding, err := cfg.Embedder.Embed(ctx, args.ErrorDescription)
-		if err != nil {
-			return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("failed to generate embedding: %v", err)}, nil
+		// Generate embedding for the query; keyword search does not need one
+		var embedding []float32
+		if args.SearchMode != searchModeKeyword {
+			var err error
+			embedding, err = cfg.Embedder.Embed(ctx, args.ErrorDescription)
+			if err != nil {
+				return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("failed to generate embedding: %v", err)}, nil
+			}
+		}
+
+		minSimilarity := args.MinSimilarity
+		if minSimilarity <= 0 {
+			minSimilarity = cfg.MinSimilarity
+		}
+		if minSimilarity <= 0 {
+			minSimilarity = memory.DefaultMinSimilarity
+		}
+		limit := cfg.MaxResultCount
+		if limit <= 0 {
+			limit = defaultSearchResults
+		}
+		fetch := limit
+		if args.ClusterResults {
+			fetch *= clusterOverfetch
+		}
+		if reranked {
+			fetch *= rankingOverfetch
 		}
 
 		// Search for similar issues
-		experiences, err := cfg.Store.SearchSimilarIssues(ctx, embedding, 3)
+		var experiences []memory.Experience
+		var total int64
+		var err error
+		switch args.SearchMode {
+		case searchModeKeyword:
+			experiences, err = cfg.Store.HybridSearch(ctx, args.ErrorDescription, nil, fetch, 0, cfg.ProjectID, true)
+		case searchModeHybrid:
+			experiences, err = cfg.Store.HybridSearch(ctx, args.ErrorDescription, embedding, fetch, hybridSearchAlpha, cfg.ProjectID, true)
+		default:
+			if args.Offset > 0 {
+				experiences, total, err = cfg.Store.SearchSimilarIssuesPage(ctx, args.ErrorDescription, embedding, args.Offset, fetch, minSimilarity, cfg.ProjectID, true, args.Tags)
+			} else {
+				// The first page is not counted, which would scan every embedding; one
+				// more result than shown tells whether there are more. Only this call
+				// goes through stores merging the results of several, like MultiStore
+				if paged {
+					fetch++
+				}
+				experiences, err = cfg.Store.SearchSimilarIssues(ctx, args.ErrorDescription, embedding, fetch, minSimilarity, cfg.ProjectID, true, args.Tags)
+			}
+			// Not every store applies the threshold, so drop anything below it here as well
+			experiences = slices.DeleteFunc(experiences, func(exp memory.Experience) bool {
+				return exp.SimilarityScore < minSimilarity
+			})
+		}
 		if err != nil {
 			return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("failed to search issues: %v", err)}, nil
 		}
 
+		// Keep one result per group of near-duplicates
+		if args.ClusterResults && len(experiences) > 1 {
+			ids := make([]int, len(experiences))
+			for i, exp := range experiences {
+				ids[i] = exp.ID
+			}
+			vectors, err := cfg.Store.GetEmbeddings(ctx, ids)
+			if err != nil {
+				return SearchPastIssuesResult{Success: false, Error: fmt.Sprintf("failed to cluster issues: %v", err)}, nil
+			}
+			experiences = memory.CollapseClusters(experiences, vectors, resultClusterEps)
+		}
+
+		if reranked {
+			// Confidence compares the most similar result with the next one, so it is
+			// assigned while the results are still ordered by similarity
+			if vectorMode {
+				memory.AssignConfidence(experiences)
+			}
+			memory.RankExperiences(experiences, args.RankingMode, memory.DefaultRankingAlpha, time.Now())
+		}
+		more := len(experiences) > limit
+		if more {
+			experiences = experiences[:limit]
+		}
+		slog.DebugContext(commandContext(ctx), "searched past issues",
+			slog.String("tool", "search_past_issues"),
+			slog.String("search_mode", cmp.Or(args.SearchMode, searchModeVector)),
+			slog.String("ranking_mode", cmp.Or(args.RankingMode, memory.RankingSimilarity)),
+			slog.Int("results", len(experiences)))
+
+		result := SearchPastIssuesResult{Success: true}
+		switch {
+		case paged && args.Offset > 0:
+			result.TotalCount = total
+			result.Offset = args.Offset
+			result.HasMore = int64(args.Offset+len(experiences)) < total
+		case paged:
+			result.HasMore = more
+		}
 		if len(experiences) == 0 {
-			return SearchPastIssuesResult{Success: true, Data: "没有找到相关的历史问题。"}, nil
+			result.Data = "没有找到相关的历史问题。"
+			return result, nil
+		}
+		if vectorMode && !reranked {
+			memory.AssignConfidence(experiences)
 		}
 
-		// Format results
+		// Format results; keyword and hybrid scores are relevance scores, not similarities
 		var results []map[string]any
 		for _, exp := range experiences {
-			// similarity must big than 75%
-			if exp.SimilarityScore < 0.75 {
-				continue
+			item := map[string]any{
+				"id":       exp.ID,
+				"pattern":  exp.ErrorPattern,
+				"cause":    exp.RootCause,
+				"solution": exp.Solution,
+				"tags":     exp.Tags,
 			}
-
-			results = append(results, map[string]any{
-				"id":         exp.ID,
-				"pattern":    exp.ErrorPattern,
-				"cause":      exp.RootCause,
-				"solution":   exp.Solution,
-				"similarity": fmt.Sprintf("%.2f%%", exp.SimilarityScore*100),
-			})
+			switch args.SearchMode {
+			case searchModeKeyword, searchModeHybrid:
+				item["relevance"] = fmt.Sprintf("%.2f", exp.SimilarityScore)
+			default:
+				item["similarity"] = fmt.Sprintf("%.2f%%", exp.SimilarityScore*100)
+				item["confidence_level"] = exp.ConfidenceLevel
+			}
+			if reranked {
+				item["occurred_at"] = exp.OccurredAt.Format(time.DateOnly)
+			}
+			results = append(results, item)
 		}
 
-		return SearchPastIssuesResult{Success: true, Data: results}, nil
+		result.Data = results
+		return result, nil
 	}
 
-	return functiontool.New(functiontool.Config{
+	return newFunctionTool(cfg, functiontool.Config{
 		Name:        "search_past_issues",
-		Description: "当遇到不确定的错误或复杂 Bug 时，搜索过去是否处理过类似问题。返回相关的历史问题和解决方案。",
+		Description: "当遇到不确定的错误或复杂 Bug 时，搜索过去是否处理过类似问题。返回相关的历史问题和解决方案。search_mode 可选 vector（语义相似，默认）、keyword（精确匹配错误码等关键词）或 hybrid（两者结合）。tags 可限定只返回带有全部指定标签（如 panic、timeout）的问题。结果被大量相似问题占满时，可设置 cluster_results，每组近似重复的问题只返回最相关的一条。vector 模式的结果带有 confidence_level（high、medium 或 low），low 的结果仅供参考。ranking_mode 可选 similarity（按相似度排序，默认）、recency（最近发生的在前）或 combined（综合相似度与时间，较新的方案优先于多年前可能已过时的方案），后两者的结果带有 occurred_at。vector 模式按 similarity 排序且未设置 cluster_results 时，结果带有 has_more，has_more 为 true 时可将 offset 设为已获取的结果数来获取下一页，offset 大于 0 时结果还带有 total_count（匹配的问题总数）。",
 	}, handler)
 }
 
@@ -159,52 +449,233 @@ func createReadFileTool(cfg ToolsConfig) (tool.Tool, error) {
 			return ReadFileResult{Success: false, Error: "filepath is required"}, nil
 		}
 
-		filePath := args.Filepath
+		// Security check: ensure path is within working directory or its mount
+		absPath, err := resolvePath(cfg, args.Filepath)
+		if err != nil {
+			return ReadFileResult{Success: false, Error: err.Error()}, nil
+		}
+		if err := checkExtension(cfg, absPath); err != nil {
+			return ReadFileResult{Success: false, Error: err.Error()}, nil
+		}
 
-		// Resolve relative paths against working directory
-		if !filepath.IsAbs(filePath) {
-			filePath = filepath.Join(cfg.WorkDir, filePath)
+		if args.MetadataOnly {
+			metadata, err := readFileMetadata(absPath)
+			if err != nil {
+				return ReadFileResult{Success: false, Error: err.Error()}, nil
+			}
+			return ReadFileResult{Success: true, Metadata: metadata}, nil
 		}
 
-		// Security check: ensure path is within working directory
-		absPath, err := filepath.Abs(filePath)
-		if err != nil {
-			return ReadFileResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}, nil
+		if args.Offset < 0 {
+			return ReadFileResult{Success: false, Error: "offset must not be negative"}, nil
 		}
+		maxBytes := args.MaxBytes
+		if maxBytes <= 0 {
+			maxBytes = defaultReadBytes
+		}
+		maxBytes = min(maxBytes, maxReadBytes)
 
-		absWorkDir, err := filepath.Abs(cfg.WorkDir)
+		content, hasMore, err := readFileWindow(absPath, int64(args.Offset), maxBytes)
 		if err != nil {
-			return ReadFileResult{Success: false, Error: fmt.Sprintf("invalid working directory: %v", err)}, nil
+			return ReadFileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+		}
+		absWorkDir, _ := filepath.Abs(cfg.WorkDir)
+		if strings.HasPrefix(args.Filepath, mountPrefix) {
+			sessionContext(cfg, ctx).RecordReadFile(args.Filepath)
+		} else if relPath, err := filepath.Rel(absWorkDir, absPath); err == nil {
+			sessionContext(cfg, ctx).RecordReadFile(relPath)
 		}
 
-		// Use filepath.Rel to safely check if path is within working directory
-		// This prevents path traversal attacks like "/home/user/work-evil" bypassing "/home/user/work"
-		relPath, err := filepath.Rel(absWorkDir, absPath)
-		if err != nil || strings.HasPrefix(relPath, "..") || filepath.IsAbs(relPath) {
-			return ReadFileResult{Success: false, Error: "access denied: path is outside working directory"}, nil
+		result := ReadFileResult{Success: true, Data: content, HasMore: hasMore}
+		if hasMore {
+			result.NextOffset = args.Offset + len(content)
 		}
+		return result, nil
+	}
 
-		content, err := os.ReadFile(absPath)
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "read_file_content",
+		Description: "读取指定路径的代码文件内容。用于理解和分析代码。每次最多返回 max_bytes 字节（默认 10000，最大 50000）；has_more 为 true 时，以 next_offset 作为 offset 再次调用即可读取后续内容。设置 metadata_only 时只返回文件大小、修改时间、权限、MIME 类型和估算行数。" + mountsDescription(cfg),
+	}, handler)
+}
+
+// createWriteFileTool creates the write_file_content tool.
+// This tool allows the agent to apply fixes to files in the working directory.
+// It uses the same security check as read_file_content, refuses content larger
+// than 1 MB and writes atomically, so a crash mid-write never leaves a partial file.
+func createWriteFileTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args WriteFileArgs) (WriteFileResult, error) {
+		if args.Filepath == "" {
+			return WriteFileResult{Success: false, Error: "filepath is required"}, nil
+		}
+		if len(args.Content) > maxWriteSize {
+			return WriteFileResult{Success: false, Error: fmt.Sprintf("content too large: %d bytes exceeds the limit of %d bytes", len(args.Content), maxWriteSize)}, nil
+		}
+		mode := args.Mode
+		if mode == "" {
+			mode = OverwriteReplace
+		}
+		if mode != OverwriteReplace && mode != OverwriteAppend && mode != OverwritePrepend {
+			return WriteFileResult{Success: false, Error: fmt.Sprintf("unknown mode %q, expected replace, append or prepend", mode)}, nil
+		}
+
+		// Security check: ensure path is within working directory
+		absPath, err := SafeAbsPath(args.Filepath, cfg.WorkDir)
 		if err != nil {
-			return ReadFileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+			return WriteFileResult{Success: false, Error: err.Error()}, nil
 		}
 
-		// Limit content size
-		maxSize := 10000
-		contentStr := string(content)
-		if len(contentStr) > maxSize {
-			contentStr = truncateString(contentStr, maxSize) + "\n... (truncated)"
+		perm := os.FileMode(0o644)
+		var existing []byte
+		info, err := os.Stat(absPath)
+		switch {
+		case err == nil:
+			if info.IsDir() {
+				return WriteFileResult{Success: false, Error: fmt.Sprintf("%s is a directory", args.Filepath)}, nil
+			}
+			perm = info.Mode().Perm()
+			if mode != OverwriteReplace {
+				if existing, err = os.ReadFile(absPath); err != nil {
+					return WriteFileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+				}
+			}
+		case os.IsNotExist(err):
+			if !args.CreateIfNotExists {
+				return WriteFileResult{Success: false, Error: fmt.Sprintf("file %s does not exist, set create_if_not_exists to create it", args.Filepath)}, nil
+			}
+			if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+				return WriteFileResult{Success: false, Error: fmt.Sprintf("failed to create directory: %v", err)}, nil
+			}
+		default:
+			return WriteFileResult{Success: false, Error: fmt.Sprintf("failed to stat file: %v", err)}, nil
 		}
 
-		return ReadFileResult{Success: true, Data: contentStr}, nil
+		var data []byte
+		switch mode {
+		case OverwriteAppend:
+			data = append(existing, args.Content...)
+		case OverwritePrepend:
+			data = append([]byte(args.Content), existing...)
+		default:
+			data = []byte(args.Content)
+		}
+
+		if err := writeFileAtomic(absPath, data, perm); err != nil {
+			return WriteFileResult{Success: false, Error: err.Error()}, nil
+		}
+
+		return WriteFileResult{Success: true, Data: fmt.Sprintf("已写入 %s（%d 字节）。", args.Filepath, len(data))}, nil
 	}
 
-	return functiontool.New(functiontool.Config{
-		Name:        "read_file_content",
-		Description: "读取指定路径的代码文件内容。用于理解和分析代码。",
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "write_file_content",
+		Description: "写入工作目录中的文件，用于应用代码修复。mode 可选 replace（覆盖，默认）、append（追加到末尾）或 prepend（插入到开头）；文件不存在时需设置 create_if_not_exists。内容不能超过 1 MB。",
 	}, handler)
 }
 
+// writeFileAtomic writes data to a temporary file in the same directory as path and
+// renames it over path, so readers see either the old or the new content in full.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %v", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file mode: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %v", err)
+	}
+	return nil
+}
+
+// readFileMetadata stats the file at path and sniffs its MIME type from the first
+// mimeSniffLen bytes, without reading the rest of the file.
+func readFileMetadata(path string) (*FileMetadata, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", filepath.Base(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	head := make([]byte, mimeSniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	return &FileMetadata{
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		Mode:      info.Mode().String(),
+		MIMEType:  http.DetectContentType(head[:n]),
+		LineCount: int((info.Size() + averageLineLength - 1) / averageLineLength),
+	}, nil
+}
+
+// readFileWindow reads up to maxBytes of the file at path, starting at offset, and reports
+// whether the file continues after them. A multi-byte character cut by the end of the
+// window is left for the next read, so the returned text is valid UTF-8 if the file is.
+func readFileWindow(path string, offset int64, maxBytes int) (string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", false, err
+	}
+	if offset > info.Size() {
+		return "", false, fmt.Errorf("offset %d is beyond the end of the file (%d bytes)", offset, info.Size())
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", false, err
+	}
+
+	// Read one byte more than requested to find out whether the file continues
+	buf := make([]byte, maxBytes+1)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	if n <= maxBytes {
+		return string(buf[:n]), false, nil
+	}
+
+	end := maxBytes
+	for i := maxBytes; i > 0 && i > maxBytes-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			end = i
+			break
+		}
+	}
+	return string(buf[:end]), true, nil
+}
+
 // truncateString truncates a string to the specified byte limit while ensuring
 // the result is valid UTF-8. It avoids cutting multi-byte characters in half.
 func truncateString(s string, limit int) string {
@@ -229,65 +700,83 @@ func truncateString(s string, limit int) string {
 	return ""
 }
 
+// maxListDirectoryDepth is the deepest list_directory lists, whatever Depth asks for.
+const maxListDirectoryDepth = 5
+
 // createListDirectoryTool creates the list_directory tool.
-// This tool allows the agent to list files and directories in the working directory.
-// It includes security checks to prevent path traversal attacks and returns
-// file metadata including name, size, and directory status.
+// This tool allows the agent to list files and directories in the working directory,
+// optionally with the subdirectories below it up to a depth, as nodes shaped like those
+// of list_files_recursive. It includes security checks to prevent path traversal attacks
+// at every level and returns file metadata including name, size, and directory status.
 func createListDirectoryTool(cfg ToolsConfig) (tool.Tool, error) {
 	handler := func(ctx tool.Context, args ListDirectoryArgs) (ListDirectoryResult, error) {
-		dirPath := args.Path
-		if dirPath == "" {
-			dirPath = cfg.WorkDir
+		if args.Depth < 0 {
+			return ListDirectoryResult{Success: false, Error: fmt.Sprintf("invalid depth %d: must not be negative", args.Depth)}, nil
 		}
 
-		// Resolve relative paths
-		if !filepath.IsAbs(dirPath) {
-			dirPath = filepath.Join(cfg.WorkDir, dirPath)
-		}
-
-		// Security check: ensure path is within working directory
-		absPath, err := filepath.Abs(dirPath)
+		// Security check: ensure path is within working directory or its mount
+		absPath, err := resolvePath(cfg, args.Path)
 		if err != nil {
-			return ListDirectoryResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}, nil
+			return ListDirectoryResult{Success: false, Error: err.Error()}, nil
 		}
-
-		absWorkDir, err := filepath.Abs(cfg.WorkDir)
+		entries, err := os.ReadDir(absPath)
 		if err != nil {
-			return ListDirectoryResult{Success: false, Error: fmt.Sprintf("invalid working directory: %v", err)}, nil
+			return ListDirectoryResult{Success: false, Error: fmt.Sprintf("failed to read directory: %v", err)}, nil
 		}
 
-		// Use filepath.Rel to safely check if path is within working directory
-		// This prevents path traversal attacks like "/home/user/work-evil" bypassing "/home/user/work"
-		relPath, err := filepath.Rel(absWorkDir, absPath)
-		if err != nil || strings.HasPrefix(relPath, "..") || filepath.IsAbs(relPath) {
-			return ListDirectoryResult{Success: false, Error: "access denied: path is outside working directory"}, nil
-		}
+		l := &directoryLister{cfg: cfg, maxDepth: min(args.Depth, maxListDirectoryDepth), showHidden: args.ShowHidden}
+		return ListDirectoryResult{Success: true, Data: l.list(entries, args.Path, 0), Truncated: l.truncated}, nil
+	}
 
-		entries, err := os.ReadDir(absPath)
-		if err != nil {
-			return ListDirectoryResult{Success: false, Error: fmt.Sprintf("failed to read directory: %v", err)}, nil
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:         "list_directory",
+		Description:  "列出指定目录下的文件和子目录。depth 指定递归列出的子目录层数（默认 0 只列出当前层，最多 5 层），子目录的条目放在 children 中；默认不列出以 . 开头的隐藏文件，可用 show_hidden 显示。用于探索项目结构。" + mountsDescription(cfg),
+		OutputSchema: listDirectoryOutputSchema,
+	}, handler)
+}
+
+// directoryLister builds the entries returned by list_directory.
+type directoryLister struct {
+	cfg        ToolsConfig
+	maxDepth   int
+	showHidden bool
+	nodes      int  // Entries listed so far
+	truncated  bool // Whether the node cap was reached
+}
+
+// list returns the nodes of entries, the entries of the directory at dirPath as given in
+// the tool arguments, which is depth levels below the listed directory. Subdirectories
+// are listed while depth is below maxDepth, each passing the path security check again;
+// those failing it or that cannot be read are listed without children.
+func (l *directoryLister) list(entries []os.DirEntry, dirPath string, depth int) []TreeNode {
+	nodes := []TreeNode{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !l.showHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		if l.nodes >= maxTreeNodes {
+			l.truncated = true
+			return nodes
 		}
+		l.nodes++
 
-		var items []map[string]any
-		for _, entry := range entries {
-			info, _ := entry.Info()
-			item := map[string]any{
-				"name":  entry.Name(),
-				"isDir": entry.IsDir(),
+		node := TreeNode{Name: name, IsDir: entry.IsDir()}
+		if !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				node.Size = info.Size()
 			}
-			if info != nil {
-				item["size"] = info.Size()
+		} else if depth < l.maxDepth {
+			childPath := path.Join(filepath.ToSlash(dirPath), name)
+			if absPath, err := resolvePath(l.cfg, childPath); err == nil {
+				if children, err := os.ReadDir(absPath); err == nil {
+					node.Children = l.list(children, childPath, depth+1)
+				}
 			}
-			items = append(items, item)
 		}
-
-		return ListDirectoryResult{Success: true, Data: items}, nil
+		nodes = append(nodes, node)
 	}
-
-	return functiontool.New(functiontool.Config{
-		Name:        "list_directory",
-		Description: "列出指定目录下的文件和子目录。用于探索项目结构。",
-	}, handler)
+	return nodes
 }
 
 // createListFilesTool creates the list_files tool.
@@ -296,32 +785,10 @@ func createListDirectoryTool(cfg ToolsConfig) (tool.Tool, error) {
 // and returns file metadata including name, size, and directory status.
 func createListFilesTool(cfg ToolsConfig) (tool.Tool, error) {
 	handler := func(ctx tool.Context, args ListFilesArgs) (ListFilesResult, error) {
-		dirPath := args.Path
-		if dirPath == "" {
-			dirPath = cfg.WorkDir
-		}
-
-		// Resolve relative paths
-		if !filepath.IsAbs(dirPath) {
-			dirPath = filepath.Join(cfg.WorkDir, dirPath)
-		}
-
-		// Security check: ensure path is within working directory
-		absPath, err := filepath.Abs(dirPath)
-		if err != nil {
-			return ListFilesResult{Success: false, Error: fmt.Sprintf("invalid path: %v", err)}, nil
-		}
-
-		absWorkDir, err := filepath.Abs(cfg.WorkDir)
+		// Security check: ensure path is within working directory or its mount
+		absPath, err := resolvePath(cfg, args.Path)
 		if err != nil {
-			return ListFilesResult{Success: false, Error: fmt.Sprintf("invalid working directory: %v", err)}, nil
-		}
-
-		// Use filepath.Rel to safely check if path is within working directory
-		// This prevents path traversal attacks like "/home/user/work-evil" bypassing "/home/user/work"
-		relPath, err := filepath.Rel(absWorkDir, absPath)
-		if err != nil || strings.HasPrefix(relPath, "..") || filepath.IsAbs(relPath) {
-			return ListFilesResult{Success: false, Error: "access denied: path is outside working directory"}, nil
+			return ListFilesResult{Success: false, Error: err.Error()}, nil
 		}
 
 		entries, err := os.ReadDir(absPath)
@@ -345,9 +812,9 @@ func createListFilesTool(cfg ToolsConfig) (tool.Tool, error) {
 		return ListFilesResult{Success: true, Data: items}, nil
 	}
 
-	return functiontool.New(functiontool.Config{
+	return newFunctionTool(cfg, functiontool.Config{
 		Name:        "list_files",
-		Description: "列出指定目录下的文件和子目录。用于探索项目结构。",
+		Description: "列出指定目录下的文件和子目录。用于探索项目结构。" + mountsDescription(cfg),
 	}, handler)
 }
 
@@ -360,6 +827,16 @@ func createSaveExperienceTool(cfg ToolsConfig) (tool.Tool, error) {
 		if args.ErrorPattern == "" || args.RootCause == "" || args.Solution == "" {
 			return SaveExperienceResult{Success: false, Error: "error_pattern, root_cause, and solution are all required"}, nil
 		}
+		if err := allowSave(cfg, ctx); err != nil {
+			return SaveExperienceResult{Success: false, Error: err.Error()}, nil
+		}
+
+		// Exact duplicates are detected by content hash before paying for an embedding
+		if id, err := cfg.Store.FindExperienceByHash(ctx, memory.ContentHash(args.ErrorPattern)); err == nil {
+			return SaveExperienceResult{Success: false, Error: fmt.Sprintf("%v: the same error pattern is already saved (id %d)", memory.ErrDuplicateExperience, id)}, nil
+		} else if !errors.Is(err, memory.ErrNotFound) {
+			return SaveExperienceResult{Success: false, Error: fmt.Sprintf("failed to check for duplicates: %v", err)}, nil
+		}
 
 		// Generate embedding for the error pattern
 		embedding, err := cfg.Embedder.Embed(ctx, args.ErrorPattern)
@@ -368,22 +845,35 @@ func createSaveExperienceTool(cfg ToolsConfig) (tool.Tool, error) {
 		}
 
 		// Save to database
-		if err := cfg.Store.SaveExperience(ctx, args.ErrorPattern, args.RootCause, args.Solution, embedding); err != nil {
+		if err := cfg.Store.SaveExperience(ctx, userIDFromContext(ctx), args.ErrorPattern, args.RootCause, args.Solution, args.Tags, embedding); err != nil {
+			var exists *memory.ErrExperienceAlreadyExists
+			if errors.As(err, &exists) {
+				return SaveExperienceResult{Success: false, Error: fmt.Sprintf("an experience with the same error pattern already exists (id %d)", exists.ExistingID)}, nil
+			}
+			var merged *memory.ErrExperienceMerged
+			if errors.As(err, &merged) {
+				return SaveExperienceResult{Success: true, Data: fmt.Sprintf("已存在高度相似的经验 (id %d，相似度 %.2f%%)，已合并到该经验并增加其出现次数。", merged.ExistingID, merged.Similarity*100)}, nil
+			}
 			return SaveExperienceResult{Success: false, Error: fmt.Sprintf("failed to save experience: %v", err)}, nil
 		}
 
 		return SaveExperienceResult{Success: true, Data: "经验已成功保存到知识库。"}, nil
 	}
 
-	return functiontool.New(functiontool.Config{
+	return newFunctionTool(cfg, functiontool.Config{
 		Name:        "save_experience",
-		Description: "将成功解决的问题经验保存到知识库中，供将来参考。",
+		Description: "将成功解决的问题经验保存到知识库中，供将来参考。可通过 tags 添加 panic、nil-pointer、timeout 等标签，便于按类别检索。",
 	}, handler)
 }
 
 // BuildTools creates all agent tools with the given configuration.
+// Tools disabled by a ToolEnablementRule matching one of cfg.ProjectRules are left out,
+// and debug tools are only included when cfg.DebugTools is set.
 func BuildTools(cfg ToolsConfig) ([]tool.Tool, error) {
 	var tools []tool.Tool
+	if cfg.Limiter == nil {
+		cfg.Limiter = NewSessionLimiter()
+	}
 
 	searchTool, err := createSearchPastIssuesTool(cfg)
 	if err != nil {
@@ -397,6 +887,24 @@ func BuildTools(cfg ToolsConfig) ([]tool.Tool, error) {
 	}
 	tools = append(tools, readFileTool)
 
+	readURLTool, err := createReadURLTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create read_url tool: %w", err)
+	}
+	tools = append(tools, readURLTool)
+
+	writeFileTool, err := createWriteFileTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write_file_content tool: %w", err)
+	}
+	tools = append(tools, writeFileTool)
+
+	applyPatchTool, err := createApplyPatchTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create apply_patch tool: %w", err)
+	}
+	tools = append(tools, applyPatchTool)
+
 	listDirTool, err := createListDirectoryTool(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create list_directory tool: %w", err)
@@ -409,11 +917,220 @@ func BuildTools(cfg ToolsConfig) ([]tool.Tool, error) {
 	}
 	tools = append(tools, listFilesTool)
 
+	treeTool, err := createListFilesRecursiveTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list_files_recursive tool: %w", err)
+	}
+	tools = append(tools, treeTool)
+
+	grepTool, err := createGrepTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grep_in_files tool: %w", err)
+	}
+	tools = append(tools, grepTool)
+
+	gitDiffTool, err := createGitDiffTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create git_diff tool: %w", err)
+	}
+	tools = append(tools, gitDiffTool)
+
+	compareTool, err := createCompareFilesTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compare_files tool: %w", err)
+	}
+	tools = append(tools, compareTool)
+
+	goTestsTool, err := createRunGoTestsTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run_go_tests tool: %w", err)
+	}
+	tools = append(tools, goTestsTool)
+
 	saveExpTool, err := createSaveExperienceTool(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create save_experience tool: %w", err)
 	}
 	tools = append(tools, saveExpTool)
 
-	return tools, nil
+	getExpTool, err := createGetExperienceByIDTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create get_experience_by_id tool: %w", err)
+	}
+	tools = append(tools, getExpTool)
+
+	byTagTool, err := createListExperiencesByTagTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list_experiences_by_tag tool: %w", err)
+	}
+	tools = append(tools, byTagTool)
+
+	versionsTool, err := createExperienceVersionsTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create experience_versions tool: %w", err)
+	}
+	tools = append(tools, versionsTool)
+
+	revertTool, err := createRevertExperienceTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revert_experience tool: %w", err)
+	}
+	tools = append(tools, revertTool)
+
+	updateTool, err := createUpdateExperienceTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create update_experience tool: %w", err)
+	}
+	tools = append(tools, updateTool)
+
+	deleteTool, err := createDeleteExperienceTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delete_experience tool: %w", err)
+	}
+	tools = append(tools, deleteTool)
+
+	mergeTool, err := createMergeExperiencesTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge_experiences tool: %w", err)
+	}
+	tools = append(tools, mergeTool)
+
+	personalTool, err := createSearchPersonalHistoryTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search_personal_history tool: %w", err)
+	}
+	tools = append(tools, personalTool)
+
+	smartReadTool, err := createSmartReadTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create smart_read_file tool: %w", err)
+	}
+	tools = append(tools, smartReadTool)
+
+	chunkReadTool, err := createChunkReadFileTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunk_read_file tool: %w", err)
+	}
+	tools = append(tools, chunkReadTool)
+
+	watchTool, err := createWatchFileTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watch_file tool: %w", err)
+	}
+	tools = append(tools, watchTool)
+
+	queryRulesTool, err := createQueryRulesTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query_rules tool: %w", err)
+	}
+	tools = append(tools, queryRulesTool)
+
+	addRuleTool, err := createAddProjectRuleTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create add_project_rule tool: %w", err)
+	}
+	tools = append(tools, addRuleTool)
+
+	listRulesTool, err := createListProjectRulesTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list_project_rules tool: %w", err)
+	}
+	tools = append(tools, listRulesTool)
+
+	updateRuleTool, err := createUpdateProjectRuleTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create update_project_rule tool: %w", err)
+	}
+	tools = append(tools, updateRuleTool)
+
+	deleteRuleTool, err := createDeleteProjectRuleTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delete_project_rule tool: %w", err)
+	}
+	tools = append(tools, deleteRuleTool)
+
+	setContextTool, err := createSetProjectContextTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create set_project_context tool: %w", err)
+	}
+	tools = append(tools, setContextTool)
+
+	getContextTool, err := createGetProjectContextTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create get_project_context tool: %w", err)
+	}
+	tools = append(tools, getContextTool)
+
+	listContextTool, err := createListProjectContextTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list_project_context tool: %w", err)
+	}
+	tools = append(tools, listContextTool)
+
+	recentResultsTool, err := createRecentToolResultsTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create get_recent_tool_results tool: %w", err)
+	}
+	tools = append(tools, recentResultsTool)
+
+	summarizeTool, err := createSummarizeSessionTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create summarize_session tool: %w", err)
+	}
+	tools = append(tools, summarizeTool)
+
+	typeAssertTool, err := createTypeAssertionAuditTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit_type_assertions tool: %w", err)
+	}
+	tools = append(tools, typeAssertTool)
+
+	complexityTool, err := createCodeComplexityTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create code_complexity tool: %w", err)
+	}
+	tools = append(tools, complexityTool)
+
+	validateSyntaxTool, err := createValidateGoSyntaxTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validate_go_syntax tool: %w", err)
+	}
+	tools = append(tools, validateSyntaxTool)
+
+	goModuleTool, err := createReadGoModuleInfoTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create read_go_module_info tool: %w", err)
+	}
+	tools = append(tools, goModuleTool)
+
+	unitTestTool, err := createGenerateUnitTestTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create generate_unit_test tool: %w", err)
+	}
+	tools = append(tools, unitTestTool)
+
+	codeSearchTool, err := createCodeSearchTool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create code_search tool: %w", err)
+	}
+	tools = append(tools, codeSearchTool)
+
+	if cfg.DebugTools {
+		findSimilarTool, err := createFindSimilarExperiencesTool(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create find_similar_experiences tool: %w", err)
+		}
+		tools = append(tools, findSimilarTool)
+
+		if cfg.SQL != nil {
+			sqlTool, err := createExecuteSQLTool(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create execute_sql tool: %w", err)
+			}
+			tools = append(tools, sqlTool)
+		}
+	}
+
+	enablementRules := append(append([]ToolEnablementRule{}, DefaultToolEnablementRules...), cfg.ToolEnablementRules...)
+	return filterTools(tools, cfg.ProjectRules, enablementRules), nil
 }
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// newFunctionTool creates a function tool like functiontool.New. When cfg.Tracer is set,
+// each call of handler is recorded as a "tool.<name>" span carrying the arguments as
+// tool.args_json, and the store and embedding spans of the call become its children.
+// When cfg.AuditLog is set, each call is also written to the audit log.
+func newFunctionTool[TArgs, TResults any](cfg ToolsConfig, toolCfg functiontool.Config, handler functiontool.Func[TArgs, TResults]) (tool.Tool, error) {
+	if cfg.Tracer != nil {
+		handler = traceHandler(cfg.Tracer, toolCfg.Name, handler)
+	}
+	if cfg.AuditLog != nil {
+		handler = auditHandler(cfg.AuditLog, toolCfg.Name, handler)
+	}
+	return functiontool.New(toolCfg, handler)
+}
+
+// traceHandler wraps handler in a span named "tool."+name.
+func traceHandler[TArgs, TResults any](tracer trace.Tracer, name string, handler functiontool.Func[TArgs, TResults]) functiontool.Func[TArgs, TResults] {
+	return func(ctx tool.Context, args TArgs) (TResults, error) {
+		parent := context.Background()
+		if ctx != nil {
+			parent = ctx
+		}
+		spanCtx, span := tracer.Start(parent, "tool."+name)
+		defer span.End()
+
+		if span.IsRecording() {
+			if argsJSON, err := json.Marshal(args); err == nil {
+				span.SetAttributes(attribute.String("tool.args_json", string(argsJSON)))
+			}
+		}
+
+		if ctx != nil {
+			ctx = &spanToolContext{Context: ctx, spanCtx: spanCtx}
+		}
+		start := time.Now()
+		result, err := handler(ctx, args)
+		span.SetAttributes(attribute.Int64("tool.latency_ms", time.Since(start).Milliseconds()))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	}
+}
+
+// spanToolContext is a tool.Context whose context.Context methods come from spanCtx,
+// so that spans started by the tool handler are children of the tool span.
+type spanToolContext struct {
+	tool.Context
+	spanCtx context.Context
+}
+
+func (c *spanToolContext) Deadline() (time.Time, bool) { return c.spanCtx.Deadline() }
+func (c *spanToolContext) Done() <-chan struct{}       { return c.spanCtx.Done() }
+func (c *spanToolContext) Err() error                  { return c.spanCtx.Err() }
+func (c *spanToolContext) Value(key any) any           { return c.spanCtx.Value(key) }
@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultTreeDepth is the depth listed by list_files_recursive when MaxDepth is not set.
+	defaultTreeDepth = 5
+	// maxTreeDepth is the deepest list_files_recursive lists, whatever MaxDepth asks for.
+	maxTreeDepth = 10
+	// maxTreeNodes caps the number of files and directories returned by list_files_recursive.
+	maxTreeNodes = 2000
+)
+
+// ListFilesRecursiveArgs is the input for list_files_recursive tool.
+type ListFilesRecursiveArgs struct {
+	Path            string   `json:"path,omitempty"`             // Directory to list (relative to WorkDir or absolute, empty for WorkDir)
+	MaxDepth        int      `json:"max_depth,omitempty"`        // Levels below Path to list (default 5, at most 10)
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"` // Gitignore-style patterns of entries to leave out, e.g. "vendor/" or "*.pb.go"
+	IncludeHidden   bool     `json:"include_hidden,omitempty"`   // List entries whose name starts with "." (default false)
+}
+
+// TreeNode is a file or directory listed by list_files_recursive.
+type TreeNode struct {
+	Name     string     `json:"name"`               // Base name of the entry
+	IsDir    bool       `json:"is_dir,omitempty"`   // Whether the entry is a directory
+	Size     int64      `json:"size,omitempty"`     // Size of a file in bytes
+	Children []TreeNode `json:"children,omitempty"` // Entries of a directory, sorted by name
+}
+
+// ListFilesRecursiveResult is the output for list_files_recursive tool.
+type ListFilesRecursiveResult struct {
+	Success   bool      `json:"success"`             // Whether the operation succeeded
+	Tree      *TreeNode `json:"tree,omitempty"`      // Directory at Path with the entries below it
+	NodeCount int       `json:"node_count"`          // Number of entries listed below Path
+	Truncated bool      `json:"truncated,omitempty"` // Whether entries were left out because of the 2000 entry cap
+	Error     string    `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// treeNodeDefs returns the $defs of an output schema referring to TreeNode as
+// "#/$defs/TreeNode". Output schemas containing TreeNode are written by hand because
+// the schema inferred by functiontool cannot describe the recursive type.
+func treeNodeDefs() map[string]*jsonschema.Schema {
+	return map[string]*jsonschema.Schema{
+		"TreeNode": {
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name":     {Type: "string"},
+				"is_dir":   {Type: "boolean"},
+				"size":     {Type: "integer"},
+				"children": {Type: "array", Items: &jsonschema.Schema{Ref: "#/$defs/TreeNode"}},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// listFilesRecursiveOutputSchema is the output schema of list_files_recursive, see
+// treeNodeDefs.
+var listFilesRecursiveOutputSchema = &jsonschema.Schema{
+	Type: "object",
+	Defs: treeNodeDefs(),
+	Properties: map[string]*jsonschema.Schema{
+		"success":    {Type: "boolean"},
+		"tree":       {Ref: "#/$defs/TreeNode"},
+		"node_count": {Type: "integer"},
+		"truncated":  {Type: "boolean"},
+		"error":      {Type: "string"},
+	},
+	Required: []string{"success", "node_count"},
+}
+
+// createListFilesRecursiveTool creates the list_files_recursive tool.
+// Unlike list_directory, which lists a single level, this tool returns the directory
+// tree below a path so the agent can see the layout of a project in one call. Entries
+// ignored by .gitignore files found along the way are left out, as are .git directories
+// and symlinked directories, which are listed but not followed.
+func createListFilesRecursiveTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args ListFilesRecursiveArgs) (ListFilesRecursiveResult, error) {
+		depth := args.MaxDepth
+		if depth <= 0 {
+			depth = defaultTreeDepth
+		}
+		depth = min(depth, maxTreeDepth)
+
+		var excludes []ignoreRule
+		for _, pattern := range args.ExcludePatterns {
+			rule, ok := parseIgnoreRule(pattern, "")
+			if !ok {
+				continue
+			}
+			if _, err := path.Match(rule.pattern, ""); err != nil {
+				return ListFilesRecursiveResult{Success: false, Error: fmt.Sprintf("invalid exclude pattern %q: %v", pattern, err)}, nil
+			}
+			excludes = append(excludes, rule)
+		}
+
+		// Security check: ensure path is within working directory
+		absPath, err := SafeAbsPath(args.Path, cfg.WorkDir)
+		if err != nil {
+			return ListFilesRecursiveResult{Success: false, Error: err.Error()}, nil
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return ListFilesRecursiveResult{Success: false, Error: fmt.Sprintf("failed to read directory: %v", err)}, nil
+		}
+		if !info.IsDir() {
+			return ListFilesRecursiveResult{Success: false, Error: fmt.Sprintf("%s is not a directory", args.Path)}, nil
+		}
+
+		w := &treeWalker{maxDepth: depth, includeHidden: args.IncludeHidden}
+		root := TreeNode{Name: filepath.Base(absPath), IsDir: true}
+		root.Children = w.walk(absPath, "", 1, excludes)
+		return ListFilesRecursiveResult{Success: true, Tree: &root, NodeCount: w.nodes, Truncated: w.truncated}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:         "list_files_recursive",
+		Description:  "以树形结构递归列出目录下的文件和子目录（默认 5 层，最多 10 层，最多 2000 个条目），遵循 .gitignore，可排除指定模式。用于一次了解整个项目的结构。",
+		OutputSchema: listFilesRecursiveOutputSchema,
+	}, handler)
+}
+
+// treeWalker builds the tree returned by list_files_recursive.
+type treeWalker struct {
+	maxDepth      int
+	includeHidden bool
+	nodes         int  // Entries listed so far
+	truncated     bool // Whether the node cap was reached
+}
+
+// walk returns the entries of the directory dir, whose slash-separated path relative
+// to the listed directory is rel, at the given depth. rules are the ignore rules of
+// the directories above dir; those of dir's own .gitignore are added to them.
+// Directories that cannot be read are listed without children.
+func (w *treeWalker) walk(dir, rel string, depth int, rules []ignoreRule) []TreeNode {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	rules = append(rules[:len(rules):len(rules)], readGitignore(filepath.Join(dir, ".gitignore"), rel)...)
+
+	var nodes []TreeNode
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == ".git" || (!w.includeHidden && strings.HasPrefix(name, ".")) {
+			continue
+		}
+		entryRel := path.Join(rel, name)
+		if ignored(rules, entryRel, entry.IsDir()) {
+			continue
+		}
+		if w.nodes >= maxTreeNodes {
+			w.truncated = true
+			return nodes
+		}
+		w.nodes++
+
+		node := TreeNode{Name: name, IsDir: entry.IsDir()}
+		if entry.IsDir() {
+			if depth < w.maxDepth {
+				node.Children = w.walk(filepath.Join(dir, name), entryRel, depth+1, rules)
+			}
+		} else if info, err := entry.Info(); err == nil {
+			node.Size = info.Size()
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// ignoreRule is a pattern of a .gitignore file or of ListFilesRecursiveArgs.ExcludePatterns.
+type ignoreRule struct {
+	base     string // Slash-separated directory the rule applies to, relative to the listed directory
+	pattern  string // Glob matched against the path relative to base, or against the base name
+	anchored bool   // Whether the pattern contains a "/" and so matches the path relative to base
+	negate   bool   // Whether the pattern started with "!" and re-includes matching entries
+	dirOnly  bool   // Whether the pattern ended with "/" and only matches directories
+}
+
+// parseIgnoreRule parses a line of a .gitignore file in the directory base. It returns
+// false for blank lines and comments. Only the subset of the gitignore syntax that
+// path.Match supports is handled; a leading "**/" is treated like an unanchored pattern.
+func parseIgnoreRule(line, base string) (ignoreRule, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	line = strings.TrimPrefix(line, "**/")
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// matches reports whether the entry at the slash-separated path rel matches the rule.
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+			return false
+		}
+	}
+	if !r.anchored {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(r.pattern, rel)
+	return ok
+}
+
+// ignored reports whether the entry at rel is ignored by rules. As in git, the last
+// matching rule decides, so a later "!" rule can re-include an entry.
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.matches(rel, isDir) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+// readGitignore returns the rules of the .gitignore file at file, in the directory base.
+// A missing or unreadable file has no rules.
+func readGitignore(file, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// TypeAssertArgs is the input for audit_type_assertions tool.
+type TypeAssertArgs struct {
+	Filepath string `json:"filepath,omitempty"` // Go file to audit (relative to WorkDir or absolute)
+	Package  string `json:"package,omitempty"`  // Package directory to audit instead of a single file
+}
+
+// UnsafeAssertion is a type assertion that panics when the dynamic type does not match.
+type UnsafeAssertion struct {
+	File         string `json:"file"`          // File path relative to WorkDir
+	Func         string `json:"func"`          // Enclosing function, "Type.Method" for methods, empty at package level
+	Line         int    `json:"line"`          // Line of the assertion
+	Column       int    `json:"column"`        // Column of the assertion
+	AssertedType string `json:"asserted_type"` // Type asserted to, e.g. "*os.PathError"
+}
+
+// TypeAssertResult is the output for audit_type_assertions tool.
+type TypeAssertResult struct {
+	Success  bool              `json:"success"`            // Whether the operation succeeded
+	Findings []UnsafeAssertion `json:"findings,omitempty"` // Unsafe assertions found, in file and line order
+	Error    string            `json:"error,omitempty"`    // Error message if the operation failed
+}
+
+// createTypeAssertionAuditTool creates the audit_type_assertions tool.
+// This tool finds type assertions written without the comma-ok form, such as
+// x := val.(T), which panic at runtime when val holds a different type.
+func createTypeAssertionAuditTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args TypeAssertArgs) (TypeAssertResult, error) {
+		target := args.Filepath
+		if target == "" {
+			target = args.Package
+		}
+		if target == "" {
+			return TypeAssertResult{Success: false, Error: "filepath or package is required"}, nil
+		}
+
+		absPath, err := SafeAbsPath(target, cfg.WorkDir)
+		if err != nil {
+			return TypeAssertResult{Success: false, Error: err.Error()}, nil
+		}
+
+		files, err := goFilesAt(absPath)
+		if err != nil {
+			return TypeAssertResult{Success: false, Error: err.Error()}, nil
+		}
+
+		absWorkDir, _ := filepath.Abs(cfg.WorkDir)
+		findings := []UnsafeAssertion{}
+		for _, file := range files {
+			rel, err := filepath.Rel(absWorkDir, file)
+			if err != nil {
+				rel = file
+			}
+			found, err := auditTypeAssertions(file, filepath.ToSlash(rel))
+			if err != nil {
+				return TypeAssertResult{Success: false, Error: err.Error()}, nil
+			}
+			findings = append(findings, found...)
+		}
+
+		return TypeAssertResult{Success: true, Findings: findings}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "audit_type_assertions",
+		Description: "检查 Go 文件或包目录中未使用 comma-ok 形式的类型断言（如 x := v.(T)），这类断言在类型不匹配时会 panic。",
+	}, handler)
+}
+
+// goFilesAt returns path itself if it is a file, or the non-test Go files directly
+// inside it if it is a directory, in lexical order.
+func goFilesAt(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %v", filepath.Base(path), err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			files = append(files, filepath.Join(path, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// auditTypeAssertions parses the Go file at path and returns every type assertion
+// that is not used in the two-value comma-ok form. Type switches are not reported.
+func auditTypeAssertions(path, rel string) ([]UnsafeAssertion, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", rel, err)
+	}
+
+	// Assertions in "v, ok := x.(T)" and "var v, ok = x.(T)" are safe
+	safe := make(map[*ast.TypeAssertExpr]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			if len(s.Lhs) == 2 && len(s.Rhs) == 1 {
+				if ta, ok := ast.Unparen(s.Rhs[0]).(*ast.TypeAssertExpr); ok {
+					safe[ta] = true
+				}
+			}
+		case *ast.ValueSpec:
+			if len(s.Names) == 2 && len(s.Values) == 1 {
+				if ta, ok := ast.Unparen(s.Values[0]).(*ast.TypeAssertExpr); ok {
+					safe[ta] = true
+				}
+			}
+		}
+		return true
+	})
+
+	var findings []UnsafeAssertion
+	for _, decl := range f.Decls {
+		funcName := ""
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			funcName = fn.Name.Name
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				funcName = memory.ReceiverName(fn.Recv.List[0].Type) + "." + funcName
+			}
+		}
+
+		ast.Inspect(decl, func(n ast.Node) bool {
+			ta, ok := n.(*ast.TypeAssertExpr)
+			// A nil Type is the x.(type) of a type switch
+			if !ok || ta.Type == nil || safe[ta] {
+				return true
+			}
+			pos := fset.Position(ta.Pos())
+			findings = append(findings, UnsafeAssertion{
+				File:         rel,
+				Func:         funcName,
+				Line:         pos.Line,
+				Column:       pos.Column,
+				AssertedType: types.ExprString(ta.Type),
+			})
+			return true
+		})
+	}
+
+	return findings, nil
+}
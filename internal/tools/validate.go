@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/adk/tool"
+)
+
+// ValidationError describes a single problem with the arguments of a tool call.
+type ValidationError struct {
+	Field   string // Name of the offending argument
+	Message string // Human-readable description of the problem
+}
+
+// argSchemas maps tool names to the JSON schema generated from their argument struct.
+var argSchemas = map[string]*jsonschema.Schema{}
+
+func init() {
+	registerArgSchema[SearchPastIssuesArgs]("search_past_issues")
+	registerArgSchema[ReadFileArgs]("read_file_content")
+	registerArgSchema[ReadURLArgs]("read_url")
+	registerArgSchema[WriteFileArgs]("write_file_content")
+	registerArgSchema[ApplyPatchArgs]("apply_patch")
+	registerArgSchema[ListDirectoryArgs]("list_directory")
+	registerArgSchema[ListFilesArgs]("list_files")
+	registerArgSchema[ListFilesRecursiveArgs]("list_files_recursive")
+	registerArgSchema[GrepArgs]("grep_in_files")
+	registerArgSchema[GitDiffArgs]("git_diff")
+	registerArgSchema[CompareFilesArgs]("compare_files")
+	registerArgSchema[RunGoTestsArgs]("run_go_tests")
+	registerArgSchema[SaveExperienceArgs]("save_experience")
+	registerArgSchema[GetExperienceByIDArgs]("get_experience_by_id")
+	registerArgSchema[ListExperiencesByTagArgs]("list_experiences_by_tag")
+	registerArgSchema[ExperienceVersionsArgs]("experience_versions")
+	registerArgSchema[RevertExperienceArgs]("revert_experience")
+	registerArgSchema[UpdateExperienceArgs]("update_experience")
+	registerArgSchema[DeleteExperienceArgs]("delete_experience")
+	registerArgSchema[MergeExperiencesArgs]("merge_experiences")
+	registerArgSchema[UserSearchArgs]("search_personal_history")
+	registerArgSchema[SmartReadArgs]("smart_read_file")
+	registerArgSchema[ChunkReadFileArgs]("chunk_read_file")
+	registerArgSchema[WatchFileArgs]("watch_file")
+	registerArgSchema[QueryRulesArgs]("query_rules")
+	registerArgSchema[AddProjectRuleArgs]("add_project_rule")
+	registerArgSchema[ListProjectRulesArgs]("list_project_rules")
+	registerArgSchema[UpdateProjectRuleArgs]("update_project_rule")
+	registerArgSchema[DeleteProjectRuleArgs]("delete_project_rule")
+	registerArgSchema[SetProjectContextArgs]("set_project_context")
+	registerArgSchema[GetProjectContextArgs]("get_project_context")
+	registerArgSchema[ListProjectContextArgs]("list_project_context")
+	registerArgSchema[RecentToolResultsArgs]("get_recent_tool_results")
+	registerArgSchema[SummarizeSessionArgs]("summarize_session")
+	registerArgSchema[TypeAssertArgs]("audit_type_assertions")
+	registerArgSchema[CodeComplexityArgs]("code_complexity")
+	registerArgSchema[ValidateGoSyntaxArgs]("validate_go_syntax")
+	registerArgSchema[ReadGoModuleInfoArgs]("read_go_module_info")
+	registerArgSchema[GenerateUnitTestArgs]("generate_unit_test")
+	registerArgSchema[CodeSearchArgs]("code_search")
+	registerArgSchema[FindSimilarExperiencesArgs]("find_similar_experiences")
+	registerArgSchema[ExecuteSQLArgs]("execute_sql")
+}
+
+// registerArgSchema generates the JSON schema for T from its JSON tags and
+// registers it for toolName. It panics if the schema cannot be generated,
+// since that is a programming error in the argument struct.
+func registerArgSchema[T any](toolName string) {
+	schema, err := jsonschema.For[T](nil)
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate schema for %s: %v", toolName, err))
+	}
+	argSchemas[toolName] = schema
+}
+
+// ValidateToolArgs checks the raw arguments produced by the LLM for toolName against
+// the schema of the tool's argument struct. It reports missing required fields,
+// unknown fields and values of the wrong type. Tools without a registered schema
+// are not validated.
+func ValidateToolArgs(toolName string, args map[string]interface{}) []ValidationError {
+	schema, ok := argSchemas[toolName]
+	if !ok {
+		return nil
+	}
+
+	var errs []ValidationError
+	for _, field := range schema.Required {
+		if _, ok := args[field]; !ok {
+			errs = append(errs, ValidationError{Field: field, Message: "is required"})
+		}
+	}
+
+	fields := make([]string, 0, len(args))
+	for field := range args {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		prop, ok := schema.Properties[field]
+		if !ok {
+			errs = append(errs, ValidationError{Field: field, Message: "is not a known argument"})
+			continue
+		}
+		if want := schemaType(prop); want != "" && !matchesType(args[field], want) {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("must be of type %s, got %s", want, jsonTypeOf(args[field])),
+			})
+		}
+	}
+
+	return errs
+}
+
+// ValidateArgsCallback is a before-tool callback that rejects tool calls whose
+// arguments do not match the tool's schema. Instead of running the tool it returns
+// a failed result describing the violations, so the LLM can correct its call.
+func ValidateArgsCallback(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+	errs := ValidateToolArgs(t.Name(), args)
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return map[string]any{"success": false, "error": formatValidationErrors(errs)}, nil
+}
+
+// formatValidationErrors joins validation errors into a single error message.
+func formatValidationErrors(errs []ValidationError) string {
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		parts = append(parts, fmt.Sprintf("%s %s", e.Field, e.Message))
+	}
+	return "invalid arguments: " + strings.Join(parts, "; ")
+}
+
+// schemaType returns the JSON type declared by schema, ignoring "null".
+func schemaType(schema *jsonschema.Schema) string {
+	if schema.Type != "" {
+		return schema.Type
+	}
+	for _, t := range schema.Types {
+		if t != "null" {
+			return t
+		}
+	}
+	return ""
+}
+
+// matchesType reports whether v is a valid JSON value of the given schema type.
+func matchesType(v any, want string) bool {
+	switch want {
+	case "integer":
+		switch n := v.(type) {
+		case float64:
+			return n == math.Trunc(n)
+		case int, int32, int64:
+			return true
+		}
+		return false
+	case "number":
+		switch v.(type) {
+		case float64, float32, int, int32, int64:
+			return true
+		}
+		return false
+	default:
+		return jsonTypeOf(v) == want
+	}
+}
+
+// jsonTypeOf returns the JSON type name of a decoded JSON value.
+func jsonTypeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int32, int64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/easeaico/adk-memory-agent/internal/memory"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	// defaultWatchInterval is how long watch_file waits for new lines when PollInterval is
+	// not set.
+	defaultWatchInterval = 10 * time.Second
+	// maxWatchInterval is the longest watch_file waits for new lines.
+	maxWatchInterval = 60 * time.Second
+	// watchPollEvery is how often watch_file checks the file for new lines.
+	watchPollEvery = 200 * time.Millisecond
+	// defaultWatchLines is the number of lines watch_file returns when MaxNewLines is not set.
+	defaultWatchLines = 100
+	// maxWatchLines is the most lines watch_file returns at once.
+	maxWatchLines = 1000
+)
+
+// WatchFileArgs is the input for watch_file tool.
+type WatchFileArgs struct {
+	Filepath     string `json:"filepath"`                // Path to the file to watch (relative to WorkDir, absolute, or "@alias/path" in a mount)
+	PollInterval string `json:"poll_interval,omitempty"` // How long to wait for new lines, e.g. "30s" (default 10s, at most 60s)
+	MaxNewLines  int    `json:"max_new_lines,omitempty"` // Maximum lines to return (default 100, at most 1000)
+}
+
+// WatchFileResult is the output for watch_file tool.
+type WatchFileResult struct {
+	Success   bool     `json:"success"`             // Whether the operation succeeded
+	Lines     []string `json:"lines,omitempty"`     // Lines appended to the file since the last call, without their newline
+	Truncated bool     `json:"truncated,omitempty"` // Whether more lines are waiting, returned by the next call
+	Message   string   `json:"message,omitempty"`   // "no changes" when nothing was appended in time
+	Error     string   `json:"error,omitempty"`     // Error message if the operation failed
+}
+
+// watchAllowedExtensions are the extensions watch_file follows: logs and other plain
+// text output, and files without an extension. ToolsConfig.DeniedExtensions still
+// applies, while ToolsConfig.AllowedExtensions, meant for read_file_content, does not.
+var watchAllowedExtensions = []string{"", ".log", ".txt", ".out", ".err"}
+
+// watchBatch is the lines found by one poll of watchFile and the position after them,
+// or the error that stopped it.
+type watchBatch struct {
+	lines []memory.LogLine
+	tail  memory.LogTail
+	err   error
+}
+
+// createWatchFileTool creates the watch_file tool.
+// The first call in a session waits for lines appended to the file from then on; later
+// calls return the lines appended since the lines returned last, so that the agent can
+// follow a log file across turns. Only complete lines are returned.
+func createWatchFileTool(cfg ToolsConfig) (tool.Tool, error) {
+	handler := func(ctx tool.Context, args WatchFileArgs) (WatchFileResult, error) {
+		if args.Filepath == "" {
+			return WatchFileResult{Success: false, Error: "filepath is required"}, nil
+		}
+		interval := defaultWatchInterval
+		if args.PollInterval != "" {
+			d, err := time.ParseDuration(args.PollInterval)
+			if err != nil || d <= 0 {
+				return WatchFileResult{Success: false, Error: fmt.Sprintf("invalid poll_interval %q, use a duration such as \"30s\"", args.PollInterval)}, nil
+			}
+			interval = min(d, maxWatchInterval)
+		}
+		maxLines := args.MaxNewLines
+		if maxLines <= 0 {
+			maxLines = defaultWatchLines
+		}
+		maxLines = min(maxLines, maxWatchLines)
+
+		absPath, err := resolvePath(cfg, args.Filepath)
+		if err != nil {
+			return WatchFileResult{Success: false, Error: err.Error()}, nil
+		}
+		if err := checkExtensionIn(cfg, absPath, watchAllowedExtensions); err != nil {
+			return WatchFileResult{Success: false, Error: err.Error()}, nil
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return WatchFileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+		}
+		if info.IsDir() {
+			return WatchFileResult{Success: false, Error: "filepath is a directory"}, nil
+		}
+		session := sessionContext(cfg, ctx)
+		tail, ok := session.WatchTail(absPath)
+		if !ok {
+			tail = memory.LogTail{Path: absPath, File: info, Offset: info.Size()}
+		}
+
+		watchCtx, cancel := context.WithTimeout(commandContext(ctx), interval)
+		defer cancel()
+		// Return as soon as a poll finds new lines; cancel stops the watcher
+		batch, ok := <-watchFile(watchCtx, tail, watchPollEvery)
+		if !ok {
+			session.SetWatchTail(absPath, tail)
+			return WatchFileResult{Success: true, Message: "no changes"}, nil
+		}
+		if batch.err != nil {
+			return WatchFileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", batch.err)}, nil
+		}
+		lines := batch.lines[:min(len(batch.lines), maxLines)]
+		texts := make([]string, len(lines))
+		for i, line := range lines {
+			texts[i] = line.Text
+		}
+		// Lines left out are returned by the next call
+		batch.tail.Offset = lines[len(lines)-1].End
+		session.SetWatchTail(absPath, batch.tail)
+		return WatchFileResult{Success: true, Lines: texts, Truncated: len(lines) < len(batch.lines)}, nil
+	}
+
+	return newFunctionTool(cfg, functiontool.Config{
+		Name:        "watch_file",
+		Description: "监视文件（如日志文件）新追加的行。第一次调用等待从现在起追加的行；之后的调用返回上次返回之后追加的行。最多等待 poll_interval（如 \"30s\"，默认 10s，最长 60s），一旦有新的完整行就立即返回，最多 max_new_lines 行（默认 100），truncated 为 true 时再次调用可取得剩余的行；超时没有新行时 message 为 \"no changes\"。用于在用户复现问题时跟踪日志并总结新的输出。" + mountsDescription(cfg),
+	}, handler)
+}
+
+// watchFile polls the file of tail every interval and sends the complete lines appended
+// to it after tail on the returned channel until ctx is done, when the channel is closed.
+// Reading starts over from the beginning when the file is truncated or replaced, e.g. by
+// log rotation. A read error is sent as the last batch.
+func watchFile(ctx context.Context, tail memory.LogTail, interval time.Duration) <-chan watchBatch {
+	batches := make(chan watchBatch)
+	go func() {
+		defer close(batches)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			lines, err := tail.ReadLines(maxReadBytes)
+			if err != nil || len(lines) > 0 {
+				select {
+				case batches <- watchBatch{lines: lines, tail: tail, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return batches
+}
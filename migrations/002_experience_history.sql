@@ -0,0 +1,12 @@
+-- Experience History
+-- Keeps every previous version of an issue_history row so that updates can be audited and reverted
+CREATE TABLE experience_history (
+    id SERIAL PRIMARY KEY,
+    experience_id INT NOT NULL REFERENCES issue_history(id) ON DELETE CASCADE,
+    version INT NOT NULL,           -- 1 is the original content, incremented on every update
+    pattern TEXT,
+    cause TEXT,
+    solution TEXT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (experience_id, version)
+);
@@ -0,0 +1,5 @@
+-- Per-user experiences
+-- Records which user's session produced each experience so history can be searched per user
+ALTER TABLE issue_history ADD COLUMN user_id TEXT;
+
+CREATE INDEX idx_issue_history_user_id ON issue_history(user_id);
@@ -0,0 +1,5 @@
+-- Experience sources
+-- Distinguishes experiences learned from sessions from documentation ingested by the codebase indexer
+ALTER TABLE issue_history ADD COLUMN source VARCHAR(50) NOT NULL DEFAULT 'session';
+
+CREATE INDEX idx_issue_history_task_signature ON issue_history(task_signature);
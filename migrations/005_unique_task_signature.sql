@@ -0,0 +1,7 @@
+-- Session experience lookup by task signature
+-- The task signature is only the first 50 runes of the error pattern, so different
+-- experiences can share it; it is indexed for lookups but not unique. Exact duplicates
+-- are detected by the content hash instead (see 006_content_hash.sql).
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(task_signature)
+    WHERE source = 'session';
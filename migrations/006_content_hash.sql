@@ -0,0 +1,16 @@
+-- Content hash deduplication
+-- SHA-256 of error_pattern, used to detect exact duplicates before calling the embedding API
+ALTER TABLE issue_history ADD COLUMN hash TEXT;
+
+-- Only the oldest of several session experiences with the same pattern gets its hash,
+-- so that existing duplicates are kept without violating the unique index below.
+UPDATE issue_history a SET hash = encode(sha256(convert_to(a.error_pattern, 'UTF8')), 'hex')
+WHERE a.source <> 'session'
+   OR NOT EXISTS (
+       SELECT 1 FROM issue_history b
+       WHERE b.source = 'session' AND b.error_pattern = a.error_pattern AND b.id < a.id
+   );
+
+CREATE UNIQUE INDEX idx_issue_history_session_hash
+    ON issue_history(hash)
+    WHERE source = 'session';
@@ -0,0 +1,14 @@
+-- Soft delete
+-- Merged experiences are marked deleted instead of removed so they can be audited.
+-- Deleted rows are excluded from the session indexes and the uniqueness constraint.
+ALTER TABLE issue_history ADD COLUMN deleted_at TIMESTAMP;
+
+DROP INDEX idx_issue_history_session_signature;
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(task_signature)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+DROP INDEX idx_issue_history_session_hash;
+CREATE UNIQUE INDEX idx_issue_history_session_hash
+    ON issue_history(hash)
+    WHERE source = 'session' AND deleted_at IS NULL;
@@ -0,0 +1,6 @@
+-- Embedding model
+-- Name of the model that produced each embedding, so vectors from different models
+-- can be told apart. Existing embeddings were all produced by text-embedding-004.
+ALTER TABLE issue_history ADD COLUMN embedding_model TEXT;
+
+UPDATE issue_history SET embedding_model = 'text-embedding-004' WHERE embedding IS NOT NULL;
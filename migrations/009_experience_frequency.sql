@@ -0,0 +1,4 @@
+-- Experience frequency
+-- Number of times an experience was encountered; near-duplicate saves increment it
+-- instead of inserting a new row.
+ALTER TABLE issue_history ADD COLUMN frequency INTEGER NOT NULL DEFAULT 1;
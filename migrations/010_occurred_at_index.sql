@@ -0,0 +1,4 @@
+-- Experience expiry
+-- Experiences older than MAX_EXPERIENCE_AGE_DAYS are excluded from search and purged daily.
+-- The cut-off is configured at runtime, so it is computed in queries rather than stored.
+CREATE INDEX idx_issue_history_occurred_at ON issue_history(occurred_at);
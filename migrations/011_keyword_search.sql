@@ -0,0 +1,4 @@
+-- Keyword search
+-- HybridSearch matches query terms against error patterns with full-text search. The
+-- 'simple' configuration does not stem words, so error codes such as ECONNRESET match exactly.
+CREATE INDEX idx_issue_history_error_pattern_fts ON issue_history USING GIN (to_tsvector('simple', error_pattern));
@@ -0,0 +1,20 @@
+-- Project isolation
+-- Experiences and rules belong to the project they were saved for, so that projects
+-- sharing a database do not see each other's memories. Rows with an empty project_id
+-- are shared by all projects; existing rows become shared.
+ALTER TABLE issue_history ADD COLUMN project_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE project_rules ADD COLUMN project_id TEXT NOT NULL DEFAULT '';
+
+-- Session experiences are unique per project
+DROP INDEX idx_issue_history_session_signature;
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(project_id, task_signature)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+DROP INDEX idx_issue_history_session_hash;
+CREATE UNIQUE INDEX idx_issue_history_session_hash
+    ON issue_history(project_id, hash)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+CREATE INDEX idx_issue_history_project_id ON issue_history(project_id);
+CREATE INDEX idx_project_rules_project_id ON project_rules(project_id);
@@ -0,0 +1,6 @@
+-- Experience tags
+-- Free-form labels such as "panic" or "timeout" that searches can filter on, narrowing
+-- similarity results to experiences of the same kind.
+ALTER TABLE issue_history ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_issue_history_tags ON issue_history USING GIN (tags);
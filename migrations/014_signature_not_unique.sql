@@ -0,0 +1,8 @@
+-- Task signatures are not unique
+-- Databases migrated before 005_unique_task_signature.sql was relaxed enforce a unique
+-- task signature per project, which made experiences that only share the first 50 runes
+-- of their pattern collide. Session experiences are unique by content hash only.
+DROP INDEX idx_issue_history_session_signature;
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(project_id, task_signature)
+    WHERE source = 'session' AND deleted_at IS NULL;
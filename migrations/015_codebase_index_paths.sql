@@ -0,0 +1,6 @@
+-- Codebase index signatures identify the file
+-- Task signatures of codebase_index rows now start with a hash of the file path, so the
+-- rows of a changed file can be replaced. Rows with the old path-less signature can never
+-- be replaced and are dropped; the next index_codebase run indexes their files again.
+DELETE FROM issue_history
+WHERE source = 'codebase_index' AND task_signature NOT LIKE 'codebase_index:%:%';
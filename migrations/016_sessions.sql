@@ -0,0 +1,8 @@
+-- Sessions
+-- History of agent conversations started with --session-id, so that they can be resumed
+-- after the process restarts. history is the gob-encoded list of genai.Content turns.
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    history BYTEA NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
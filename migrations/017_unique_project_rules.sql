@@ -0,0 +1,12 @@
+-- Unique project rules
+-- A project has each rule at most once per category, so that adding a rule again updates
+-- it instead of duplicating it. Existing duplicates are reduced to their oldest row.
+DELETE FROM project_rules r
+USING project_rules older
+WHERE older.project_id = r.project_id
+  AND older.category = r.category
+  AND older.rule_content = r.rule_content
+  AND older.id < r.id;
+
+ALTER TABLE project_rules
+    ADD CONSTRAINT project_rules_project_category_content_key UNIQUE (project_id, category, rule_content);
@@ -0,0 +1,11 @@
+-- Embedding meta
+-- Model and dimension of the vectors in issue_history.embedding, checked on startup
+-- against the configured embedding model. The column was created as vector(768) for
+-- text-embedding-004.
+CREATE TABLE embedding_meta (
+    model_name TEXT NOT NULL,
+    dimension INT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO embedding_meta (model_name, dimension) VALUES ('text-embedding-004', 768);
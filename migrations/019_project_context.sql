@@ -0,0 +1,10 @@
+-- Project Context
+-- Free-form facts about each project, such as its entry point or CI pipeline URL, that
+-- the agent keeps in its system prompt.
+CREATE TABLE project_context (
+    project_id TEXT NOT NULL DEFAULT '',
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, key)
+);
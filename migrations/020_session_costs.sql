@@ -0,0 +1,15 @@
+-- Session Costs
+-- Token usage and estimated API cost of each agent session, recorded when it ends.
+CREATE TABLE session_costs (
+    id SERIAL PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    project_id TEXT NOT NULL DEFAULT '',
+    input_tokens BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    embedding_calls BIGINT NOT NULL DEFAULT 0,
+    embedding_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ended_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_session_costs_ended_at ON session_costs (ended_at DESC);
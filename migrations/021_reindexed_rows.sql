@@ -0,0 +1,4 @@
+-- Reindexed Rows
+-- Number of embedded experiences when the IVFFlat vector index was last rebuilt, so that
+-- it is rebuilt again once the table has grown enough for its lists to be stale.
+ALTER TABLE embedding_meta ADD COLUMN reindexed_rows BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+// Package migrations embeds the SQL migrations of the agent's database schema, so
+// that the agent can apply them on startup (see memory.PostgresStore.Migrate).
+package migrations
+
+import "embed"
+
+// FS holds the migration files. Each is named <version>_<description>.sql, where
+// version is the position of the migration in the sequence, starting at 1.
+// schema_postgres.sql, generated from them, is not a migration and is left out.
+//
+//go:embed [0-9]*.sql
+var FS embed.FS
+
+//go:generate go run ../cmd/gen-schema -o schema_postgres.sql
@@ -0,0 +1,331 @@
+-- Code generated by gen-schema from the migrations in this directory. DO NOT EDIT.
+--
+-- Database schema at migration version 21. Apply it to an empty database with
+--   psql -v ON_ERROR_STOP=1 --single-transaction -f schema_postgres.sql
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Version 1: 001_init.sql
+
+-- Enable pgvector extension
+CREATE EXTENSION IF NOT EXISTS vector;
+
+-- Semantic Memory: Project Rules
+-- Stores static, globally-effective knowledge like code style and architecture constraints
+CREATE TABLE project_rules (
+    id SERIAL PRIMARY KEY,
+    category VARCHAR(50) NOT NULL,  -- e.g., "STYLE", "SECURITY", "ARCHITECTURE"
+    rule_content TEXT NOT NULL,     -- e.g., "禁止在循环中使用 defer"
+    priority INT DEFAULT 1,         -- Rule weight
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Index for category-based queries
+CREATE INDEX idx_rules_category ON project_rules(category);
+
+-- Episodic Memory: Issue History
+-- Stores dynamically accumulated experience, supports vector semantic search
+CREATE TABLE issue_history (
+    id SERIAL PRIMARY KEY,
+    task_signature VARCHAR(255),    -- Short fingerprint of task/error
+    error_pattern TEXT,             -- Original error message or phenomenon description
+    root_cause TEXT,                -- Root cause analysis
+    solution_summary TEXT,          -- Solution/code change summary
+    embedding vector(768),          -- Core: Embedding vector of problem description
+    occurred_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Vector index using IVFFlat for similarity search
+CREATE INDEX ON issue_history USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100);
+
+-- Insert some sample rules for testing
+INSERT INTO project_rules (category, rule_content, priority) VALUES
+    ('STYLE', '禁止在循环中使用 defer', 1),
+    ('STYLE', '所有导出的函数必须有文档注释', 1),
+    ('SECURITY', '禁止在代码中硬编码密钥或密码', 2),
+    ('ARCHITECTURE', '数据库操作必须通过 Repository 层', 1),
+    ('ARCHITECTURE', 'HTTP Handler 不得直接调用数据库', 1);
+
+INSERT INTO schema_migrations (version, name) VALUES (1, '001_init.sql');
+
+-- Version 2: 002_experience_history.sql
+
+-- Experience History
+-- Keeps every previous version of an issue_history row so that updates can be audited and reverted
+CREATE TABLE experience_history (
+    id SERIAL PRIMARY KEY,
+    experience_id INT NOT NULL REFERENCES issue_history(id) ON DELETE CASCADE,
+    version INT NOT NULL,           -- 1 is the original content, incremented on every update
+    pattern TEXT,
+    cause TEXT,
+    solution TEXT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (experience_id, version)
+);
+
+INSERT INTO schema_migrations (version, name) VALUES (2, '002_experience_history.sql');
+
+-- Version 3: 003_user_id.sql
+
+-- Per-user experiences
+-- Records which user's session produced each experience so history can be searched per user
+ALTER TABLE issue_history ADD COLUMN user_id TEXT;
+
+CREATE INDEX idx_issue_history_user_id ON issue_history(user_id);
+
+INSERT INTO schema_migrations (version, name) VALUES (3, '003_user_id.sql');
+
+-- Version 4: 004_experience_source.sql
+
+-- Experience sources
+-- Distinguishes experiences learned from sessions from documentation ingested by the codebase indexer
+ALTER TABLE issue_history ADD COLUMN source VARCHAR(50) NOT NULL DEFAULT 'session';
+
+CREATE INDEX idx_issue_history_task_signature ON issue_history(task_signature);
+
+INSERT INTO schema_migrations (version, name) VALUES (4, '004_experience_source.sql');
+
+-- Version 5: 005_unique_task_signature.sql
+
+-- Session experience lookup by task signature
+-- The task signature is only the first 50 runes of the error pattern, so different
+-- experiences can share it; it is indexed for lookups but not unique. Exact duplicates
+-- are detected by the content hash instead (see 006_content_hash.sql).
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(task_signature)
+    WHERE source = 'session';
+
+INSERT INTO schema_migrations (version, name) VALUES (5, '005_unique_task_signature.sql');
+
+-- Version 6: 006_content_hash.sql
+
+-- Content hash deduplication
+-- SHA-256 of error_pattern, used to detect exact duplicates before calling the embedding API
+ALTER TABLE issue_history ADD COLUMN hash TEXT;
+
+-- Only the oldest of several session experiences with the same pattern gets its hash,
+-- so that existing duplicates are kept without violating the unique index below.
+UPDATE issue_history a SET hash = encode(sha256(convert_to(a.error_pattern, 'UTF8')), 'hex')
+WHERE a.source <> 'session'
+   OR NOT EXISTS (
+       SELECT 1 FROM issue_history b
+       WHERE b.source = 'session' AND b.error_pattern = a.error_pattern AND b.id < a.id
+   );
+
+CREATE UNIQUE INDEX idx_issue_history_session_hash
+    ON issue_history(hash)
+    WHERE source = 'session';
+
+INSERT INTO schema_migrations (version, name) VALUES (6, '006_content_hash.sql');
+
+-- Version 7: 007_soft_delete.sql
+
+-- Soft delete
+-- Merged experiences are marked deleted instead of removed so they can be audited.
+-- Deleted rows are excluded from the session indexes and the uniqueness constraint.
+ALTER TABLE issue_history ADD COLUMN deleted_at TIMESTAMP;
+
+DROP INDEX idx_issue_history_session_signature;
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(task_signature)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+DROP INDEX idx_issue_history_session_hash;
+CREATE UNIQUE INDEX idx_issue_history_session_hash
+    ON issue_history(hash)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+INSERT INTO schema_migrations (version, name) VALUES (7, '007_soft_delete.sql');
+
+-- Version 8: 008_embedding_model.sql
+
+-- Embedding model
+-- Name of the model that produced each embedding, so vectors from different models
+-- can be told apart. Existing embeddings were all produced by text-embedding-004.
+ALTER TABLE issue_history ADD COLUMN embedding_model TEXT;
+
+UPDATE issue_history SET embedding_model = 'text-embedding-004' WHERE embedding IS NOT NULL;
+
+INSERT INTO schema_migrations (version, name) VALUES (8, '008_embedding_model.sql');
+
+-- Version 9: 009_experience_frequency.sql
+
+-- Experience frequency
+-- Number of times an experience was encountered; near-duplicate saves increment it
+-- instead of inserting a new row.
+ALTER TABLE issue_history ADD COLUMN frequency INTEGER NOT NULL DEFAULT 1;
+
+INSERT INTO schema_migrations (version, name) VALUES (9, '009_experience_frequency.sql');
+
+-- Version 10: 010_occurred_at_index.sql
+
+-- Experience expiry
+-- Experiences older than MAX_EXPERIENCE_AGE_DAYS are excluded from search and purged daily.
+-- The cut-off is configured at runtime, so it is computed in queries rather than stored.
+CREATE INDEX idx_issue_history_occurred_at ON issue_history(occurred_at);
+
+INSERT INTO schema_migrations (version, name) VALUES (10, '010_occurred_at_index.sql');
+
+-- Version 11: 011_keyword_search.sql
+
+-- Keyword search
+-- HybridSearch matches query terms against error patterns with full-text search. The
+-- 'simple' configuration does not stem words, so error codes such as ECONNRESET match exactly.
+CREATE INDEX idx_issue_history_error_pattern_fts ON issue_history USING GIN (to_tsvector('simple', error_pattern));
+
+INSERT INTO schema_migrations (version, name) VALUES (11, '011_keyword_search.sql');
+
+-- Version 12: 012_project_id.sql
+
+-- Project isolation
+-- Experiences and rules belong to the project they were saved for, so that projects
+-- sharing a database do not see each other's memories. Rows with an empty project_id
+-- are shared by all projects; existing rows become shared.
+ALTER TABLE issue_history ADD COLUMN project_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE project_rules ADD COLUMN project_id TEXT NOT NULL DEFAULT '';
+
+-- Session experiences are unique per project
+DROP INDEX idx_issue_history_session_signature;
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(project_id, task_signature)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+DROP INDEX idx_issue_history_session_hash;
+CREATE UNIQUE INDEX idx_issue_history_session_hash
+    ON issue_history(project_id, hash)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+CREATE INDEX idx_issue_history_project_id ON issue_history(project_id);
+CREATE INDEX idx_project_rules_project_id ON project_rules(project_id);
+
+INSERT INTO schema_migrations (version, name) VALUES (12, '012_project_id.sql');
+
+-- Version 13: 013_experience_tags.sql
+
+-- Experience tags
+-- Free-form labels such as "panic" or "timeout" that searches can filter on, narrowing
+-- similarity results to experiences of the same kind.
+ALTER TABLE issue_history ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_issue_history_tags ON issue_history USING GIN (tags);
+
+INSERT INTO schema_migrations (version, name) VALUES (13, '013_experience_tags.sql');
+
+-- Version 14: 014_signature_not_unique.sql
+
+-- Task signatures are not unique
+-- Databases migrated before 005_unique_task_signature.sql was relaxed enforce a unique
+-- task signature per project, which made experiences that only share the first 50 runes
+-- of their pattern collide. Session experiences are unique by content hash only.
+DROP INDEX idx_issue_history_session_signature;
+CREATE INDEX idx_issue_history_session_signature
+    ON issue_history(project_id, task_signature)
+    WHERE source = 'session' AND deleted_at IS NULL;
+
+INSERT INTO schema_migrations (version, name) VALUES (14, '014_signature_not_unique.sql');
+
+-- Version 15: 015_codebase_index_paths.sql
+
+-- Codebase index signatures identify the file
+-- Task signatures of codebase_index rows now start with a hash of the file path, so the
+-- rows of a changed file can be replaced. Rows with the old path-less signature can never
+-- be replaced and are dropped; the next index_codebase run indexes their files again.
+DELETE FROM issue_history
+WHERE source = 'codebase_index' AND task_signature NOT LIKE 'codebase_index:%:%';
+
+INSERT INTO schema_migrations (version, name) VALUES (15, '015_codebase_index_paths.sql');
+
+-- Version 16: 016_sessions.sql
+
+-- Sessions
+-- History of agent conversations started with --session-id, so that they can be resumed
+-- after the process restarts. history is the gob-encoded list of genai.Content turns.
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    history BYTEA NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version, name) VALUES (16, '016_sessions.sql');
+
+-- Version 17: 017_unique_project_rules.sql
+
+-- Unique project rules
+-- A project has each rule at most once per category, so that adding a rule again updates
+-- it instead of duplicating it. Existing duplicates are reduced to their oldest row.
+DELETE FROM project_rules r
+USING project_rules older
+WHERE older.project_id = r.project_id
+  AND older.category = r.category
+  AND older.rule_content = r.rule_content
+  AND older.id < r.id;
+
+ALTER TABLE project_rules
+    ADD CONSTRAINT project_rules_project_category_content_key UNIQUE (project_id, category, rule_content);
+
+INSERT INTO schema_migrations (version, name) VALUES (17, '017_unique_project_rules.sql');
+
+-- Version 18: 018_embedding_meta.sql
+
+-- Embedding meta
+-- Model and dimension of the vectors in issue_history.embedding, checked on startup
+-- against the configured embedding model. The column was created as vector(768) for
+-- text-embedding-004.
+CREATE TABLE embedding_meta (
+    model_name TEXT NOT NULL,
+    dimension INT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO embedding_meta (model_name, dimension) VALUES ('text-embedding-004', 768);
+
+INSERT INTO schema_migrations (version, name) VALUES (18, '018_embedding_meta.sql');
+
+-- Version 19: 019_project_context.sql
+
+-- Project Context
+-- Free-form facts about each project, such as its entry point or CI pipeline URL, that
+-- the agent keeps in its system prompt.
+CREATE TABLE project_context (
+    project_id TEXT NOT NULL DEFAULT '',
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, key)
+);
+
+INSERT INTO schema_migrations (version, name) VALUES (19, '019_project_context.sql');
+
+-- Version 20: 020_session_costs.sql
+
+-- Session Costs
+-- Token usage and estimated API cost of each agent session, recorded when it ends.
+CREATE TABLE session_costs (
+    id SERIAL PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    project_id TEXT NOT NULL DEFAULT '',
+    input_tokens BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    embedding_calls BIGINT NOT NULL DEFAULT 0,
+    embedding_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ended_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_session_costs_ended_at ON session_costs (ended_at DESC);
+
+INSERT INTO schema_migrations (version, name) VALUES (20, '020_session_costs.sql');
+
+-- Version 21: 021_reindexed_rows.sql
+
+-- Reindexed Rows
+-- Number of embedded experiences when the IVFFlat vector index was last rebuilt, so that
+-- it is rebuilt again once the table has grown enough for its lists to be stale.
+ALTER TABLE embedding_meta ADD COLUMN reindexed_rows BIGINT NOT NULL DEFAULT 0;
+
+INSERT INTO schema_migrations (version, name) VALUES (21, '021_reindexed_rows.sql');